@@ -0,0 +1,81 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/savid/iptv/internal/config"
+	"github.com/savid/iptv/internal/data"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+// newGroupsCmd returns the "groups" subcommand, which lists the playlist's
+// groups with their channel counts and tuner URL slugs.
+func newGroupsCmd() *cobra.Command {
+	groupsCfg := config.DefaultConfig()
+	groupsCfg.LogLevel = "warn"
+
+	cmd := &cobra.Command{
+		Use:   "groups",
+		Short: "List the playlist's channel groups",
+		Long: `Fetches the M3U playlist and prints each group-title with its channel
+count and the URL slug of its tuner (e.g. <base-url>/<slug>/discover.json).`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			return runGroups(cmd.Context(), groupsCfg)
+		},
+	}
+
+	cmd.Flags().StringVar(&groupsCfg.M3UURL, "m3u", "", "M3U playlist URL (required)")
+	cmd.Flags().StringVar(&groupsCfg.LogLevel, "log-level", groupsCfg.LogLevel, "Log level (debug, info, warn, error)")
+	cmd.Flags().BoolVar(&groupsCfg.LiveOnly, "live-only", groupsCfg.LiveOnly, "Count live streams only, as the proxy does with --live-only")
+
+	if err := cmd.MarkFlagRequired("m3u"); err != nil {
+		log.WithError(err).Fatal("Failed to mark m3u flag as required")
+	}
+
+	return cmd
+}
+
+func runGroups(ctx context.Context, groupsCfg *config.Config) error {
+	level, err := logrus.ParseLevel(groupsCfg.LogLevel)
+	if err != nil {
+		return err
+	}
+
+	log.SetLevel(level)
+
+	store := data.NewStore()
+
+	if err := data.NewFetcher(log, groupsCfg, store).FetchM3U(ctx); err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+
+	fmt.Fprintln(w, "GROUP\tCHANNELS\tSLUG")
+
+	grouped := 0
+
+	for _, group := range store.GetGroups() {
+		channels, _ := store.GetChannelsByGroup(group)
+		grouped += len(channels)
+
+		fmt.Fprintf(w, "%s\t%d\t%s\n", group, len(channels), store.GroupSlug(group))
+	}
+
+	// Channels without a group-title are only in the all-channels lineup.
+	all, _ := store.GetChannelsByGroup("")
+	if ungrouped := len(all) - grouped; ungrouped > 0 {
+		fmt.Fprintf(w, "(no group)\t%d\t\n", ungrouped)
+	}
+
+	if err := w.Flush(); err != nil {
+		return fmt.Errorf("failed to write groups: %w", err)
+	}
+
+	return nil
+}
@@ -5,6 +5,7 @@ import (
 	"context"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 	"time"
 
@@ -15,8 +16,9 @@ import (
 )
 
 var (
-	cfg = config.DefaultConfig()
-	log = logrus.New()
+	cfg        = config.DefaultConfig()
+	log        = logrus.New()
+	dumpConfig bool
 )
 
 func main() {
@@ -29,17 +31,13 @@ func main() {
 
 	// Required flags
 	rootCmd.Flags().StringVar(&cfg.M3UURL, "m3u", "", "M3U playlist URL (required)")
-	rootCmd.Flags().StringVar(&cfg.EPGURL, "epg", "", "EPG XML URL (required)")
+	rootCmd.Flags().StringVar(&cfg.EPGURL, "epg", "", "EPG XML URL, comma-separated for multiple (required unless --epg-sources is set)")
 	rootCmd.Flags().StringVar(&cfg.BaseURL, "base", "", "Base URL for stream URLs (required)")
 
 	if err := rootCmd.MarkFlagRequired("m3u"); err != nil {
 		log.WithError(err).Fatal("Failed to mark m3u flag as required")
 	}
 
-	if err := rootCmd.MarkFlagRequired("epg"); err != nil {
-		log.WithError(err).Fatal("Failed to mark epg flag as required")
-	}
-
 	if err := rootCmd.MarkFlagRequired("base"); err != nil {
 		log.WithError(err).Fatal("Failed to mark base flag as required")
 	}
@@ -47,15 +45,107 @@ func main() {
 	// Server flags
 	rootCmd.Flags().StringVar(&cfg.BindAddr, "bind", cfg.BindAddr, "Bind address")
 	rootCmd.Flags().IntVar(&cfg.Port, "port", cfg.Port, "Port number")
+	rootCmd.Flags().StringVar(&cfg.Listen, "listen", "", "Listen address as host:port (e.g. :8080, 127.0.0.1:8080, [::1]:8080); overrides --bind and --port")
 	rootCmd.Flags().StringVar(&cfg.LogLevel, "log-level", cfg.LogLevel, "Log level (debug, info, warn, error)")
+	rootCmd.Flags().BoolVar(&dumpConfig, "dump-config", false, "Print the effective configuration as JSON, with secrets redacted, and exit")
+	rootCmd.Flags().StringVar(&cfg.TLSCert, "tls-cert", "", "TLS certificate file (enables HTTPS with --tls-key)")
+	rootCmd.Flags().StringVar(&cfg.TLSKey, "tls-key", "", "TLS private key file (enables HTTPS with --tls-cert)")
+	rootCmd.Flags().StringVar(&cfg.AuthUser, "auth-user", "", "Basic auth username (requires --auth-pass)")
+	rootCmd.Flags().StringVar(&cfg.AuthPass, "auth-pass", "", "Basic auth password (requires --auth-user)")
+	rootCmd.Flags().StringSliceVar(&cfg.AllowCIDRs, "allow-cidr", cfg.AllowCIDRs, "CIDR allowed to access the proxy (repeatable, default: allow all)")
+	rootCmd.Flags().StringSliceVar(&cfg.TrustedProxies, "trusted-proxy", cfg.TrustedProxies, "CIDR of a reverse proxy whose X-Forwarded-For is trusted (repeatable)")
+	rootCmd.Flags().Float64Var(&cfg.TuneRate, "tune-rate", cfg.TuneRate, "Tunes per minute allowed per client IP, with bursts of the same size; excess tunes get 429 (0 disables)")
+	rootCmd.Flags().StringVar(&cfg.AccessLogLevel, "access-log-level", cfg.AccessLogLevel, "Log level for HTTP access logs")
+	rootCmd.Flags().StringSliceVar(&cfg.AccessLogSkip, "access-log-skip", cfg.AccessLogSkip, "Paths to log at debug level only (repeatable)")
 
 	// HDHomeRun flags
 	rootCmd.Flags().IntVar(&cfg.TunerCount, "tuner-count", cfg.TunerCount, "Number of tuners to advertise")
 	rootCmd.Flags().StringVar(&cfg.DeviceID, "device-id", cfg.DeviceID, "Device ID")
 	rootCmd.Flags().StringVar(&cfg.DeviceName, "device-name", cfg.DeviceName, "Device name prefix shown in Plex")
+	rootCmd.Flags().StringVar(&cfg.DeviceAuth, "device-auth", cfg.DeviceAuth, "DeviceAuth token advertised in discover.json")
+	rootCmd.Flags().BoolVar(&cfg.TunerLocks, "tuner-locks", cfg.TunerLocks, "Reserve one of --tuner-count tuners per proxied stream until it ends, rejecting tunes when all are in use (requires --proxy-streams)")
+	rootCmd.Flags().BoolVar(&cfg.LineupHDFlag, "lineup-hd-flag", cfg.LineupHDFlag, "Mark high-definition channels (quality marker or resolution attribute) with HD in lineup.json")
+	rootCmd.Flags().StringVar(&cfg.NumberFormat, "number-format", cfg.NumberFormat, "Lineup guide number template: {n} (lineup position), {group} (group number), {channel} (position in group), e.g. {group}.{channel}")
+	rootCmd.Flags().StringArrayVar(&cfg.ModelRules, "model-rule", cfg.ModelRules, "Advertise a different tuner model to clients whose User-Agent contains a substring, as \"User-Agent=Model[:Firmware]\"; first match wins (repeatable)")
+	rootCmd.Flags().BoolVar(&cfg.LineupLogos, "lineup-logos", cfg.LineupLogos, "Include each channel's tvg-logo as ImageURL in lineup.json (after --data-uri-logos rewriting)")
+	rootCmd.Flags().IntVar(&cfg.ChannelsPerTuner, "channels-per-tuner", cfg.ChannelsPerTuner, "Also serve groups (and the root) with more channels than this as numbered sub-tuners of at most this many channels (0 disables)")
+	rootCmd.Flags().IntVar(&cfg.MaxGroupTuners, "max-group-tuners", cfg.MaxGroupTuners, "Expose only the N largest groups (by channel count) as group tuners; the rest are only on the all-channels device (0 = no limit)")
+	rootCmd.Flags().BoolVar(&cfg.DedupeRootLineup, "dedupe-root-lineup", cfg.DedupeRootLineup, "List channels that appear in several groups only once in the all-channels lineup (by stream URL)")
+	rootCmd.Flags().StringVar(&cfg.DuplicateNames, "duplicate-names", cfg.DuplicateNames, `Channels sharing a name in a lineup: suffix ("Name (2)", also added to the EPG), keep (names unchanged), or merge (list only the first)`)
+
+	// Stream flags
+	rootCmd.Flags().BoolVar(&cfg.ProxyStreams, "proxy-streams", cfg.ProxyStreams, "Relay streams through the proxy instead of redirecting to the upstream URL")
+	rootCmd.Flags().StringVar(&cfg.StreamTokenParam, "stream-token-param", "", "Query parameter set to an auth token on stream URLs when tuning")
+	rootCmd.Flags().StringVar(&cfg.StreamTokenEnv, "stream-token-env", "", "Environment variable holding the stream token")
+	rootCmd.Flags().StringVar(&cfg.StreamTokenFile, "stream-token-file", "", "File holding the stream token, re-read on every tune so it can be refreshed externally")
+	rootCmd.Flags().DurationVar(&cfg.StreamTimeout, "stream-timeout", cfg.StreamTimeout, "Time to wait for upstream response headers when proxying streams")
+	rootCmd.Flags().IntVar(&cfg.MaxConnsPerHost, "max-conns-per-host", cfg.MaxConnsPerHost, "Maximum concurrent proxied stream connections per upstream host; extra tunes wait for a free slot (0 = unlimited)")
+	rootCmd.Flags().StringVar(&cfg.OfflineClip, "offline-clip", "", "MPEG-TS clip looped to the client when a proxied upstream stream fails")
+	rootCmd.Flags().BoolVar(&cfg.ProxyCatchup, "proxy-catchup", cfg.ProxyCatchup, "Point catchup-source in the ?proxy=1 playlist at the proxy's /catchup/ endpoint")
+	rootCmd.Flags().DurationVar(&cfg.ProbeInterval, "probe-interval", cfg.ProbeInterval, "Probe every channel's upstream stream URL this often in the background, see /api/channels.json (0 disables)")
+	rootCmd.Flags().IntVar(&cfg.ProbeConcurrency, "probe-concurrency", cfg.ProbeConcurrency, "Maximum concurrent stream probes")
+	rootCmd.Flags().IntVar(&cfg.ProbeRate, "probe-rate", cfg.ProbeRate, "Maximum stream probes started per second")
+	rootCmd.Flags().IntVar(&cfg.DeadAfter, "dead-after", cfg.DeadAfter, "Consecutive failed probes after which a channel counts as dead")
+	rootCmd.Flags().BoolVar(&cfg.HideDeadChannels, "hide-dead-channels", cfg.HideDeadChannels, "Hide dead channels from lineups until a probe succeeds again (requires --probe-interval)")
 
 	// Data flags
+	rootCmd.Flags().StringArrayVar(&cfg.M3UBackupURLs, "m3u-backup", cfg.M3UBackupURLs, "Backup M3U playlist URL, tried in order when the --m3u URL fails (repeatable)")
 	rootCmd.Flags().DurationVar(&cfg.RefreshInterval, "refresh", cfg.RefreshInterval, "Data refresh interval")
+	rootCmd.Flags().StringVar(&cfg.RefreshAt, "refresh-at", "", "Refresh daily at this local time (HH:MM) instead of every --refresh interval")
+	rootCmd.Flags().DurationVar(&cfg.InitialFetchTimeout, "initial-fetch-timeout", cfg.InitialFetchTimeout, "Deadline for the startup fetch of all sources, so startup fails fast (0 disables)")
+	rootCmd.Flags().BoolVar(&cfg.ResumeDownloads, "resume-downloads", cfg.ResumeDownloads, "Resume interrupted M3U/EPG downloads with Range requests when the server supports them")
+	rootCmd.Flags().Float64Var(&cfg.MaxChannelDropRatio, "max-channel-drop-ratio", cfg.MaxChannelDropRatio, "Reject M3U refreshes that drop more than this fraction of channels versus the last accepted playlist (0 disables)")
+	rootCmd.Flags().StringVar(&cfg.DisabledChannelsFile, "disabled-channels-file", "", "JSON file where channels disabled via the API are persisted")
+	rootCmd.Flags().StringVar(&cfg.RetainDir, "retain-dir", "", "Directory where the last good M3U/EPG is kept and served when a refresh fails, including after a restart")
+
+	// Channel flags
+	rootCmd.Flags().BoolVar(&cfg.LiveOnly, "live-only", cfg.LiveOnly, "Drop VOD entries (positive #EXTINF duration) from the playlist")
+	rootCmd.Flags().BoolVar(&cfg.M3ULenient, "m3u-lenient", cfg.M3ULenient, "Skip malformed M3U entries (an #EXTINF without a URL, or a URL without an #EXTINF) instead of rejecting the playlist; see /api/debug/m3u.json")
+	rootCmd.Flags().BoolVar(&cfg.CleanNames, "clean-names", cfg.CleanNames, "Trim whitespace and strip zero-width/control characters from M3U channel names and EPG display-names")
+	rootCmd.Flags().BoolVar(&cfg.NormalizeGroups, "normalize-groups", cfg.NormalizeGroups, "Merge group-titles that differ only in whitespace or case (e.g. \"US  Sports\" and \"us sports\")")
+	rootCmd.Flags().BoolVar(&cfg.TitleCaseGroups, "title-case-groups", cfg.TitleCaseGroups, "Capitalize each word of normalized group names (requires --normalize-groups)")
+	rootCmd.Flags().BoolVar(&cfg.CollapseQualityVariants, "collapse-quality-variants", cfg.CollapseQualityVariants, "Keep only the highest-quality variant of duplicate channels (e.g. ESPN HD over ESPN)")
+	rootCmd.Flags().StringSliceVar(&cfg.QualityRanking, "quality-ranking", cfg.QualityRanking, `Quality markers from best to worst, used for variant collapsing and EPG match tiebreaks; "" stands for no marker (default UHD,4K,FHD,HD,"",SD)`)
+
+	// Status logging flags
+	rootCmd.Flags().DurationVar(&cfg.StatusInterval, "status-interval", cfg.StatusInterval, "Interval for the tuner status summary log (0 disables)")
+	rootCmd.Flags().IntVar(&cfg.StatusMinChannels, "status-min-channels", cfg.StatusMinChannels, "Only list groups with at least this many channels in the startup breakdown")
+
+	rootCmd.Flags().DurationVar(&cfg.TuneWindow, "tune-window", cfg.TuneWindow, "Sliding window for counting recent tune requests in /health")
+
+	// EPG flags
+	rootCmd.Flags().StringVar(&cfg.EPGSourcesFile, "epg-sources", "", "JSON file of EPG sources with per-source options (replaces --epg)")
+	rootCmd.Flags().StringArrayVar(&cfg.EPGInline, "epg-inline", cfg.EPGInline, "EPG content supplied directly as @/path/to/file.xml or a data: URI, merged after other sources (repeatable)")
+	rootCmd.Flags().StringVar(&cfg.EPGOverride, "epg-override", "", "Hand-curated XMLTV file merged at the highest priority, ahead of every other EPG source")
+	rootCmd.Flags().Float64Var(&cfg.MinMatchRate, "min-match-rate", cfg.MinMatchRate, "Reject EPG refreshes where fewer than this fraction of channels match (0 disables)")
+	rootCmd.Flags().StringVar(&cfg.EPGFailure, "epg-failure", cfg.EPGFailure, "When every EPG source fails: keep (last good EPG), fake (placeholders only), or fail")
+	rootCmd.Flags().StringSliceVar(&cfg.MatchOrder, "match-order", cfg.MatchOrder, "EPG matching strategies to run, in order (tvgid, display, whitespace, normalized; default all four in that order)")
+	rootCmd.Flags().StringArrayVar(&cfg.ForceFakeEPG, "force-fake-epg", cfg.ForceFakeEPG, "Channel name or tvg-id that always gets placeholder EPG (repeatable)")
+	rootCmd.Flags().BoolVar(&cfg.RepairEPG, "repair-epg", cfg.RepairEPG, "Trim or drop overlapping programmes on the same channel within each EPG source")
+	rootCmd.Flags().BoolVar(&cfg.FillStaleChannels, "fill-stale-channels", cfg.FillStaleChannels, "Add a current placeholder programme to channels whose guide data has all ended")
+	rootCmd.Flags().StringArrayVar(&cfg.MapChannels, "map-channel", cfg.MapChannels, `Explicitly match a channel to an EPG ID: "Channel Name=epg.id" (repeatable)`)
+	rootCmd.Flags().StringArrayVar(&cfg.EPGAliases, "epg-alias", cfg.EPGAliases, `Copy one channel's guide to another: "Alias Name=Source Name" (repeatable)`)
+	rootCmd.Flags().StringArrayVar(&cfg.CategoryMaps, "category-map", cfg.CategoryMaps, `Map an M3U group to the programme category in the EPG: "US Sports=Sports" (repeatable)`)
+	rootCmd.Flags().StringArrayVar(&cfg.BlockCategories, "block-category", cfg.BlockCategories, "Drop EPG programmes whose category matches, case-insensitive (repeatable)")
+	rootCmd.Flags().BoolVar(&cfg.BlockCategoryDropChannels, "block-category-drop-channels", cfg.BlockCategoryDropChannels, "Also drop EPG channels whose programmes were all removed by --block-category")
+
+	// EPG output flags
+	rootCmd.Flags().DurationVar(&cfg.CacheMaxAge, "cache-max-age", cfg.CacheMaxAge, "Cache-Control max-age for M3U/EPG responses (default: refresh interval)")
+	rootCmd.Flags().IntVar(&cfg.MaxDescLength, "max-desc-length", cfg.MaxDescLength, "Truncate programme descriptions to this many characters in EPG output (0 = unlimited)")
+	rootCmd.Flags().IntVar(&cfg.MaxProgrammesPerChannel, "max-programmes-per-channel", cfg.MaxProgrammesPerChannel, "Keep only the current and soonest upcoming programmes per channel, up to this many (0 = unlimited)")
+	rootCmd.Flags().BoolVar(&cfg.MarkNew, "mark-new", cfg.MarkNew, "Flag upcoming programmes that have no <previously-shown> as <new/>, so Plex can record new episodes only")
+	rootCmd.Flags().BoolVar(&cfg.EPGCompact, "epg-compact", cfg.EPGCompact, "Write EPG output without indentation to reduce its size")
+	rootCmd.Flags().StringVar(&cfg.EPGGeneratorName, "epg-generator-name", cfg.EPGGeneratorName, "generator-info-name attribute on <tv> in EPG output (empty omits it)")
+	rootCmd.Flags().StringVar(&cfg.EPGGeneratorURL, "epg-generator-url", cfg.EPGGeneratorURL, "generator-info-url attribute on <tv> in EPG output (empty omits it)")
+	rootCmd.Flags().StringVar(&cfg.DataURILogos, "data-uri-logos", cfg.DataURILogos, "Handling of logos embedded as data URIs: pass, strip, or serve (decode and serve from /logos/)")
+	rootCmd.Flags().BoolVar(&cfg.PreserveTVGID, "preserve-tvg-id", cfg.PreserveTVGID, "Keep the upstream tvg-id in the rewritten M3U instead of the matched EPG channel ID")
+	rootCmd.Flags().BoolVar(&cfg.M3UChannelNumbers, "m3u-chno", cfg.M3UChannelNumbers, "Set tvg-chno in the rewritten M3U to each channel's lineup guide number, so other players number channels as Plex does")
+	rootCmd.Flags().StringVar(&cfg.WriteM3U, "write-m3u", "", "Also write the rewritten M3U to this file after each refresh")
+	rootCmd.Flags().StringVar(&cfg.WriteEPG, "write-epg", "", "Also write the EPG to this file after each refresh")
+	rootCmd.Flags().BoolVar(&cfg.EPGSortChannels, "epg-sort-channels", cfg.EPGSortChannels, "Order EPG channels to match the lineup order")
+	rootCmd.Flags().BoolVar(&cfg.EPGGuideNumbers, "epg-guide-numbers", cfg.EPGGuideNumbers, "Add each channel's lineup guide number as an extra display-name in the EPG")
+
+	rootCmd.AddCommand(newGroupsCmd())
 
 	if err := rootCmd.Execute(); err != nil {
 		os.Exit(1)
@@ -75,6 +165,14 @@ func run(cmd *cobra.Command, args []string) error {
 		TimestampFormat: time.RFC3339,
 	})
 
+	if dumpConfig {
+		return cfg.Dump(cmd.OutOrStdout())
+	}
+
+	// Advertised URLs are built as BaseURL + "/path", so a trailing slash
+	// (e.g. "https://host/iptv/") would double up.
+	cfg.BaseURL = strings.TrimRight(cfg.BaseURL, "/")
+
 	// Validate config
 	if err := cfg.Validate(); err != nil {
 		return err
@@ -87,7 +185,10 @@ func run(cmd *cobra.Command, args []string) error {
 	}).Info("Starting IPTV proxy")
 
 	// Create and start server
-	srv := server.NewServer(log, cfg)
+	srv, err := server.NewServer(log, cfg)
+	if err != nil {
+		return err
+	}
 
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
@@ -2,11 +2,12 @@
 package main
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"os"
-	"sort"
 	"strings"
 	"time"
 
@@ -19,10 +20,12 @@ import (
 const noProgramsMsg = "NO PROGRAMS"
 
 var (
-	m3uPath  string
-	epgPath  string
-	logLevel string
-	log      = logrus.New()
+	m3uPath      string
+	epgPath      string
+	logLevel     string
+	jsonOutput   bool
+	baselinePath string
+	log          = logrus.New()
 )
 
 func main() {
@@ -42,13 +45,19 @@ Examples:
   go run cmd/matcher/main.go --m3u testdata/channels.m3u --epg testdata/epg.xml
 
   # Using URLs
-  go run cmd/matcher/main.go --m3u https://example.com/playlist.m3u --epg https://epg.example.com/epg.xml`,
+  go run cmd/matcher/main.go --m3u https://example.com/playlist.m3u --epg https://epg.example.com/epg.xml
+
+  # Save a JSON report, then check a later run against it
+  go run cmd/matcher/main.go --m3u channels.m3u --epg epg.xml --json > baseline.json
+  go run cmd/matcher/main.go --m3u channels.m3u --epg epg.xml --baseline baseline.json`,
 		RunE: run,
 	}
 
 	rootCmd.Flags().StringVar(&m3uPath, "m3u", "", "Path or URL to M3U playlist (required)")
 	rootCmd.Flags().StringVar(&epgPath, "epg", "", "Path or URL to EPG XML (required)")
 	rootCmd.Flags().StringVar(&logLevel, "log-level", "debug", "Log level (debug, info, warn, error)")
+	rootCmd.Flags().BoolVar(&jsonOutput, "json", false, "Print the match report as JSON")
+	rootCmd.Flags().StringVar(&baselinePath, "baseline", "", "JSON report from a previous run to compare against; exits non-zero if any channel became unmatched")
 
 	if err := rootCmd.MarkFlagRequired("m3u"); err != nil {
 		log.WithError(err).Fatal("Failed to mark m3u flag as required")
@@ -128,125 +137,146 @@ func run(cmd *cobra.Command, args []string) error {
 		"programmes": len(epgTV.Programs),
 	}).Info("Parsed EPG data")
 
+	var baseline *epg.MatchReport
+
+	if baselinePath != "" {
+		if baseline, err = loadReport(baselinePath); err != nil {
+			return err
+		}
+	}
+
 	// Run the actual Filter function from internal/epg
-	fmt.Println("\n" + strings.Repeat("=", 80))
-	fmt.Println("RUNNING EPG FILTER (internal/epg.Filter)")
-	fmt.Println(strings.Repeat("=", 80))
+	if !jsonOutput {
+		fmt.Println("\n" + strings.Repeat("=", 80))
+		fmt.Println("RUNNING EPG FILTER (internal/epg.Filter)")
+		fmt.Println(strings.Repeat("=", 80))
+	}
 
 	filteredEPG, channelIDMap := epg.Filter(log, epgTV, m3uChannels)
 
 	// Analyze and print results
-	analyzeResults(m3uChannels, epgTV, filteredEPG, channelIDMap)
+	report := epg.AnalyzeMatches(m3uChannels, epgTV.Channels, filteredEPG, channelIDMap)
 
-	return nil
-}
+	if jsonOutput {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
 
-// analyzeResults prints detailed matching analysis.
-func analyzeResults(m3uChannels []m3u.Channel, originalEPG, filteredEPG *epg.TV, channelIDMap map[string]string) {
-	// Build program count map
-	programCount := make(map[string]int, len(filteredEPG.Channels))
+		if err := encoder.Encode(report); err != nil {
+			return fmt.Errorf("failed to encode report: %w", err)
+		}
+	} else {
+		printReport(report)
+	}
 
-	for _, prog := range filteredEPG.Programs {
-		programCount[prog.Channel]++
+	if baseline == nil {
+		return nil
 	}
 
-	// Build reverse map: M3U name -> EPG channel
-	m3uToEPG := make(map[string]*epg.Channel, len(filteredEPG.Channels))
+	diff := epg.DiffReports(baseline, report)
 
-	for i := range filteredEPG.Channels {
-		ch := &filteredEPG.Channels[i]
-		if m3uName, ok := channelIDMap[ch.ID]; ok {
-			m3uToEPG[m3uName] = ch
-		}
+	if !jsonOutput {
+		printDiff(diff)
 	}
 
-	// Categorize results
-	var matched, unmatched []m3u.Channel
+	if diff.Regressed() {
+		cmd.SilenceUsage = true
 
-	for _, m3uCh := range m3uChannels {
-		if _, ok := m3uToEPG[m3uCh.Name]; ok {
-			matched = append(matched, m3uCh)
-		} else {
-			unmatched = append(unmatched, m3uCh)
-		}
+		return fmt.Errorf("%d channel(s) regressed from matched to unmatched: %s",
+			len(diff.NewlyUnmatched), strings.Join(diff.NewlyUnmatched, ", "))
 	}
 
-	// Print matched channels
-	fmt.Println("\n" + strings.Repeat("-", 80))
-	fmt.Printf("MATCHED CHANNELS (%d/%d)\n", len(matched), len(m3uChannels))
-	fmt.Println(strings.Repeat("-", 80))
+	return nil
+}
 
-	// Group by match type (infer from tvg-id presence and name comparison)
-	var byTVGID, byDisplayName, byNormalized []m3u.Channel
+// loadReport reads a JSON match report saved with --json.
+func loadReport(path string) (*epg.MatchReport, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read baseline: %w", err)
+	}
 
-	for _, m3uCh := range matched {
-		epgCh := m3uToEPG[m3uCh.Name]
+	var report epg.MatchReport
+	if err := json.Unmarshal(content, &report); err != nil {
+		return nil, fmt.Errorf("failed to parse baseline %s: %w", path, err)
+	}
 
-		if m3uCh.TVGID != "" && epgCh.ID == m3uCh.TVGID {
-			byTVGID = append(byTVGID, m3uCh)
-		} else if m3uCh.Name == epgCh.DisplayName {
-			byDisplayName = append(byDisplayName, m3uCh)
-		} else {
-			byNormalized = append(byNormalized, m3uCh)
-		}
+	if report.Matched == nil && report.Unmatched == nil {
+		return nil, errors.New("baseline has no matched or unmatched channels; was it saved with --json?")
 	}
 
-	// Print by category
-	if len(byTVGID) > 0 {
-		fmt.Printf("\n  [TVG-ID] (%d channels)\n", len(byTVGID))
+	return &report, nil
+}
 
-		for _, m3uCh := range byTVGID {
-			epgCh := m3uToEPG[m3uCh.Name]
-			progCount := programCount[epgCh.ID]
-			programInfo := fmt.Sprintf("%d programs", progCount)
+// printDiff prints the change in matching since the baseline.
+func printDiff(diff *epg.MatchDiff) {
+	fmt.Println("\n" + strings.Repeat("=", 80))
+	fmt.Println("CHANGES SINCE BASELINE")
+	fmt.Println(strings.Repeat("=", 80))
 
-			if progCount == 0 {
-				programInfo = noProgramsMsg
-			}
+	if len(diff.NewlyMatched) == 0 && len(diff.NewlyUnmatched) == 0 && len(diff.StrategyChanged) == 0 {
+		fmt.Println("  No changes")
 
-			fmt.Printf("    %-40s -> %-30s [%s]\n",
-				truncate(m3uCh.Name, 40),
-				truncate(epgCh.DisplayName, 30),
-				programInfo,
-			)
-		}
+		return
 	}
 
-	if len(byDisplayName) > 0 {
-		fmt.Printf("\n  [DISPLAY-NAME] (%d channels)\n", len(byDisplayName))
+	fmt.Printf("  Newly matched (%d):\n", len(diff.NewlyMatched))
 
-		for _, m3uCh := range byDisplayName {
-			epgCh := m3uToEPG[m3uCh.Name]
-			progCount := programCount[epgCh.ID]
-			programInfo := fmt.Sprintf("%d programs", progCount)
+	for _, name := range diff.NewlyMatched {
+		fmt.Printf("    + %s\n", name)
+	}
 
-			if progCount == 0 {
-				programInfo = noProgramsMsg
-			}
+	fmt.Printf("  Newly unmatched (%d):\n", len(diff.NewlyUnmatched))
 
-			fmt.Printf("    %-40s -> %-30s [%s]\n",
-				truncate(m3uCh.Name, 40),
-				truncate(epgCh.DisplayName, 30),
-				programInfo,
-			)
-		}
+	for _, name := range diff.NewlyUnmatched {
+		fmt.Printf("    - %s\n", name)
+	}
+
+	fmt.Printf("  Strategy changed (%d):\n", len(diff.StrategyChanged))
+
+	for _, change := range diff.StrategyChanged {
+		fmt.Printf("    ~ %-40s %s -> %s\n", truncate(change.Name, 40), change.From, change.To)
 	}
+}
 
-	if len(byNormalized) > 0 {
-		fmt.Printf("\n  [NORMALIZED] (%d channels)\n", len(byNormalized))
+// printReport prints the matching analysis.
+func printReport(report *epg.MatchReport) {
+	summary := report.Summary
+
+	// Print matched channels
+	fmt.Println("\n" + strings.Repeat("-", 80))
+	fmt.Printf("MATCHED CHANNELS (%d/%d)\n", summary.Matched, summary.Total)
+	fmt.Println(strings.Repeat("-", 80))
+
+	sections := []struct {
+		label    string
+		strategy string
+	}{
+		{"TVG-ID", epg.MatchTVGID},
+		{"DISPLAY-NAME", epg.MatchDisplayName},
+		{"WHITESPACE", epg.MatchWhitespace},
+		{"NORMALIZED", epg.MatchNormalizedName},
+	}
+
+	for _, section := range sections {
+		if summary.ByStrategy[section.strategy] == 0 {
+			continue
+		}
 
-		for _, m3uCh := range byNormalized {
-			epgCh := m3uToEPG[m3uCh.Name]
-			progCount := programCount[epgCh.ID]
-			programInfo := fmt.Sprintf("%d programs", progCount)
+		fmt.Printf("\n  [%s] (%d channels)\n", section.label, summary.ByStrategy[section.strategy])
 
-			if progCount == 0 {
+		for _, ch := range report.Matched {
+			if ch.Strategy != section.strategy {
+				continue
+			}
+
+			programInfo := fmt.Sprintf("%d programs", ch.Programmes)
+			if ch.Programmes == 0 {
 				programInfo = noProgramsMsg
 			}
 
 			fmt.Printf("    %-40s -> %-30s [%s]\n",
-				truncate(m3uCh.Name, 40),
-				truncate(epgCh.DisplayName, 30),
+				truncate(ch.Name, 40),
+				truncate(ch.EPGDisplayName, 30),
 				programInfo,
 			)
 		}
@@ -254,21 +284,20 @@ func analyzeResults(m3uChannels []m3u.Channel, originalEPG, filteredEPG *epg.TV,
 
 	// Print unmatched channels
 	fmt.Println("\n" + strings.Repeat("-", 80))
-	fmt.Printf("UNMATCHED CHANNELS (%d/%d)\n", len(unmatched), len(m3uChannels))
+	fmt.Printf("UNMATCHED CHANNELS (%d/%d)\n", summary.Unmatched, summary.Total)
 	fmt.Println(strings.Repeat("-", 80))
 
-	if len(unmatched) == 0 {
+	if len(report.Unmatched) == 0 {
 		fmt.Println("  All channels matched!")
 	} else {
-		for _, m3uCh := range unmatched {
-			fmt.Printf("\n  %s\n", m3uCh.Name)
-			fmt.Printf("    tvg-id: %q\n", m3uCh.TVGID)
+		for _, ch := range report.Unmatched {
+			fmt.Printf("\n  %s\n", ch.Name)
+			fmt.Printf("    tvg-id: %q\n", ch.TVGID)
 
-			closeMatches := findClosestMatches(m3uCh.Name, originalEPG.Channels)
-			if len(closeMatches) > 0 {
+			if len(ch.CloseMatches) > 0 {
 				fmt.Println("    close matches in EPG:")
 
-				for _, match := range closeMatches {
+				for _, match := range ch.CloseMatches {
 					fmt.Printf("      - %s\n", match)
 				}
 			} else {
@@ -282,95 +311,38 @@ func analyzeResults(m3uChannels []m3u.Channel, originalEPG, filteredEPG *epg.TV,
 	fmt.Println("SUMMARY")
 	fmt.Println(strings.Repeat("=", 80))
 
-	matchRate := float64(len(matched)) / float64(len(m3uChannels)) * 100
-
-	fmt.Printf("  Total M3U channels:  %d\n", len(m3uChannels))
-	fmt.Printf("  Matched:             %d (%.1f%%)\n", len(matched), matchRate)
-	fmt.Printf("  Unmatched:           %d\n", len(unmatched))
+	fmt.Printf("  Total M3U channels:  %d\n", summary.Total)
+	fmt.Printf("  Matched:             %d (%.1f%%)\n", summary.Matched, summary.MatchRate*100)
+	fmt.Printf("  Unmatched:           %d\n", summary.Unmatched)
 	fmt.Println()
 	fmt.Printf("  By strategy:\n")
-	fmt.Printf("    tvg-id:       %d\n", len(byTVGID))
-	fmt.Printf("    display-name: %d\n", len(byDisplayName))
-	fmt.Printf("    normalized:   %d\n", len(byNormalized))
-
-	// Count channels with real programs (not fake)
-	withPrograms := 0
-
-	for _, m3uCh := range matched {
-		epgCh := m3uToEPG[m3uCh.Name]
-		if programCount[epgCh.ID] > 1 { // More than just the fake program
-			withPrograms++
-		}
-	}
+	fmt.Printf("    tvg-id:       %d\n", summary.ByStrategy[epg.MatchTVGID])
+	fmt.Printf("    display-name: %d\n", summary.ByStrategy[epg.MatchDisplayName])
+	fmt.Printf("    whitespace:   %d\n", summary.ByStrategy[epg.MatchWhitespace])
+	fmt.Printf("    normalized:   %d\n", summary.ByStrategy[epg.MatchNormalizedName])
 
 	fmt.Println()
-	fmt.Printf("  Matched with programs: %d\n", withPrograms)
-	fmt.Printf("  Matched without programs: %d\n", len(matched)-withPrograms)
+	fmt.Printf("  Matched with programs: %d\n", summary.WithProgrammes)
+	fmt.Printf("  Matched without programs: %d\n", summary.WithoutProgrammes)
 
 	fmt.Println(strings.Repeat("=", 80))
 }
 
-// findClosestMatches finds EPG channels with similar names using simple token matching.
-func findClosestMatches(m3uName string, epgChannels []epg.Channel) []string {
-	// Simple tokenization for matching
-	m3uLower := strings.ToLower(m3uName)
-	tokens := strings.Fields(m3uLower)
-
-	if len(tokens) == 0 {
-		return nil
-	}
-
-	type scored struct {
-		name  string
-		score int
-	}
-
-	candidates := make([]scored, 0, 10)
-
-	for _, ch := range epgChannels {
-		epgLower := strings.ToLower(ch.DisplayName)
-		epgTokens := strings.Fields(epgLower)
-
-		// Count matching tokens
-		matches := 0
-
-		for _, t1 := range tokens {
-			for _, t2 := range epgTokens {
-				if t1 == t2 {
-					matches++
-
-					break
-				}
-			}
-		}
-
-		if matches > 0 {
-			candidates = append(candidates, scored{
-				name:  ch.DisplayName,
-				score: matches,
-			})
-		}
+// truncate shortens s to at most maxLen runes, ending in "..." when there is
+// room for it.
+func truncate(s string, maxLen int) string {
+	runes := []rune(s)
+	if len(runes) <= maxLen {
+		return s
 	}
 
-	// Sort by score (descending)
-	sort.Slice(candidates, func(i, j int) bool {
-		return candidates[i].score > candidates[j].score
-	})
-
-	// Return top 5
-	result := make([]string, 0, 5)
-
-	for i := 0; i < len(candidates) && i < 5; i++ {
-		result = append(result, candidates[i].name)
+	if maxLen <= 0 {
+		return ""
 	}
 
-	return result
-}
-
-func truncate(s string, maxLen int) string {
-	if len(s) <= maxLen {
-		return s
+	if maxLen <= 3 {
+		return string(runes[:maxLen])
 	}
 
-	return s[:maxLen-3] + "..."
+	return string(runes[:maxLen-3]) + "..."
 }
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"testing"
+	"unicode/utf8"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTruncate(t *testing.T) {
+	tests := []struct {
+		name   string
+		input  string
+		maxLen int
+		want   string
+	}{
+		{"fits", "ESPN", 10, "ESPN"},
+		{"exact", "ESPN", 4, "ESPN"},
+		{"ascii", "Discovery Channel", 10, "Discove..."},
+		{"unicode", "Télé Zürich Sport HD", 10, "Télé Zü..."},
+		{"unicode fits", "Télé Zürich", 11, "Télé Zürich"},
+		{"max 3", "Télé Zürich", 3, "Tél"},
+		{"max 2", "Télé Zürich", 2, "Té"},
+		{"max 1", "Télé Zürich", 1, "T"},
+		{"max 0", "Télé Zürich", 0, ""},
+		{"short name", "É", 1, "É"},
+		{"empty", "", 3, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := truncate(tt.input, tt.maxLen)
+
+			require.Equal(t, tt.want, got)
+			require.True(t, utf8.ValidString(got))
+		})
+	}
+}
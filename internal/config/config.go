@@ -4,9 +4,35 @@ package config
 import (
 	"errors"
 	"fmt"
+	"net"
 	"net/url"
+	"os"
+	"strconv"
 	"strings"
 	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Ways of handling logos embedded as data URIs.
+const (
+	DataURILogosPass  = "pass"  // Publish data URIs unchanged.
+	DataURILogosStrip = "strip" // Remove data URI logos.
+	DataURILogosServe = "serve" // Decode and serve them from the proxy.
+)
+
+// Behaviors when every EPG source fails during a refresh.
+const (
+	EPGFailureKeep = "keep" // Keep the last good EPG (placeholders if there is none).
+	EPGFailureFake = "fake" // Replace the EPG with placeholders only.
+	EPGFailureFail = "fail" // Fail the refresh (and startup).
+)
+
+// Handling of channels that share a name within a lineup.
+const (
+	DuplicateNamesSuffix = "suffix" // Name later occurrences "Name (2)", "Name (3)", ...
+	DuplicateNamesKeep   = "keep"   // Keep every name as is; only the guide numbers differ.
+	DuplicateNamesMerge  = "merge"  // List only the first channel with each name.
 )
 
 // Config holds the application configuration.
@@ -16,30 +42,237 @@ type Config struct {
 	EPGURL  string
 	BaseURL string
 
+	// Playlists tried in order when the M3U URL fails
+	M3UBackupURLs []string
+
+	// Structured EPG sources (JSON file); replaces EPGURL when set
+	EPGSourcesFile string
+
+	// EPG content supplied inline ("@/path/file.xml" or a data: URI)
+	EPGInline []string
+
+	// Hand-curated XMLTV file merged ahead of every other EPG source
+	EPGOverride string
+
 	// Server
 	BindAddr string
 	Port     int
+	Listen   string // "host:port"; overrides BindAddr and Port when set
 	LogLevel string
 
+	// TLS (both must be set to serve HTTPS)
+	TLSCert string
+	TLSKey  string
+
+	// Basic auth (both must be set to enable)
+	AuthUser string
+	AuthPass string
+
+	// IP allowlist (empty = allow all)
+	AllowCIDRs     []string
+	TrustedProxies []string
+
+	// Tunes per minute allowed per client IP (0 = unlimited)
+	TuneRate float64
+
+	// Access logging
+	AccessLogLevel string
+	AccessLogSkip  []string
+
 	// HDHomeRun
 	TunerCount int
 	DeviceID   string
 	DeviceName string
+	DeviceAuth string
+
+	// Reserve one of TunerCount tuners per proxied stream, rejecting tunes
+	// when all are in use
+	TunerLocks bool
+
+	// Drop channels with duplicate stream URLs from the root (all channels) lineup
+	DedupeRootLineup bool
+
+	// Handling of channels that share a name within a lineup (suffix, keep, merge)
+	DuplicateNames string
+
+	// Also serve groups (and the root) larger than this as numbered
+	// sub-tuners of at most this many channels (0 = never split)
+	ChannelsPerTuner int
+
+	// Expose only this many groups (the largest by channel count) as their
+	// own tuner devices; the rest are only on the root (0 = no limit)
+	MaxGroupTuners int
+
+	// Set the HD field on lineup entries for high-definition channels
+	LineupHDFlag bool
+
+	// Set ImageURL on lineup entries from the channel tvg-logo
+	LineupLogos bool
+
+	// Lineup GuideNumber template (m3u.Number* placeholders, empty = "{n}")
+	NumberFormat string
+
+	// Per-User-Agent tuner model overrides ("User-Agent=Model[:Firmware]")
+	ModelRules []string
+
+	// Stream proxying (relay streams instead of redirecting to upstream)
+	ProxyStreams  bool
+	StreamTimeout time.Duration
+	OfflineClip   string // MPEG-TS clip looped when the upstream stream fails
+
+	// Maximum concurrent proxied upstream connections per host (0 = unlimited)
+	MaxConnsPerHost int
+
+	// Point catchup-source in the ?proxy=1 playlist at /catchup/
+	ProxyCatchup bool
+
+	// Background stream probing (0 interval = never): concurrent probes,
+	// probes started per second, and consecutive failures before a channel
+	// counts as dead
+	ProbeInterval    time.Duration
+	ProbeConcurrency int
+	ProbeRate        int
+	DeadAfter        int
+
+	// Hide dead channels from lineups
+	HideDeadChannels bool
+
+	// Stream URL auth token, set as a query parameter when tuning
+	StreamTokenParam string
+	StreamTokenEnv   string
+	StreamTokenFile  string
 
 	// Data refresh
-	RefreshInterval time.Duration
+	RefreshInterval     time.Duration
+	RefreshAt           string // Daily wall-clock refresh time ("HH:MM"), replaces the interval
+	InitialFetchTimeout time.Duration
+
+	// Resume interrupted downloads with Range requests
+	ResumeDownloads bool
+
+	// File the disabled channel set is persisted to (empty = in-memory only)
+	DisabledChannelsFile string
+
+	// Directory the last good M3U/EPG is retained in across restarts (empty = disabled)
+	RetainDir string
+
+	// Status logging
+	StatusInterval    time.Duration
+	StatusMinChannels int
+	TuneWindow        time.Duration
+
+	// Drop VOD entries (positive #EXTINF duration) from the playlist
+	LiveOnly bool
+
+	// Skip malformed M3U entries instead of rejecting the playlist
+	M3ULenient bool
+
+	// Trim whitespace and strip invisible characters from channel names
+	CleanNames bool
+
+	// Merge group-titles differing only in whitespace or case
+	NormalizeGroups bool
+	TitleCaseGroups bool
+
+	// Quality variant collapsing (e.g. "ESPN" vs "ESPN HD")
+	CollapseQualityVariants bool
+	QualityRanking          []string
+
+	// Minimum fraction of M3U channels with real EPG data to accept a refresh
+	MinMatchRate float64
+
+	// Largest fraction of channels an M3U refresh may drop versus the last
+	// accepted playlist (0 = disabled)
+	MaxChannelDropRatio float64
+
+	// What to do when every EPG source fails (EPGFailure*)
+	EPGFailure string
+
+	// EPG matching strategies to run, in order (empty = default order)
+	MatchOrder []string
+
+	// Channels (name or tvg-id) that always get placeholder EPG data
+	ForceFakeEPG []string
+
+	// Give channels whose programmes have all ended a current placeholder
+	FillStaleChannels bool
+
+	// Trim or drop overlapping programmes within each channel of a source
+	RepairEPG bool
+
+	// Explicit channel → EPG ID mappings ("Channel Name=epg.id")
+	MapChannels []string
+
+	// EPG aliases ("Alias Name=Source Name")
+	EPGAliases []string
+
+	// Group → programme category mappings ("US Sports=Sports")
+	CategoryMaps []string
+
+	// Drop programmes in these categories (case-insensitive), and with
+	// BlockCategoryDropChannels any channel left without programmes
+	BlockCategories           []string
+	BlockCategoryDropChannels bool
+
+	// EPG output
+	EPGSortChannels bool
+	EPGGuideNumbers bool // Add each channel's lineup number as a second display-name
+	MaxDescLength   int  // 0 = unlimited
+
+	// Programmes kept per channel, soonest first (0 = unlimited)
+	MaxProgrammesPerChannel int
+
+	// Flag upcoming programmes without <previously-shown> as <new/>
+	MarkNew bool
+
+	// <tv> generator-info attributes in EPG output (empty omits them)
+	EPGGeneratorName string
+	EPGGeneratorURL  string
+
+	// Write EPG output without indentation
+	EPGCompact bool
+
+	// Keep upstream tvg-ids in the rewritten M3U instead of matched EPG IDs
+	PreserveTVGID bool
+
+	// Emit each channel's lineup guide number as tvg-chno in the rewritten M3U
+	M3UChannelNumbers bool
+
+	// Handling of data URI logos (pass, strip, serve)
+	DataURILogos string
+
+	// Files the rewritten M3U/EPG are written to after each refresh
+	WriteM3U string
+	WriteEPG string
+
+	// HTTP caching (0 = use RefreshInterval)
+	CacheMaxAge time.Duration
 }
 
 // DefaultConfig returns a config with sensible defaults.
 func DefaultConfig() *Config {
 	return &Config{
-		BindAddr:        "0.0.0.0",
-		Port:            8080,
-		LogLevel:        "info",
-		TunerCount:      2,
-		DeviceID:        "iptv-proxy-001",
-		DeviceName:      "IPTV-Proxy",
-		RefreshInterval: 30 * time.Minute,
+		BindAddr:         "0.0.0.0",
+		Port:             8080,
+		LogLevel:         "info",
+		AccessLogLevel:   "info",
+		DataURILogos:     DataURILogosPass,
+		DuplicateNames:   DuplicateNamesSuffix,
+		EPGFailure:       EPGFailureKeep,
+		CleanNames:       true,
+		EPGGeneratorName: "iptv-proxy",
+		EPGGeneratorURL:  "https://github.com/savid/iptv",
+		TunerCount:       2,
+		DeviceID:         "iptv-proxy-001",
+		DeviceAuth:       "iptv-proxy",
+		DeviceName:       "IPTV-Proxy",
+		RefreshInterval:  30 * time.Minute,
+		StatusInterval:   1 * time.Minute,
+		TuneWindow:       5 * time.Minute,
+		StreamTimeout:    30 * time.Second,
+		ProbeConcurrency: 4,
+		ProbeRate:        10,
+		DeadAfter:        3,
 	}
 }
 
@@ -53,19 +286,37 @@ func (c *Config) Validate() error {
 		return fmt.Errorf("invalid M3U URL: %w", err)
 	}
 
-	if c.EPGURL == "" {
+	for _, backup := range c.M3UBackupURLs {
+		if backup == "" {
+			return errors.New("--m3u-backup must not be empty")
+		}
+
+		if _, err := url.Parse(backup); err != nil {
+			return fmt.Errorf("invalid M3U backup URL: %w", err)
+		}
+	}
+
+	if c.EPGURL == "" && c.EPGSourcesFile == "" && len(c.EPGInline) == 0 && c.EPGOverride == "" {
 		return errors.New("--epg is required")
 	}
 
-	epgURLs := c.EPGURLs()
-	if len(epgURLs) == 0 {
+	for _, inline := range c.EPGInline {
+		if !IsInlineSource(inline) {
+			return fmt.Errorf("invalid --epg-inline %q: must start with @ or data:", inline)
+		}
+	}
+
+	if c.EPGURL != "" && c.EPGSourcesFile == "" && len(c.EPGURLs()) == 0 {
 		return errors.New("--epg must contain at least one valid URL")
 	}
 
-	for i, epgURL := range epgURLs {
-		if _, err := url.Parse(epgURL); err != nil {
-			return fmt.Errorf("invalid EPG URL at position %d: %w", i+1, err)
-		}
+	sources, err := c.EPGSources()
+	if err != nil {
+		return err
+	}
+
+	if len(sources) == 0 {
+		return errors.New("at least one enabled EPG source is required")
 	}
 
 	if c.BaseURL == "" {
@@ -80,16 +331,258 @@ func (c *Config) Validate() error {
 		return fmt.Errorf("port must be between 1 and 65535, got %d", c.Port)
 	}
 
+	if c.Listen != "" {
+		if _, _, err := ParseListen(c.Listen); err != nil {
+			return fmt.Errorf("invalid --listen %q: %w", c.Listen, err)
+		}
+	}
+
+	if (c.TLSCert == "") != (c.TLSKey == "") {
+		return errors.New("--tls-cert and --tls-key must be provided together")
+	}
+
+	if (c.AuthUser == "") != (c.AuthPass == "") {
+		return errors.New("--auth-user and --auth-pass must be provided together")
+	}
+
+	if c.TuneRate < 0 {
+		return fmt.Errorf("--tune-rate must not be negative, got %v", c.TuneRate)
+	}
+
+	for _, cidr := range c.AllowCIDRs {
+		if _, _, err := net.ParseCIDR(cidr); err != nil {
+			return fmt.Errorf("invalid --allow-cidr %q: %w", cidr, err)
+		}
+	}
+
+	for _, cidr := range c.TrustedProxies {
+		if _, _, err := net.ParseCIDR(cidr); err != nil {
+			return fmt.Errorf("invalid --trusted-proxy %q: %w", cidr, err)
+		}
+	}
+
+	if _, err := c.EPGAliasMap(); err != nil {
+		return err
+	}
+
+	if _, err := c.CategoryMapping(); err != nil {
+		return err
+	}
+
+	for _, category := range c.BlockCategories {
+		if strings.TrimSpace(category) == "" {
+			return errors.New("--block-category must not be empty")
+		}
+	}
+
+	if _, err := c.ModelRuleList(); err != nil {
+		return err
+	}
+
+	if _, err := c.ChannelMapping(); err != nil {
+		return err
+	}
+
+	if _, err := logrus.ParseLevel(c.AccessLogLevel); err != nil {
+		return fmt.Errorf("invalid access log level: %w", err)
+	}
+
+	if c.StatusInterval < 0 {
+		return errors.New("status interval must not be negative")
+	}
+
+	if c.MinMatchRate < 0 || c.MinMatchRate > 1 {
+		return fmt.Errorf("min match rate must be between 0 and 1, got %v", c.MinMatchRate)
+	}
+
+	if c.MaxChannelDropRatio < 0 || c.MaxChannelDropRatio > 1 {
+		return fmt.Errorf("max channel drop ratio must be between 0 and 1, got %v", c.MaxChannelDropRatio)
+	}
+
+	if c.TuneWindow <= 0 {
+		return errors.New("tune window must be positive")
+	}
+
+	switch c.DataURILogos {
+	case DataURILogosPass, DataURILogosStrip, DataURILogosServe:
+	default:
+		return fmt.Errorf("invalid --data-uri-logos %q (valid: pass, strip, serve)", c.DataURILogos)
+	}
+
+	switch c.DuplicateNames {
+	case DuplicateNamesSuffix, DuplicateNamesKeep, DuplicateNamesMerge:
+	default:
+		return fmt.Errorf("invalid --duplicate-names %q (valid: suffix, keep, merge)", c.DuplicateNames)
+	}
+
+	switch c.EPGFailure {
+	case EPGFailureKeep, EPGFailureFake, EPGFailureFail:
+	default:
+		return fmt.Errorf("invalid --epg-failure %q (valid: keep, fake, fail)", c.EPGFailure)
+	}
+
+	if c.RefreshAt != "" {
+		if _, _, err := c.RefreshAtTime(); err != nil {
+			return err
+		}
+	}
+
+	if c.MaxDescLength < 0 {
+		return errors.New("max description length must not be negative")
+	}
+
+	if c.MaxProgrammesPerChannel < 0 {
+		return errors.New("max programmes per channel must not be negative")
+	}
+
+	if c.TitleCaseGroups && !c.NormalizeGroups {
+		return errors.New("--title-case-groups requires --normalize-groups")
+	}
+
+	if c.InitialFetchTimeout < 0 {
+		return errors.New("initial fetch timeout must not be negative")
+	}
+
+	if c.CacheMaxAge < 0 {
+		return errors.New("cache max-age must not be negative")
+	}
+
+	if c.StreamTimeout <= 0 {
+		return errors.New("stream timeout must be positive")
+	}
+
+	if c.MaxConnsPerHost < 0 {
+		return errors.New("max connections per host must not be negative")
+	}
+
+	if c.ProbeInterval < 0 {
+		return errors.New("probe interval must not be negative")
+	}
+
+	if c.ProbeConcurrency < 1 {
+		return fmt.Errorf("--probe-concurrency must be at least 1, got %d", c.ProbeConcurrency)
+	}
+
+	if c.ProbeRate < 1 {
+		return fmt.Errorf("--probe-rate must be at least 1, got %d", c.ProbeRate)
+	}
+
+	if c.DeadAfter < 1 {
+		return fmt.Errorf("--dead-after must be at least 1, got %d", c.DeadAfter)
+	}
+
+	if c.HideDeadChannels && c.ProbeInterval == 0 {
+		return errors.New("--hide-dead-channels requires --probe-interval")
+	}
+
+	if c.TunerLocks && !c.ProxyStreams {
+		return errors.New("--tuner-locks requires --proxy-streams")
+	}
+
+	if c.OfflineClip != "" && !c.ProxyStreams {
+		return errors.New("--offline-clip requires --proxy-streams")
+	}
+
+	if c.OfflineClip != "" {
+		info, err := os.Stat(c.OfflineClip)
+		if err != nil {
+			return fmt.Errorf("invalid --offline-clip: %w", err)
+		}
+
+		if !info.Mode().IsRegular() || info.Size() == 0 {
+			return fmt.Errorf("invalid --offline-clip %q: must be a non-empty file", c.OfflineClip)
+		}
+	}
+
+	if c.StreamTokenParam != "" && (c.StreamTokenEnv == "") == (c.StreamTokenFile == "") {
+		return errors.New("--stream-token-param requires exactly one of --stream-token-env or --stream-token-file")
+	}
+
+	if c.StreamTokenParam == "" && (c.StreamTokenEnv != "" || c.StreamTokenFile != "") {
+		return errors.New("--stream-token-env and --stream-token-file require --stream-token-param")
+	}
+
 	if c.TunerCount < 1 {
 		return errors.New("tuner count must be at least 1")
 	}
 
+	if c.ChannelsPerTuner < 0 {
+		return fmt.Errorf("--channels-per-tuner must not be negative, got %d", c.ChannelsPerTuner)
+	}
+
+	if c.MaxGroupTuners < 0 {
+		return fmt.Errorf("--max-group-tuners must not be negative, got %d", c.MaxGroupTuners)
+	}
+
 	return nil
 }
 
-// ListenAddr returns the full listen address.
+// PathPrefix returns the path of BaseURL without a trailing slash, e.g.
+// "/iptv" for https://host/iptv/, or "" when the proxy is served at the root.
+func (c *Config) PathPrefix() string {
+	base, err := url.Parse(c.BaseURL)
+	if err != nil {
+		return ""
+	}
+
+	return strings.TrimRight(base.Path, "/")
+}
+
+// ListenAddr returns the full listen address: Listen when set, otherwise
+// BindAddr and Port.
 func (c *Config) ListenAddr() string {
-	return fmt.Sprintf("%s:%d", c.BindAddr, c.Port)
+	if c.Listen != "" {
+		return c.Listen
+	}
+
+	return net.JoinHostPort(c.BindAddr, strconv.Itoa(c.Port))
+}
+
+// ParseListen splits a "host:port" listen address. The host may be empty
+// (":8080", all interfaces) or a bracketed IPv6 address ("[::1]:9000").
+func ParseListen(listen string) (string, int, error) {
+	host, portStr, err := net.SplitHostPort(listen)
+	if err != nil {
+		return "", 0, err
+	}
+
+	port, err := strconv.Atoi(portStr)
+	if err != nil || port < 1 || port > 65535 {
+		return "", 0, fmt.Errorf("port must be between 1 and 65535, got %q", portStr)
+	}
+
+	return host, port, nil
+}
+
+// TLSEnabled returns true if the server should serve HTTPS.
+func (c *Config) TLSEnabled() bool {
+	return c.TLSCert != "" && c.TLSKey != ""
+}
+
+// AuthEnabled returns true if HTTP basic auth is configured.
+func (c *Config) AuthEnabled() bool {
+	return c.AuthUser != "" && c.AuthPass != ""
+}
+
+// RefreshAtTime parses RefreshAt ("HH:MM", 24-hour clock) into an hour and
+// minute.
+func (c *Config) RefreshAtTime() (int, int, error) {
+	parsed, err := time.Parse("15:04", c.RefreshAt)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid --refresh-at %q: expected HH:MM (24-hour)", c.RefreshAt)
+	}
+
+	return parsed.Hour(), parsed.Minute(), nil
+}
+
+// EffectiveCacheMaxAge returns the max-age advertised for M3U and EPG
+// responses, defaulting to the refresh interval when not set.
+func (c *Config) EffectiveCacheMaxAge() time.Duration {
+	if c.CacheMaxAge > 0 {
+		return c.CacheMaxAge
+	}
+
+	return c.RefreshInterval
 }
 
 // EPGURLs returns the list of EPG URLs (comma-separated in EPGURL).
@@ -110,3 +603,38 @@ func (c *Config) EPGURLs() []string {
 
 	return result
 }
+
+// EPGAliasMap parses EPGAliases into a map of alias name → source name.
+func (c *Config) EPGAliasMap() (map[string]string, error) {
+	return parsePairs(c.EPGAliases, "--epg-alias", "Alias=Source")
+}
+
+// CategoryMapping parses CategoryMaps into a map of M3U group → programme
+// category.
+func (c *Config) CategoryMapping() (map[string]string, error) {
+	return parsePairs(c.CategoryMaps, "--category-map", "Group=Category")
+}
+
+// ChannelMapping parses MapChannels into a map of M3U name → EPG channel ID.
+func (c *Config) ChannelMapping() (map[string]string, error) {
+	return parsePairs(c.MapChannels, "--map-channel", "Channel Name=epg.id")
+}
+
+// parsePairs parses "key=value" entries, trimming whitespace around both.
+func parsePairs(entries []string, flag, format string) (map[string]string, error) {
+	pairs := make(map[string]string, len(entries))
+
+	for _, entry := range entries {
+		key, value, ok := strings.Cut(entry, "=")
+		key = strings.TrimSpace(key)
+		value = strings.TrimSpace(value)
+
+		if !ok || key == "" || value == "" {
+			return nil, fmt.Errorf("invalid %s %q: expected \"%s\"", flag, entry, format)
+		}
+
+		pairs[key] = value
+	}
+
+	return pairs, nil
+}
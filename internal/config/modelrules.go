@@ -0,0 +1,53 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ModelRule overrides the tuner model advertised to clients whose User-Agent
+// contains UserAgent (case-insensitive).
+type ModelRule struct {
+	UserAgent    string
+	ModelNumber  string
+	FirmwareName string // Empty keeps the default firmware name
+}
+
+// ModelRuleList parses ModelRules ("User-Agent substring=Model[:Firmware]")
+// in the order given; the first matching rule wins.
+func (c *Config) ModelRuleList() ([]ModelRule, error) {
+	rules := make([]ModelRule, 0, len(c.ModelRules))
+
+	for _, entry := range c.ModelRules {
+		userAgent, identity, ok := strings.Cut(entry, "=")
+		model, firmware, _ := strings.Cut(identity, ":")
+
+		rule := ModelRule{
+			UserAgent:    strings.TrimSpace(userAgent),
+			ModelNumber:  strings.TrimSpace(model),
+			FirmwareName: strings.TrimSpace(firmware),
+		}
+
+		if !ok || rule.UserAgent == "" || rule.ModelNumber == "" {
+			return nil, fmt.Errorf("invalid --model-rule %q: expected \"User-Agent=Model[:Firmware]\"", entry)
+		}
+
+		rules = append(rules, rule)
+	}
+
+	return rules, nil
+}
+
+// MatchModelRule returns the first rule whose UserAgent is contained in
+// userAgent.
+func MatchModelRule(rules []ModelRule, userAgent string) (ModelRule, bool) {
+	userAgent = strings.ToLower(userAgent)
+
+	for _, rule := range rules {
+		if strings.Contains(userAgent, strings.ToLower(rule.UserAgent)) {
+			return rule, true
+		}
+	}
+
+	return ModelRule{}, false
+}
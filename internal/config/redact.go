@@ -0,0 +1,99 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+)
+
+// redactedValue replaces secrets in Redacted output.
+const redactedValue = "xxxxx"
+
+// Redacted returns a copy of the config safe to print: auth credentials are
+// masked, as are URL passwords and query parameter values, which often carry
+// provider credentials or API keys.
+func (c *Config) Redacted() *Config {
+	redacted := *c
+
+	redacted.AuthUser = redactSecret(c.AuthUser)
+	redacted.AuthPass = redactSecret(c.AuthPass)
+	redacted.M3UURL = redactURLSecrets(c.M3UURL)
+	redacted.BaseURL = redactURLSecrets(c.BaseURL)
+
+	if c.EPGURL != "" {
+		urls := strings.Split(c.EPGURL, ",")
+		for i, u := range urls {
+			urls[i] = redactURLSecrets(strings.TrimSpace(u))
+		}
+
+		redacted.EPGURL = strings.Join(urls, ",")
+	}
+
+	if c.M3UBackupURLs != nil {
+		redacted.M3UBackupURLs = make([]string, len(c.M3UBackupURLs))
+		for i, u := range c.M3UBackupURLs {
+			redacted.M3UBackupURLs[i] = redactURLSecrets(u)
+		}
+	}
+
+	return &redacted
+}
+
+// Dump writes the redacted config to w as indented JSON.
+func (c *Config) Dump(w io.Writer) error {
+	data, err := json.MarshalIndent(c.Redacted(), "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal config: %w", err)
+	}
+
+	if _, err := fmt.Fprintf(w, "%s\n", data); err != nil {
+		return fmt.Errorf("failed to write config: %w", err)
+	}
+
+	return nil
+}
+
+// redactSecret masks a non-empty secret.
+func redactSecret(secret string) string {
+	if secret == "" {
+		return ""
+	}
+
+	return redactedValue
+}
+
+// redactURLSecrets masks the password and every query parameter value of
+// rawURL, keeping the rest readable. Unparseable input is masked entirely.
+func redactURLSecrets(rawURL string) string {
+	if rawURL == "" {
+		return ""
+	}
+
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return redactedValue
+	}
+
+	if u.User != nil {
+		if _, ok := u.User.Password(); ok {
+			u.User = url.UserPassword(u.User.Username(), redactedValue)
+		}
+	}
+
+	if u.RawQuery != "" {
+		query := u.Query()
+		for key, values := range query {
+			for i := range values {
+				values[i] = redactedValue
+			}
+
+			query[key] = values
+		}
+
+		u.RawQuery = query.Encode()
+	}
+
+	return u.String()
+}
@@ -0,0 +1,158 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// EPGSource describes a single EPG source and its per-source options.
+type EPGSource struct {
+	// URL of the XMLTV document, or inline content ("@/path/file.xml" or a
+	// data: URI) read directly instead of over HTTP.
+	URL string `json:"url"`
+	// Priority orders sources in the merge; lower values win. Sources with
+	// equal priority keep their configured order.
+	Priority int `json:"priority,omitempty"`
+	// Headers are extra HTTP headers sent when fetching this source.
+	Headers map[string]string `json:"headers,omitempty"`
+	// Timezone (IANA name) applied to programme times that carry no offset.
+	Timezone string `json:"timezone,omitempty"`
+	// Disabled skips the source without removing it from the file.
+	Disabled bool `json:"disabled,omitempty"`
+}
+
+// IsInlineSource reports whether ref supplies EPG content directly: a file
+// path prefixed with "@" or a data: URI.
+func IsInlineSource(ref string) bool {
+	return strings.HasPrefix(ref, "@") || isDataURI(ref)
+}
+
+// isDataURI reports whether ref has a data: scheme, which is case-insensitive.
+func isDataURI(ref string) bool {
+	return len(ref) >= 5 && strings.EqualFold(ref[:5], "data:")
+}
+
+// Inline reports whether the source's content is supplied inline.
+func (s EPGSource) Inline() bool {
+	return IsInlineSource(s.URL)
+}
+
+// Label returns a short description of the source for logs and status
+// output. Data URIs are summarised rather than printed in full, and URLs are
+// redacted since /health is served without authentication.
+func (s EPGSource) Label() string {
+	if isDataURI(s.URL) {
+		return fmt.Sprintf("data: (inline, %d bytes)", len(s.URL))
+	}
+
+	return RedactURL(s.URL)
+}
+
+// RedactURL returns rawURL without credentials or a query string, which
+// often carry API keys. Unparseable input is returned unchanged.
+func RedactURL(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.User == nil && u.RawQuery == "" {
+		return rawURL
+	}
+
+	u.User = nil
+	u.RawQuery = ""
+
+	return u.String()
+}
+
+// Location returns the source's timezone, or nil if none is configured.
+func (s EPGSource) Location() (*time.Location, error) {
+	if s.Timezone == "" {
+		return nil, nil //nolint:nilnil // No timezone override is a valid state
+	}
+
+	loc, err := time.LoadLocation(s.Timezone)
+	if err != nil {
+		return nil, fmt.Errorf("invalid timezone %q: %w", s.Timezone, err)
+	}
+
+	return loc, nil
+}
+
+// EPGSources returns the enabled EPG sources in merge priority order. Sources
+// are loaded from EPGSourcesFile when set; otherwise each comma-separated
+// --epg URL becomes a source with default options. Each --epg-inline value is
+// appended as a further source with default options. EPGOverride, when set, is
+// placed first so its programmes win every overlap.
+func (c *Config) EPGSources() ([]EPGSource, error) {
+	var sources []EPGSource
+
+	if c.EPGSourcesFile != "" {
+		loaded, err := loadEPGSources(c.EPGSourcesFile)
+		if err != nil {
+			return nil, err
+		}
+
+		sources = loaded
+	} else {
+		for _, u := range c.EPGURLs() {
+			sources = append(sources, EPGSource{URL: u})
+		}
+	}
+
+	for _, inline := range c.EPGInline {
+		sources = append(sources, EPGSource{URL: inline})
+	}
+
+	enabled := make([]EPGSource, 0, len(sources))
+
+	for i, source := range sources {
+		if source.Disabled {
+			continue
+		}
+
+		if source.URL == "" {
+			return nil, fmt.Errorf("EPG source at position %d has no URL", i+1)
+		}
+
+		if !source.Inline() {
+			if _, err := url.Parse(source.URL); err != nil {
+				return nil, fmt.Errorf("invalid EPG URL at position %d: %w", i+1, err)
+			}
+		}
+
+		if _, err := source.Location(); err != nil {
+			return nil, fmt.Errorf("EPG source at position %d: %w", i+1, err)
+		}
+
+		enabled = append(enabled, source)
+	}
+
+	sort.SliceStable(enabled, func(i, j int) bool {
+		return enabled[i].Priority < enabled[j].Priority
+	})
+
+	// The override file always wins, whatever the other sources' priorities.
+	if c.EPGOverride != "" {
+		enabled = append([]EPGSource{{URL: "@" + c.EPGOverride}}, enabled...)
+	}
+
+	return enabled, nil
+}
+
+// loadEPGSources reads a JSON array of EPG sources from disk.
+func loadEPGSources(path string) ([]EPGSource, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read EPG sources file: %w", err)
+	}
+
+	var sources []EPGSource
+	if err := json.Unmarshal(data, &sources); err != nil {
+		return nil, fmt.Errorf("failed to parse EPG sources file: %w", err)
+	}
+
+	return sources, nil
+}
@@ -0,0 +1,108 @@
+package data
+
+import (
+	"sync"
+	"time"
+)
+
+// ChannelHealth is the outcome of the probes of one channel's stream URL.
+type ChannelHealth struct {
+	URL         string
+	Alive       bool
+	Failures    int // Consecutive failed probes
+	LastProbe   time.Time
+	LastSuccess time.Time
+	LastError   string
+}
+
+// ChannelHealthTracker records stream probe outcomes by URL across
+// playlist refreshes.
+type ChannelHealthTracker struct {
+	mu       sync.RWMutex
+	channels map[string]*ChannelHealth
+	now      func() time.Time
+}
+
+// NewChannelHealthTracker creates an empty tracker.
+func NewChannelHealthTracker() *ChannelHealthTracker {
+	return &ChannelHealthTracker{
+		channels: make(map[string]*ChannelHealth),
+		now:      time.Now,
+	}
+}
+
+// RecordSuccess marks the latest probe of url as successful.
+func (t *ChannelHealthTracker) RecordSuccess(url string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	health := t.entry(url)
+	health.Alive = true
+	health.Failures = 0
+	health.LastProbe = t.now()
+	health.LastSuccess = health.LastProbe
+	health.LastError = ""
+}
+
+// RecordFailure marks the latest probe of url as failed with err.
+func (t *ChannelHealthTracker) RecordFailure(url string, err error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	health := t.entry(url)
+	health.Alive = false
+	health.Failures++
+	health.LastProbe = t.now()
+	health.LastError = redactError(err)
+}
+
+// Get returns the probe history of url, if it has been probed.
+func (t *ChannelHealthTracker) Get(url string) (ChannelHealth, bool) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	health, ok := t.channels[url]
+	if !ok {
+		return ChannelHealth{}, false
+	}
+
+	return *health, true
+}
+
+// Dead returns true if the last deadAfter (at least one) probes of url all
+// failed. Unprobed URLs are not dead.
+func (t *ChannelHealthTracker) Dead(url string, deadAfter int) bool {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	health, ok := t.channels[url]
+
+	return ok && health.Failures >= max(deadAfter, 1)
+}
+
+// Retain drops the history of URLs no longer in urls.
+func (t *ChannelHealthTracker) Retain(urls []string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	keep := make(map[string]bool, len(urls))
+	for _, url := range urls {
+		keep[url] = true
+	}
+
+	for url := range t.channels {
+		if !keep[url] {
+			delete(t.channels, url)
+		}
+	}
+}
+
+func (t *ChannelHealthTracker) entry(url string) *ChannelHealth {
+	health, ok := t.channels[url]
+	if !ok {
+		health = &ChannelHealth{URL: url}
+		t.channels[url] = health
+	}
+
+	return health
+}
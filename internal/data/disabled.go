@@ -0,0 +1,160 @@
+package data
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+
+	"github.com/savid/iptv/internal/m3u"
+)
+
+// DisabledChannels is the set of channels hidden from the lineup, keyed by
+// tvg-id or channel name so it survives playlist refreshes. When a file path
+// is set, every change is written back to disk.
+type DisabledChannels struct {
+	mu      sync.RWMutex
+	path    string
+	keys    map[string]bool
+	version uint64 // Incremented on every change
+}
+
+// NewDisabledChannels creates an empty, in-memory disabled channel set.
+func NewDisabledChannels() *DisabledChannels {
+	return &DisabledChannels{
+		keys: make(map[string]bool),
+	}
+}
+
+// Load reads the disabled set from path and persists future changes there.
+// A missing file is treated as an empty set.
+func (d *DisabledChannels) Load(path string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.path = path
+	d.keys = make(map[string]bool)
+	d.version++
+
+	content, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+
+	if err != nil {
+		return fmt.Errorf("failed to read disabled channels file: %w", err)
+	}
+
+	var keys []string
+	if err := json.Unmarshal(content, &keys); err != nil {
+		return fmt.Errorf("failed to parse disabled channels file %s: %w", path, err)
+	}
+
+	for _, key := range keys {
+		d.keys[key] = true
+	}
+
+	return nil
+}
+
+// Disable hides the channel with the given tvg-id or name.
+func (d *DisabledChannels) Disable(key string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.keys[key] {
+		return nil
+	}
+
+	d.keys[key] = true
+
+	if err := d.save(); err != nil {
+		delete(d.keys, key)
+
+		return err
+	}
+
+	d.version++
+
+	return nil
+}
+
+// Enable un-hides the channel with the given tvg-id or name.
+func (d *DisabledChannels) Enable(key string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if !d.keys[key] {
+		return nil
+	}
+
+	delete(d.keys, key)
+
+	if err := d.save(); err != nil {
+		d.keys[key] = true
+
+		return err
+	}
+
+	d.version++
+
+	return nil
+}
+
+// Contains returns true if the channel is disabled by tvg-id or name.
+func (d *DisabledChannels) Contains(ch m3u.Channel) bool {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	return d.keys[ch.Name] || (ch.TVGID != "" && d.keys[ch.TVGID])
+}
+
+// Version returns a counter that changes whenever the set changes, so
+// derived data can be cached until the next change.
+func (d *DisabledChannels) Version() uint64 {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	return d.version
+}
+
+// List returns the disabled keys, sorted.
+func (d *DisabledChannels) List() []string {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	return d.sortedKeys()
+}
+
+func (d *DisabledChannels) sortedKeys() []string {
+	keys := make([]string, 0, len(d.keys))
+
+	for key := range d.keys {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	return keys
+}
+
+// save writes the set to disk via a temp file and rename. Callers must hold
+// the write lock.
+func (d *DisabledChannels) save() error {
+	if d.path == "" {
+		return nil
+	}
+
+	content, err := json.MarshalIndent(d.sortedKeys(), "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode disabled channels: %w", err)
+	}
+
+	if err := WriteFileAtomic(d.path, content, 0o644); err != nil {
+		return fmt.Errorf("failed to write disabled channels file: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,50 @@
+package data
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/savid/iptv/internal/m3u"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDisabledChannels_MatchesByNameOrTVGID(t *testing.T) {
+	disabled := NewDisabledChannels()
+
+	require.NoError(t, disabled.Disable("espn.us"))
+	require.NoError(t, disabled.Disable("CNN"))
+
+	require.True(t, disabled.Contains(m3u.Channel{Name: "ESPN HD", TVGID: "espn.us"}))
+	require.True(t, disabled.Contains(m3u.Channel{Name: "CNN"}))
+	require.False(t, disabled.Contains(m3u.Channel{Name: "HBO", TVGID: "hbo.us"}))
+
+	require.NoError(t, disabled.Enable("CNN"))
+	require.False(t, disabled.Contains(m3u.Channel{Name: "CNN"}))
+	require.Equal(t, []string{"espn.us"}, disabled.List())
+}
+
+func TestDisabledChannels_Persisted(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "disabled.json")
+
+	disabled := NewDisabledChannels()
+	require.NoError(t, disabled.Load(path))
+	require.Empty(t, disabled.List())
+
+	require.NoError(t, disabled.Disable("espn.us"))
+	require.NoError(t, disabled.Disable("CNN"))
+	require.NoError(t, disabled.Enable("CNN"))
+
+	reloaded := NewDisabledChannels()
+	require.NoError(t, reloaded.Load(path))
+	require.Equal(t, []string{"espn.us"}, reloaded.List())
+}
+
+func TestDisabledChannels_InvalidFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "disabled.json")
+	require.NoError(t, os.WriteFile(path, []byte("{not json"), 0o600))
+
+	err := NewDisabledChannels().Load(path)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "failed to parse disabled channels file")
+}
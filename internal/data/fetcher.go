@@ -1,14 +1,17 @@
 package data
 
 import (
+	"bytes"
 	"compress/gzip"
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"strings"
 	"time"
 
+	"github.com/savid/iptv/internal/config"
 	"github.com/savid/iptv/internal/epg"
 	"github.com/savid/iptv/internal/m3u"
 	"github.com/sirupsen/logrus"
@@ -17,30 +20,54 @@ import (
 const (
 	defaultTimeout = 5 * time.Minute
 	maxBodySize    = 500 * 1024 * 1024 // 500MB for large EPG files
+
+	// maxResumeAttempts is how many times an interrupted download is resumed
+	// with a Range request before giving up.
+	maxResumeAttempts = 3
+
+	// An EPG body larger than this that yields no channels or programmes is
+	// treated as a failed source rather than a genuinely empty guide.
+	minEmptyEPGBodySize = 1024
 )
 
+// ErrUpstreamAuth is returned when an upstream redirects to content that is
+// neither an M3U playlist nor XMLTV, typically a login page after the
+// provider session expired.
+var ErrUpstreamAuth = errors.New("upstream redirected to a non-playlist page (authentication required?)")
+
+// ErrAllEPGSourcesFailed is returned by FetchEPG when no EPG source could be
+// used and EPGFailure is set to fail.
+var ErrAllEPGSourcesFailed = errors.New("all EPG sources failed")
+
 // Fetcher fetches M3U and EPG data from remote URLs.
 type Fetcher struct {
 	log        logrus.FieldLogger
+	cfg        *config.Config
 	httpClient *http.Client
 	m3uURL     string
-	epgURLs    []string
 	store      *Store
+	onRefresh  func() // Called after each EPG refresh, may be nil
 }
 
 // NewFetcher creates a new data fetcher.
-func NewFetcher(log logrus.FieldLogger, m3uURL string, epgURLs []string, store *Store) *Fetcher {
+func NewFetcher(log logrus.FieldLogger, cfg *config.Config, store *Store) *Fetcher {
 	return &Fetcher{
 		log: log.WithField("component", "fetcher"),
+		cfg: cfg,
 		httpClient: &http.Client{
 			Timeout: defaultTimeout,
 		},
-		m3uURL:  m3uURL,
-		epgURLs: epgURLs,
-		store:   store,
+		m3uURL: cfg.M3UURL,
+		store:  store,
 	}
 }
 
+// OnRefresh sets fn to be called after every refresh that stores new EPG
+// data.
+func (f *Fetcher) OnRefresh(fn func()) {
+	f.onRefresh = fn
+}
+
 // FetchAll fetches both M3U and EPG data.
 func (f *Fetcher) FetchAll(ctx context.Context) error {
 	if err := f.FetchM3U(ctx); err != nil {
@@ -56,19 +83,49 @@ func (f *Fetcher) FetchAll(ctx context.Context) error {
 
 // FetchM3U fetches and parses the M3U playlist.
 func (f *Fetcher) FetchM3U(ctx context.Context) error {
-	f.log.WithField("url", f.m3uURL).Info("Fetching M3U playlist")
-
-	data, err := f.fetch(ctx, f.m3uURL)
+	channels, source, err := f.fetchPlaylist(ctx)
 	if err != nil {
-		return fmt.Errorf("failed to fetch M3U: %w", err)
+		return err
 	}
 
-	channels, err := m3u.Parse(data)
-	if err != nil {
-		return fmt.Errorf("failed to parse M3U: %w", err)
+	channels = m3u.ResolveURLs(channels, source)
+
+	if f.cfg.LiveOnly {
+		live := m3u.LiveOnly(channels)
+		if dropped := len(channels) - len(live); dropped > 0 {
+			f.log.WithField("dropped", dropped).Info("Dropped VOD entries from M3U playlist")
+		}
+
+		channels = live
+	}
+
+	if f.cfg.CleanNames {
+		channels = m3u.CleanNames(channels)
+	}
+
+	if f.cfg.NormalizeGroups {
+		channels = m3u.NormalizeGroups(channels, f.cfg.TitleCaseGroups)
+	}
+
+	if f.cfg.CollapseQualityVariants {
+		channels = epg.CollapseQualityVariants(f.log, channels, f.cfg.QualityRanking)
+	}
+
+	if err := f.checkChannelDrop(len(channels)); err != nil {
+		return err
+	}
+
+	var playlistLogos map[string]Logo
+
+	if logos := newLogoRewriter(f.log, f.cfg); logos.enabled() {
+		logos.rewriteChannels(channels)
+		f.store.AddLogos(logos.logos)
+		playlistLogos = logos.logos
 	}
 
 	f.store.SetM3U(channels)
+	f.store.PruneLogos()
+	f.retainM3U(channels, playlistLogos)
 	f.log.WithField("channels", len(channels)).Info("M3U playlist loaded")
 
 	f.logGroupSummary(channels)
@@ -76,6 +133,104 @@ func (f *Fetcher) FetchM3U(ctx context.Context) error {
 	return nil
 }
 
+// checkChannelDrop rejects a refresh whose channel count fell by more than
+// MaxChannelDropRatio versus the playlist currently stored.
+func (f *Fetcher) checkChannelDrop(count int) error {
+	if f.cfg.MaxChannelDropRatio <= 0 {
+		return nil
+	}
+
+	previous, ok := f.store.GetM3U()
+	if !ok || len(previous) == 0 || count >= len(previous) {
+		return nil
+	}
+
+	dropRatio := float64(len(previous)-count) / float64(len(previous))
+	if dropRatio <= f.cfg.MaxChannelDropRatio {
+		return nil
+	}
+
+	f.log.WithFields(logrus.Fields{
+		"previous":            len(previous),
+		"channels":            count,
+		"dropRatio":           dropRatio,
+		"maxChannelDropRatio": f.cfg.MaxChannelDropRatio,
+	}).Error("M3U channel count dropped too far, keeping previous M3U data")
+
+	return fmt.Errorf("M3U channel count dropped from %d to %d, more than %.2f", len(previous), count, f.cfg.MaxChannelDropRatio)
+}
+
+// fetchPlaylist fetches and parses the M3U playlist, falling back to each
+// backup URL in order when the primary fails. It returns the channels and the
+// URL they were loaded from.
+func (f *Fetcher) fetchPlaylist(ctx context.Context) ([]m3u.Channel, string, error) {
+	sources := append([]string{f.m3uURL}, f.cfg.M3UBackupURLs...)
+	errs := make([]error, 0, len(sources))
+
+	for i, source := range sources {
+		f.log.WithField("url", config.RedactURL(source)).Info("Fetching M3U playlist")
+
+		channels, err := f.fetchPlaylistFrom(ctx, source)
+		if err == nil {
+			if i > 0 {
+				f.log.WithField("url", config.RedactURL(source)).Warn("Loaded M3U playlist from backup source")
+			}
+
+			return channels, source, nil
+		}
+
+		errs = append(errs, err)
+
+		if ctx.Err() != nil {
+			break
+		}
+
+		if i < len(sources)-1 {
+			f.log.WithFields(logrus.Fields{
+				"url":   config.RedactURL(source),
+				"error": redactError(err),
+			}).Warn("M3U source failed, trying next backup")
+		}
+	}
+
+	return nil, "", errors.Join(errs...)
+}
+
+// fetchPlaylistFrom fetches and parses the M3U playlist at url.
+func (f *Fetcher) fetchPlaylistFrom(ctx context.Context, url string) ([]m3u.Channel, error) {
+	data, err := f.fetch(ctx, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch M3U: %w", err)
+	}
+
+	if !f.cfg.M3ULenient {
+		channels, err := m3u.Parse(data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse M3U: %w", err)
+		}
+
+		f.store.SetM3UParseReport(m3u.ParseReport{})
+
+		return channels, nil
+	}
+
+	channels, report, err := m3u.ParseLenient(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse M3U: %w", err)
+	}
+
+	if report.Skipped > 0 {
+		f.log.WithFields(logrus.Fields{
+			"url":     config.RedactURL(url),
+			"skipped": report.Skipped,
+		}).Warn("Skipped malformed M3U entries")
+	}
+
+	f.store.SetM3UParseReport(report)
+
+	return channels, nil
+}
+
 // logGroupSummary logs a summary of channels per group.
 func (f *Fetcher) logGroupSummary(channels []m3u.Channel) {
 	groupCounts := make(map[string]int, 32)
@@ -106,45 +261,135 @@ func (f *Fetcher) FetchEPG(ctx context.Context) error {
 		return fmt.Errorf("M3U data not available, cannot filter EPG")
 	}
 
-	results := make([]*epg.FilterResult, 0, len(f.epgURLs))
+	sources, err := f.cfg.EPGSources()
+	if err != nil {
+		return fmt.Errorf("failed to load EPG sources: %w", err)
+	}
+
+	labels := make([]string, 0, len(sources))
+
+	for _, source := range sources {
+		labels = append(labels, source.Label())
+	}
+
+	health := f.store.EPGSources()
+	health.Track(labels)
+
+	results := make([]*epg.FilterResult, 0, len(sources))
+	sourceChannels := make([]epg.Channel, 0)
+
+	for i, source := range sources {
+		label := source.Label()
 
-	for i, epgURL := range f.epgURLs {
 		f.log.WithFields(logrus.Fields{
-			"url":      epgURL,
+			"url":      label,
 			"priority": i + 1,
-			"total":    len(f.epgURLs),
+			"total":    len(sources),
 		}).Info("Fetching EPG source")
 
-		data, err := f.fetch(ctx, epgURL)
+		data, err := f.fetchSource(ctx, source)
 		if err != nil {
-			f.log.WithError(err).WithField("url", epgURL).Warn("Failed to fetch EPG source")
+			f.log.WithError(err).WithField("url", label).Warn("Failed to fetch EPG source")
+			health.RecordFailure(label, err)
+
+			continue
+		}
+
+		if err := checkEPGBody(data); err != nil {
+			f.log.WithError(err).WithField("url", label).Warn("Rejected EPG source")
+			health.RecordFailure(label, err)
 
 			continue
 		}
 
-		epgData, err := epg.Parse(data)
+		epgData, err := epg.ParseWithLogger(f.log, data)
 		if err != nil {
-			f.log.WithError(err).WithField("url", epgURL).Warn("Failed to parse EPG source")
+			f.log.WithError(err).WithField("url", label).Warn("Failed to parse EPG source")
+			health.RecordFailure(label, err)
 
 			continue
 		}
 
-		result := epg.FilterForMerge(f.log, epgData, m3uChannels)
+		if len(epgData.Channels) == 0 && len(epgData.Programs) == 0 && len(data) > minEmptyEPGBodySize {
+			f.log.WithFields(logrus.Fields{
+				"url":   label,
+				"bytes": len(data),
+			}).Warn("Rejected EPG source: no channels or programmes found in a non-empty response")
+			health.RecordFailure(label, errors.New("no channels or programmes found in a non-empty response"))
+
+			continue
+		}
+
+		// Validated in config; a nil location leaves times untouched.
+		if loc, _ := source.Location(); loc != nil {
+			epg.ApplyTimezone(epgData, loc)
+		}
+
+		if filled := epg.FillMissingStops(epgData); filled > 0 {
+			f.log.WithFields(logrus.Fields{
+				"url":        label,
+				"programmes": filled,
+			}).Debug("Filled in missing programme stop times")
+		}
+
+		if f.cfg.CleanNames {
+			if cleaned := epg.CleanDisplayNames(epgData); cleaned > 0 {
+				f.log.WithFields(logrus.Fields{
+					"url":      label,
+					"channels": cleaned,
+				}).Debug("Cleaned channel display-names")
+			}
+		}
+
+		if f.cfg.RepairEPG {
+			if repaired := epg.RepairOverlaps(epgData); repaired > 0 {
+				f.log.WithFields(logrus.Fields{
+					"url":        label,
+					"programmes": repaired,
+				}).Info("Repaired overlapping programmes")
+			}
+		}
+
+		health.RecordSuccess(label)
+
+		sourceChannels = append(sourceChannels, epgData.Channels...)
+
+		result := epg.FilterForMergeWithOptions(f.log, epgData, m3uChannels, f.matchOptions())
 		results = append(results, result)
 
 		f.log.WithFields(logrus.Fields{
-			"url":        epgURL,
+			"url":        label,
 			"channels":   len(result.ChannelMap),
 			"programmes": len(result.EPG.Programs),
 		}).Info("Filtered EPG source")
 	}
 
 	if len(results) == 0 {
-		return fmt.Errorf("all EPG sources failed")
+		return f.handleAllSourcesFailed(m3uChannels)
 	}
 
 	// Merge all results with program-level deduplication.
-	merged := epg.MergeEPGs(results)
+	merged := epg.MergeEPGsWithChannels(results, m3uChannels)
+
+	// Copy guide data onto aliased channels.
+	aliases, err := f.cfg.EPGAliasMap()
+	if err != nil {
+		return err
+	}
+
+	epg.ApplyAliases(f.log, merged, aliases)
+	f.warnUnresolvedMappings(m3uChannels, merged.ChannelMap)
+
+	// Reject the refresh if too few channels got real guide data.
+	matchRate := epg.MatchRate(m3uChannels, merged.ChannelMap)
+	if matchRate < f.cfg.MinMatchRate {
+		f.log.WithFields(logrus.Fields{
+			"matchRate":    matchRate,
+			"minMatchRate": f.cfg.MinMatchRate,
+		}).Error("EPG match rate below threshold, keeping previous EPG data")
+
+		return fmt.Errorf("EPG match rate %.2f below minimum %.2f", matchRate, f.cfg.MinMatchRate)
+	}
 
 	// Build final TV struct.
 	finalEPG := &epg.TV{
@@ -152,10 +397,41 @@ func (f *Fetcher) FetchEPG(ctx context.Context) error {
 		Programs: merged.Programs,
 	}
 
-	// Add fake channels for unmatched M3U channels.
-	finalEPG = epg.AddFakeChannels(f.log, finalEPG, m3uChannels, merged.ChannelMap)
+	finalEPG, blockedNames := epg.BlockCategories(f.log, finalEPG, merged.ChannelMap, f.cfg.BlockCategories, f.cfg.BlockCategoryDropChannels)
+
+	var guideLogos map[string]Logo
+
+	if logos := newLogoRewriter(f.log, f.cfg); logos.enabled() {
+		logos.rewriteEPG(finalEPG)
+		f.store.AddLogos(logos.logos)
+		guideLogos = logos.logos
+	}
+
+	// Add fake channels for unmatched M3U channels. Channels dropped for
+	// blocked categories stay dropped.
+	placeholderChannels := withoutNames(m3uChannels, blockedNames)
+	categories := f.categoryMapping()
+	finalEPG = epg.AddFakeChannels(f.log, finalEPG, placeholderChannels, merged.ChannelMap, categories)
+
+	if f.cfg.FillStaleChannels {
+		finalEPG = epg.FillStaleChannels(f.log, finalEPG, placeholderChannels, merged.ChannelMap, categories, time.Now())
+	}
+
+	finalEPG = epg.LimitProgrammes(f.log, finalEPG, f.cfg.MaxProgrammesPerChannel, time.Now())
+
+	if f.cfg.MarkNew {
+		var marked int
+
+		finalEPG, marked = epg.MarkNew(finalEPG, time.Now())
+		f.log.WithField("programmes", marked).Debug("Flagged upcoming programmes as new")
+	}
 
 	f.store.SetEPG(finalEPG, merged.ChannelMap)
+	f.store.PruneLogos()
+	f.store.SetEPGSourceChannels(sourceChannels)
+	f.store.SetMatchSummary(epg.CountStrategies(results, m3uChannels))
+	f.retainEPG(finalEPG, merged.ChannelMap, guideLogos)
+	f.refreshed()
 
 	f.log.WithFields(logrus.Fields{
 		"sources":    len(results),
@@ -166,23 +442,227 @@ func (f *Fetcher) FetchEPG(ctx context.Context) error {
 	return nil
 }
 
-func (f *Fetcher) fetch(ctx context.Context, url string) ([]byte, error) {
+// handleAllSourcesFailed applies the configured EPGFailure behavior when no
+// EPG source could be used. The M3U data is already stored and is kept.
+func (f *Fetcher) handleAllSourcesFailed(m3uChannels []m3u.Channel) error {
+	switch f.cfg.EPGFailure {
+	case config.EPGFailureFail:
+		return ErrAllEPGSourcesFailed
+	case config.EPGFailureKeep:
+		if _, _, ok := f.store.GetEPG(); ok {
+			f.log.Warn("All EPG sources failed, keeping previous EPG data")
+
+			return nil
+		}
+
+		f.log.Warn("All EPG sources failed and no previous EPG data, using placeholders")
+	default:
+		f.log.Warn("All EPG sources failed, using placeholders")
+	}
+
+	channelMap := make(map[string]string)
+	fakeEPG := epg.AddFakeChannels(f.log, &epg.TV{}, m3uChannels, channelMap, f.categoryMapping())
+
+	f.store.SetEPG(fakeEPG, channelMap)
+	f.store.PruneLogos()
+	f.store.SetEPGSourceChannels(nil)
+	f.refreshed()
+
+	return nil
+}
+
+// refreshed runs the OnRefresh callback, if any.
+func (f *Fetcher) refreshed() {
+	if f.onRefresh != nil {
+		f.onRefresh()
+	}
+}
+
+// checkEPGBody rejects responses that are clearly not XMLTV, such as HTML
+// error pages served with a 200 status.
+func checkEPGBody(data []byte) error {
+	if contentType := http.DetectContentType(data); strings.HasPrefix(contentType, "text/html") {
+		return fmt.Errorf("response looks like HTML, not XMLTV (%d bytes)", len(data))
+	}
+
+	return nil
+}
+
+// withoutNames returns channels without those named in names.
+func withoutNames(channels []m3u.Channel, names []string) []m3u.Channel {
+	if len(names) == 0 {
+		return channels
+	}
+
+	skip := make(map[string]bool, len(names))
+	for _, name := range names {
+		skip[name] = true
+	}
+
+	kept := make([]m3u.Channel, 0, len(channels))
+
+	for _, ch := range channels {
+		if !skip[ch.Name] {
+			kept = append(kept, ch)
+		}
+	}
+
+	return kept
+}
+
+// fetchSource returns the raw content of an EPG source, reading inline
+// sources directly instead of making an HTTP request.
+func (f *Fetcher) fetchSource(ctx context.Context, source config.EPGSource) ([]byte, error) {
+	if source.Inline() {
+		return readInline(source.URL)
+	}
+
+	return f.fetch(ctx, source.URL, source.Headers)
+}
+
+func (f *Fetcher) fetch(ctx context.Context, url string, headers map[string]string) ([]byte, error) {
+	var (
+		data       []byte
+		validator  string
+		redirectTo string
+	)
+
+	for attempt := 0; ; attempt++ {
+		result, err := f.fetchFrom(ctx, url, headers, len(data), validator)
+		redirectTo = result.redirectTo
+
+		if result.ranged {
+			data = append(data, result.body...)
+		} else {
+			// A full body, including when the server ignored the Range header
+			// or the document changed since the first attempt.
+			data = result.body
+			validator = result.validator
+		}
+
+		if err == nil {
+			break
+		}
+
+		if f.cfg.ResumeDownloads && errors.Is(err, errContentRangeMismatch) &&
+			attempt < maxResumeAttempts && ctx.Err() == nil {
+			f.log.WithError(err).WithField("url", config.RedactURL(url)).Warn("Resumed download returned the wrong range, restarting")
+
+			continue
+		}
+
+		if !f.cfg.ResumeDownloads || !result.resumable || len(data) == 0 ||
+			attempt >= maxResumeAttempts || ctx.Err() != nil {
+			return nil, err
+		}
+
+		f.log.WithError(err).WithFields(logrus.Fields{
+			"url":      config.RedactURL(url),
+			"received": len(data),
+		}).Warn("Download interrupted, resuming")
+	}
+
+	if redirectTo != "" && !recognizableContent(data) {
+		redacted := config.RedactURL(redirectTo)
+
+		f.log.WithFields(logrus.Fields{
+			"url":      config.RedactURL(url),
+			"redirect": redacted,
+		}).Warn("Upstream redirected to unrecognized content, treating as an authentication failure")
+
+		return nil, fmt.Errorf("%w: redirected to %s", ErrUpstreamAuth, redacted)
+	}
+
+	f.log.WithField("size", len(data)).Debug("Fetched data")
+
+	return data, nil
+}
+
+// recognizableContent reports whether data looks like an M3U playlist or an
+// XMLTV document (possibly gzip-compressed), rather than e.g. an HTML page.
+func recognizableContent(data []byte) bool {
+	trimmed := bytes.TrimLeft(bytes.TrimPrefix(data, []byte("\xef\xbb\xbf")), " \t\r\n")
+
+	switch {
+	case bytes.HasPrefix(trimmed, []byte("#EXTM3U")), bytes.HasPrefix(trimmed, []byte("#EXTINF")):
+		return true
+	case bytes.HasPrefix(trimmed, []byte{0x1f, 0x8b}):
+		return true
+	case bytes.HasPrefix(trimmed, []byte("<")):
+		return !strings.HasPrefix(http.DetectContentType(trimmed), "text/html")
+	default:
+		return false
+	}
+}
+
+// fetchResult is the outcome of a single download attempt.
+type fetchResult struct {
+	body []byte
+
+	// ranged is set when body continues from the requested offset (206).
+	ranged bool
+
+	// resumable is set when a failed read could be continued with a Range
+	// request.
+	resumable bool
+
+	// validator is the response's ETag, or its Last-Modified date, sent as
+	// If-Range when resuming so a changed document is fetched whole.
+	validator string
+
+	// redirectTo is the URL the response came from when the request was
+	// redirected, or empty.
+	redirectTo string
+}
+
+// errContentRangeMismatch is returned when a resumed download doesn't continue
+// at the requested offset.
+var errContentRangeMismatch = errors.New("partial response does not start at the requested offset")
+
+// fetchFrom downloads url, requesting the bytes from offset onward when it is
+// non-zero, provided the document still matches validator. On a read error it
+// returns what was received along with the error.
+func (f *Fetcher) fetchFrom(ctx context.Context, url string, headers map[string]string, offset int, validator string) (fetchResult, error) {
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
+		return fetchResult{}, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	for key, value := range headers {
+		req.Header.Set(key, value)
 	}
 
 	// Accept gzip encoding
 	req.Header.Set("Accept-Encoding", "gzip")
 
+	if offset > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+		req.Header.Set("If-Range", validator)
+	}
+
 	resp, err := f.httpClient.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("request failed: %w", err)
+		return fetchResult{}, fmt.Errorf("request failed: %w", err)
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	result := fetchResult{}
+
+	if final := resp.Request.URL.String(); final != req.URL.String() {
+		result.redirectTo = final
+	}
+
+	switch {
+	case resp.StatusCode == http.StatusOK:
+		offset = 0
+	case resp.StatusCode == http.StatusPartialContent && offset > 0:
+		if !strings.HasPrefix(resp.Header.Get("Content-Range"), fmt.Sprintf("bytes %d-", offset)) {
+			return fetchResult{}, fmt.Errorf("%w: got %q", errContentRangeMismatch, resp.Header.Get("Content-Range"))
+		}
+
+		result.ranged = true
+	default:
+		return fetchResult{}, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
 	}
 
 	var reader io.Reader = resp.Body
@@ -191,21 +671,85 @@ func (f *Fetcher) fetch(ctx context.Context, url string) ([]byte, error) {
 	if strings.EqualFold(resp.Header.Get("Content-Encoding"), "gzip") {
 		gzReader, gzErr := gzip.NewReader(resp.Body)
 		if gzErr != nil {
-			return nil, fmt.Errorf("failed to create gzip reader: %w", gzErr)
+			return fetchResult{}, fmt.Errorf("failed to create gzip reader: %w", gzErr)
 		}
 		defer gzReader.Close()
 
+		// Some aggregators concatenate several gzip members in one response.
+		// Multistream is the default, but set it explicitly so all members
+		// are read rather than stopping at the first boundary.
+		gzReader.Multistream(true)
+
 		reader = gzReader
+	} else {
+		// Weak ETags can't be used with If-Range.
+		result.validator = resp.Header.Get("ETag")
+		if result.validator == "" || strings.HasPrefix(result.validator, "W/") {
+			result.validator = resp.Header.Get("Last-Modified")
+		}
+
+		// Ranges address the encoded bytes, so only plain bodies can resume,
+		// and only when a validator guards against a changed document.
+		result.resumable = resp.Header.Get("Accept-Ranges") == "bytes" && (result.validator != "" || result.ranged)
 	}
 
-	limitedReader := io.LimitReader(reader, maxBodySize)
+	limitedReader := io.LimitReader(reader, int64(maxBodySize-offset))
 
-	data, err := io.ReadAll(limitedReader)
+	result.body, err = io.ReadAll(limitedReader)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read response body: %w", err)
+		return result, fmt.Errorf("failed to read response body: %w", err)
 	}
 
-	f.log.WithField("size", len(data)).Debug("Fetched data")
+	return result, nil
+}
 
-	return data, nil
+// matchOptions builds EPG match options from the config.
+func (f *Fetcher) matchOptions() epg.MatchOptions {
+	// Validated in config.
+	channelMap, _ := f.cfg.ChannelMapping()
+
+	return epg.MatchOptions{
+		ForceFake:      f.cfg.ForceFakeEPG,
+		ChannelMap:     channelMap,
+		QualityRanking: f.cfg.QualityRanking,
+		Order:          f.cfg.MatchOrder,
+		CategoryMap:    f.categoryMapping(),
+	}
+}
+
+// categoryMapping returns the configured group → category mappings.
+func (f *Fetcher) categoryMapping() map[string]string {
+	// Validated in config.
+	categories, _ := f.cfg.CategoryMapping()
+
+	return categories
+}
+
+// warnUnresolvedMappings logs explicit channel mappings whose EPG ID was not
+// found in any source.
+func (f *Fetcher) warnUnresolvedMappings(m3uChannels []m3u.Channel, channelMap map[string]string) {
+	mapping, _ := f.cfg.ChannelMapping()
+	if len(mapping) == 0 {
+		return
+	}
+
+	matched := make(map[string]bool, len(channelMap))
+
+	for _, m3uName := range channelMap {
+		matched[m3uName] = true
+	}
+
+	for _, ch := range m3uChannels {
+		epgID, mapped := mapping[ch.Name]
+		if !mapped || matched[ch.Name] {
+			continue
+		}
+
+		f.log.WithFields(logrus.Fields{
+			"channel": ch.Name,
+			"epgID":   epgID,
+		}).Warn("Mapped EPG ID not found in any source, using placeholder EPG")
+
+		matched[ch.Name] = true
+	}
 }
@@ -0,0 +1,887 @@
+package data
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/savid/iptv/internal/config"
+	"github.com/savid/iptv/internal/epg"
+	"github.com/savid/iptv/internal/m3u"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+)
+
+const testM3U = `#EXTM3U
+#EXTINF:-1 tvg-id="espn.us" group-title="Sports",ESPN
+http://stream.example.com/espn
+#EXTINF:-1 group-title="News",CNN
+http://stream.example.com/cnn
+#EXTINF:-1 group-title="News",BBC
+http://stream.example.com/bbc
+#EXTINF:-1 group-title="Movies",HBO
+http://stream.example.com/hbo
+`
+
+const testEPG = `<?xml version="1.0" encoding="UTF-8"?>
+<tv>
+  <channel id="espn.us"><display-name>ESPN</display-name></channel>
+  <programme channel="espn.us" start="20260104120000 +0000" stop="20260104130000 +0000">
+    <title>SportsCenter</title>
+  </programme>
+</tv>`
+
+func newTestLogger() logrus.FieldLogger {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+
+	return logger
+}
+
+// newTestUpstream serves fixed bodies by path.
+func newTestUpstream(t *testing.T, bodies map[string]string) *httptest.Server {
+	t.Helper()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, ok := bodies[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+
+			return
+		}
+
+		_, _ = io.WriteString(w, body)
+	}))
+	t.Cleanup(srv.Close)
+
+	return srv
+}
+
+func newTestFetcherConfig(srv *httptest.Server) *config.Config {
+	cfg := config.DefaultConfig()
+	cfg.M3UURL = srv.URL + "/playlist.m3u"
+	cfg.EPGURL = srv.URL + "/epg.xml"
+	cfg.BaseURL = "http://localhost:8080"
+
+	return cfg
+}
+
+func TestFetchAll(t *testing.T) {
+	srv := newTestUpstream(t, map[string]string{
+		"/playlist.m3u": testM3U,
+		"/epg.xml":      testEPG,
+	})
+
+	store := NewStore()
+	fetcher := NewFetcher(newTestLogger(), newTestFetcherConfig(srv), store)
+
+	require.NoError(t, fetcher.FetchAll(context.Background()))
+
+	channels, ok := store.GetM3U()
+	require.True(t, ok)
+	require.Len(t, channels, 4)
+
+	epgData, channelMap, ok := store.GetEPG()
+	require.True(t, ok)
+	require.Len(t, epgData.Channels, 4)
+	require.Equal(t, "ESPN", channelMap["espn.us"])
+}
+
+func TestFetchEPG_BlockCategoryDropChannels(t *testing.T) {
+	guide := `<?xml version="1.0" encoding="UTF-8"?>
+<tv>
+  <channel id="espn.us"><display-name>ESPN</display-name></channel>
+  <programme channel="espn.us" start="20260104120000 +0000" stop="20260104130000 +0000">
+    <title>After Dark</title>
+    <category>Adult</category>
+  </programme>
+</tv>`
+
+	srv := newTestUpstream(t, map[string]string{
+		"/playlist.m3u": testM3U,
+		"/epg.xml":      guide,
+	})
+
+	cfg := newTestFetcherConfig(srv)
+	cfg.BlockCategories = []string{"Adult"}
+	cfg.BlockCategoryDropChannels = true
+
+	store := NewStore()
+	require.NoError(t, NewFetcher(newTestLogger(), cfg, store).FetchAll(context.Background()))
+
+	epgData, channelMap, ok := store.GetEPG()
+	require.True(t, ok)
+	require.NotContains(t, channelMap, "espn.us")
+
+	// ESPN is dropped rather than replaced by a placeholder; the unmatched
+	// channels still get theirs.
+	names := make([]string, 0, len(epgData.Channels))
+	for _, ch := range epgData.Channels {
+		names = append(names, ch.DisplayName)
+	}
+
+	require.ElementsMatch(t, []string{"CNN", "BBC", "HBO"}, names)
+}
+
+func TestFetchEPG_MinMatchRateRejectsUpdate(t *testing.T) {
+	srv := newTestUpstream(t, map[string]string{
+		"/playlist.m3u": testM3U,
+		"/epg.xml":      testEPG,
+	})
+
+	cfg := newTestFetcherConfig(srv)
+	cfg.MinMatchRate = 0.5 // Only 1 of 4 channels matches.
+
+	store := NewStore()
+	previous := &epg.TV{Channels: []epg.Channel{{ID: "previous"}}}
+	store.SetEPG(previous, map[string]string{"previous": "Previous"})
+
+	fetcher := NewFetcher(newTestLogger(), cfg, store)
+
+	err := fetcher.FetchAll(context.Background())
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "match rate")
+
+	epgData, _, ok := store.GetEPG()
+	require.True(t, ok)
+	require.Same(t, previous, epgData)
+
+	// A threshold the data satisfies lets the update through.
+	cfg.MinMatchRate = 0.25
+
+	require.NoError(t, fetcher.FetchAll(context.Background()))
+
+	epgData, _, _ = store.GetEPG()
+	require.NotSame(t, previous, epgData)
+}
+
+func TestFetchM3U_MaxChannelDropRatio(t *testing.T) {
+	var count atomic.Int32
+
+	count.Store(10)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		var sb strings.Builder
+
+		sb.WriteString("#EXTM3U\n")
+
+		for i := range int(count.Load()) {
+			fmt.Fprintf(&sb, "#EXTINF:-1,Channel %d\nhttp://stream.example.com/%d\n", i, i)
+		}
+
+		_, _ = io.WriteString(w, sb.String())
+	}))
+	t.Cleanup(srv.Close)
+
+	cfg := newTestFetcherConfig(srv)
+	cfg.MaxChannelDropRatio = 0.5
+
+	store := NewStore()
+	fetcher := NewFetcher(newTestLogger(), cfg, store)
+
+	require.NoError(t, fetcher.FetchM3U(context.Background()))
+
+	// A 90% drop is rejected and the previous playlist kept.
+	count.Store(1)
+
+	err := fetcher.FetchM3U(context.Background())
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "dropped from 10 to 1")
+
+	channels, _ := store.GetM3U()
+	require.Len(t, channels, 10)
+
+	// A 10% drop is accepted.
+	count.Store(9)
+
+	require.NoError(t, fetcher.FetchM3U(context.Background()))
+
+	channels, _ = store.GetM3U()
+	require.Len(t, channels, 9)
+}
+
+func TestFetchM3U_Lenient(t *testing.T) {
+	const malformed = `#EXTM3U
+#EXTINF:-1,Broken
+#EXTINF:-1,ESPN
+http://stream.example.com/espn
+`
+
+	srv := newTestUpstream(t, map[string]string{"/playlist.m3u": malformed})
+
+	cfg := newTestFetcherConfig(srv)
+	store := NewStore()
+	fetcher := NewFetcher(newTestLogger(), cfg, store)
+
+	require.ErrorIs(t, fetcher.FetchM3U(context.Background()), m3u.ErrOrphanedChannel)
+
+	cfg.M3ULenient = true
+
+	require.NoError(t, fetcher.FetchM3U(context.Background()))
+
+	channels, _ := store.GetM3U()
+	require.Len(t, channels, 1)
+
+	report := store.M3UParseReport()
+	require.Equal(t, 1, report.Skipped)
+	require.Equal(t, []string{"#EXTINF:-1,Broken"}, report.Samples)
+}
+
+func TestFetchEPG_SourceHeadersAndPriority(t *testing.T) {
+	const secondEPG = `<?xml version="1.0" encoding="UTF-8"?>
+<tv>
+  <channel id="cnn.us"><display-name>CNN</display-name></channel>
+  <programme channel="cnn.us" start="20260104120000" stop="20260104130000">
+    <title>Newsroom</title>
+  </programme>
+</tv>`
+
+	var gotToken string
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/playlist.m3u":
+			_, _ = io.WriteString(w, testM3U)
+		case "/a.xml":
+			_, _ = io.WriteString(w, testEPG)
+		case "/b.xml":
+			gotToken = r.Header.Get("X-Token")
+			_, _ = io.WriteString(w, secondEPG)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	path := filepath.Join(t.TempDir(), "sources.json")
+	content := `[
+  {"url": "` + srv.URL + `/a.xml", "priority": 2},
+  {"url": "` + srv.URL + `/b.xml", "priority": 1, "headers": {"X-Token": "secret"}, "timezone": "America/New_York"}
+]`
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+
+	cfg := newTestFetcherConfig(srv)
+	cfg.EPGURL = ""
+	cfg.EPGSourcesFile = path
+
+	store := NewStore()
+	fetcher := NewFetcher(newTestLogger(), cfg, store)
+
+	require.NoError(t, fetcher.FetchAll(context.Background()))
+	require.Equal(t, "secret", gotToken)
+
+	epgData, channelMap, ok := store.GetEPG()
+	require.True(t, ok)
+	require.Equal(t, "ESPN", channelMap["espn.us"])
+	require.Equal(t, "CNN", channelMap["cnn.us"])
+
+	for _, prog := range epgData.Programs {
+		if prog.Title == "Newsroom" {
+			require.Equal(t, "20260104120000 -0500", prog.Start)
+		}
+	}
+}
+
+func TestFetchEPG_Override(t *testing.T) {
+	srv := newTestUpstream(t, map[string]string{
+		"/playlist.m3u": testM3U,
+		"/epg.xml":      testEPG,
+	})
+
+	overrideEPG := `<tv>
+  <channel id="espn.us"><display-name>ESPN</display-name></channel>
+  <programme channel="espn.us" start="20260104120000 +0000" stop="20260104130000 +0000">
+    <title>Curated Match</title>
+  </programme>
+</tv>`
+
+	path := filepath.Join(t.TempDir(), "override.xml")
+	require.NoError(t, os.WriteFile(path, []byte(overrideEPG), 0o600))
+
+	cfg := newTestFetcherConfig(srv)
+	cfg.EPGOverride = path
+
+	store := NewStore()
+	fetcher := NewFetcher(newTestLogger(), cfg, store)
+
+	require.NoError(t, fetcher.FetchAll(context.Background()))
+
+	epgData, _, ok := store.GetEPG()
+	require.True(t, ok)
+
+	var titles []string
+
+	for _, prog := range epgData.Programs {
+		if prog.Channel == "espn.us" {
+			titles = append(titles, prog.Title)
+		}
+	}
+
+	require.Equal(t, []string{"Curated Match"}, titles)
+}
+
+func TestFetchEPG_InlineSources(t *testing.T) {
+	srv := newTestUpstream(t, map[string]string{
+		"/playlist.m3u": testM3U,
+		"/epg.xml":      testEPG,
+	})
+
+	fileEPG := `<?xml version="1.0" encoding="UTF-8"?>
+<tv>
+  <channel id="cnn.us"><display-name>CNN</display-name></channel>
+  <programme channel="cnn.us" start="20260104120000 +0000" stop="20260104130000 +0000">
+    <title>Newsroom</title>
+  </programme>
+</tv>`
+
+	dataEPG := `<tv>
+  <channel id="bbc.uk"><display-name>BBC</display-name></channel>
+  <programme channel="bbc.uk" start="20260104120000 +0000" stop="20260104130000 +0000">
+    <title>World News</title>
+  </programme>
+</tv>`
+
+	path := filepath.Join(t.TempDir(), "inline.xml")
+	require.NoError(t, os.WriteFile(path, []byte(fileEPG), 0o600))
+
+	cfg := newTestFetcherConfig(srv)
+	cfg.EPGInline = []string{
+		"@" + path,
+		"data:application/xml;base64," + base64.StdEncoding.EncodeToString([]byte(dataEPG)),
+	}
+
+	store := NewStore()
+	fetcher := NewFetcher(newTestLogger(), cfg, store)
+
+	require.NoError(t, fetcher.FetchAll(context.Background()))
+
+	epgData, channelMap, ok := store.GetEPG()
+	require.True(t, ok)
+	require.Equal(t, "ESPN", channelMap["espn.us"])
+	require.Equal(t, "CNN", channelMap["cnn.us"])
+	require.Equal(t, "BBC", channelMap["bbc.uk"])
+
+	titles := make(map[string]bool)
+	for _, prog := range epgData.Programs {
+		titles[prog.Title] = true
+	}
+
+	require.True(t, titles["SportsCenter"])
+	require.True(t, titles["Newsroom"])
+	require.True(t, titles["World News"])
+}
+
+func TestFetchEPG_RecordsSourceHealth(t *testing.T) {
+	srv := newTestUpstream(t, map[string]string{
+		"/playlist.m3u": testM3U,
+		"/epg.xml":      testEPG,
+	})
+
+	cfg := newTestFetcherConfig(srv)
+	cfg.EPGURL = srv.URL + "/missing.xml," + srv.URL + "/epg.xml"
+
+	store := NewStore()
+	fetcher := NewFetcher(newTestLogger(), cfg, store)
+
+	require.NoError(t, fetcher.FetchAll(context.Background()))
+
+	health := store.EPGSources().Snapshot()
+	require.Len(t, health, 2)
+
+	require.Equal(t, srv.URL+"/missing.xml", health[0].Source)
+	require.False(t, health[0].OK)
+	require.Contains(t, health[0].LastError, "404")
+	require.True(t, health[0].LastSuccess.IsZero())
+
+	require.Equal(t, srv.URL+"/epg.xml", health[1].Source)
+	require.True(t, health[1].OK)
+	require.Empty(t, health[1].LastError)
+	require.False(t, health[1].LastSuccess.IsZero())
+}
+
+func TestFetchM3U_Backup(t *testing.T) {
+	backupM3U := `#EXTM3U
+#EXTINF:-1 tvg-id="espn.us" tvg-logo="/logos/espn.png" group-title="Sports",ESPN
+live/espn.ts
+#EXTINF:-1 group-title="News",CNN
+http://stream.example.com/cnn
+`
+
+	srv := newTestUpstream(t, map[string]string{
+		"/backup/list.m3u":    backupM3U,
+		"/epg.xml":            testEPG,
+		"/playlist-spare.m3u": testM3U,
+	})
+
+	cfg := newTestFetcherConfig(srv)
+	cfg.M3UURL = srv.URL + "/missing.m3u"
+	cfg.M3UBackupURLs = []string{srv.URL + "/also-missing.m3u", srv.URL + "/backup/list.m3u", srv.URL + "/playlist-spare.m3u"}
+
+	store := NewStore()
+	fetcher := NewFetcher(newTestLogger(), cfg, store)
+
+	require.NoError(t, fetcher.FetchM3U(context.Background()))
+
+	channels, ok := store.GetM3U()
+	require.True(t, ok)
+	require.Len(t, channels, 2)
+	require.Equal(t, srv.URL+"/backup/live/espn.ts", channels[0].URL)
+	require.Equal(t, srv.URL+"/logos/espn.png", channels[0].TVGLogo)
+	require.Equal(t, "http://stream.example.com/cnn", channels[1].URL)
+}
+
+func TestFetchM3U_AllSourcesFail(t *testing.T) {
+	srv := newTestUpstream(t, map[string]string{})
+
+	cfg := newTestFetcherConfig(srv)
+	cfg.M3UBackupURLs = []string{srv.URL + "/backup.m3u"}
+
+	fetcher := NewFetcher(newTestLogger(), cfg, NewStore())
+
+	err := fetcher.FetchM3U(context.Background())
+	require.Error(t, err)
+	require.Equal(t, 2, strings.Count(err.Error(), "failed to fetch M3U"))
+}
+
+func TestFetchM3U_RedactsLoggedURLs(t *testing.T) {
+	srv := newTestUpstream(t, map[string]string{"/backup.m3u": testM3U})
+
+	cfg := newTestFetcherConfig(srv)
+	cfg.M3UURL = srv.URL + "/missing.m3u?password=hunter2"
+	cfg.M3UBackupURLs = []string{srv.URL + "/backup.m3u"}
+
+	var logged bytes.Buffer
+
+	logger := logrus.New()
+	logger.SetOutput(&logged)
+
+	require.NoError(t, NewFetcher(logger, cfg, NewStore()).FetchM3U(context.Background()))
+	require.Contains(t, logged.String(), "Fetching M3U playlist")
+	require.NotContains(t, logged.String(), "hunter2")
+}
+
+func TestFetchM3U_LoginRedirect(t *testing.T) {
+	loginPage := "<!DOCTYPE html>\n<html><head><title>Sign in</title></head><body><form>...</form></body></html>"
+
+	var expired atomic.Bool
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/playlist.m3u":
+			if expired.Load() {
+				http.Redirect(w, r, "/login?session=abc123&next=/playlist.m3u", http.StatusFound)
+
+				return
+			}
+
+			http.Redirect(w, r, "/cdn/playlist.m3u", http.StatusFound)
+		case "/cdn/playlist.m3u":
+			_, _ = io.WriteString(w, testM3U)
+		case "/login":
+			_, _ = io.WriteString(w, loginPage)
+		case "/epg.xml":
+			_, _ = io.WriteString(w, testEPG)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	store := NewStore()
+	fetcher := NewFetcher(newTestLogger(), newTestFetcherConfig(srv), store)
+
+	// A redirect to real playlist content is fine.
+	require.NoError(t, fetcher.FetchAll(context.Background()))
+
+	previous, ok := store.GetM3U()
+	require.True(t, ok)
+	require.Len(t, previous, 4)
+
+	expired.Store(true)
+
+	err := fetcher.FetchAll(context.Background())
+	require.ErrorIs(t, err, ErrUpstreamAuth)
+	require.Contains(t, err.Error(), srv.URL+"/login")
+	require.NotContains(t, err.Error(), "abc123")
+
+	// The last good playlist is kept.
+	current, ok := store.GetM3U()
+	require.True(t, ok)
+	require.Equal(t, previous, current)
+}
+
+func TestRecognizableContent(t *testing.T) {
+	require.True(t, recognizableContent([]byte(testM3U)))
+	require.True(t, recognizableContent([]byte("\xef\xbb\xbf#EXTM3U\n")))
+	require.True(t, recognizableContent([]byte(testEPG)))
+	require.True(t, recognizableContent([]byte{0x1f, 0x8b, 0x08}))
+	require.False(t, recognizableContent([]byte("<html><body>Login</body></html>")))
+	require.False(t, recognizableContent([]byte("Please sign in")))
+	require.False(t, recognizableContent(nil))
+}
+
+func TestFetchAll_CleanNames(t *testing.T) {
+	playlist := "#EXTM3U\n" +
+		"#EXTINF:-1 tvg-id=\"espn.us\",ESPN \u200b\n" +
+		"http://stream.example.com/espn\n" +
+		"#EXTINF:-1,CNN\u200b \n" +
+		"http://stream.example.com/cnn\n"
+
+	guide := `<tv>
+  <channel id="espn.us"><display-name>ESPN` + "\u200b " + `</display-name></channel>
+</tv>`
+
+	srv := newTestUpstream(t, map[string]string{
+		"/playlist.m3u": playlist,
+		"/epg.xml":      guide,
+	})
+
+	store := NewStore()
+	fetcher := NewFetcher(newTestLogger(), newTestFetcherConfig(srv), store)
+
+	require.NoError(t, fetcher.FetchAll(context.Background()))
+
+	channels, ok := store.GetM3U()
+	require.True(t, ok)
+	require.Equal(t, "ESPN", channels[0].Name)
+	require.Equal(t, "CNN", channels[1].Name)
+
+	epgData, channelMap, ok := store.GetEPG()
+	require.True(t, ok)
+	require.Equal(t, "ESPN", channelMap["espn.us"])
+
+	// The lineup GuideName (the channel name) and the EPG display-name are
+	// byte-identical, for matched and placeholder channels alike.
+	displayNames := make([]string, 0, len(epgData.Channels))
+	for _, ch := range epgData.Channels {
+		displayNames = append(displayNames, ch.DisplayName)
+	}
+
+	require.ElementsMatch(t, []string{"ESPN", "CNN"}, displayNames)
+}
+
+func TestReadInline(t *testing.T) {
+	data, err := readInline("data:,%3Ctv%2F%3E")
+	require.NoError(t, err)
+	require.Equal(t, "<tv/>", string(data))
+
+	data, err = readInline("DATA:application/xml;BASE64,PHR2Lz4=")
+	require.NoError(t, err)
+	require.Equal(t, "<tv/>", string(data))
+
+	_, err = readInline("data:application/xml;base64")
+	require.Error(t, err)
+
+	_, err = readInline("@" + filepath.Join(t.TempDir(), "missing.xml"))
+	require.Error(t, err)
+}
+
+func TestFetchEPG_RejectsHTMLSource(t *testing.T) {
+	htmlPage := "<!DOCTYPE html>\n<html><head><title>Error</title></head><body>Service unavailable</body></html>"
+
+	srv := newTestUpstream(t, map[string]string{
+		"/playlist.m3u": testM3U,
+		"/error.html":   htmlPage,
+		"/epg.xml":      testEPG,
+	})
+
+	cfg := newTestFetcherConfig(srv)
+	cfg.EPGURL = srv.URL + "/error.html," + srv.URL + "/epg.xml"
+
+	store := NewStore()
+	fetcher := NewFetcher(newTestLogger(), cfg, store)
+
+	require.NoError(t, fetcher.FetchAll(context.Background()))
+
+	_, channelMap, ok := store.GetEPG()
+	require.True(t, ok)
+	require.Equal(t, "ESPN", channelMap["espn.us"])
+
+	// With only the HTML source the refresh fails and the previous EPG stays.
+	cfg.EPGURL = srv.URL + "/error.html"
+	cfg.EPGFailure = config.EPGFailureFail
+
+	previous, _, _ := store.GetEPG()
+
+	err := fetcher.FetchAll(context.Background())
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "all EPG sources failed")
+
+	current, _, _ := store.GetEPG()
+	require.Same(t, previous, current)
+}
+
+func TestFetchEPG_RejectsEmptyLargeSource(t *testing.T) {
+	// A well-formed document with no channels or programmes, padded well
+	// beyond a trivially empty guide.
+	emptyEPG := "<tv>" + strings.Repeat("<!-- padding -->", 100) + "</tv>"
+
+	srv := newTestUpstream(t, map[string]string{
+		"/playlist.m3u": testM3U,
+		"/epg.xml":      emptyEPG,
+	})
+
+	cfg := newTestFetcherConfig(srv)
+	cfg.EPGFailure = config.EPGFailureFail
+
+	store := NewStore()
+	fetcher := NewFetcher(newTestLogger(), cfg, store)
+
+	err := fetcher.FetchAll(context.Background())
+	require.Error(t, err)
+	require.ErrorIs(t, err, ErrAllEPGSourcesFailed)
+}
+
+func TestFetchEPG_AllSourcesFailed(t *testing.T) {
+	newFetcher := func(t *testing.T, failure string) (*Fetcher, *Store, *config.Config) {
+		t.Helper()
+
+		srv := newTestUpstream(t, map[string]string{
+			"/playlist.m3u": testM3U,
+			"/epg.xml":      testEPG,
+		})
+
+		cfg := newTestFetcherConfig(srv)
+		cfg.EPGFailure = failure
+		store := NewStore()
+
+		return NewFetcher(newTestLogger(), cfg, store), store, cfg
+	}
+
+	t.Run("keep", func(t *testing.T) {
+		fetcher, store, cfg := newFetcher(t, config.EPGFailureKeep)
+		require.NoError(t, fetcher.FetchAll(context.Background()))
+
+		previous, _, _ := store.GetEPG()
+
+		cfg.EPGURL = strings.TrimSuffix(cfg.EPGURL, "/epg.xml") + "/missing.xml"
+		require.NoError(t, fetcher.FetchAll(context.Background()))
+
+		current, channelMap, _ := store.GetEPG()
+		require.Same(t, previous, current)
+		require.Equal(t, "ESPN", channelMap["espn.us"])
+	})
+
+	t.Run("keep without previous data", func(t *testing.T) {
+		fetcher, store, cfg := newFetcher(t, config.EPGFailureKeep)
+		cfg.EPGURL = strings.TrimSuffix(cfg.EPGURL, "/epg.xml") + "/missing.xml"
+
+		require.NoError(t, fetcher.FetchAll(context.Background()))
+
+		channels, ok := store.GetM3U()
+		require.True(t, ok)
+		require.Len(t, channels, 4)
+
+		epgData, channelMap, ok := store.GetEPG()
+		require.True(t, ok)
+		require.Empty(t, channelMap)
+		require.Len(t, epgData.Channels, 4)
+	})
+
+	t.Run("fake", func(t *testing.T) {
+		fetcher, store, cfg := newFetcher(t, config.EPGFailureFake)
+		require.NoError(t, fetcher.FetchAll(context.Background()))
+
+		cfg.EPGURL = strings.TrimSuffix(cfg.EPGURL, "/epg.xml") + "/missing.xml"
+		require.NoError(t, fetcher.FetchAll(context.Background()))
+
+		epgData, channelMap, ok := store.GetEPG()
+		require.True(t, ok)
+		require.Empty(t, channelMap)
+		require.Len(t, epgData.Channels, 4)
+
+		for _, prog := range epgData.Programs {
+			require.NotEqual(t, "SportsCenter", prog.Title)
+		}
+	})
+
+	t.Run("fail", func(t *testing.T) {
+		fetcher, store, cfg := newFetcher(t, config.EPGFailureFail)
+		cfg.EPGURL = strings.TrimSuffix(cfg.EPGURL, "/epg.xml") + "/missing.xml"
+
+		err := fetcher.FetchAll(context.Background())
+		require.ErrorIs(t, err, ErrAllEPGSourcesFailed)
+
+		// The M3U fetched before the EPG failure is kept.
+		channels, ok := store.GetM3U()
+		require.True(t, ok)
+		require.Len(t, channels, 4)
+
+		_, _, ok = store.GetEPG()
+		require.False(t, ok)
+	})
+}
+
+func TestFetch_GzipMultipleMembers(t *testing.T) {
+	// Split the EPG across two independently compressed gzip members.
+	half := len(testEPG) / 2
+
+	var body bytes.Buffer
+
+	for _, part := range []string{testEPG[:half], testEPG[half:]} {
+		gz := gzip.NewWriter(&body)
+		_, err := gz.Write([]byte(part))
+		require.NoError(t, err)
+		require.NoError(t, gz.Close())
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Encoding", "gzip")
+		_, _ = w.Write(body.Bytes())
+	}))
+	t.Cleanup(srv.Close)
+
+	fetcher := NewFetcher(newTestLogger(), newTestFetcherConfig(srv), NewStore())
+
+	data, err := fetcher.fetch(context.Background(), srv.URL+"/epg.xml", nil)
+	require.NoError(t, err)
+	require.Equal(t, testEPG, string(data))
+}
+
+func TestFetchAll_OnRefresh(t *testing.T) {
+	srv := newTestUpstream(t, map[string]string{
+		"/playlist.m3u": testM3U,
+		"/epg.xml":      testEPG,
+	})
+
+	store := NewStore()
+	fetcher := NewFetcher(newTestLogger(), newTestFetcherConfig(srv), store)
+
+	refreshes := 0
+
+	fetcher.OnRefresh(func() {
+		// The new guide is stored before the callback runs.
+		_, _, ok := store.GetEPG()
+		require.True(t, ok)
+
+		refreshes++
+	})
+
+	require.NoError(t, fetcher.FetchAll(context.Background()))
+	require.Equal(t, 1, refreshes)
+}
+
+// newFlakyUpstream serves testEPG, dropping the connection halfway through the
+// first response. rangeSupported controls whether Range requests are honoured;
+// contentRange overrides the Content-Range of the partial response.
+func newFlakyUpstream(t *testing.T, rangeSupported bool, contentRange string) (*httptest.Server, *[]string) {
+	t.Helper()
+
+	half := len(testEPG) / 2
+	ranges := make([]string, 0)
+	requests := 0
+
+	if contentRange == "" {
+		contentRange = fmt.Sprintf("bytes %d-%d/%d", half, len(testEPG)-1, len(testEPG))
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		ranges = append(ranges, r.Header.Get("Range"))
+
+		if rangeSupported {
+			w.Header().Set("Accept-Ranges", "bytes")
+			w.Header().Set("ETag", `"v1"`)
+		}
+
+		if requests == 1 {
+			w.Header().Set("Content-Length", strconv.Itoa(len(testEPG)))
+			_, _ = w.Write([]byte(testEPG[:half]))
+			w.(http.Flusher).Flush() //nolint:forcetypeassert // httptest server supports flushing
+
+			panic(http.ErrAbortHandler)
+		}
+
+		if rangeSupported && r.Header.Get("Range") == fmt.Sprintf("bytes=%d-", half) && r.Header.Get("If-Range") == `"v1"` {
+			w.Header().Set("Content-Range", contentRange)
+			w.WriteHeader(http.StatusPartialContent)
+			_, _ = w.Write([]byte(testEPG[half:]))
+
+			return
+		}
+
+		_, _ = w.Write([]byte(testEPG))
+	}))
+	t.Cleanup(srv.Close)
+
+	return srv, &ranges
+}
+
+func TestFetch_ResumesWithRange(t *testing.T) {
+	srv, ranges := newFlakyUpstream(t, true, "")
+
+	cfg := newTestFetcherConfig(srv)
+	cfg.ResumeDownloads = true
+
+	data, err := NewFetcher(newTestLogger(), cfg, NewStore()).fetch(context.Background(), srv.URL+"/epg.xml", nil)
+	require.NoError(t, err)
+	require.Equal(t, testEPG, string(data))
+	require.Equal(t, []string{"", fmt.Sprintf("bytes=%d-", len(testEPG)/2)}, *ranges)
+}
+
+func TestFetch_NoResumeWithoutAcceptRanges(t *testing.T) {
+	srv, _ := newFlakyUpstream(t, false, "")
+
+	cfg := newTestFetcherConfig(srv)
+	cfg.ResumeDownloads = true
+
+	// Without Accept-Ranges the interrupted download isn't resumed.
+	_, err := NewFetcher(newTestLogger(), cfg, NewStore()).fetch(context.Background(), srv.URL+"/epg.xml", nil)
+	require.Error(t, err)
+}
+
+func TestFetch_RestartsOnContentRangeMismatch(t *testing.T) {
+	srv, ranges := newFlakyUpstream(t, true, "bytes 0-10/100")
+
+	cfg := newTestFetcherConfig(srv)
+	cfg.ResumeDownloads = true
+
+	// The partial response doesn't continue where the first one stopped, so
+	// the download starts over instead of splicing in the wrong bytes.
+	data, err := NewFetcher(newTestLogger(), cfg, NewStore()).fetch(context.Background(), srv.URL+"/epg.xml", nil)
+	require.NoError(t, err)
+	require.Equal(t, testEPG, string(data))
+	require.Equal(t, []string{"", fmt.Sprintf("bytes=%d-", len(testEPG)/2), ""}, *ranges)
+}
+
+func TestFetch_ResumeIgnoredRangeRedownloads(t *testing.T) {
+	half := len(testEPG) / 2
+	requests := 0
+
+	// Advertises ranges but answers the Range request with the full body.
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		requests++
+
+		w.Header().Set("Accept-Ranges", "bytes")
+		w.Header().Set("Last-Modified", "Mon, 02 Jan 2006 15:04:05 GMT")
+
+		if requests == 1 {
+			w.Header().Set("Content-Length", strconv.Itoa(len(testEPG)))
+			_, _ = w.Write([]byte(testEPG[:half]))
+			w.(http.Flusher).Flush() //nolint:forcetypeassert // httptest server supports flushing
+
+			panic(http.ErrAbortHandler)
+		}
+
+		_, _ = w.Write([]byte(testEPG))
+	}))
+	t.Cleanup(srv.Close)
+
+	cfg := newTestFetcherConfig(srv)
+	cfg.ResumeDownloads = true
+
+	data, err := NewFetcher(newTestLogger(), cfg, NewStore()).fetch(context.Background(), srv.URL+"/epg.xml", nil)
+	require.NoError(t, err)
+	require.Equal(t, testEPG, string(data))
+}
@@ -0,0 +1,78 @@
+package data
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// HostLimiter bounds the number of concurrent upstream connections per host.
+// Callers beyond the limit queue until a slot frees up.
+type HostLimiter struct {
+	mu    sync.Mutex
+	slots map[string]chan struct{}
+}
+
+// NewHostLimiter creates an empty host limiter.
+func NewHostLimiter() *HostLimiter {
+	return &HostLimiter{
+		slots: make(map[string]chan struct{}),
+	}
+}
+
+// Acquire blocks until a connection slot for host is free or ctx is done, and
+// returns a function releasing the slot (safe to call more than once). A limit
+// of 0 or less is unlimited. The limit for a host is fixed by its first use.
+func (l *HostLimiter) Acquire(ctx context.Context, host string, limit int) (func(), error) {
+	if limit <= 0 {
+		return func() {}, nil
+	}
+
+	slots := l.hostSlots(host, limit)
+
+	select {
+	case slots <- struct{}{}:
+	case <-ctx.Done():
+		return nil, fmt.Errorf("waiting for connection slot to %s: %w", host, ctx.Err())
+	}
+
+	return releaseSlot(slots), nil
+}
+
+// TryAcquire is Acquire without waiting: it returns false if no slot for
+// host is free.
+func (l *HostLimiter) TryAcquire(host string, limit int) (func(), bool) {
+	if limit <= 0 {
+		return func() {}, true
+	}
+
+	slots := l.hostSlots(host, limit)
+
+	select {
+	case slots <- struct{}{}:
+		return releaseSlot(slots), true
+	default:
+		return nil, false
+	}
+}
+
+func (l *HostLimiter) hostSlots(host string, limit int) chan struct{} {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	slots, ok := l.slots[host]
+	if !ok {
+		slots = make(chan struct{}, limit)
+		l.slots[host] = slots
+	}
+
+	return slots
+}
+
+func releaseSlot(slots chan struct{}) func() {
+	var once sync.Once
+
+	return func() {
+		once.Do(func() { <-slots })
+	}
+}
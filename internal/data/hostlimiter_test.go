@@ -0,0 +1,70 @@
+package data
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestHostLimiter(t *testing.T) {
+	limiter := NewHostLimiter()
+
+	release1, err := limiter.Acquire(context.Background(), "a.example.com", 1)
+	require.NoError(t, err)
+
+	// Other hosts have their own slots.
+	releaseOther, err := limiter.Acquire(context.Background(), "b.example.com", 1)
+	require.NoError(t, err)
+	releaseOther()
+
+	// The host is full, so a second caller waits until its context ends.
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	_, err = limiter.Acquire(ctx, "a.example.com", 1)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+
+	// Releasing twice frees only one slot.
+	release1()
+	release1()
+
+	release2, err := limiter.Acquire(context.Background(), "a.example.com", 1)
+	require.NoError(t, err)
+
+	ctx2, cancel2 := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel2()
+
+	_, err = limiter.Acquire(ctx2, "a.example.com", 1)
+	require.Error(t, err)
+
+	release2()
+}
+
+func TestHostLimiter_TryAcquire(t *testing.T) {
+	limiter := NewHostLimiter()
+
+	release, ok := limiter.TryAcquire("a.example.com", 1)
+	require.True(t, ok)
+
+	_, ok = limiter.TryAcquire("a.example.com", 1)
+	require.False(t, ok)
+
+	release()
+
+	_, ok = limiter.TryAcquire("a.example.com", 1)
+	require.True(t, ok)
+
+	_, ok = limiter.TryAcquire("a.example.com", 0)
+	require.True(t, ok)
+}
+
+func TestHostLimiter_Unlimited(t *testing.T) {
+	limiter := NewHostLimiter()
+
+	for range 100 {
+		_, err := limiter.Acquire(context.Background(), "a.example.com", 0)
+		require.NoError(t, err)
+	}
+}
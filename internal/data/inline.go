@@ -0,0 +1,31 @@
+package data
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// readInline returns the content of an inline source: the file named after
+// a leading "@", or the payload of a data: URI (base64 or percent-encoded).
+func readInline(ref string) ([]byte, error) {
+	if path, ok := strings.CutPrefix(ref, "@"); ok {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read inline file: %w", err)
+		}
+
+		return data, nil
+	}
+
+	if !IsDataURI(ref) {
+		return nil, fmt.Errorf("unsupported inline source %q", ref)
+	}
+
+	decoded, err := decodeDataURI(ref)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode data URI: %w", err)
+	}
+
+	return decoded.Data, nil
+}
@@ -0,0 +1,157 @@
+package data
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"mime"
+	"net/url"
+	"strings"
+
+	"github.com/savid/iptv/internal/config"
+	"github.com/savid/iptv/internal/epg"
+	"github.com/savid/iptv/internal/m3u"
+	"github.com/sirupsen/logrus"
+)
+
+// LogoPathPrefix is the URL path under which decoded data URI logos are served.
+const LogoPathPrefix = "/logos/"
+
+// Logo is a decoded data URI logo.
+type Logo struct {
+	ContentType string
+	Data        []byte
+}
+
+// IsDataURI returns true if s is a data URI (e.g. "data:image/png;base64,...").
+func IsDataURI(s string) bool {
+	return len(s) >= 5 && strings.EqualFold(s[:5], "data:")
+}
+
+// decodeDataURI decodes a "data:[<mediatype>][;base64],<data>" URI.
+func decodeDataURI(uri string) (Logo, error) {
+	if !IsDataURI(uri) {
+		return Logo{}, errors.New("not a data URI")
+	}
+
+	header, payload, found := strings.Cut(uri[5:], ",")
+	if !found {
+		return Logo{}, errors.New("data URI has no payload")
+	}
+
+	contentType := header
+	isBase64 := false
+
+	if strings.HasSuffix(strings.ToLower(header), ";base64") {
+		contentType = header[:len(header)-len(";base64")]
+		isBase64 = true
+	}
+
+	if contentType == "" {
+		contentType = "text/plain;charset=US-ASCII"
+	}
+
+	if isBase64 {
+		decoded, err := base64.StdEncoding.DecodeString(payload)
+		if err != nil {
+			return Logo{}, fmt.Errorf("invalid base64 payload: %w", err)
+		}
+
+		return Logo{ContentType: contentType, Data: decoded}, nil
+	}
+
+	decoded, err := url.PathUnescape(payload)
+	if err != nil {
+		return Logo{}, fmt.Errorf("invalid payload: %w", err)
+	}
+
+	return Logo{ContentType: contentType, Data: []byte(decoded)}, nil
+}
+
+// IsImageType returns true if contentType is an image/* media type. Only
+// images are served as logos, so a data URI can't place HTML or script on the
+// proxy's origin.
+func IsImageType(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+
+	return err == nil && strings.HasPrefix(mediaType, "image/")
+}
+
+// decodeLogo decodes a data URI logo, rejecting anything but images.
+func decodeLogo(uri string) (Logo, error) {
+	logo, err := decodeDataURI(uri)
+	if err != nil {
+		return Logo{}, err
+	}
+
+	if !IsImageType(logo.ContentType) {
+		return Logo{}, fmt.Errorf("data URI logo is not an image: %q", logo.ContentType)
+	}
+
+	return logo, nil
+}
+
+// logoRewriter replaces data URI logos according to the configured mode,
+// collecting decoded logos to serve when the mode is "serve".
+type logoRewriter struct {
+	log     logrus.FieldLogger
+	mode    string
+	baseURL string
+	logos   map[string]Logo
+}
+
+func newLogoRewriter(log logrus.FieldLogger, cfg *config.Config) *logoRewriter {
+	return &logoRewriter{
+		log:     log,
+		mode:    cfg.DataURILogos,
+		baseURL: cfg.BaseURL,
+		logos:   make(map[string]Logo),
+	}
+}
+
+// enabled returns false when data URIs are passed through untouched.
+func (r *logoRewriter) enabled() bool {
+	return r.mode == config.DataURILogosStrip || r.mode == config.DataURILogosServe
+}
+
+// rewrite returns the logo URL to publish for src.
+func (r *logoRewriter) rewrite(src string) string {
+	if !IsDataURI(src) {
+		return src
+	}
+
+	switch r.mode {
+	case config.DataURILogosStrip:
+		return ""
+	case config.DataURILogosServe:
+		logo, err := decodeLogo(src)
+		if err != nil {
+			r.log.WithError(err).Debug("Dropped undecodable data URI logo")
+
+			return ""
+		}
+
+		sum := sha256.Sum256(logo.Data)
+		key := fmt.Sprintf("%x", sum[:16])
+		r.logos[key] = logo
+
+		return r.baseURL + LogoPathPrefix + key
+	default:
+		return src
+	}
+}
+
+// rewriteChannels applies rewrite to each channel's tvg-logo.
+func (r *logoRewriter) rewriteChannels(channels []m3u.Channel) {
+	for i := range channels {
+		channels[i].TVGLogo = r.rewrite(channels[i].TVGLogo)
+	}
+}
+
+// rewriteEPG applies rewrite to each EPG channel icon.
+func (r *logoRewriter) rewriteEPG(tv *epg.TV) {
+	for i := range tv.Channels {
+		tv.Channels[i].Icon.Src = r.rewrite(tv.Channels[i].Icon.Src)
+	}
+}
@@ -0,0 +1,124 @@
+package data
+
+import (
+	"context"
+	"encoding/base64"
+	"strings"
+	"testing"
+
+	"github.com/savid/iptv/internal/config"
+	"github.com/savid/iptv/internal/epg"
+	"github.com/savid/iptv/internal/m3u"
+	"github.com/stretchr/testify/require"
+)
+
+// pngHeader is enough of a PNG to stand in for a logo.
+var pngHeader = []byte{0x89, 'P', 'N', 'G', '\r', '\n', 0x1a, '\n'}
+
+func TestDecodeDataURI(t *testing.T) {
+	logo, err := decodeDataURI("data:image/png;base64," + base64.StdEncoding.EncodeToString(pngHeader))
+	require.NoError(t, err)
+	require.Equal(t, "image/png", logo.ContentType)
+	require.Equal(t, pngHeader, logo.Data)
+
+	logo, err = decodeDataURI("data:image/svg+xml,%3Csvg%2F%3E")
+	require.NoError(t, err)
+	require.Equal(t, "image/svg+xml", logo.ContentType)
+	require.Equal(t, "<svg/>", string(logo.Data))
+
+	_, err = decodeDataURI("data:image/png;base64")
+	require.Error(t, err)
+
+	_, err = decodeDataURI("http://logo.example.com/espn.png")
+	require.Error(t, err)
+}
+
+func TestLogoRewriter_Modes(t *testing.T) {
+	dataURI := "data:image/png;base64," + base64.StdEncoding.EncodeToString(pngHeader)
+	regular := "http://logo.example.com/espn.png"
+
+	cfg := config.DefaultConfig()
+	cfg.BaseURL = "http://localhost:8080"
+
+	rw := newLogoRewriter(newTestLogger(), cfg)
+	require.False(t, rw.enabled())
+	require.Equal(t, dataURI, rw.rewrite(dataURI))
+
+	cfg.DataURILogos = config.DataURILogosStrip
+
+	rw = newLogoRewriter(newTestLogger(), cfg)
+	require.Empty(t, rw.rewrite(dataURI))
+	require.Equal(t, regular, rw.rewrite(regular))
+
+	cfg.DataURILogos = config.DataURILogosServe
+
+	rw = newLogoRewriter(newTestLogger(), cfg)
+	served := rw.rewrite(dataURI)
+	require.True(t, strings.HasPrefix(served, cfg.BaseURL+LogoPathPrefix))
+	require.Equal(t, regular, rw.rewrite(regular))
+
+	key := strings.TrimPrefix(served, cfg.BaseURL+LogoPathPrefix)
+	require.Equal(t, Logo{ContentType: "image/png", Data: pngHeader}, rw.logos[key])
+}
+
+func TestFetchM3U_ServesDataURILogos(t *testing.T) {
+	playlist := `#EXTM3U
+#EXTINF:-1 tvg-id="espn.us" tvg-logo="data:image/png;base64,` + base64.StdEncoding.EncodeToString(pngHeader) + `",ESPN
+http://stream.example.com/espn
+`
+
+	srv := newTestUpstream(t, map[string]string{"/playlist.m3u": playlist})
+
+	cfg := newTestFetcherConfig(srv)
+	cfg.DataURILogos = config.DataURILogosServe
+
+	store := NewStore()
+	fetcher := NewFetcher(newTestLogger(), cfg, store)
+
+	require.NoError(t, fetcher.FetchM3U(context.Background()))
+
+	channels, ok := store.GetM3U()
+	require.True(t, ok)
+	require.True(t, strings.HasPrefix(channels[0].TVGLogo, cfg.BaseURL+LogoPathPrefix))
+
+	logo, ok := store.Logo(strings.TrimPrefix(channels[0].TVGLogo, cfg.BaseURL+LogoPathPrefix))
+	require.True(t, ok)
+	require.Equal(t, pngHeader, logo.Data)
+
+	// The rewritten playlist no longer carries the data URI.
+	require.NotContains(t, m3u.Rewrite(channels, nil), "data:")
+}
+
+func TestLogoRewriter_RejectsNonImages(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.BaseURL = "http://localhost:8080"
+	cfg.DataURILogos = config.DataURILogosServe
+
+	rw := newLogoRewriter(newTestLogger(), cfg)
+	require.Empty(t, rw.rewrite("data:text/html,%3Cscript%3Ealert(1)%3C%2Fscript%3E"))
+	require.Empty(t, rw.rewrite("data:,plain"))
+	require.Empty(t, rw.logos)
+}
+
+func TestStore_PruneLogos(t *testing.T) {
+	store := NewStore()
+	store.AddLogos(map[string]Logo{
+		"playlist": {ContentType: "image/png"},
+		"guide":    {ContentType: "image/png"},
+		"stale":    {ContentType: "image/png"},
+	})
+	store.SetEPG(&epg.TV{Channels: []epg.Channel{
+		{ID: "espn.us", Icon: epg.Icon{Src: "http://localhost:8080" + LogoPathPrefix + "guide"}},
+	}}, nil)
+
+	// A playlist refresh keeps the guide's logos.
+	store.SetM3U([]m3u.Channel{{Name: "ESPN", TVGLogo: "http://localhost:8080" + LogoPathPrefix + "playlist"}})
+	store.PruneLogos()
+
+	_, ok := store.Logo("playlist")
+	require.True(t, ok)
+	_, ok = store.Logo("guide")
+	require.True(t, ok)
+	_, ok = store.Logo("stale")
+	require.False(t, ok)
+}
@@ -0,0 +1,228 @@
+package data
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/savid/iptv/internal/config"
+	"github.com/sirupsen/logrus"
+)
+
+// errNoUpstreamSlot is returned by probe when the stream's host has no free
+// connection slot. Viewers take priority, so the probe is skipped.
+var errNoUpstreamSlot = errors.New("no free upstream connection slot")
+
+// Prober periodically checks every channel's upstream stream URL and records
+// the outcome in the store's channel health.
+type Prober struct {
+	log    logrus.FieldLogger
+	cfg    *config.Config
+	store  *Store
+	client *http.Client
+
+	mu     sync.Mutex
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// NewProber creates a new channel prober.
+func NewProber(log logrus.FieldLogger, cfg *config.Config, store *Store) *Prober {
+	return &Prober{
+		log:   log.WithField("component", "prober"),
+		cfg:   cfg,
+		store: store,
+		client: &http.Client{
+			Timeout: cfg.StreamTimeout,
+		},
+	}
+}
+
+// Start begins probing immediately and then every ProbeInterval.
+func (p *Prober) Start(ctx context.Context) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.cancel != nil {
+		return nil // Already running
+	}
+
+	probeCtx, cancel := context.WithCancel(ctx)
+	p.cancel = cancel
+	p.done = make(chan struct{})
+
+	go p.run(probeCtx)
+
+	p.log.WithField("interval", p.cfg.ProbeInterval).Info("Channel prober started")
+
+	return nil
+}
+
+// Stop stops probing and waits for the current round to end.
+func (p *Prober) Stop() error {
+	p.mu.Lock()
+	cancel := p.cancel
+	done := p.done
+	p.cancel = nil
+	p.done = nil
+	p.mu.Unlock()
+
+	if cancel != nil {
+		cancel()
+
+		if done != nil {
+			<-done
+		}
+	}
+
+	p.log.Info("Channel prober stopped")
+
+	return nil
+}
+
+func (p *Prober) run(ctx context.Context) {
+	defer close(p.done)
+
+	p.ProbeAll(ctx)
+
+	ticker := time.NewTicker(p.cfg.ProbeInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			p.ProbeAll(ctx)
+		}
+	}
+}
+
+// ProbeAll probes each distinct channel URL once, at most ProbeConcurrency
+// at a time and starting at most ProbeRate per second. Probes share the
+// per-host upstream connection limit with viewers but never wait for it: a
+// URL whose host has no free slot is skipped until the next round.
+func (p *Prober) ProbeAll(ctx context.Context) {
+	channels, ok := p.store.GetM3U()
+	if !ok {
+		return
+	}
+
+	urls := make([]string, 0, len(channels))
+	seen := make(map[string]bool, len(channels))
+
+	for _, ch := range channels {
+		if ch.URL != "" && !seen[ch.URL] {
+			seen[ch.URL] = true
+			urls = append(urls, ch.URL)
+		}
+	}
+
+	health := p.store.ChannelHealth()
+	health.Retain(urls)
+
+	// Validated in config.
+	pace := time.NewTicker(time.Second / time.Duration(p.cfg.ProbeRate))
+	defer pace.Stop()
+
+	slots := make(chan struct{}, p.cfg.ProbeConcurrency)
+
+	var wg sync.WaitGroup
+
+	defer wg.Wait()
+
+	for i, streamURL := range urls {
+		if i > 0 {
+			select {
+			case <-ctx.Done():
+				return
+			case <-pace.C:
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case slots <- struct{}{}:
+		}
+
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+			defer func() { <-slots }()
+
+			if err := p.probe(ctx, streamURL); err != nil {
+				if ctx.Err() != nil {
+					return
+				}
+
+				if errors.Is(err, errNoUpstreamSlot) {
+					p.log.WithField("url", config.RedactURL(streamURL)).Debug("Skipped channel probe, upstream host busy")
+
+					return
+				}
+
+				health.RecordFailure(streamURL, err)
+				p.log.WithField("url", config.RedactURL(streamURL)).WithField("error", redactError(err)).Debug("Channel probe failed")
+
+				return
+			}
+
+			health.RecordSuccess(streamURL)
+		}()
+	}
+}
+
+// probe checks that streamURL answers with a successful status. HEAD is tried
+// first; servers that don't support it get a GET whose body is not read.
+func (p *Prober) probe(ctx context.Context, streamURL string) error {
+	parsed, err := url.Parse(streamURL)
+	if err != nil {
+		return fmt.Errorf("invalid stream URL: %w", err)
+	}
+
+	release, ok := p.store.Upstreams().TryAcquire(parsed.Host, p.cfg.MaxConnsPerHost)
+	if !ok {
+		return errNoUpstreamSlot
+	}
+	defer release()
+
+	status, err := p.request(ctx, http.MethodHead, streamURL)
+	if err == nil && (status == http.StatusMethodNotAllowed || status == http.StatusNotImplemented) {
+		status, err = p.request(ctx, http.MethodGet, streamURL)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if status >= http.StatusBadRequest {
+		return fmt.Errorf("unexpected status %d", status)
+	}
+
+	return nil
+}
+
+func (p *Prober) request(ctx context.Context, method, streamURL string) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, method, streamURL, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create probe request: %w", err)
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("probe request failed: %w", err)
+	}
+
+	// Live streams never end, so the body is closed unread.
+	if err := resp.Body.Close(); err != nil {
+		p.log.WithError(err).Debug("Failed to close probe response body")
+	}
+
+	return resp.StatusCode, nil
+}
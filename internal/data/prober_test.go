@@ -0,0 +1,142 @@
+package data
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/savid/iptv/internal/m3u"
+	"github.com/stretchr/testify/require"
+)
+
+// newProbeUpstreams returns a live stream, a live stream that rejects HEAD,
+// a stream answering 404, and the URL of a server that is down.
+func newProbeUpstreams(t *testing.T) (live, noHead, missing, down string) {
+	t.Helper()
+
+	liveSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	t.Cleanup(liveSrv.Close)
+
+	noHeadSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodHead {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+	}))
+	t.Cleanup(noHeadSrv.Close)
+
+	missingSrv := httptest.NewServer(http.NotFoundHandler())
+	t.Cleanup(missingSrv.Close)
+
+	downSrv := httptest.NewServer(http.NotFoundHandler())
+	downSrv.Close()
+
+	return liveSrv.URL + "/live", noHeadSrv.URL + "/live", missingSrv.URL + "/gone", downSrv.URL + "/live"
+}
+
+func TestProber_ProbeAll(t *testing.T) {
+	live, noHead, missing, down := newProbeUpstreams(t)
+
+	cfg := newTestFetcherConfig(newTestUpstream(t, nil))
+	cfg.ProbeRate = 1000
+	cfg.DeadAfter = 2
+
+	store := NewStore()
+	store.SetM3U([]m3u.Channel{
+		{Name: "Live", URL: live},
+		{Name: "No HEAD", URL: noHead},
+		{Name: "Missing", URL: missing},
+		{Name: "Down", URL: down},
+	})
+
+	prober := NewProber(newTestLogger(), cfg, store)
+	health := store.ChannelHealth()
+
+	prober.ProbeAll(context.Background())
+
+	for _, url := range []string{live, noHead} {
+		probe, ok := health.Get(url)
+		require.True(t, ok)
+		require.True(t, probe.Alive, url)
+		require.Zero(t, probe.Failures)
+		require.False(t, probe.LastSuccess.IsZero())
+	}
+
+	missingProbe, ok := health.Get(missing)
+	require.True(t, ok)
+	require.False(t, missingProbe.Alive)
+	require.Contains(t, missingProbe.LastError, "404")
+
+	// One failure is not yet dead with --dead-after 2.
+	require.False(t, health.Dead(missing, cfg.DeadAfter))
+
+	prober.ProbeAll(context.Background())
+
+	require.True(t, health.Dead(missing, cfg.DeadAfter))
+	require.True(t, health.Dead(down, cfg.DeadAfter))
+	require.False(t, health.Dead(live, cfg.DeadAfter))
+	require.False(t, health.Dead(noHead, cfg.DeadAfter))
+
+	// Channels dropped from the playlist lose their history.
+	store.SetM3U([]m3u.Channel{{Name: "Live", URL: live}})
+	prober.ProbeAll(context.Background())
+
+	_, ok = health.Get(missing)
+	require.False(t, ok)
+}
+
+func TestChannelHealth_RecoveryResetsFailures(t *testing.T) {
+	health := NewChannelHealthTracker()
+
+	require.False(t, health.Dead("http://stream.example.com/a", 1), "unprobed channels are not dead")
+
+	health.RecordFailure("http://stream.example.com/a", context.DeadlineExceeded)
+	health.RecordFailure("http://stream.example.com/a", context.DeadlineExceeded)
+	require.True(t, health.Dead("http://stream.example.com/a", 2))
+
+	health.RecordSuccess("http://stream.example.com/a")
+	require.False(t, health.Dead("http://stream.example.com/a", 1))
+
+	probe, ok := health.Get("http://stream.example.com/a")
+	require.True(t, ok)
+	require.Empty(t, probe.LastError)
+}
+
+func TestProber_SkipsBusyHosts(t *testing.T) {
+	live, _, _, _ := newProbeUpstreams(t)
+
+	cfg := newTestFetcherConfig(newTestUpstream(t, nil))
+	cfg.ProbeRate = 1000
+	cfg.MaxConnsPerHost = 1
+
+	store := NewStore()
+	store.SetM3U([]m3u.Channel{{Name: "Live", URL: live}})
+
+	parsed, err := url.Parse(live)
+	require.NoError(t, err)
+
+	// A viewer holds the host's only slot, so the probe is skipped rather
+	// than queued or recorded as a failure.
+	release, err := store.Upstreams().Acquire(context.Background(), parsed.Host, cfg.MaxConnsPerHost)
+	require.NoError(t, err)
+
+	prober := NewProber(newTestLogger(), cfg, store)
+	prober.ProbeAll(context.Background())
+
+	_, ok := store.ChannelHealth().Get(live)
+	require.False(t, ok)
+
+	release()
+	prober.ProbeAll(context.Background())
+
+	probe, ok := store.ChannelHealth().Get(live)
+	require.True(t, ok)
+	require.True(t, probe.Alive)
+}
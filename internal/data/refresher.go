@@ -14,6 +14,12 @@ type Refresher struct {
 	fetcher  *Fetcher
 	interval time.Duration
 
+	// Daily wall-clock schedule; when set, replaces the interval.
+	daily       bool
+	dailyHour   int
+	dailyMinute int
+	now         func() time.Time
+
 	mu     sync.Mutex
 	cancel context.CancelFunc
 	done   chan struct{}
@@ -25,7 +31,30 @@ func NewRefresher(log logrus.FieldLogger, fetcher *Fetcher, interval time.Durati
 		log:      log.WithField("component", "refresher"),
 		fetcher:  fetcher,
 		interval: interval,
+		now:      time.Now,
+	}
+}
+
+// SetDailyAt makes the refresher run once a day at the given local
+// wall-clock time instead of every interval.
+func (r *Refresher) SetDailyAt(hour, minute int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.daily = true
+	r.dailyHour = hour
+	r.dailyMinute = minute
+}
+
+// nextDailyRun returns the next occurrence of the daily refresh time strictly
+// after now, in now's location.
+func (r *Refresher) nextDailyRun(now time.Time) time.Time {
+	next := time.Date(now.Year(), now.Month(), now.Day(), r.dailyHour, r.dailyMinute, 0, 0, now.Location())
+	if !next.After(now) {
+		next = time.Date(now.Year(), now.Month(), now.Day()+1, r.dailyHour, r.dailyMinute, 0, 0, now.Location())
 	}
+
+	return next
 }
 
 // Start begins the refresh loop.
@@ -41,6 +70,14 @@ func (r *Refresher) Start(ctx context.Context) error {
 	r.cancel = cancel
 	r.done = make(chan struct{})
 
+	if r.daily {
+		go r.runDaily(refreshCtx)
+
+		r.log.WithField("next", r.nextDailyRun(r.now())).Info("Data refresher started")
+
+		return nil
+	}
+
 	go r.run(refreshCtx)
 
 	r.log.WithField("interval", r.interval).Info("Data refresher started")
@@ -86,6 +123,26 @@ func (r *Refresher) run(ctx context.Context) {
 	}
 }
 
+// runDaily refreshes once a day at the configured wall-clock time. The next
+// run is recomputed after each refresh, so DST changes and slow refreshes
+// don't cause drift.
+func (r *Refresher) runDaily(ctx context.Context) {
+	defer close(r.done)
+
+	for {
+		timer := time.NewTimer(r.nextDailyRun(r.now()).Sub(r.now()))
+
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+
+			return
+		case <-timer.C:
+			r.refresh(ctx)
+		}
+	}
+}
+
 func (r *Refresher) refresh(ctx context.Context) {
 	r.log.Info("Refreshing data")
 
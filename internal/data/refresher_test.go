@@ -0,0 +1,48 @@
+package data
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRefresher_NextDailyRun(t *testing.T) {
+	loc := time.FixedZone("EST", -5*60*60)
+
+	refresher := NewRefresher(newTestLogger(), nil, time.Hour)
+	refresher.SetDailyAt(4, 0)
+
+	tests := []struct {
+		name string
+		now  time.Time
+		want time.Time
+	}{
+		{
+			name: "later today",
+			now:  time.Date(2026, 1, 4, 1, 30, 0, 0, loc),
+			want: time.Date(2026, 1, 4, 4, 0, 0, 0, loc),
+		},
+		{
+			name: "already passed today",
+			now:  time.Date(2026, 1, 4, 12, 0, 0, 0, loc),
+			want: time.Date(2026, 1, 5, 4, 0, 0, 0, loc),
+		},
+		{
+			name: "exactly at the scheduled time",
+			now:  time.Date(2026, 1, 4, 4, 0, 0, 0, loc),
+			want: time.Date(2026, 1, 5, 4, 0, 0, 0, loc),
+		},
+		{
+			name: "end of month",
+			now:  time.Date(2026, 1, 31, 23, 59, 0, 0, loc),
+			want: time.Date(2026, 2, 1, 4, 0, 0, 0, loc),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.True(t, tt.want.Equal(refresher.nextDailyRun(tt.now)), refresher.nextDailyRun(tt.now))
+		})
+	}
+}
@@ -0,0 +1,125 @@
+package data
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/savid/iptv/internal/epg"
+	"github.com/savid/iptv/internal/m3u"
+)
+
+// File names of the retained data inside the retain directory.
+const (
+	retainedM3UFile = "m3u.json"
+	retainedEPGFile = "epg.json"
+)
+
+// retainedM3U is the on-disk form of the last good M3U playlist.
+type retainedM3U struct {
+	Channels []m3u.Channel   `json:"channels"`
+	Logos    map[string]Logo `json:"logos,omitempty"`
+}
+
+// retainedEPG is the on-disk form of the last good merged EPG.
+type retainedEPG struct {
+	TV         *epg.TV           `json:"tv"`
+	ChannelMap map[string]string `json:"channelMap"`
+	Logos      map[string]Logo   `json:"logos,omitempty"`
+}
+
+// LoadRetained loads the last good M3U and EPG from RetainDir into the store,
+// so the previous data is served if the initial fetch fails. It reports
+// whether both were found; missing files are not an error.
+func (f *Fetcher) LoadRetained() (bool, error) {
+	if f.cfg.RetainDir == "" {
+		return false, nil
+	}
+
+	var playlist retainedM3U
+
+	found, err := readRetained(filepath.Join(f.cfg.RetainDir, retainedM3UFile), &playlist)
+	if err != nil || !found {
+		return false, err
+	}
+
+	var guide retainedEPG
+
+	found, err = readRetained(filepath.Join(f.cfg.RetainDir, retainedEPGFile), &guide)
+	if err != nil || !found {
+		return false, err
+	}
+
+	if guide.TV == nil {
+		return false, fmt.Errorf("retained EPG in %s has no guide data", f.cfg.RetainDir)
+	}
+
+	f.store.AddLogos(playlist.Logos)
+	f.store.AddLogos(guide.Logos)
+	f.store.SetM3U(playlist.Channels)
+	f.store.SetEPG(guide.TV, guide.ChannelMap)
+	f.store.PruneLogos()
+
+	return true, nil
+}
+
+// retainM3U saves a playlist that passed every refresh guard. Failures are
+// logged but do not fail the refresh.
+func (f *Fetcher) retainM3U(channels []m3u.Channel, logos map[string]Logo) {
+	f.retain(retainedM3UFile, retainedM3U{Channels: channels, Logos: logos})
+}
+
+// retainEPG saves a merged EPG that passed every refresh guard. Failures are
+// logged but do not fail the refresh.
+func (f *Fetcher) retainEPG(tv *epg.TV, channelMap map[string]string, logos map[string]Logo) {
+	f.retain(retainedEPGFile, retainedEPG{TV: tv, ChannelMap: channelMap, Logos: logos})
+}
+
+func (f *Fetcher) retain(name string, value any) {
+	if f.cfg.RetainDir == "" {
+		return
+	}
+
+	path := filepath.Join(f.cfg.RetainDir, name)
+
+	if err := writeRetained(path, value); err != nil {
+		f.log.WithError(err).WithField("path", path).Error("Failed to retain data")
+
+		return
+	}
+
+	f.log.WithField("path", path).Debug("Retained data")
+}
+
+func writeRetained(path string, value any) error {
+	content, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Errorf("failed to encode retained data: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("failed to create retain directory: %w", err)
+	}
+
+	// Retained playlists carry stream URLs, which often embed credentials.
+	return WriteFileAtomic(path, content, 0o600)
+}
+
+func readRetained(path string, value any) (bool, error) {
+	content, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	}
+
+	if err != nil {
+		return false, fmt.Errorf("failed to read retained data: %w", err)
+	}
+
+	if err := json.Unmarshal(content, value); err != nil {
+		return false, fmt.Errorf("failed to parse retained data %s: %w", path, err)
+	}
+
+	return true, nil
+}
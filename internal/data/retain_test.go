@@ -0,0 +1,90 @@
+package data
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRetain_RestartFlow(t *testing.T) {
+	srv := newTestUpstream(t, map[string]string{
+		"/playlist.m3u": testM3U,
+		"/epg.xml":      testEPG,
+	})
+
+	cfg := newTestFetcherConfig(srv)
+	cfg.RetainDir = t.TempDir()
+
+	fetcher := NewFetcher(newTestLogger(), cfg, NewStore())
+	require.NoError(t, fetcher.FetchAll(context.Background()))
+
+	retainedEPGPath := filepath.Join(cfg.RetainDir, retainedEPGFile)
+
+	retained, err := os.ReadFile(retainedEPGPath)
+	require.NoError(t, err)
+
+	// A refresh rejected by a guard leaves the retained EPG untouched.
+	cfg.MinMatchRate = 0.5
+
+	require.Error(t, fetcher.FetchAll(context.Background()))
+
+	current, err := os.ReadFile(retainedEPGPath)
+	require.NoError(t, err)
+	require.Equal(t, retained, current)
+
+	// After a restart with the upstream down, the retained data is served.
+	down := httptest.NewServer(http.NotFoundHandler())
+	t.Cleanup(down.Close)
+
+	restartCfg := newTestFetcherConfig(down)
+	restartCfg.RetainDir = cfg.RetainDir
+
+	store := NewStore()
+	restarted := NewFetcher(newTestLogger(), restartCfg, store)
+
+	loaded, err := restarted.LoadRetained()
+	require.NoError(t, err)
+	require.True(t, loaded)
+
+	require.Error(t, restarted.FetchAll(context.Background()))
+
+	channels, ok := store.GetM3U()
+	require.True(t, ok)
+	require.Len(t, channels, 4)
+
+	epgData, channelMap, ok := store.GetEPG()
+	require.True(t, ok)
+	require.Len(t, epgData.Channels, 4)
+	require.Len(t, epgData.Programs, 4)
+	require.Equal(t, "ESPN", channelMap["espn.us"])
+}
+
+func TestLoadRetained_Missing(t *testing.T) {
+	cfg := newTestFetcherConfig(newTestUpstream(t, nil))
+	cfg.RetainDir = t.TempDir()
+
+	store := NewStore()
+
+	loaded, err := NewFetcher(newTestLogger(), cfg, store).LoadRetained()
+	require.NoError(t, err)
+	require.False(t, loaded)
+
+	_, ok := store.GetM3U()
+	require.False(t, ok)
+}
+
+func TestLoadRetained_Invalid(t *testing.T) {
+	cfg := newTestFetcherConfig(newTestUpstream(t, nil))
+	cfg.RetainDir = t.TempDir()
+
+	require.NoError(t, os.WriteFile(filepath.Join(cfg.RetainDir, retainedM3UFile), []byte("not json"), 0o600))
+
+	_, err := NewFetcher(newTestLogger(), cfg, NewStore()).LoadRetained()
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "failed to parse retained data")
+}
@@ -0,0 +1,146 @@
+package data
+
+import (
+	"fmt"
+
+	"github.com/savid/iptv/internal/m3u"
+)
+
+// RootShardName prefixes the names of sub-tuners split from the root device.
+const RootShardName = "All Channels"
+
+// Shard is a numbered sub-tuner holding a slice of a large group's channels
+// (or of all channels, for the root group "").
+type Shard struct {
+	Group string
+	Index int    // 1-based position within the group
+	Name  string // e.g. "Sports 2"
+	Slug  string
+}
+
+// SetChannelsPerTuner sets the size above which a group (or the root) is
+// also served as numbered sub-tuners of at most n channels each. 0 disables
+// splitting. Takes effect when M3U data is next set.
+func (s *Store) SetChannelsPerTuner(n int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.channelsPerTuner = n
+}
+
+// Shards returns the sub-tuners split from large groups, root first, then
+// by group name and index.
+func (s *Store) Shards() []Shard {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	shards := make([]Shard, len(s.shards))
+	copy(shards, s.shards)
+
+	return shards
+}
+
+// ShardBySlug returns the sub-tuner for a URL slug.
+func (s *Store) ShardBySlug(slug string) (Shard, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	shard, ok := s.shardBySlug[slug]
+
+	return shard, ok
+}
+
+// ShardSlug returns the URL slug of a group's index-th sub-tuner.
+func (s *Store) ShardSlug(group string, index int) (string, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, shard := range s.shards {
+		if shard.Group == group && shard.Index == index {
+			return shard.Slug, true
+		}
+	}
+
+	return "", false
+}
+
+// GetChannelsByShard returns the enabled channels of a group's index-th
+// sub-tuner. Shards slice the group's full channel list, so disabling a
+// channel doesn't move others between sub-tuners.
+func (s *Store) GetChannelsByShard(group string, index int) ([]m3u.Channel, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.m3uChannels == nil || s.channelsPerTuner <= 0 || index < 1 {
+		return nil, false
+	}
+
+	channels := s.groupChannels[group]
+	start := (index - 1) * s.channelsPerTuner
+
+	if start >= len(channels) {
+		return nil, false
+	}
+
+	end := min(start+s.channelsPerTuner, len(channels))
+	lineup := make([]m3u.Channel, 0, end-start)
+
+	for _, ch := range channels[start:end] {
+		if !s.disabled.Contains(ch) {
+			lineup = append(lineup, ch)
+		}
+	}
+
+	return lineup, true
+}
+
+// buildShards splits the root and every group with more than perTuner
+// channels into sub-tuners, giving each a slug not used by any group.
+func buildShards(groups []string, partition map[string][]m3u.Channel, slugByGroup, groupBySlug map[string]string, perTuner int) ([]Shard, map[string]Shard) {
+	shards := make([]Shard, 0)
+	bySlug := make(map[string]Shard)
+
+	if perTuner <= 0 {
+		return shards, bySlug
+	}
+
+	for _, group := range append([]string{""}, groups...) {
+		count := len(partition[group])
+		if count <= perTuner {
+			continue
+		}
+
+		name, baseSlug := RootShardName, "all"
+		if group != "" {
+			name, baseSlug = group, slugByGroup[group]
+		}
+
+		for index := 1; index <= (count+perTuner-1)/perTuner; index++ {
+			base := fmt.Sprintf("%s-%d", baseSlug, index)
+			slug := base
+
+			for n := 2; ; n++ {
+				_, groupTaken := groupBySlug[slug]
+				_, shardTaken := bySlug[slug]
+
+				if !groupTaken && !shardTaken {
+					break
+				}
+
+				slug = fmt.Sprintf("%s-%d", base, n)
+			}
+
+			shard := Shard{
+				Group: group,
+				Index: index,
+				Name:  fmt.Sprintf("%s %d", name, index),
+				Slug:  slug,
+			}
+
+			shards = append(shards, shard)
+			bySlug[slug] = shard
+		}
+	}
+
+	return shards, bySlug
+}
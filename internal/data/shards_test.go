@@ -0,0 +1,82 @@
+package data
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/savid/iptv/internal/m3u"
+	"github.com/stretchr/testify/require"
+)
+
+func TestShards_SplitLargeGroup(t *testing.T) {
+	channels := make([]m3u.Channel, 0, 160)
+
+	for i := range 150 {
+		channels = append(channels, m3u.Channel{
+			Name:  fmt.Sprintf("Sports %03d", i+1),
+			URL:   fmt.Sprintf("http://stream.example.com/sports/%d", i+1),
+			Group: "Sports",
+		})
+	}
+
+	// A real group whose slug would collide with the first sub-tuner.
+	for i := range 10 {
+		channels = append(channels, m3u.Channel{
+			Name:  fmt.Sprintf("Extra %d", i+1),
+			URL:   fmt.Sprintf("http://stream.example.com/extra/%d", i+1),
+			Group: "Sports 1",
+		})
+	}
+
+	store := NewStore()
+	store.SetChannelsPerTuner(100)
+	store.SetM3U(channels)
+
+	require.Equal(t, []Shard{
+		{Group: "", Index: 1, Name: "All Channels 1", Slug: "all-1"},
+		{Group: "", Index: 2, Name: "All Channels 2", Slug: "all-2"},
+		{Group: "Sports", Index: 1, Name: "Sports 1", Slug: "sports-1-2"},
+		{Group: "Sports", Index: 2, Name: "Sports 2", Slug: "sports-2"},
+	}, store.Shards())
+
+	first, ok := store.GetChannelsByShard("Sports", 1)
+	require.True(t, ok)
+	require.Len(t, first, 100)
+	require.Equal(t, "Sports 001", first[0].Name)
+
+	second, ok := store.GetChannelsByShard("Sports", 2)
+	require.True(t, ok)
+	require.Len(t, second, 50)
+	require.Equal(t, "Sports 101", second[0].Name)
+	require.Equal(t, "Sports 150", second[49].Name)
+
+	_, ok = store.GetChannelsByShard("Sports", 3)
+	require.False(t, ok)
+
+	shard, ok := store.ShardBySlug("sports-2")
+	require.True(t, ok)
+	require.Equal(t, "Sports 2", shard.Name)
+
+	group, ok := store.GroupBySlug("sports-1")
+	require.True(t, ok)
+	require.Equal(t, "Sports 1", group)
+
+	// Disabling a channel leaves the other sub-tuner untouched.
+	require.NoError(t, store.Disabled().Disable("Sports 001"))
+
+	first, _ = store.GetChannelsByShard("Sports", 1)
+	require.Len(t, first, 99)
+
+	second, _ = store.GetChannelsByShard("Sports", 2)
+	require.Equal(t, "Sports 101", second[0].Name)
+}
+
+func TestShards_Disabled(t *testing.T) {
+	store := NewStore()
+	store.SetM3U([]m3u.Channel{{Name: "ESPN", Group: "Sports"}})
+
+	require.Empty(t, store.Shards())
+
+	_, ok := store.GetChannelsByShard("Sports", 1)
+	require.False(t, ok)
+}
@@ -0,0 +1,53 @@
+package data
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+var (
+	slugInvalidChars = regexp.MustCompile(`[^a-z0-9-]+`)
+	slugHyphens      = regexp.MustCompile(`-+`)
+)
+
+// Slugify converts a group name to a URL-safe slug.
+// Example: "US Sports" -> "us-sports".
+func Slugify(s string) string {
+	s = strings.TrimSpace(s)
+	s = strings.ToLower(s)
+	s = strings.ReplaceAll(s, " ", "-")
+	s = strings.ReplaceAll(s, "_", "-")
+	s = slugInvalidChars.ReplaceAllString(s, "")
+
+	// Collapse multiple hyphens and trim leading/trailing hyphens
+	s = slugHyphens.ReplaceAllString(s, "-")
+
+	return strings.Trim(s, "-")
+}
+
+// buildSlugIndex assigns a unique slug to each group. Groups are expected in
+// sorted order; when two groups slugify identically, later groups get a
+// numeric suffix ("-2", "-3", ...). Returns slug → group and group → slug.
+func buildSlugIndex(groups []string) (map[string]string, map[string]string) {
+	bySlug := make(map[string]string, len(groups))
+	byGroup := make(map[string]string, len(groups))
+
+	for _, group := range groups {
+		base := Slugify(group)
+		slug := base
+
+		for n := 2; ; n++ {
+			if _, taken := bySlug[slug]; !taken {
+				break
+			}
+
+			slug = fmt.Sprintf("%s-%d", base, n)
+		}
+
+		bySlug[slug] = group
+		byGroup[group] = slug
+	}
+
+	return bySlug, byGroup
+}
@@ -0,0 +1,119 @@
+package data
+
+import (
+	"errors"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/savid/iptv/internal/config"
+)
+
+// SourceHealth is the outcome of the most recent fetches of one EPG source.
+type SourceHealth struct {
+	Source      string
+	OK          bool
+	LastSuccess time.Time
+	LastError   string
+	LastErrorAt time.Time
+}
+
+// SourceHealthTracker records per-source EPG fetch outcomes across refreshes.
+type SourceHealthTracker struct {
+	mu      sync.Mutex
+	order   []string
+	sources map[string]*SourceHealth
+	now     func() time.Time
+}
+
+// NewSourceHealthTracker creates an empty tracker.
+func NewSourceHealthTracker() *SourceHealthTracker {
+	return &SourceHealthTracker{
+		sources: make(map[string]*SourceHealth),
+		now:     time.Now,
+	}
+}
+
+// Track sets the configured sources, in merge order. History is kept for
+// sources still configured and dropped for the rest.
+func (t *SourceHealthTracker) Track(sources []string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	tracked := make(map[string]*SourceHealth, len(sources))
+
+	for _, source := range sources {
+		if health, ok := t.sources[source]; ok {
+			tracked[source] = health
+		} else {
+			tracked[source] = &SourceHealth{Source: source}
+		}
+	}
+
+	t.order = append([]string(nil), sources...)
+	t.sources = tracked
+}
+
+// RecordSuccess marks the source's latest fetch as successful.
+func (t *SourceHealthTracker) RecordSuccess(source string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	health := t.entry(source)
+	health.OK = true
+	health.LastSuccess = t.now()
+}
+
+// RecordFailure marks the source's latest fetch as failed with err.
+func (t *SourceHealthTracker) RecordFailure(source string, err error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	health := t.entry(source)
+	health.OK = false
+	health.LastError = redactError(err)
+	health.LastErrorAt = t.now()
+}
+
+// Snapshot returns the health of each tracked source in merge order.
+func (t *SourceHealthTracker) Snapshot() []SourceHealth {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	snapshot := make([]SourceHealth, 0, len(t.order))
+
+	for _, source := range t.order {
+		snapshot = append(snapshot, *t.sources[source])
+	}
+
+	return snapshot
+}
+
+// entry returns the source's record, tracking it if it is new. Callers must
+// hold t.mu.
+func (t *SourceHealthTracker) entry(source string) *SourceHealth {
+	health, ok := t.sources[source]
+	if !ok {
+		health = &SourceHealth{Source: source}
+		t.sources[source] = health
+		t.order = append(t.order, source)
+	}
+
+	return health
+}
+
+// redactError returns err's message with any request URL redacted. Errors
+// from net/http embed the full URL, credentials and all, and this message is
+// served on the unauthenticated /health endpoint.
+func redactError(err error) string {
+	msg := err.Error()
+
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		redacted := &url.Error{Op: urlErr.Op, URL: config.RedactURL(urlErr.URL), Err: urlErr.Err}
+		msg = strings.Replace(msg, urlErr.Error(), redacted.Error(), 1)
+	}
+
+	return msg
+}
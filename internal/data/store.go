@@ -3,6 +3,7 @@ package data
 
 import (
 	"sort"
+	"strings"
 	"sync"
 	"time"
 
@@ -18,22 +19,185 @@ type Store struct {
 	epgData     *epg.TV
 	channelMap  map[string]string
 	lastSync    time.Time
+
+	// Incremented whenever M3U or EPG data is set.
+	version uint64
+
+	// EPG channels from all sources before filtering, for match analysis.
+	epgSourceChannels []epg.Channel
+
+	// M3U channels matched per strategy by the last successful EPG merge.
+	matchSummary map[string]int
+
+	// Lines skipped by the last lenient M3U parse.
+	m3uParseReport m3u.ParseReport
+
+	// Group slug indexes, rebuilt whenever M3U data is set.
+	groups      []string
+	groupBySlug map[string]string
+	slugByGroup map[string]string
+
+	// Channels partitioned by group ("" = all), rebuilt whenever M3U data is
+	// set. Each channel appears in exactly one group, so the partition is
+	// bounded by the playlist size.
+	groupChannels map[string][]m3u.Channel
+
+	// Groups exposed as tuner devices (the maxGroupTuners largest, or all),
+	// rebuilt whenever M3U data is set.
+	maxGroupTuners int
+	tunerGroups    []string
+	tunerGroupSet  map[string]bool
+
+	// Sub-tuners split from groups larger than channelsPerTuner, rebuilt
+	// whenever M3U data is set.
+	channelsPerTuner int
+	shards           []Shard
+	shardBySlug      map[string]Shard
+
+	// Enabled channels per group, valid while the disabled set is at
+	// lineupVersion. Cleared whenever M3U data is set.
+	lineups       map[string][]m3u.Channel
+	lineupVersion uint64
+
+	// Decoded data URI logos served under LogoPathPrefix, by key.
+	logos map[string]Logo
+
+	tunes      *TuneCounter
+	disabled   *DisabledChannels
+	upstreams  *HostLimiter
+	epgSources *SourceHealthTracker
+	health     *ChannelHealthTracker
+	tuners     *TunerLocks
 }
 
 // NewStore creates a new data store.
 func NewStore() *Store {
 	return &Store{
 		channelMap: make(map[string]string),
+		tunes:      NewTuneCounter(defaultTuneWindow),
+		disabled:   NewDisabledChannels(),
+		upstreams:  NewHostLimiter(),
+		epgSources: NewSourceHealthTracker(),
+		health:     NewChannelHealthTracker(),
+		tuners:     NewTunerLocks(),
 	}
 }
 
+// Tunes returns the tune activity counter.
+func (s *Store) Tunes() *TuneCounter {
+	return s.tunes
+}
+
+// Upstreams returns the limiter for concurrent upstream connections.
+func (s *Store) Upstreams() *HostLimiter {
+	return s.upstreams
+}
+
+// EPGSources returns the per-source EPG fetch health.
+func (s *Store) EPGSources() *SourceHealthTracker {
+	return s.epgSources
+}
+
+// ChannelHealth returns the stream probe results per channel URL.
+func (s *Store) ChannelHealth() *ChannelHealthTracker {
+	return s.health
+}
+
+// Tuners returns the tuner reservations of proxied streams.
+func (s *Store) Tuners() *TunerLocks {
+	return s.tuners
+}
+
+// Disabled returns the set of channels hidden from the lineup.
+func (s *Store) Disabled() *DisabledChannels {
+	return s.disabled
+}
+
 // SetM3U updates the M3U channels.
 func (s *Store) SetM3U(channels []m3u.Channel) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
 	s.m3uChannels = channels
+	s.groups = collectGroups(channels)
+	s.groupBySlug, s.slugByGroup = buildSlugIndex(s.groups)
+	s.groupChannels = partitionByGroup(channels)
+	s.tunerGroups, s.tunerGroupSet = limitTunerGroups(s.groups, s.groupChannels, s.maxGroupTuners)
+	s.shards, s.shardBySlug = buildShards(s.tunerGroups, s.groupChannels, s.slugByGroup, s.groupBySlug, s.channelsPerTuner)
+	s.lineups = make(map[string][]m3u.Channel)
 	s.lastSync = time.Now()
+	s.version++
+}
+
+// AddLogos adds logos to the served set.
+func (s *Store) AddLogos(logos map[string]Logo) {
+	if len(logos) == 0 {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.logos == nil {
+		s.logos = make(map[string]Logo, len(logos))
+	}
+
+	for key, logo := range logos {
+		s.logos[key] = logo
+	}
+}
+
+// PruneLogos drops served logos that neither the current playlist nor the
+// current guide refers to.
+func (s *Store) PruneLogos() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.logos) == 0 {
+		return
+	}
+
+	referenced := make(map[string]bool, len(s.logos))
+
+	for _, ch := range s.m3uChannels {
+		if key, ok := logoKey(ch.TVGLogo); ok {
+			referenced[key] = true
+		}
+	}
+
+	if s.epgData != nil {
+		for _, ch := range s.epgData.Channels {
+			if key, ok := logoKey(ch.Icon.Src); ok {
+				referenced[key] = true
+			}
+		}
+	}
+
+	for key := range s.logos {
+		if !referenced[key] {
+			delete(s.logos, key)
+		}
+	}
+}
+
+// logoKey returns the key of a served logo URL.
+func logoKey(src string) (string, bool) {
+	i := strings.LastIndex(src, LogoPathPrefix)
+	if i < 0 {
+		return "", false
+	}
+
+	return src[i+len(LogoPathPrefix):], true
+}
+
+// Logo returns a served logo by key.
+func (s *Store) Logo(key string) (Logo, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	logo, ok := s.logos[key]
+
+	return logo, ok
 }
 
 // GetM3U returns the M3U channels.
@@ -56,6 +220,56 @@ func (s *Store) SetEPG(data *epg.TV, channelMap map[string]string) {
 	s.epgData = data
 	s.channelMap = channelMap
 	s.lastSync = time.Now()
+	s.version++
+}
+
+// SetEPGSourceChannels records the unfiltered EPG channels from all sources.
+func (s *Store) SetEPGSourceChannels(channels []epg.Channel) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.epgSourceChannels = channels
+}
+
+// GetEPGSourceChannels returns the unfiltered EPG channels from all sources.
+func (s *Store) GetEPGSourceChannels() []epg.Channel {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.epgSourceChannels
+}
+
+// SetMatchSummary records the per-strategy match counts of the last merge.
+func (s *Store) SetMatchSummary(counts map[string]int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.matchSummary = counts
+}
+
+// MatchSummary returns the per-strategy match counts of the last merge, or
+// false if no EPG sources have been merged yet.
+func (s *Store) MatchSummary() (map[string]int, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.matchSummary, s.matchSummary != nil
+}
+
+// SetM3UParseReport records the lines skipped by the last M3U parse.
+func (s *Store) SetM3UParseReport(report m3u.ParseReport) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.m3uParseReport = report
+}
+
+// M3UParseReport returns the lines skipped by the last M3U parse.
+func (s *Store) M3UParseReport() m3u.ParseReport {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.m3uParseReport
 }
 
 // GetEPG returns the EPG data.
@@ -78,6 +292,15 @@ func (s *Store) LastSync() time.Time {
 	return s.lastSync
 }
 
+// Version returns a counter that changes whenever M3U or EPG data is set, so
+// callers can cache what they derive from it.
+func (s *Store) Version() uint64 {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.version
+}
+
 // HasData returns true if both M3U and EPG data are available.
 func (s *Store) HasData() bool {
 	s.mu.RLock()
@@ -91,10 +314,41 @@ func (s *Store) GetGroups() []string {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
+	groups := make([]string, len(s.groups))
+	copy(groups, s.groups)
+
+	return groups
+}
+
+// GroupBySlug returns the group name for a URL slug.
+func (s *Store) GroupBySlug(slug string) (string, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	group, ok := s.groupBySlug[slug]
+
+	return group, ok
+}
+
+// GroupSlug returns the unique URL slug for a group name. Groups not present
+// in the current M3U data fall back to Slugify.
+func (s *Store) GroupSlug(group string) string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if slug, ok := s.slugByGroup[group]; ok {
+		return slug
+	}
+
+	return Slugify(group)
+}
+
+// collectGroups returns the unique, non-empty group-titles sorted alphabetically.
+func collectGroups(channels []m3u.Channel) []string {
 	seen := make(map[string]bool)
 	groups := make([]string, 0)
 
-	for _, ch := range s.m3uChannels {
+	for _, ch := range channels {
 		if ch.Group != "" && !seen[ch.Group] {
 			seen[ch.Group] = true
 			groups = append(groups, ch.Group)
@@ -106,27 +360,68 @@ func (s *Store) GetGroups() []string {
 	return groups
 }
 
-// GetChannelsByGroup returns channels matching a specific group.
-// Empty group returns all channels.
+// GetChannelsByGroup returns enabled channels matching a specific group.
+// If group is empty, returns all enabled channels. Results are cached until
+// the M3U data or the disabled set changes; callers must not modify them.
 func (s *Store) GetChannelsByGroup(group string) ([]m3u.Channel, bool) {
+	version := s.disabled.Version()
+
 	s.mu.RLock()
-	defer s.mu.RUnlock()
 
 	if s.m3uChannels == nil {
+		s.mu.RUnlock()
+
 		return nil, false
 	}
 
-	if group == "" {
-		return s.m3uChannels, true
+	if lineup, ok := s.lineups[group]; ok && s.lineupVersion == version {
+		s.mu.RUnlock()
+
+		return lineup, true
 	}
 
-	filtered := make([]m3u.Channel, 0)
+	s.mu.RUnlock()
 
-	for _, ch := range s.m3uChannels {
-		if ch.Group == group {
-			filtered = append(filtered, ch)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.m3uChannels == nil {
+		return nil, false
+	}
+
+	if s.lineupVersion != version {
+		s.lineups = make(map[string][]m3u.Channel)
+		s.lineupVersion = version
+	}
+
+	if lineup, ok := s.lineups[group]; ok {
+		return lineup, true
+	}
+
+	channels := s.groupChannels[group]
+	lineup := make([]m3u.Channel, 0, len(channels))
+
+	for _, ch := range channels {
+		if !s.disabled.Contains(ch) {
+			lineup = append(lineup, ch)
+		}
+	}
+
+	s.lineups[group] = lineup
+
+	return lineup, true
+}
+
+// partitionByGroup indexes channels by group-title, with every channel also
+// listed under "" in playlist order.
+func partitionByGroup(channels []m3u.Channel) map[string][]m3u.Channel {
+	partition := map[string][]m3u.Channel{"": channels}
+
+	for _, ch := range channels {
+		if ch.Group != "" {
+			partition[ch.Group] = append(partition[ch.Group], ch)
 		}
 	}
 
-	return filtered, true
+	return partition
 }
@@ -363,3 +363,111 @@ func TestGetChannelsByGroup_NonExistent(t *testing.T) {
 	require.True(t, ok)
 	require.Empty(t, channels)
 }
+
+func TestGroupSlugIndex(t *testing.T) {
+	store := NewStore()
+
+	store.SetM3U([]m3u.Channel{
+		{Name: "ESPN", Group: "US Sports"},
+		{Name: "FS1", Group: "US-Sports"},
+		{Name: "CNN", Group: "News"},
+	})
+
+	// Colliding slugs are disambiguated in sorted group order.
+	require.Equal(t, "us-sports", store.GroupSlug("US Sports"))
+	require.Equal(t, "us-sports-2", store.GroupSlug("US-Sports"))
+
+	group, ok := store.GroupBySlug("us-sports-2")
+	require.True(t, ok)
+	require.Equal(t, "US-Sports", group)
+
+	group, ok = store.GroupBySlug("news")
+	require.True(t, ok)
+	require.Equal(t, "News", group)
+
+	// Forward and reverse mappings agree for every group.
+	for _, g := range store.GetGroups() {
+		resolved, found := store.GroupBySlug(store.GroupSlug(g))
+		require.True(t, found)
+		require.Equal(t, g, resolved)
+	}
+}
+
+func TestGroupSlugIndex_UpdatedOnRefresh(t *testing.T) {
+	store := NewStore()
+
+	store.SetM3U([]m3u.Channel{
+		{Name: "ESPN", Group: "Sports"},
+		{Name: "CNN", Group: "News"},
+	})
+
+	_, ok := store.GroupBySlug("movies")
+	require.False(t, ok)
+
+	store.SetM3U([]m3u.Channel{
+		{Name: "HBO", Group: "Movies"},
+		{Name: "CNN", Group: "News"},
+	})
+
+	group, ok := store.GroupBySlug("movies")
+	require.True(t, ok)
+	require.Equal(t, "Movies", group)
+
+	_, ok = store.GroupBySlug("sports")
+	require.False(t, ok)
+}
+
+func TestGetChannelsByGroup_SkipsDisabled(t *testing.T) {
+	store := NewStore()
+	store.SetM3U([]m3u.Channel{
+		{Name: "ESPN", TVGID: "espn.us", Group: "Sports"},
+		{Name: "Fox Sports", Group: "Sports"},
+		{Name: "CNN", Group: "News"},
+	})
+
+	require.NoError(t, store.Disabled().Disable("espn.us"))
+
+	sports, ok := store.GetChannelsByGroup("Sports")
+	require.True(t, ok)
+	require.Len(t, sports, 1)
+	require.Equal(t, "Fox Sports", sports[0].Name)
+
+	// The disabled set survives a refresh of the playlist.
+	store.SetM3U([]m3u.Channel{
+		{Name: "ESPN (Backup)", TVGID: "espn.us", Group: "Sports"},
+		{Name: "CNN", Group: "News"},
+	})
+
+	all, ok := store.GetChannelsByGroup("")
+	require.True(t, ok)
+	require.Len(t, all, 1)
+	require.Equal(t, "CNN", all[0].Name)
+}
+
+func TestGetChannelsByGroup_CacheInvalidation(t *testing.T) {
+	store := NewStore()
+	store.SetM3U([]m3u.Channel{
+		{Name: "ESPN", Group: "Sports"},
+		{Name: "Fox Sports", Group: "Sports"},
+	})
+
+	sports, _ := store.GetChannelsByGroup("Sports")
+	require.Len(t, sports, 2)
+
+	// Disabling a channel invalidates the cached lineup.
+	require.NoError(t, store.Disabled().Disable("ESPN"))
+
+	sports, _ = store.GetChannelsByGroup("Sports")
+	require.Len(t, sports, 1)
+
+	require.NoError(t, store.Disabled().Enable("ESPN"))
+
+	sports, _ = store.GetChannelsByGroup("Sports")
+	require.Len(t, sports, 2)
+
+	// So does a playlist refresh.
+	store.SetM3U([]m3u.Channel{{Name: "ESPN", Group: "Sports"}})
+
+	sports, _ = store.GetChannelsByGroup("Sports")
+	require.Len(t, sports, 1)
+}
@@ -0,0 +1,68 @@
+package data
+
+import (
+	"sort"
+
+	"github.com/savid/iptv/internal/m3u"
+)
+
+// SetMaxGroupTuners limits how many groups are exposed as their own tuner
+// devices to the n with the most channels. The rest stay reachable only via
+// the all-channels device. 0 exposes every group. Takes effect when M3U data
+// is next set.
+func (s *Store) SetMaxGroupTuners(n int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.maxGroupTuners = n
+}
+
+// TunerGroups returns the groups exposed as tuner devices, sorted
+// alphabetically.
+func (s *Store) TunerGroups() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	groups := make([]string, len(s.tunerGroups))
+	copy(groups, s.tunerGroups)
+
+	return groups
+}
+
+// IsTunerGroup reports whether a group is exposed as a tuner device. The
+// root group "" always is.
+func (s *Store) IsTunerGroup(group string) bool {
+	if group == "" {
+		return true
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.tunerGroupSet[group]
+}
+
+// limitTunerGroups returns the limit groups with the most channels (ties by
+// name), sorted alphabetically, or all groups when limit is 0.
+func limitTunerGroups(groups []string, partition map[string][]m3u.Channel, limit int) ([]string, map[string]bool) {
+	exposed := groups
+
+	if limit > 0 && len(groups) > limit {
+		bySize := make([]string, len(groups))
+		copy(bySize, groups)
+
+		sort.SliceStable(bySize, func(i, j int) bool {
+			return len(partition[bySize[i]]) > len(partition[bySize[j]])
+		})
+
+		exposed = bySize[:limit]
+		sort.Strings(exposed)
+	}
+
+	set := make(map[string]bool, len(exposed))
+	for _, group := range exposed {
+		set[group] = true
+	}
+
+	return exposed, set
+}
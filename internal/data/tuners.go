@@ -0,0 +1,98 @@
+package data
+
+import (
+	"sync"
+	"time"
+)
+
+// TunerLease describes a tuner reserved by a proxied stream.
+type TunerLease struct {
+	Channel string // Guide number tuned
+	Name    string // Channel name
+	Client  string // Remote address of the viewer
+	Since   time.Time
+}
+
+// TunerLocks tracks which of each device's tuners are reserved by a running
+// stream, keyed by device ID.
+type TunerLocks struct {
+	mu      sync.Mutex
+	devices map[string][]*TunerLease
+	now     func() time.Time
+}
+
+// NewTunerLocks creates an empty set of tuner locks.
+func NewTunerLocks() *TunerLocks {
+	return &TunerLocks{
+		devices: make(map[string][]*TunerLease),
+		now:     time.Now,
+	}
+}
+
+// Reserve takes the lowest free of the device's count tuners for lease. It
+// returns the tuner index and a function releasing it (safe to call more than
+// once), or false when every tuner is in use.
+func (l *TunerLocks) Reserve(device string, count int, lease TunerLease) (int, func(), bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	tuners := l.tuners(device, count)
+
+	for index, current := range tuners {
+		if current != nil {
+			continue
+		}
+
+		lease.Since = l.now()
+		reserved := &lease
+		tuners[index] = reserved
+
+		var once sync.Once
+
+		return index, func() {
+			once.Do(func() {
+				l.mu.Lock()
+				defer l.mu.Unlock()
+
+				if slots := l.devices[device]; index < len(slots) && slots[index] == reserved {
+					slots[index] = nil
+				}
+			})
+		}, true
+	}
+
+	return 0, nil, false
+}
+
+// Snapshot returns the device's count tuners in order, nil where free.
+func (l *TunerLocks) Snapshot(device string, count int) []*TunerLease {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	tuners := l.tuners(device, count)
+	snapshot := make([]*TunerLease, len(tuners))
+
+	for i, lease := range tuners {
+		if lease != nil {
+			leaseCopy := *lease
+			snapshot[i] = &leaseCopy
+		}
+	}
+
+	return snapshot
+}
+
+// tuners returns the device's tuner slots, resized to count. Callers must
+// hold the lock.
+func (l *TunerLocks) tuners(device string, count int) []*TunerLease {
+	tuners := l.devices[device]
+
+	if len(tuners) != count {
+		resized := make([]*TunerLease, count)
+		copy(resized, tuners)
+		tuners = resized
+		l.devices[device] = tuners
+	}
+
+	return tuners
+}
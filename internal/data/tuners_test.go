@@ -0,0 +1,48 @@
+package data
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTunerLocks(t *testing.T) {
+	locks := NewTunerLocks()
+
+	first, releaseFirst, ok := locks.Reserve("dev", 2, TunerLease{Channel: "1"})
+	require.True(t, ok)
+	require.Equal(t, 0, first)
+
+	second, releaseSecond, ok := locks.Reserve("dev", 2, TunerLease{Channel: "2"})
+	require.True(t, ok)
+	require.Equal(t, 1, second)
+
+	_, _, ok = locks.Reserve("dev", 2, TunerLease{Channel: "3"})
+	require.False(t, ok, "all tuners in use")
+
+	// Devices have their own tuners.
+	_, releaseOther, ok := locks.Reserve("other", 2, TunerLease{Channel: "1"})
+	require.True(t, ok)
+
+	defer releaseOther()
+
+	releaseFirst()
+	releaseFirst()
+
+	snapshot := locks.Snapshot("dev", 2)
+	require.Nil(t, snapshot[0])
+	require.Equal(t, "2", snapshot[1].Channel)
+	require.False(t, snapshot[1].Since.IsZero())
+
+	// The lowest free tuner is reused; the stale release doesn't free it.
+	index, releaseThird, ok := locks.Reserve("dev", 2, TunerLease{Channel: "3"})
+	require.True(t, ok)
+	require.Equal(t, 0, index)
+
+	releaseFirst()
+	require.Equal(t, "3", locks.Snapshot("dev", 2)[0].Channel)
+
+	releaseThird()
+	releaseSecond()
+	require.Equal(t, []*TunerLease{nil, nil}, locks.Snapshot("dev", 2))
+}
@@ -0,0 +1,71 @@
+package data
+
+import (
+	"sync"
+	"time"
+)
+
+const defaultTuneWindow = 5 * time.Minute
+
+// TuneCounter counts tune requests within a sliding time window. Tunes are
+// redirects, so this approximates concurrent streams by recent activity.
+type TuneCounter struct {
+	mu     sync.Mutex
+	window time.Duration
+	events []time.Time
+	peak   int
+	now    func() time.Time
+}
+
+// NewTuneCounter creates a tune counter with the given sliding window.
+func NewTuneCounter(window time.Duration) *TuneCounter {
+	return &TuneCounter{
+		window: window,
+		events: make([]time.Time, 0, 16),
+		now:    time.Now,
+	}
+}
+
+// SetWindow changes the sliding window duration.
+func (c *TuneCounter) SetWindow(window time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.window = window
+}
+
+// Record registers a tune request.
+func (c *TuneCounter) Record() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.now()
+	c.prune(now)
+	c.events = append(c.events, now)
+
+	if len(c.events) > c.peak {
+		c.peak = len(c.events)
+	}
+}
+
+// Stats returns the number of tunes in the current window and the peak seen.
+func (c *TuneCounter) Stats() (current, peak int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.prune(c.now())
+
+	return len(c.events), c.peak
+}
+
+// prune drops events older than the window. Caller must hold mu.
+func (c *TuneCounter) prune(now time.Time) {
+	cutoff := now.Add(-c.window)
+	keep := 0
+
+	for keep < len(c.events) && !c.events[keep].After(cutoff) {
+		keep++
+	}
+
+	c.events = c.events[keep:]
+}
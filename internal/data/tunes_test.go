@@ -0,0 +1,63 @@
+package data
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTuneCounter_SlidingWindow(t *testing.T) {
+	now := time.Date(2026, 1, 4, 12, 0, 0, 0, time.UTC)
+	counter := NewTuneCounter(time.Minute)
+	counter.now = func() time.Time { return now }
+
+	counter.Record()
+	counter.Record()
+
+	now = now.Add(30 * time.Second)
+	counter.Record()
+
+	current, peak := counter.Stats()
+	require.Equal(t, 3, current)
+	require.Equal(t, 3, peak)
+
+	// First two tunes fall out of the window.
+	now = now.Add(45 * time.Second)
+
+	current, peak = counter.Stats()
+	require.Equal(t, 1, current)
+	require.Equal(t, 3, peak)
+
+	now = now.Add(time.Minute)
+
+	current, peak = counter.Stats()
+	require.Equal(t, 0, current)
+	require.Equal(t, 3, peak)
+}
+
+func TestTuneCounter_Concurrent(t *testing.T) {
+	counter := NewTuneCounter(time.Hour)
+
+	var wg sync.WaitGroup
+
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for j := 0; j < 100; j++ {
+				counter.Record()
+				counter.Stats()
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	current, peak := counter.Stats()
+	require.Equal(t, 1000, current)
+	require.Equal(t, 1000, peak)
+}
@@ -0,0 +1,80 @@
+package epg
+
+import (
+	"fmt"
+
+	"github.com/sirupsen/logrus"
+)
+
+// ApplyAliases copies the guide of a source channel onto alias channels.
+// aliases maps alias M3U name → source M3U name. For each alias whose source
+// has matched EPG data, a new channel entry with a suffixed ID is added along
+// with copies of the source's programmes, and the alias is recorded in the
+// channel map. Aliases that already have their own EPG match are left alone.
+func ApplyAliases(log logrus.FieldLogger, merged *MergeResult, aliases map[string]string) {
+	if len(aliases) == 0 {
+		return
+	}
+
+	m3uToEPGID := make(map[string]string, len(merged.ChannelMap))
+
+	for epgID, m3uName := range merged.ChannelMap {
+		m3uToEPGID[m3uName] = epgID
+	}
+
+	for alias, source := range aliases {
+		if _, matched := m3uToEPGID[alias]; matched {
+			log.WithField("alias", alias).Debug("Alias channel already has EPG data, skipping alias")
+
+			continue
+		}
+
+		sourceID, ok := m3uToEPGID[source]
+		if !ok {
+			log.WithFields(logrus.Fields{
+				"alias":  alias,
+				"source": source,
+			}).Warn("EPG alias source channel has no EPG data")
+
+			continue
+		}
+
+		aliasID := nextSuffixedID(sourceID, merged.ChannelMap)
+
+		for _, ch := range merged.Channels {
+			if ch.ID == sourceID {
+				ch.ID = aliasID
+				ch.DisplayName = alias
+				merged.Channels = append(merged.Channels, ch)
+
+				break
+			}
+		}
+
+		for _, prog := range merged.Programs {
+			if prog.Channel == sourceID {
+				prog.Channel = aliasID
+				merged.Programs = append(merged.Programs, prog)
+			}
+		}
+
+		merged.ChannelMap[aliasID] = alias
+		m3uToEPGID[alias] = aliasID
+
+		log.WithFields(logrus.Fields{
+			"alias":  alias,
+			"source": source,
+			"epgID":  aliasID,
+		}).Debug("Applied EPG alias")
+	}
+}
+
+// nextSuffixedID returns the first "{id}-{n}" (n ≥ 2) not already in use.
+func nextSuffixedID(id string, used map[string]string) string {
+	for n := 2; ; n++ {
+		candidate := fmt.Sprintf("%s-%d", id, n)
+		if _, exists := used[candidate]; !exists {
+			return candidate
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package epg
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestApplyAliases(t *testing.T) {
+	merged := &MergeResult{
+		Channels: []Channel{
+			{ID: "espn.us", DisplayName: "ESPN", Icon: Icon{Src: "http://logo.example.com/espn.png"}},
+		},
+		Programs: []Programme{
+			{Channel: "espn.us", Start: "20260104120000 +0000", Title: "SportsCenter"},
+			{Channel: "espn.us", Start: "20260104130000 +0000", Title: "NFL Live"},
+		},
+		ChannelMap: map[string]string{"espn.us": "ESPN"},
+	}
+
+	ApplyAliases(newTestLogger(), merged, map[string]string{
+		"ESPN Backup": "ESPN",
+		"Orphan":      "Missing Source",
+	})
+
+	require.Equal(t, "ESPN Backup", merged.ChannelMap["espn.us-2"])
+	require.NotContains(t, merged.ChannelMap, "Orphan")
+	require.Len(t, merged.Channels, 2)
+	require.Equal(t, "espn.us-2", merged.Channels[1].ID)
+	require.Equal(t, "ESPN Backup", merged.Channels[1].DisplayName)
+	require.Equal(t, "http://logo.example.com/espn.png", merged.Channels[1].Icon.Src)
+
+	aliasTitles := make([]string, 0)
+
+	for _, prog := range merged.Programs {
+		if prog.Channel == "espn.us-2" {
+			aliasTitles = append(aliasTitles, prog.Title)
+		}
+	}
+
+	require.Equal(t, []string{"SportsCenter", "NFL Live"}, aliasTitles)
+}
+
+func TestApplyAliases_AlreadyMatched(t *testing.T) {
+	merged := &MergeResult{
+		Channels: []Channel{
+			{ID: "espn.us", DisplayName: "ESPN"},
+			{ID: "espn2.us", DisplayName: "ESPN 2"},
+		},
+		Programs: []Programme{
+			{Channel: "espn.us", Title: "SportsCenter"},
+			{Channel: "espn2.us", Title: "College Football"},
+		},
+		ChannelMap: map[string]string{"espn.us": "ESPN", "espn2.us": "ESPN 2"},
+	}
+
+	ApplyAliases(newTestLogger(), merged, map[string]string{"ESPN 2": "ESPN"})
+
+	require.Len(t, merged.Channels, 2)
+	require.Len(t, merged.Programs, 2)
+}
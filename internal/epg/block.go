@@ -0,0 +1,102 @@
+package epg
+
+import (
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+// BlockCategories returns a copy of the EPG without programmes whose source
+// category matches one of categories (case-insensitive); a category
+// FilterForMerge mapped from the M3U group is not matched. With dropChannels,
+// channels that lose every one of their programmes are removed as well and
+// deleted from channelMap, and the M3U names they were mapped to are returned
+// so callers can keep them out of placeholder generation. No categories, or no
+// blocked programmes, returns tv as is.
+func BlockCategories(
+	log logrus.FieldLogger,
+	tv *TV,
+	channelMap map[string]string,
+	categories []string,
+	dropChannels bool,
+) (*TV, []string) {
+	if len(categories) == 0 {
+		return tv, nil
+	}
+
+	blocked := make(map[string]bool, len(categories))
+	for _, category := range categories {
+		blocked[strings.ToLower(strings.TrimSpace(category))] = true
+	}
+
+	programs := make([]Programme, 0, len(tv.Programs))
+	kept := make(map[string]bool, len(tv.Channels))
+	emptied := make(map[string]bool)
+
+	for _, prog := range tv.Programs {
+		if category := prog.originalCategory(); category != "" && blocked[strings.ToLower(strings.TrimSpace(category))] {
+			emptied[prog.Channel] = true
+
+			continue
+		}
+
+		kept[prog.Channel] = true
+		programs = append(programs, prog)
+	}
+
+	dropped := len(tv.Programs) - len(programs)
+	if dropped == 0 {
+		return tv, nil
+	}
+
+	channels := tv.Channels
+
+	var droppedNames []string
+
+	if dropChannels {
+		channels = make([]Channel, 0, len(tv.Channels))
+
+		for _, ch := range tv.Channels {
+			if emptied[ch.ID] && !kept[ch.ID] {
+				if name, ok := channelMap[ch.ID]; ok {
+					droppedNames = append(droppedNames, name)
+					delete(channelMap, ch.ID)
+				}
+
+				continue
+			}
+
+			channels = append(channels, ch)
+		}
+	}
+
+	log.WithFields(logrus.Fields{
+		"programmes": dropped,
+		"channels":   len(tv.Channels) - len(channels),
+	}).Info("Dropped programmes in blocked categories")
+
+	return &TV{
+		XMLName:  tv.XMLName,
+		Channels: channels,
+		Programs: programs,
+	}, droppedNames
+}
+
+// setCategory replaces the programme's category, remembering the source's.
+func (p *Programme) setCategory(category string) {
+	if p.sourceCategory == nil {
+		source := p.Category
+		p.sourceCategory = &source
+	}
+
+	p.Category = category
+}
+
+// originalCategory returns the category given by the source guide.
+func (p *Programme) originalCategory() string {
+	if p.sourceCategory != nil {
+		return *p.sourceCategory
+	}
+
+	return p.Category
+}
@@ -0,0 +1,105 @@
+package epg
+
+import (
+	"testing"
+
+	"github.com/savid/iptv/internal/m3u"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+)
+
+func blockTestTV() *TV {
+	return &TV{
+		Channels: []Channel{{ID: "mixed.us"}, {ID: "adult.us"}, {ID: "news.us"}},
+		Programs: []Programme{
+			{Channel: "mixed.us", Title: "Late Show", Category: "Adult"},
+			{Channel: "mixed.us", Title: "Morning Show", Category: "Talk"},
+			{Channel: "adult.us", Title: "After Dark", Category: " adult "},
+			{Channel: "news.us", Title: "Headlines"},
+		},
+	}
+}
+
+func TestBlockCategories(t *testing.T) {
+	tv := blockTestTV()
+
+	filtered, dropped := BlockCategories(logrus.New(), tv, nil, []string{"adult"}, false)
+
+	titles := make([]string, 0, len(filtered.Programs))
+	for _, prog := range filtered.Programs {
+		titles = append(titles, prog.Title)
+	}
+
+	require.Equal(t, []string{"Morning Show", "Headlines"}, titles)
+	require.Len(t, filtered.Channels, 3, "channels are kept without dropChannels")
+	require.Len(t, tv.Programs, 4, "input is not modified")
+	require.Empty(t, dropped)
+}
+
+func TestBlockCategories_DropChannels(t *testing.T) {
+	channelMap := map[string]string{"mixed.us": "Mixed", "adult.us": "Adult", "news.us": "News"}
+
+	filtered, dropped := BlockCategories(logrus.New(), blockTestTV(), channelMap, []string{"Adult"}, true)
+
+	ids := make([]string, 0, len(filtered.Channels))
+	for _, ch := range filtered.Channels {
+		ids = append(ids, ch.ID)
+	}
+
+	// Only the channel whose every programme was blocked is dropped.
+	require.Equal(t, []string{"mixed.us", "news.us"}, ids)
+	require.Len(t, filtered.Programs, 2)
+	require.Equal(t, map[string]string{"mixed.us": "Mixed", "news.us": "News"}, channelMap)
+	require.Equal(t, []string{"Adult"}, dropped)
+}
+
+func TestBlockCategories_NoMatch(t *testing.T) {
+	tv := blockTestTV()
+
+	filtered, _ := BlockCategories(logrus.New(), tv, nil, nil, true)
+	require.Same(t, tv, filtered)
+
+	filtered, _ = BlockCategories(logrus.New(), tv, nil, []string{"Sports"}, true)
+	require.Same(t, tv, filtered)
+}
+
+func TestBlockCategories_MatchesSourceCategory(t *testing.T) {
+	source := &TV{
+		Channels: []Channel{
+			{ID: "mixed.us", DisplayName: "Mixed"},
+			{ID: "adult.us", DisplayName: "Late Night"},
+		},
+		Programs: []Programme{
+			{Channel: "mixed.us", Start: "20260104220000 +0000", Title: "Late Show", Category: "Adult"},
+			{Channel: "mixed.us", Start: "20260104080000 +0000", Title: "Morning Show", Category: "Talk"},
+			{Channel: "adult.us", Start: "20260104230000 +0000", Title: "After Dark", Category: "Adult"},
+		},
+	}
+	m3uChannels := []m3u.Channel{
+		{Name: "Mixed", Group: "Movies"},
+		{Name: "Late Night", Group: "Movies"},
+	}
+
+	// FilterForMerge replaces every category with the M3U group, "Movies".
+	result := FilterForMerge(logrus.New(), source, m3uChannels)
+	for _, prog := range result.EPG.Programs {
+		require.Equal(t, "Movies", prog.Category)
+	}
+
+	merged := MergeEPGsWithChannels([]*FilterResult{result}, m3uChannels)
+
+	filtered, dropped := BlockCategories(logrus.New(), &TV{
+		Channels: merged.Channels,
+		Programs: merged.Programs,
+	}, merged.ChannelMap, []string{"adult"}, true)
+
+	require.Len(t, filtered.Programs, 1)
+	require.Equal(t, "Morning Show", filtered.Programs[0].Title)
+	require.Len(t, filtered.Channels, 1)
+	require.Equal(t, []string{"Late Night"}, dropped)
+	require.NotContains(t, merged.ChannelMap, "adult.us")
+
+	// Blocking by the mapped group category matches nothing.
+	unchanged, _ := BlockCategories(logrus.New(), filtered, merged.ChannelMap, []string{"Movies"}, true)
+	require.Same(t, filtered, unchanged)
+}
@@ -3,109 +3,36 @@ package epg
 import (
 	"crypto/md5" //nolint:gosec // MD5 is used for ID generation, not security
 	"fmt"
+	"sort"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/savid/iptv/internal/m3u"
+	"github.com/savid/iptv/internal/names"
 	"github.com/sirupsen/logrus"
 )
 
-// Common country/region prefixes to strip for normalized matching.
-// Order matters: longer/more specific prefixes should come first.
-var countryPrefixes = []string{
-	// Double-space variants first (more specific)
-	"USA  ", "World  ", "AUS  ",
-	// Colon variants
-	"US:", "AU:", "AUS:", "UK:", "PH:", "BR:", "CA:", "NZ:", "MX:", "ID:",
-	// Space variants
-	"USA ", "UK ", "PH ", "BR ", "ID ", "MY ", "MX ", "AUS ",
-	// Multi-word prefixes
-	"Carib ", "World ", "Latin ", "US ",
-}
-
-// regionPrefixes maps normalized region codes for region-aware matching.
-var regionPrefixes = map[string]string{
-	"US:": "us", "USA ": "us", "USA  ": "us", "US ": "us",
-	"AU:": "au", "AUS:": "au", "AUS ": "au", "AUS  ": "au",
-	"UK:": "uk", "UK ": "uk",
-	"PH:": "ph", "PH ": "ph",
-	"BR:": "br", "BR ": "br",
-	"CA:": "ca",
-	"NZ:": "nz",
-	"MX:": "mx", "MX ": "mx",
-	"ID:": "id", "ID ": "id",
-	"MY ":    "my",
-	"Carib ": "carib",
-	"World ": "world", "World  ": "world",
-	"Latin ": "latin",
-}
-
-// Common quality/variant suffixes to strip for normalized matching.
-var qualitySuffixes = []string{
-	"(HD)", "(FHD)", "(SD)", "(4K)", "(UHD)",
-	"(S)", "(A)", "(H)", "(D)", "(C)", "(P)", "(FL)", "(F)", "(E)", "(R)",
-	"(North America)", "(EMEA)", "(PRIME)", "(TUBI)",
-	" FHD", " HD",
-}
-
-// extractRegion returns the normalized region code from a channel name, or empty string if none.
-func extractRegion(name string) string {
-	upperName := strings.ToUpper(name)
-
-	for prefix, region := range regionPrefixes {
-		if strings.HasPrefix(upperName, strings.ToUpper(prefix)) {
-			return region
-		}
-	}
-
-	return ""
-}
-
-// normalizeChannelName strips country prefixes, quality suffixes, and normalizes whitespace.
-func normalizeChannelName(name string) string {
-	normalized := name
-
-	// Strip country prefixes (case-insensitive).
-	upperName := strings.ToUpper(normalized)
-	for _, prefix := range countryPrefixes {
-		if strings.HasPrefix(upperName, strings.ToUpper(prefix)) {
-			normalized = normalized[len(prefix):]
-			upperName = strings.ToUpper(normalized)
-		}
-	}
-
-	// Strip quality suffixes.
-	for _, suffix := range qualitySuffixes {
-		upperName = strings.ToUpper(normalized)
-		upperSuffix := strings.ToUpper(suffix)
-
-		for strings.Contains(upperName, upperSuffix) {
-			idx := strings.Index(upperName, upperSuffix)
-			if idx >= 0 {
-				normalized = normalized[:idx] + normalized[idx+len(suffix):]
-				upperName = strings.ToUpper(normalized)
-			}
-		}
-	}
-
-	// Normalize whitespace: collapse multiple spaces, trim.
-	normalized = strings.Join(strings.Fields(normalized), " ")
-	normalized = strings.TrimSpace(normalized)
-
-	// Convert to lowercase for comparison.
-	return strings.ToLower(normalized)
-}
+// PlaceholderDescription is the description used for generated placeholder programmes.
+const PlaceholderDescription = "No programme information available"
 
 // m3uNormalizedInfo holds normalized name and region for an M3U channel.
 type m3uNormalizedInfo struct {
 	originalName   string
 	normalizedName string
 	region         string
+
+	// fromTVGName is set when normalizedName was derived from the channel's
+	// tvg-name rather than its display name.
+	fromTVGName bool
 }
 
 // buildNormalizedNameMap creates a map from normalized M3U channel names to channel info.
 // Only includes channels WITHOUT tvg-id, since channels with tvg-id should match via tvg-id.
 // Also skips channels whose name has a tvg-id variant (which will match via tvg-id instead).
+// A channel's tvg-name is indexed too, mapping back to its display name, since
+// providers often put the cleanest name there; display names take precedence
+// when both normalize to the same key.
 func buildNormalizedNameMap(m3uChannels []m3u.Channel) map[string]m3uNormalizedInfo {
 	// First, find all channel names that have a tvg-id variant.
 	namesWithTVGID := make(map[string]bool, len(m3uChannels))
@@ -117,6 +44,7 @@ func buildNormalizedNameMap(m3uChannels []m3u.Channel) map[string]m3uNormalizedI
 	}
 
 	normalizedMap := make(map[string]m3uNormalizedInfo, len(m3uChannels))
+	tvgNames := make([]m3u.Channel, 0)
 
 	for _, channel := range m3uChannels {
 		// Skip channels with tvg-id - they should match via tvg-id, not normalized name.
@@ -130,8 +58,8 @@ func buildNormalizedNameMap(m3uChannels []m3u.Channel) map[string]m3uNormalizedI
 		}
 
 		if channel.Name != "" {
-			normalized := normalizeChannelName(channel.Name)
-			region := extractRegion(channel.Name)
+			normalized := names.Normalize(channel.Name)
+			region := names.Region(channel.Name)
 
 			// Only store first occurrence (prefer earlier channels).
 			if _, exists := normalizedMap[normalized]; !exists {
@@ -141,21 +69,185 @@ func buildNormalizedNameMap(m3uChannels []m3u.Channel) map[string]m3uNormalizedI
 					region:         region,
 				}
 			}
+
+			if channel.TVGName != "" && channel.TVGName != channel.Name {
+				tvgNames = append(tvgNames, channel)
+			}
+		}
+	}
+
+	for _, channel := range tvgNames {
+		normalized := names.Normalize(channel.TVGName)
+		if normalized == "" {
+			continue
+		}
+
+		if _, exists := normalizedMap[normalized]; exists {
+			continue
+		}
+
+		region := names.Region(channel.TVGName)
+		if region == "" {
+			region = names.Region(channel.Name)
+		}
+
+		normalizedMap[normalized] = m3uNormalizedInfo{
+			originalName:   channel.Name,
+			normalizedName: normalized,
+			region:         region,
+			fromTVGName:    true,
 		}
 	}
 
 	return normalizedMap
 }
 
+// Matching strategy names, used to configure the order strategies run in.
+const (
+	MatchTVGID          = "tvgid"
+	MatchDisplayName    = "display"
+	MatchWhitespace     = "whitespace" // Display-name equal once whitespace runs are collapsed
+	MatchNormalizedName = "normalized"
+)
+
+// MatchExplicit names matches made by an explicit --map-channel mapping,
+// which always run first and are not part of the configurable order.
+// MatchPlaceholder counts channels no strategy matched, which get
+// placeholder guide data.
+const (
+	MatchExplicit    = "explicit"
+	MatchPlaceholder = "placeholder"
+)
+
+// DefaultMatchOrder is the order matching strategies run in by default.
+var DefaultMatchOrder = []string{MatchTVGID, MatchDisplayName, MatchWhitespace, MatchNormalizedName}
+
+// ValidateMatchOrder checks that order only names known strategies, each at
+// most once. Strategies left out of the order are not run.
+func ValidateMatchOrder(order []string) error {
+	seen := make(map[string]bool, len(order))
+
+	for _, strategy := range order {
+		switch strategy {
+		case MatchTVGID, MatchDisplayName, MatchWhitespace, MatchNormalizedName:
+		default:
+			return fmt.Errorf("unknown match strategy %q (valid: %s)", strategy, strings.Join(DefaultMatchOrder, ", "))
+		}
+
+		if seen[strategy] {
+			return fmt.Errorf("match strategy %q listed more than once", strategy)
+		}
+
+		seen[strategy] = true
+	}
+
+	return nil
+}
+
+// MatchOptions customizes how M3U channels are matched to EPG channels.
+type MatchOptions struct {
+	// ForceFake lists M3U channel names or tvg-ids that must never be matched
+	// to EPG data; they always receive placeholder guide data.
+	ForceFake []string
+
+	// ChannelMap explicitly maps M3U channel names to EPG channel IDs. These
+	// matches run before the automatic strategies; if the EPG ID does not
+	// exist, the channel is not auto-matched and receives placeholder data.
+	ChannelMap map[string]string
+
+	// QualityRanking orders quality markers from best to worst. When several
+	// EPG channels match a name equally well, the best-ranked one wins.
+	// Defaults to DefaultQualityRanking.
+	QualityRanking []string
+
+	// Order lists the matching strategies to run, in order. Explicit
+	// ChannelMap matches always run first. Defaults to DefaultMatchOrder.
+	Order []string
+
+	// CategoryMap maps M3U group names to the programme category written to
+	// the EPG. Unmapped groups are used as the category unchanged.
+	CategoryMap map[string]string
+}
+
+// matchOrder returns the configured strategy order or the default.
+func (o MatchOptions) matchOrder() []string {
+	if len(o.Order) == 0 {
+		return DefaultMatchOrder
+	}
+
+	return o.Order
+}
+
+// matchable returns the M3U channels eligible for EPG matching.
+func (o MatchOptions) matchable(m3uChannels []m3u.Channel) []m3u.Channel {
+	if len(o.ForceFake) == 0 && len(o.ChannelMap) == 0 {
+		return m3uChannels
+	}
+
+	forced := make(map[string]bool, len(o.ForceFake))
+
+	for _, key := range o.ForceFake {
+		forced[key] = true
+	}
+
+	result := make([]m3u.Channel, 0, len(m3uChannels))
+
+	for _, ch := range m3uChannels {
+		if forced[ch.Name] || (ch.TVGID != "" && forced[ch.TVGID]) {
+			continue
+		}
+
+		if _, mapped := o.ChannelMap[ch.Name]; mapped {
+			continue
+		}
+
+		result = append(result, ch)
+	}
+
+	return result
+}
+
+// explicitMatches returns the configured mappings for channels present in
+// the playlist.
+func (o MatchOptions) explicitMatches(m3uChannels []m3u.Channel) map[string]string {
+	if len(o.ChannelMap) == 0 {
+		return nil
+	}
+
+	explicit := make(map[string]string, len(o.ChannelMap))
+
+	for _, ch := range m3uChannels {
+		if epgID, ok := o.ChannelMap[ch.Name]; ok {
+			explicit[ch.Name] = epgID
+		}
+	}
+
+	return explicit
+}
+
 // FilterForMerge filters EPG data without generating fake channels.
 // Used when merging multiple EPG sources - fake data is added after merging.
 func FilterForMerge(log logrus.FieldLogger, epgData *TV, m3uChannels []m3u.Channel) *FilterResult {
-	channelNameMap := buildChannelNameMap(m3uChannels)
-	tvgIDMap := buildTVGIDMap(m3uChannels)
-	normalizedNameMap := buildNormalizedNameMap(m3uChannels)
+	return FilterForMergeWithOptions(log, epgData, m3uChannels, MatchOptions{})
+}
 
-	categoryMap := buildCategoryMap(m3uChannels)
-	matchedChannels, channelIDMap := matchChannels(log, epgData.Channels, channelNameMap, tvgIDMap, normalizedNameMap)
+// FilterForMergeWithOptions is FilterForMerge with custom match options.
+func FilterForMergeWithOptions(
+	log logrus.FieldLogger,
+	epgData *TV,
+	m3uChannels []m3u.Channel,
+	opts MatchOptions,
+) *FilterResult {
+	matchable := opts.matchable(m3uChannels)
+	channelNameMap := buildChannelNameMap(matchable)
+	tvgIDMap := buildTVGIDMap(matchable)
+	normalizedNameMap := buildNormalizedNameMap(matchable)
+
+	categoryMap := buildCategoryMap(m3uChannels, opts.CategoryMap)
+	explicit := opts.explicitMatches(m3uChannels)
+	matchedChannels, channelIDMap, strategies := matchChannelsWithOptions(
+		log, epgData.Channels, channelNameMap, tvgIDMap, normalizedNameMap, explicit, opts,
+	)
 
 	// Track original IDs for duplicated channels.
 	originalIDMap := make(map[string][]string, len(channelIDMap))
@@ -175,7 +267,7 @@ func FilterForMerge(log logrus.FieldLogger, epgData *TV, m3uChannels []m3u.Chann
 		if displayName, exists := channelIDMap[program.Channel]; exists {
 			programWithCategory := program
 			if category, ok := categoryMap[displayName]; ok {
-				programWithCategory.Category = category
+				programWithCategory.setCategory(category)
 			}
 
 			filteredPrograms = append(filteredPrograms, programWithCategory)
@@ -188,7 +280,7 @@ func FilterForMerge(log logrus.FieldLogger, epgData *TV, m3uChannels []m3u.Chann
 
 				if displayName, ok := channelIDMap[suffixedID]; ok {
 					if category, catOK := categoryMap[displayName]; catOK {
-						duplicatedProgram.Category = category
+						duplicatedProgram.setCategory(category)
 					}
 				}
 
@@ -204,18 +296,33 @@ func FilterForMerge(log logrus.FieldLogger, epgData *TV, m3uChannels []m3u.Chann
 			Programs: filteredPrograms,
 		},
 		ChannelMap: channelIDMap,
+		Strategies: strategies,
 	}
 }
 
 // Filter filters EPG data to only include channels and programs that match the M3U playlist.
 // Returns the filtered EPG and a map of channel IDs to display names.
 func Filter(log logrus.FieldLogger, epgData *TV, m3uChannels []m3u.Channel) (*TV, map[string]string) {
-	channelNameMap := buildChannelNameMap(m3uChannels)
-	tvgIDMap := buildTVGIDMap(m3uChannels)
-	normalizedNameMap := buildNormalizedNameMap(m3uChannels)
+	return FilterWithOptions(log, epgData, m3uChannels, MatchOptions{})
+}
 
-	categoryMap := buildCategoryMap(m3uChannels)
-	matchedChannels, channelIDMap := matchChannels(log, epgData.Channels, channelNameMap, tvgIDMap, normalizedNameMap)
+// FilterWithOptions is Filter with custom match options.
+func FilterWithOptions(
+	log logrus.FieldLogger,
+	epgData *TV,
+	m3uChannels []m3u.Channel,
+	opts MatchOptions,
+) (*TV, map[string]string) {
+	matchable := opts.matchable(m3uChannels)
+	channelNameMap := buildChannelNameMap(matchable)
+	tvgIDMap := buildTVGIDMap(matchable)
+	normalizedNameMap := buildNormalizedNameMap(matchable)
+
+	categoryMap := buildCategoryMap(m3uChannels, opts.CategoryMap)
+	explicit := opts.explicitMatches(m3uChannels)
+	matchedChannels, channelIDMap, _ := matchChannelsWithOptions(
+		log, epgData.Channels, channelNameMap, tvgIDMap, normalizedNameMap, explicit, opts,
+	)
 
 	channelsWithPrograms := make(map[string]bool, len(matchedChannels))
 
@@ -237,7 +344,7 @@ func Filter(log logrus.FieldLogger, epgData *TV, m3uChannels []m3u.Channel) (*TV
 		if displayName, exists := channelIDMap[program.Channel]; exists {
 			programWithCategory := program
 			if category, ok := categoryMap[displayName]; ok {
-				programWithCategory.Category = category
+				programWithCategory.setCategory(category)
 			}
 
 			filteredPrograms = append(filteredPrograms, programWithCategory)
@@ -251,7 +358,7 @@ func Filter(log logrus.FieldLogger, epgData *TV, m3uChannels []m3u.Channel) (*TV
 
 				if displayName, ok := channelIDMap[suffixedID]; ok {
 					if category, catOK := categoryMap[displayName]; catOK {
-						duplicatedProgram.Category = category
+						duplicatedProgram.setCategory(category)
 					}
 				}
 
@@ -293,27 +400,46 @@ func buildChannelNameMap(m3uChannels []m3u.Channel) map[string]bool {
 	return channelMap
 }
 
-// buildTVGIDMap creates a map from tvg-id to M3U channel name for ID-based matching.
-func buildTVGIDMap(m3uChannels []m3u.Channel) map[string]string {
-	tvgIDMap := make(map[string]string, len(m3uChannels))
+// buildTVGIDMap creates a map from tvg-id to the M3U channel names carrying
+// it, in playlist order, for ID-based matching.
+func buildTVGIDMap(m3uChannels []m3u.Channel) map[string][]string {
+	tvgIDMap := make(map[string][]string, len(m3uChannels))
+	seen := make(map[string]bool, len(m3uChannels))
 
 	for _, channel := range m3uChannels {
-		if channel.TVGID != "" && channel.Name != "" {
-			tvgIDMap[channel.TVGID] = channel.Name
+		if channel.TVGID == "" || channel.Name == "" {
+			continue
 		}
+
+		key := channel.TVGID + "\x00" + channel.Name
+		if seen[key] {
+			continue
+		}
+
+		seen[key] = true
+		tvgIDMap[channel.TVGID] = append(tvgIDMap[channel.TVGID], channel.Name)
 	}
 
 	return tvgIDMap
 }
 
-// buildCategoryMap creates a map from channel name to category (group-title from M3U).
-func buildCategoryMap(m3uChannels []m3u.Channel) map[string]string {
+// buildCategoryMap creates a map from channel name to category (group-title
+// from M3U). Groups found in categories are replaced by their mapped category;
+// others pass through unchanged.
+func buildCategoryMap(m3uChannels []m3u.Channel, categories map[string]string) map[string]string {
 	categoryMap := make(map[string]string, len(m3uChannels))
 
 	for _, channel := range m3uChannels {
-		if channel.Name != "" && channel.Group != "" {
-			categoryMap[channel.Name] = channel.Group
+		if channel.Name == "" || channel.Group == "" {
+			continue
 		}
+
+		category := channel.Group
+		if mapped, ok := categories[category]; ok {
+			category = mapped
+		}
+
+		categoryMap[channel.Name] = category
 	}
 
 	return categoryMap
@@ -329,6 +455,8 @@ type matcherState struct {
 	matchedEPG        map[int]bool
 	idUsageCount      map[string]int
 	epgIDToCandidates map[string][]int
+	qualityRanking    []string
+	strategies        map[string]string // M3U name → strategy that matched it
 }
 
 func newMatcherState(log logrus.FieldLogger, epgChannels []Channel) *matcherState {
@@ -341,6 +469,8 @@ func newMatcherState(log logrus.FieldLogger, epgChannels []Channel) *matcherStat
 		matchedEPG:        make(map[int]bool, len(epgChannels)),
 		idUsageCount:      make(map[string]int, len(epgChannels)),
 		epgIDToCandidates: make(map[string][]int, len(epgChannels)),
+		qualityRanking:    DefaultQualityRanking,
+		strategies:        make(map[string]string, len(epgChannels)),
 	}
 
 	for i, ch := range epgChannels {
@@ -352,9 +482,10 @@ func newMatcherState(log logrus.FieldLogger, epgChannels []Channel) *matcherStat
 	return state
 }
 
-func (s *matcherState) addMatch(epgIdx int, m3uName string, logMsg string) {
+func (s *matcherState) addMatch(epgIdx int, m3uName, strategy, logMsg string) {
 	s.matchedEPG[epgIdx] = true
 	s.matchedM3U[m3uName] = true
+	s.strategies[m3uName] = strategy
 
 	epgCopy := s.epgChannels[epgIdx]
 	if epgCopy.ID == "" {
@@ -385,20 +516,67 @@ func (s *matcherState) addMatch(epgIdx int, m3uName string, logMsg string) {
 	}).Debug(logMsg)
 }
 
-func (s *matcherState) matchByTVGID(tvgIDMap map[string]string) {
-	for tvgID, m3uName := range tvgIDMap {
-		if s.matchedM3U[m3uName] {
+// matchExplicit applies configured M3U name → EPG ID mappings. Several M3U
+// channels may map to the same EPG ID; addMatch suffixes the duplicates.
+func (s *matcherState) matchExplicit(explicit map[string]string) {
+	names := make([]string, 0, len(explicit))
+
+	for m3uName := range explicit {
+		names = append(names, m3uName)
+	}
+
+	// Sort for deterministic suffix assignment.
+	sort.Strings(names)
+
+	for _, m3uName := range names {
+		epgID := explicit[m3uName]
+
+		candidates := s.epgIDToCandidates[epgID]
+		if len(candidates) == 0 {
+			s.log.WithFields(logrus.Fields{
+				"m3uChannel": m3uName,
+				"epgID":      epgID,
+			}).Debug("Mapped EPG ID not found in source")
+
 			continue
 		}
 
+		s.addMatch(candidates[0], m3uName, MatchExplicit, "Matched channel by explicit mapping")
+	}
+}
+
+// matchByTVGID matches M3U channels to EPG channels with the same ID. When
+// several M3U channels share a tvg-id and there are fewer EPG candidates than
+// channels, the extra channels share an already matched candidate; addMatch
+// gives them suffixed IDs so each receives a copy of the guide.
+func (s *matcherState) matchByTVGID(tvgIDMap map[string][]string) {
+	for tvgID, m3uNames := range tvgIDMap {
 		candidates := s.epgIDToCandidates[tvgID]
 		if len(candidates) == 0 {
 			continue
 		}
 
-		bestIdx := s.findBestTVGIDCandidate(candidates, m3uName)
-		if bestIdx >= 0 {
-			s.addMatch(bestIdx, m3uName, "Matched channel by tvg-id")
+		shared := -1
+
+		for _, m3uName := range m3uNames {
+			if s.matchedM3U[m3uName] {
+				continue
+			}
+
+			bestIdx := s.findBestTVGIDCandidate(candidates, m3uName)
+			if bestIdx < 0 {
+				bestIdx = shared
+			}
+
+			if bestIdx < 0 {
+				continue
+			}
+
+			s.addMatch(bestIdx, m3uName, MatchTVGID, "Matched channel by tvg-id")
+
+			if shared < 0 {
+				shared = bestIdx
+			}
 		}
 	}
 }
@@ -424,6 +602,9 @@ func (s *matcherState) findBestTVGIDCandidate(candidates []int, m3uName string)
 }
 
 func (s *matcherState) matchByDisplayName(channelNameMap map[string]bool) {
+	s.logNameIDCollisions(channelNameMap)
+
+	// Only DisplayName is compared; an M3U name equal to an EPG ID is not a match.
 	for i, epgChannel := range s.epgChannels {
 		if s.matchedEPG[i] {
 			continue
@@ -433,13 +614,83 @@ func (s *matcherState) matchByDisplayName(channelNameMap map[string]bool) {
 			continue
 		}
 
-		s.addMatch(i, epgChannel.DisplayName, "Matched channel by display-name")
+		s.addMatch(i, epgChannel.DisplayName, MatchDisplayName, "Matched channel by display-name")
+	}
+}
+
+// logNameIDCollisions logs M3U channel names that equal the ID of an EPG
+// channel with a different display-name. Such names are never matched by ID,
+// but the coincidence is worth knowing about when a match looks wrong.
+func (s *matcherState) logNameIDCollisions(channelNameMap map[string]bool) {
+	collisions := make(map[string]string)
+
+	for _, epgChannel := range s.epgChannels {
+		if channelNameMap[epgChannel.ID] && epgChannel.DisplayName != epgChannel.ID {
+			collisions[epgChannel.ID] = epgChannel.DisplayName
+		}
+	}
+
+	m3uNames := make([]string, 0, len(collisions))
+	for name := range collisions {
+		m3uNames = append(m3uNames, name)
+	}
+
+	sort.Strings(m3uNames)
+
+	for _, name := range m3uNames {
+		s.log.WithFields(logrus.Fields{
+			"m3uChannel":     name,
+			"epgDisplayName": collisions[name],
+		}).Warn("M3U channel name equals the ID of another EPG channel, not matching by ID")
+	}
+}
+
+// matchByCollapsedWhitespace matches EPG display-names to M3U names that
+// differ only in runs of whitespace (e.g. "ESPN  2" and "ESPN 2").
+func (s *matcherState) matchByCollapsedWhitespace(channelNameMap map[string]bool) {
+	m3uNames := make([]string, 0, len(channelNameMap))
+	for name := range channelNameMap {
+		m3uNames = append(m3uNames, name)
+	}
+
+	sort.Strings(m3uNames)
+
+	// Collapsed form → M3U name, preferring a name already in collapsed form.
+	collapsed := make(map[string]string, len(m3uNames))
+
+	for _, name := range m3uNames {
+		key := names.CollapseWhitespace(name)
+		if existing, ok := collapsed[key]; !ok || (existing != key && name == key) {
+			collapsed[key] = name
+		}
+	}
+
+	for i, epgChannel := range s.epgChannels {
+		if s.matchedEPG[i] {
+			continue
+		}
+
+		m3uName, ok := collapsed[names.CollapseWhitespace(epgChannel.DisplayName)]
+		if !ok || s.matchedM3U[m3uName] {
+			continue
+		}
+
+		s.addMatch(i, m3uName, MatchWhitespace, "Matched channel by display-name ignoring whitespace")
 	}
 }
 
 func (s *matcherState) matchByNormalizedName(normalizedNameMap map[string]m3uNormalizedInfo) {
+	// Display-name keys run first so a tvg-name only matches channels whose
+	// display name found nothing.
+	s.matchNormalizedEntries(normalizedNameMap, false)
+	s.matchNormalizedEntries(normalizedNameMap, true)
+}
+
+// matchNormalizedEntries matches the normalized-name entries derived from
+// tvg-name (fromTVGName) or from display names.
+func (s *matcherState) matchNormalizedEntries(normalizedNameMap map[string]m3uNormalizedInfo, fromTVGName bool) {
 	for _, m3uInfo := range normalizedNameMap {
-		if s.matchedM3U[m3uInfo.originalName] {
+		if m3uInfo.fromTVGName != fromTVGName || s.matchedM3U[m3uInfo.originalName] {
 			continue
 		}
 
@@ -449,29 +700,36 @@ func (s *matcherState) matchByNormalizedName(normalizedNameMap map[string]m3uNor
 				"m3uChannel":     m3uInfo.originalName,
 				"epgDisplayName": s.epgChannels[bestIdx].DisplayName,
 				"region":         m3uInfo.region,
+				"tvgName":        m3uInfo.fromTVGName,
 			}).Debug("Matched channel by normalized name")
 
-			s.addMatch(bestIdx, m3uInfo.originalName, "Matched channel by normalized name")
+			s.addMatch(bestIdx, m3uInfo.originalName, MatchNormalizedName, "Matched channel by normalized name")
 		}
 	}
 }
 
+// findBestNormalizedMatch picks the unmatched EPG channel with the same
+// normalized name and the best region score, breaking ties by quality rank.
 func (s *matcherState) findBestNormalizedMatch(m3uInfo m3uNormalizedInfo) int {
 	bestIdx := -1
 	bestScore := -1
+	bestRank := 0
 
 	for i, epgChannel := range s.epgChannels {
 		if s.matchedEPG[i] {
 			continue
 		}
 
-		if normalizeChannelName(epgChannel.DisplayName) != m3uInfo.normalizedName {
+		if names.Normalize(epgChannel.DisplayName) != m3uInfo.normalizedName {
 			continue
 		}
 
-		score := scoreRegionMatch(m3uInfo.region, extractRegion(epgChannel.DisplayName))
-		if score > bestScore {
+		score := scoreRegionMatch(m3uInfo.region, names.Region(epgChannel.DisplayName))
+		rank := qualityRank(epgChannel.DisplayName, s.qualityRanking)
+
+		if score > bestScore || (score == bestScore && rank < bestRank) {
 			bestScore = score
+			bestRank = rank
 			bestIdx = i
 		}
 	}
@@ -515,17 +773,46 @@ func matchChannels(
 	log logrus.FieldLogger,
 	epgChannels []Channel,
 	channelNameMap map[string]bool,
-	tvgIDMap map[string]string,
+	tvgIDMap map[string][]string,
 	normalizedNameMap map[string]m3uNormalizedInfo,
 ) ([]Channel, map[string]string) {
+	matched, idMap, _ := matchChannelsWithOptions(log, epgChannels, channelNameMap, tvgIDMap, normalizedNameMap, nil, MatchOptions{})
+
+	return matched, idMap
+}
+
+func matchChannelsWithOptions(
+	log logrus.FieldLogger,
+	epgChannels []Channel,
+	channelNameMap map[string]bool,
+	tvgIDMap map[string][]string,
+	normalizedNameMap map[string]m3uNormalizedInfo,
+	explicit map[string]string,
+	opts MatchOptions,
+) ([]Channel, map[string]string, map[string]string) {
 	state := newMatcherState(log, epgChannels)
+	if len(opts.QualityRanking) > 0 {
+		state.qualityRanking = opts.QualityRanking
+	}
+
+	state.matchExplicit(explicit)
+
+	for _, strategy := range opts.matchOrder() {
+		switch strategy {
+		case MatchTVGID:
+			state.matchByTVGID(tvgIDMap)
+		case MatchDisplayName:
+			state.matchByDisplayName(channelNameMap)
+		case MatchWhitespace:
+			state.matchByCollapsedWhitespace(channelNameMap)
+		case MatchNormalizedName:
+			state.matchByNormalizedName(normalizedNameMap)
+		}
+	}
 
-	state.matchByTVGID(tvgIDMap)
-	state.matchByDisplayName(channelNameMap)
-	state.matchByNormalizedName(normalizedNameMap)
 	state.logUnmatched(channelNameMap)
 
-	return state.matchedChannels, state.channelIDMap
+	return state.matchedChannels, state.channelIDMap, state.strategies
 }
 
 func generateFakeEPGData(
@@ -570,7 +857,16 @@ func generateFakeEPGData(
 	return fakeChannels
 }
 
-// generateFakePrograms creates placeholder program entries for channels without program data.
+// timeNow returns the current time; replaced in tests.
+var timeNow = time.Now
+
+// placeholderWindow is how far ahead placeholder programmes for channels
+// without guide data reach. Several days keeps the guide filled when
+// refreshes fail or run less often than daily.
+const placeholderWindow = 3 * 24 * time.Hour
+
+// generateFakePrograms creates placeholder program entries for channels
+// without program data, from the current hour for placeholderWindow.
 func generateFakePrograms(
 	channels []Channel,
 	channelsWithPrograms map[string]bool,
@@ -579,32 +875,46 @@ func generateFakePrograms(
 ) []Programme {
 	fakePrograms := make([]Programme, 0)
 
+	start := timeNow().UTC().Truncate(time.Hour)
+	stop := start.Add(placeholderWindow)
+
 	for _, ch := range channels {
 		if channelsWithPrograms[ch.ID] {
 			continue
 		}
 
-		displayName := ch.DisplayName
-		if name, ok := channelIDMap[ch.ID]; ok {
-			displayName = name
-		}
+		fakePrograms = append(fakePrograms, placeholderProgramme(ch, categoryMap, channelIDMap, start, stop))
+	}
 
-		fakeProgram := Programme{
-			Channel:     ch.ID,
-			Start:       "20260101000000 +0000",
-			Stop:        "20260101235959 +0000",
-			Title:       displayName,
-			Description: "No programme information available",
-		}
+	return fakePrograms
+}
 
-		if category, ok := categoryMap[displayName]; ok {
-			fakeProgram.Category = category
-		}
+// placeholderProgramme creates a placeholder programme for ch between start
+// and stop, titled with the channel's M3U name when it has one.
+func placeholderProgramme(
+	ch Channel,
+	categoryMap map[string]string,
+	channelIDMap map[string]string,
+	start, stop time.Time,
+) Programme {
+	displayName := ch.DisplayName
+	if name, ok := channelIDMap[ch.ID]; ok {
+		displayName = name
+	}
 
-		fakePrograms = append(fakePrograms, fakeProgram)
+	prog := Programme{
+		Channel:     ch.ID,
+		Start:       FormatTime(start),
+		Stop:        FormatTime(stop),
+		Title:       displayName,
+		Description: PlaceholderDescription,
 	}
 
-	return fakePrograms
+	if category, ok := categoryMap[displayName]; ok {
+		prog.Category = category
+	}
+
+	return prog
 }
 
 func generateChannelID(displayName string) string {
@@ -622,3 +932,25 @@ func isNumericSuffix(s string) bool {
 
 	return err == nil
 }
+
+// PlaceholderOnlyChannels returns the IDs of channels whose programmes are all
+// generated placeholders (or that have no programmes at all), in channel order.
+func PlaceholderOnlyChannels(tv *TV) []string {
+	hasRealProgram := make(map[string]bool, len(tv.Channels))
+
+	for _, prog := range tv.Programs {
+		if prog.Description != PlaceholderDescription {
+			hasRealProgram[prog.Channel] = true
+		}
+	}
+
+	ids := make([]string, 0)
+
+	for _, ch := range tv.Channels {
+		if !hasRealProgram[ch.ID] {
+			ids = append(ids, ch.ID)
+		}
+	}
+
+	return ids
+}
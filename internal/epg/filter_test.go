@@ -3,9 +3,11 @@ package epg
 import (
 	"io"
 	"testing"
+	"time"
 
 	"github.com/savid/iptv/internal/m3u"
 	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
 	"github.com/stretchr/testify/require"
 )
 
@@ -304,11 +306,21 @@ func TestFilter_GenerateFakeProgrammes(t *testing.T) {
 		{Name: "ESPN", URL: "http://stream.example.com/1"},
 	}
 
+	now := time.Date(2026, 3, 10, 14, 25, 0, 0, time.UTC)
+	timeNow = func() time.Time { return now }
+
+	t.Cleanup(func() { timeNow = time.Now })
+
 	filtered, _ := Filter(log, epgData, m3uChannels)
 
 	require.Len(t, filtered.Programs, 1)
 	require.Equal(t, "ESPN", filtered.Programs[0].Title)
 	require.Equal(t, "No programme information available", filtered.Programs[0].Description)
+
+	// Placeholders run from the current hour for several days, so the guide
+	// doesn't go blank at midnight UTC.
+	require.Equal(t, FormatTime(time.Date(2026, 3, 10, 14, 0, 0, 0, time.UTC)), filtered.Programs[0].Start)
+	require.Equal(t, FormatTime(time.Date(2026, 3, 13, 14, 0, 0, 0, time.UTC)), filtered.Programs[0].Stop)
 }
 
 func TestFilter_EmptyM3UChannels(t *testing.T) {
@@ -403,12 +415,12 @@ func TestBuildTVGIDMap(t *testing.T) {
 	tests := []struct {
 		name     string
 		channels []m3u.Channel
-		expected map[string]string
+		expected map[string][]string
 	}{
 		{
 			name:     "empty channels",
 			channels: []m3u.Channel{},
-			expected: map[string]string{},
+			expected: map[string][]string{},
 		},
 		{
 			name: "channels with tvg-id",
@@ -416,7 +428,7 @@ func TestBuildTVGIDMap(t *testing.T) {
 				{Name: "ESPN", TVGID: "espn.us"},
 				{Name: "CNN", TVGID: "cnn.us"},
 			},
-			expected: map[string]string{"espn.us": "ESPN", "cnn.us": "CNN"},
+			expected: map[string][]string{"espn.us": {"ESPN"}, "cnn.us": {"CNN"}},
 		},
 		{
 			name: "channels without tvg-id",
@@ -424,7 +436,7 @@ func TestBuildTVGIDMap(t *testing.T) {
 				{Name: "ESPN", TVGID: ""},
 				{Name: "CNN"},
 			},
-			expected: map[string]string{},
+			expected: map[string][]string{},
 		},
 		{
 			name: "mixed channels",
@@ -433,7 +445,7 @@ func TestBuildTVGIDMap(t *testing.T) {
 				{Name: "HBO", TVGID: ""},
 				{Name: "CNN", TVGID: "cnn.us"},
 			},
-			expected: map[string]string{"espn.us": "ESPN", "cnn.us": "CNN"},
+			expected: map[string][]string{"espn.us": {"ESPN"}, "cnn.us": {"CNN"}},
 		},
 		{
 			name: "channel with empty name ignored",
@@ -441,7 +453,16 @@ func TestBuildTVGIDMap(t *testing.T) {
 				{Name: "", TVGID: "orphan.id"},
 				{Name: "ESPN", TVGID: "espn.us"},
 			},
-			expected: map[string]string{"espn.us": "ESPN"},
+			expected: map[string][]string{"espn.us": {"ESPN"}},
+		},
+		{
+			name: "shared tvg-id keeps every name",
+			channels: []m3u.Channel{
+				{Name: "ESPN", TVGID: "espn.us"},
+				{Name: "ESPN Backup", TVGID: "espn.us"},
+				{Name: "ESPN", TVGID: "espn.us"},
+			},
+			expected: map[string][]string{"espn.us": {"ESPN", "ESPN Backup"}},
 		},
 	}
 
@@ -676,68 +697,7 @@ func TestBuildCategoryMap(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			result := buildCategoryMap(tt.channels)
-			require.Equal(t, tt.expected, result)
-		})
-	}
-}
-
-func TestNormalizeChannelName(t *testing.T) {
-	tests := []struct {
-		name     string
-		input    string
-		expected string
-	}{
-		{
-			name:     "simple name",
-			input:    "ESPN",
-			expected: "espn",
-		},
-		{
-			name:     "US prefix with colon",
-			input:    "US: ESPN",
-			expected: "espn",
-		},
-		{
-			name:     "USA prefix with spaces",
-			input:    "USA  ESPN",
-			expected: "espn",
-		},
-		{
-			name:     "AU prefix",
-			input:    "AU: Fox Sports 501",
-			expected: "fox sports 501",
-		},
-		{
-			name:     "quality suffix HD",
-			input:    "ESPN (HD)",
-			expected: "espn",
-		},
-		{
-			name:     "multiple suffixes",
-			input:    "US ESPN 1 (HD) (S)",
-			expected: "espn 1",
-		},
-		{
-			name:     "Carib prefix",
-			input:    "Carib ESPN (D)",
-			expected: "espn",
-		},
-		{
-			name:     "normalize whitespace",
-			input:    "FOX   NEWS  ",
-			expected: "fox news",
-		},
-		{
-			name:     "EMEA suffix",
-			input:    "PH TFC (EMEA)",
-			expected: "tfc",
-		},
-	}
-
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			result := normalizeChannelName(tt.input)
+			result := buildCategoryMap(tt.channels, nil)
 			require.Equal(t, tt.expected, result)
 		})
 	}
@@ -796,6 +756,27 @@ func TestBuildNormalizedNameMap(t *testing.T) {
 				"espn": {originalName: "USA  ESPN", normalizedName: "espn", region: "us"},
 			},
 		},
+		{
+			name: "tvg-name indexed",
+			channels: []m3u.Channel{
+				{Name: "|US| ESPN FHD", TVGName: "ESPN"},
+			},
+			expected: map[string]m3uNormalizedInfo{
+				"|us| espn": {originalName: "|US| ESPN FHD", normalizedName: "|us| espn"},
+				"espn":      {originalName: "|US| ESPN FHD", normalizedName: "espn", fromTVGName: true},
+			},
+		},
+		{
+			name: "display name preferred over tvg-name",
+			channels: []m3u.Channel{
+				{Name: "ESPN 2", TVGName: "ESPN"},
+				{Name: "ESPN"},
+			},
+			expected: map[string]m3uNormalizedInfo{
+				"espn 2": {originalName: "ESPN 2", normalizedName: "espn 2"},
+				"espn":   {originalName: "ESPN", normalizedName: "espn"},
+			},
+		},
 	}
 
 	for _, tt := range tests {
@@ -849,3 +830,329 @@ func TestMatchChannelsByNormalizedName(t *testing.T) {
 	require.True(t, foundCNN, "USA  CNN should be matched via normalized name")
 	require.True(t, foundFOX, "Carib FOX should be matched via normalized name")
 }
+
+func TestPlaceholderOnlyChannels(t *testing.T) {
+	epgData := &TV{
+		Channels: []Channel{
+			{ID: "espn.us", DisplayName: "ESPN"},
+			{ID: "cnn.us", DisplayName: "CNN"},
+		},
+		Programs: []Programme{
+			{Channel: "espn.us", Title: "SportsCenter"},
+		},
+	}
+	m3uChannels := []m3u.Channel{
+		{Name: "ESPN"},
+		{Name: "CNN"},
+		{Name: "Local"},
+	}
+
+	filtered, channelMap := Filter(newTestLogger(), epgData, m3uChannels)
+
+	ids := PlaceholderOnlyChannels(filtered)
+	require.Len(t, ids, 2)
+
+	names := []string{channelMap[ids[0]], channelMap[ids[1]]}
+	require.ElementsMatch(t, []string{"CNN", "Local"}, names)
+}
+
+func TestFilterWithOptions_ForceFake(t *testing.T) {
+	epgData := &TV{
+		Channels: []Channel{
+			{ID: "espn.us", DisplayName: "ESPN"},
+			{ID: "cnn.us", DisplayName: "CNN"},
+		},
+		Programs: []Programme{
+			{Channel: "espn.us", Title: "SportsCenter"},
+			{Channel: "cnn.us", Title: "Newsroom"},
+		},
+	}
+	m3uChannels := []m3u.Channel{
+		{Name: "ESPN", TVGID: "espn.us"},
+		{Name: "CNN"},
+	}
+
+	tests := []struct {
+		name      string
+		forceFake []string
+	}{
+		{name: "by name", forceFake: []string{"ESPN"}},
+		{name: "by tvg-id", forceFake: []string{"espn.us"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filtered, channelMap := FilterWithOptions(newTestLogger(), epgData, m3uChannels, MatchOptions{ForceFake: tt.forceFake})
+
+			require.NotContains(t, channelMap, "espn.us")
+			require.Equal(t, "CNN", channelMap["cnn.us"])
+
+			// The forced channel still exists with placeholder data only.
+			fakeID := generateChannelID("ESPN")
+			require.Equal(t, "ESPN", channelMap[fakeID])
+			require.Contains(t, PlaceholderOnlyChannels(filtered), fakeID)
+
+			result := FilterForMergeWithOptions(newTestLogger(), epgData, m3uChannels, MatchOptions{ForceFake: tt.forceFake})
+			require.NotContains(t, result.ChannelMap, "espn.us")
+			require.Len(t, result.ChannelMap, 1)
+		})
+	}
+}
+
+func TestFilterWithOptions_ExplicitChannelMap(t *testing.T) {
+	epgData := &TV{
+		Channels: []Channel{
+			{ID: "espn.us", DisplayName: "ESPN"},
+			{ID: "local.station", DisplayName: "WXYZ"},
+		},
+		Programs: []Programme{
+			{Channel: "espn.us", Title: "SportsCenter"},
+			{Channel: "local.station", Title: "Local News"},
+		},
+	}
+	m3uChannels := []m3u.Channel{
+		{Name: "ESPN"},
+		{Name: "My Local Channel"},
+		{Name: "Local Backup"},
+	}
+
+	opts := MatchOptions{ChannelMap: map[string]string{
+		"My Local Channel": "local.station",
+		"Local Backup":     "local.station",
+	}}
+
+	filtered, channelMap := FilterWithOptions(newTestLogger(), epgData, m3uChannels, opts)
+
+	require.Equal(t, "ESPN", channelMap["espn.us"])
+	require.Equal(t, "Local Backup", channelMap["local.station"])
+	require.Equal(t, "My Local Channel", channelMap["local.station-2"])
+
+	titles := make(map[string]string)
+	for _, prog := range filtered.Programs {
+		titles[prog.Channel] = prog.Title
+	}
+
+	require.Equal(t, "Local News", titles["local.station"])
+	require.Equal(t, "Local News", titles["local.station-2"])
+}
+
+func TestFilterWithOptions_ExplicitChannelMapMissingID(t *testing.T) {
+	epgData := &TV{
+		Channels: []Channel{
+			{ID: "espn.us", DisplayName: "ESPN"},
+		},
+		Programs: []Programme{
+			{Channel: "espn.us", Title: "SportsCenter"},
+		},
+	}
+	m3uChannels := []m3u.Channel{
+		{Name: "ESPN"},
+	}
+
+	// ESPN would match by display-name, but the explicit mapping wins and
+	// points at an ID that doesn't exist, so it falls back to placeholder data.
+	opts := MatchOptions{ChannelMap: map[string]string{"ESPN": "missing.id"}}
+
+	filtered, channelMap := FilterWithOptions(newTestLogger(), epgData, m3uChannels, opts)
+
+	require.NotContains(t, channelMap, "espn.us")
+
+	fakeID := generateChannelID("ESPN")
+	require.Equal(t, "ESPN", channelMap[fakeID])
+	require.Equal(t, []string{fakeID}, PlaceholderOnlyChannels(filtered))
+}
+
+func TestFilterWithOptions_QualityRankingTiebreak(t *testing.T) {
+	epgData := &TV{
+		Channels: []Channel{
+			{ID: "espn.sd", DisplayName: "ESPN (SD)"},
+			{ID: "espn.hd", DisplayName: "ESPN (HD)"},
+			{ID: "espn.uhd", DisplayName: "ESPN (UHD)"},
+		},
+	}
+	m3uChannels := []m3u.Channel{
+		{Name: "ESPN"},
+	}
+
+	// Default ranking: UHD beats HD and SD even though it is listed last.
+	_, channelMap := FilterWithOptions(newTestLogger(), epgData, m3uChannels, MatchOptions{})
+	require.Equal(t, "ESPN", channelMap["espn.uhd"])
+
+	// A custom ranking that prefers HD changes the winner.
+	opts := MatchOptions{QualityRanking: []string{"HD", "UHD", "SD"}}
+
+	_, channelMap = FilterWithOptions(newTestLogger(), epgData, m3uChannels, opts)
+	require.Equal(t, "ESPN", channelMap["espn.hd"])
+	require.NotContains(t, channelMap, "espn.uhd")
+}
+
+func TestFilterWithOptions_MatchOrder(t *testing.T) {
+	// ESPN's tvg-id points at one EPG channel while its name matches another.
+	epgData := &TV{
+		Channels: []Channel{
+			{ID: "espn.us", DisplayName: "ESPN Sports Network"},
+			{ID: "espn.other", DisplayName: "ESPN"},
+		},
+	}
+	m3uChannels := []m3u.Channel{
+		{Name: "ESPN", TVGID: "espn.us"},
+	}
+
+	_, channelMap := FilterWithOptions(newTestLogger(), epgData, m3uChannels, MatchOptions{})
+	require.Equal(t, "ESPN", channelMap["espn.us"])
+	require.NotContains(t, channelMap, "espn.other")
+
+	opts := MatchOptions{Order: []string{MatchDisplayName, MatchTVGID}}
+
+	_, channelMap = FilterWithOptions(newTestLogger(), epgData, m3uChannels, opts)
+	require.Equal(t, "ESPN", channelMap["espn.other"])
+	require.NotContains(t, channelMap, "espn.us")
+}
+
+func TestFilterForMerge_NameEqualsOtherEPGID(t *testing.T) {
+	logger, hook := test.NewNullLogger()
+
+	epgData := &TV{
+		Channels: []Channel{
+			{ID: "espn2", DisplayName: "ESPN 2"},
+		},
+	}
+	m3uChannels := []m3u.Channel{
+		{Name: "espn2"},
+		{Name: "ESPN 2"},
+	}
+
+	result := FilterForMerge(logger, epgData, m3uChannels)
+	require.Equal(t, map[string]string{"espn2": "ESPN 2"}, result.ChannelMap)
+	require.NotContains(t, result.Strategies, "espn2")
+
+	var warned bool
+
+	for _, entry := range hook.AllEntries() {
+		if entry.Level == logrus.WarnLevel && entry.Data["m3uChannel"] == "espn2" {
+			warned = true
+		}
+	}
+
+	require.True(t, warned, "expected a warning about the name/ID collision")
+}
+
+func TestFilterWithOptions_NormalizedTVGName(t *testing.T) {
+	epgData := &TV{
+		Channels: []Channel{
+			{ID: "espn.us", DisplayName: "ESPN"},
+			{ID: "cnn.us", DisplayName: "CNN"},
+		},
+	}
+	m3uChannels := []m3u.Channel{
+		{Name: "|US| ESPN FHD", TVGName: "ESPN"},
+		{Name: "CNN", TVGName: "|US| CNN"},
+	}
+
+	result := FilterForMergeWithOptions(newTestLogger(), epgData, m3uChannels, MatchOptions{})
+	require.Equal(t, map[string]string{"espn.us": "|US| ESPN FHD", "cnn.us": "CNN"}, result.ChannelMap)
+	require.Equal(t, MatchNormalizedName, result.Strategies["|US| ESPN FHD"])
+	require.Equal(t, MatchDisplayName, result.Strategies["CNN"])
+}
+
+func TestFilterWithOptions_CategoryMap(t *testing.T) {
+	epgData := &TV{
+		Channels: []Channel{
+			{ID: "espn.us", DisplayName: "ESPN"},
+			{ID: "cnn.us", DisplayName: "CNN"},
+		},
+		Programs: []Programme{
+			{Channel: "espn.us", Title: "SportsCenter"},
+			{Channel: "cnn.us", Title: "Newsroom"},
+		},
+	}
+	m3uChannels := []m3u.Channel{
+		{Name: "ESPN", Group: "US Sports"},
+		{Name: "CNN", Group: "News"},
+	}
+	opts := MatchOptions{CategoryMap: map[string]string{"US Sports": "Sports"}}
+
+	result := FilterForMergeWithOptions(newTestLogger(), epgData, m3uChannels, opts)
+
+	categories := make(map[string]string, len(result.EPG.Programs))
+	for _, prog := range result.EPG.Programs {
+		categories[prog.Channel] = prog.Category
+	}
+
+	require.Equal(t, map[string]string{"espn.us": "Sports", "cnn.us": "News"}, categories)
+
+	// Placeholder programmes use the mapped category too.
+	fake := AddFakeChannels(newTestLogger(), &TV{}, m3uChannels, map[string]string{}, opts.CategoryMap)
+
+	categories = make(map[string]string, len(fake.Programs))
+	for _, prog := range fake.Programs {
+		categories[prog.Title] = prog.Category
+	}
+
+	require.Equal(t, map[string]string{"ESPN": "Sports", "CNN": "News"}, categories)
+}
+
+func TestFilterWithOptions_CollapsedWhitespace(t *testing.T) {
+	epgData := &TV{
+		Channels: []Channel{
+			{ID: "espn2.us", DisplayName: "ESPN  2"},
+			{ID: "espn2.uk", DisplayName: "UK: ESPN 2"},
+		},
+	}
+	m3uChannels := []m3u.Channel{
+		{Name: "ESPN 2"},
+	}
+
+	result := FilterForMergeWithOptions(newTestLogger(), epgData, m3uChannels, MatchOptions{})
+	require.Equal(t, map[string]string{"espn2.us": "ESPN 2"}, result.ChannelMap)
+	require.Equal(t, MatchWhitespace, result.Strategies["ESPN 2"])
+
+	// Without the strategy, exact display-name matching misses the double space.
+	opts := MatchOptions{Order: []string{MatchTVGID, MatchDisplayName}}
+
+	result = FilterForMergeWithOptions(newTestLogger(), epgData, m3uChannels, opts)
+	require.Empty(t, result.ChannelMap)
+}
+
+func TestValidateMatchOrder(t *testing.T) {
+	require.NoError(t, ValidateMatchOrder(nil))
+	require.NoError(t, ValidateMatchOrder([]string{MatchNormalizedName, MatchTVGID}))
+	require.NoError(t, ValidateMatchOrder(DefaultMatchOrder))
+
+	err := ValidateMatchOrder([]string{"tvgid", "fuzzy"})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), `unknown match strategy "fuzzy"`)
+
+	err = ValidateMatchOrder([]string{"tvgid", "tvgid"})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "more than once")
+}
+
+func TestFilter_SharedTVGID(t *testing.T) {
+	epgData := &TV{
+		Channels: []Channel{
+			{ID: "espn.us", DisplayName: "ESPN"},
+		},
+		Programs: []Programme{
+			{Channel: "espn.us", Title: "SportsCenter"},
+		},
+	}
+	m3uChannels := []m3u.Channel{
+		{Name: "ESPN East", TVGID: "espn.us"},
+		{Name: "ESPN West", TVGID: "espn.us"},
+	}
+
+	filtered, channelMap := Filter(newTestLogger(), epgData, m3uChannels)
+
+	require.Equal(t, "ESPN East", channelMap["espn.us"])
+	require.Equal(t, "ESPN West", channelMap["espn.us-2"])
+	require.Empty(t, PlaceholderOnlyChannels(filtered))
+
+	titles := make(map[string]string)
+	for _, prog := range filtered.Programs {
+		titles[prog.Channel] = prog.Title
+	}
+
+	require.Equal(t, "SportsCenter", titles["espn.us"])
+	require.Equal(t, "SportsCenter", titles["espn.us-2"])
+}
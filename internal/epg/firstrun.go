@@ -0,0 +1,35 @@
+package epg
+
+import "time"
+
+// MarkNew returns a copy of the EPG in which every upcoming programme (start
+// at or after now) without a <previously-shown> marker is flagged <new/>,
+// approximating first runs for sources that omit the marker. Placeholder
+// programmes and programmes with an unparseable start are left alone. Also
+// returns the number of programmes flagged. The input is not modified.
+func MarkNew(tv *TV, now time.Time) (*TV, int) {
+	programs := make([]Programme, len(tv.Programs))
+	copy(programs, tv.Programs)
+
+	marked := 0
+
+	for i, prog := range programs {
+		if prog.New != nil || prog.PreviouslyShown != nil || prog.Description == PlaceholderDescription {
+			continue
+		}
+
+		start, err := ParseTime(prog.Start)
+		if err != nil || start.Before(now) {
+			continue
+		}
+
+		programs[i].New = &Flag{}
+		marked++
+	}
+
+	return &TV{
+		XMLName:  tv.XMLName,
+		Channels: tv.Channels,
+		Programs: programs,
+	}, marked
+}
@@ -0,0 +1,60 @@
+package epg
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMarkNew(t *testing.T) {
+	now := time.Date(2026, 1, 4, 12, 0, 0, 0, time.UTC)
+
+	tv := &TV{
+		Programs: []Programme{
+			{Channel: "a", Start: "20260104110000 +0000", Title: "Already aired"},
+			{Channel: "a", Start: "20260104120000 +0000", Title: "Upcoming"},
+			{Channel: "a", Start: "20260104130000 +0000", Title: "Repeat", PreviouslyShown: &PreviouslyShown{Start: "20250101"}},
+			{Channel: "b", Start: "20260104130000 +0000", Title: "B", Description: PlaceholderDescription},
+			{Channel: "c", Start: "garbage", Title: "Unparseable"},
+		},
+	}
+
+	marked, count := MarkNew(tv, now)
+
+	require.Equal(t, 1, count)
+
+	for _, prog := range marked.Programs {
+		require.Equal(t, prog.Title == "Upcoming", prog.New != nil, prog.Title)
+	}
+
+	// The input is not modified.
+	require.Nil(t, tv.Programs[1].New)
+
+	data, err := Marshal(marked)
+	require.NoError(t, err)
+
+	out := string(data)
+	require.Equal(t, 1, strings.Count(out, "<new></new>"))
+	require.Contains(t, out, `<previously-shown start="20250101"></previously-shown>`)
+}
+
+func TestParse_FirstRunMarkers(t *testing.T) {
+	tv, err := Parse([]byte(`<tv>
+  <programme channel="a" start="20260104120000 +0000" stop="20260104130000 +0000">
+    <title>Fresh</title>
+    <new/>
+  </programme>
+  <programme channel="a" start="20260104130000 +0000" stop="20260104140000 +0000">
+    <title>Rerun</title>
+    <previously-shown start="20250101" channel="b"/>
+  </programme>
+</tv>`))
+	require.NoError(t, err)
+	require.Len(t, tv.Programs, 2)
+	require.NotNil(t, tv.Programs[0].New)
+	require.Nil(t, tv.Programs[0].PreviouslyShown)
+	require.Nil(t, tv.Programs[1].New)
+	require.Equal(t, &PreviouslyShown{Start: "20250101", Channel: "b"}, tv.Programs[1].PreviouslyShown)
+}
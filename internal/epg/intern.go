@@ -0,0 +1,35 @@
+package epg
+
+// internProgrammes replaces repeated programme strings with a single shared
+// copy. Huge EPGs repeat the same channel IDs, slot times, titles and
+// descriptions across thousands of programmes; each decoded copy otherwise
+// holds its own backing array for as long as the guide is cached.
+func internProgrammes(tv *TV) {
+	pool := make(map[string]string, len(tv.Channels)*4)
+
+	intern := func(s string) string {
+		if s == "" {
+			return s
+		}
+
+		if shared, ok := pool[s]; ok {
+			return shared
+		}
+
+		pool[s] = s
+
+		return s
+	}
+
+	for i := range tv.Programs {
+		prog := &tv.Programs[i]
+
+		prog.Channel = intern(prog.Channel)
+		prog.Start = intern(prog.Start)
+		prog.Stop = intern(prog.Stop)
+		prog.Title = intern(prog.Title)
+		prog.SubTitle = intern(prog.SubTitle)
+		prog.Description = intern(prog.Description)
+		prog.Category = intern(prog.Category)
+	}
+}
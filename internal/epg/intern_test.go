@@ -0,0 +1,97 @@
+package epg
+
+import (
+	"fmt"
+	"runtime"
+	"strings"
+	"testing"
+	"unsafe"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+)
+
+// syndicatedEPG builds a guide where every channel airs the same schedule,
+// as happens with regional feeds of one network.
+func syndicatedEPG(channels, slots int) []byte {
+	var b strings.Builder
+
+	b.WriteString(`<?xml version="1.0" encoding="UTF-8"?>` + "\n<tv>\n")
+
+	for c := range channels {
+		fmt.Fprintf(&b, "  <channel id=\"ch%d\"><display-name>Channel %d</display-name></channel>\n", c, c)
+	}
+
+	description := strings.Repeat("A long syndicated episode description. ", 8)
+
+	for c := range channels {
+		for s := range slots {
+			fmt.Fprintf(&b, "  <programme channel=\"ch%d\" start=\"202601041%02d000 +0000\" stop=\"202601041%02d000 +0000\">", c, s, s+1)
+			fmt.Fprintf(&b, "<title>Show %d</title><desc>%s</desc><category>News</category></programme>\n", s, description)
+		}
+	}
+
+	b.WriteString("</tv>\n")
+
+	return []byte(b.String())
+}
+
+func TestParse_InternsRepeatedStrings(t *testing.T) {
+	tv, err := Parse(syndicatedEPG(3, 2))
+	require.NoError(t, err)
+	require.Len(t, tv.Programs, 6)
+
+	first, last := tv.Programs[0], tv.Programs[4]
+	require.Equal(t, first.Title, last.Title)
+	require.Equal(t, unsafe.StringData(first.Title), unsafe.StringData(last.Title))
+	require.Equal(t, unsafe.StringData(first.Description), unsafe.StringData(last.Description))
+	require.Equal(t, unsafe.StringData(first.Start), unsafe.StringData(last.Start))
+
+	// Channel IDs are shared between a channel's programmes only.
+	require.Equal(t, unsafe.StringData(tv.Programs[0].Channel), unsafe.StringData(tv.Programs[1].Channel))
+	require.NotEqual(t, tv.Programs[0].Channel, tv.Programs[2].Channel)
+}
+
+// BenchmarkParse reports the heap retained by a parsed guide ("retained-B/op")
+// with and without interning.
+func BenchmarkParse(b *testing.B) {
+	data := syndicatedEPG(200, 24)
+	log := logrus.New()
+
+	run := func(b *testing.B, parse func() (*TV, error)) {
+		b.Helper()
+		b.ReportAllocs()
+
+		var retained uint64
+
+		for range b.N {
+			var before, after runtime.MemStats
+
+			runtime.GC()
+			runtime.ReadMemStats(&before)
+
+			tv, err := parse()
+			if err != nil {
+				b.Fatal(err)
+			}
+
+			runtime.GC()
+			runtime.ReadMemStats(&after)
+			runtime.KeepAlive(tv)
+
+			if after.HeapAlloc > before.HeapAlloc {
+				retained += after.HeapAlloc - before.HeapAlloc
+			}
+		}
+
+		b.ReportMetric(float64(retained)/float64(b.N), "retained-B/op")
+	}
+
+	b.Run("plain", func(b *testing.B) {
+		run(b, func() (*TV, error) { return decodeTV(log, data) })
+	})
+
+	b.Run("interned", func(b *testing.B) {
+		run(b, func() (*TV, error) { return ParseWithLogger(log, data) })
+	})
+}
@@ -0,0 +1,108 @@
+package epg
+
+import (
+	"sort"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// LimitProgrammes returns a copy of the EPG keeping at most maxPerChannel
+// programmes per channel: the current programme and the soonest upcoming
+// ones, topped up with the most recent past programmes when a channel has
+// fewer upcoming than the limit. Programmes with unparseable stop times count
+// as upcoming, so the current programme always survives. Kept programmes
+// stay in their original order. A maxPerChannel of 0 or less, or an EPG with
+// no channel over the limit, returns tv as is.
+func LimitProgrammes(log logrus.FieldLogger, tv *TV, maxPerChannel int, now time.Time) *TV {
+	if maxPerChannel <= 0 {
+		return tv
+	}
+
+	byChannel := make(map[string][]int, len(tv.Channels))
+	for i, prog := range tv.Programs {
+		byChannel[prog.Channel] = append(byChannel[prog.Channel], i)
+	}
+
+	drop := make(map[int]bool)
+	capped := 0
+
+	for _, indexes := range byChannel {
+		if len(indexes) <= maxPerChannel {
+			continue
+		}
+
+		capped++
+
+		for _, i := range overLimit(tv.Programs, indexes, maxPerChannel, now) {
+			drop[i] = true
+		}
+	}
+
+	if capped == 0 {
+		return tv
+	}
+
+	log.WithFields(logrus.Fields{
+		"channels":   capped,
+		"programmes": len(drop),
+	}).Info("Dropped programmes over the per-channel limit")
+
+	programs := make([]Programme, 0, len(tv.Programs)-len(drop))
+
+	for i, prog := range tv.Programs {
+		if !drop[i] {
+			programs = append(programs, prog)
+		}
+	}
+
+	return &TV{
+		XMLName:  tv.XMLName,
+		Channels: tv.Channels,
+		Programs: programs,
+	}
+}
+
+// overLimit returns the indexes of one channel's programmes that fall
+// outside the limit, ranking upcoming programmes by soonest start, then past
+// programmes by most recent.
+func overLimit(programs []Programme, indexes []int, limit int, now time.Time) []int {
+	type ranked struct {
+		index    int
+		start    time.Time
+		upcoming bool
+	}
+
+	candidates := make([]ranked, 0, len(indexes))
+
+	for _, i := range indexes {
+		start, _ := ParseTime(programs[i].Start)
+		stop, err := ParseTime(programs[i].Stop)
+
+		candidates = append(candidates, ranked{
+			index:    i,
+			start:    start,
+			upcoming: err != nil || stop.After(now),
+		})
+	}
+
+	sort.SliceStable(candidates, func(a, b int) bool {
+		ca, cb := candidates[a], candidates[b]
+		if ca.upcoming != cb.upcoming {
+			return ca.upcoming
+		}
+
+		if ca.upcoming {
+			return ca.start.Before(cb.start)
+		}
+
+		return ca.start.After(cb.start)
+	})
+
+	dropped := make([]int, 0, len(candidates)-limit)
+	for _, c := range candidates[limit:] {
+		dropped = append(dropped, c.index)
+	}
+
+	return dropped
+}
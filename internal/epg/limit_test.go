@@ -0,0 +1,76 @@
+package epg
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLimitProgrammes(t *testing.T) {
+	now := time.Date(2026, 3, 10, 14, 25, 0, 0, time.UTC)
+	base := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+
+	// 48 ten-minute programmes from 12:00, so 14:20-14:30 is current.
+	programs := make([]Programme, 0, 50)
+	for i := range 48 {
+		start := base.Add(time.Duration(i) * 10 * time.Minute)
+		programs = append(programs, Programme{
+			Channel: "busy.us",
+			Start:   FormatTime(start),
+			Stop:    FormatTime(start.Add(10 * time.Minute)),
+			Title:   fmt.Sprintf("Slot %02d", i),
+		})
+	}
+
+	programs = append(programs,
+		Programme{Channel: "quiet.us", Start: "20260310140000 +0000", Stop: "20260310150000 +0000", Title: "Quiet Now"},
+		Programme{Channel: "quiet.us", Start: "20260310150000 +0000", Stop: "20260310160000 +0000", Title: "Quiet Next"},
+	)
+
+	tv := &TV{
+		Channels: []Channel{{ID: "busy.us"}, {ID: "quiet.us"}},
+		Programs: programs,
+	}
+
+	limited := LimitProgrammes(logrus.New(), tv, 3, now)
+
+	titles := make([]string, 0, len(limited.Programs))
+	for _, prog := range limited.Programs {
+		titles = append(titles, prog.Title)
+	}
+
+	require.Equal(t, []string{"Slot 14", "Slot 15", "Slot 16", "Quiet Now", "Quiet Next"}, titles)
+
+	// The input is not modified.
+	require.Len(t, tv.Programs, 50)
+}
+
+func TestLimitProgrammes_TopsUpWithRecentPast(t *testing.T) {
+	now := time.Date(2026, 3, 10, 14, 25, 0, 0, time.UTC)
+
+	tv := &TV{
+		Channels: []Channel{{ID: "old.us"}},
+		Programs: []Programme{
+			{Channel: "old.us", Start: "20260310100000 +0000", Stop: "20260310110000 +0000", Title: "Ten"},
+			{Channel: "old.us", Start: "20260310110000 +0000", Stop: "20260310120000 +0000", Title: "Eleven"},
+			{Channel: "old.us", Start: "20260310120000 +0000", Stop: "20260310130000 +0000", Title: "Noon"},
+			{Channel: "old.us", Start: "20260310140000 +0000", Title: "Open-ended"},
+		},
+	}
+
+	limited := LimitProgrammes(logrus.New(), tv, 2, now)
+
+	require.Len(t, limited.Programs, 2)
+	require.Equal(t, "Noon", limited.Programs[0].Title)
+	require.Equal(t, "Open-ended", limited.Programs[1].Title)
+}
+
+func TestLimitProgrammes_Unlimited(t *testing.T) {
+	tv := &TV{Programs: []Programme{{Channel: "a"}, {Channel: "a"}}}
+
+	require.Same(t, tv, LimitProgrammes(logrus.New(), tv, 0, time.Now()))
+	require.Same(t, tv, LimitProgrammes(logrus.New(), tv, 2, time.Now()))
+}
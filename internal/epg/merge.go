@@ -1,6 +1,9 @@
 package epg
 
 import (
+	"sort"
+	"time"
+
 	"github.com/savid/iptv/internal/m3u"
 	"github.com/sirupsen/logrus"
 )
@@ -9,6 +12,7 @@ import (
 type FilterResult struct {
 	EPG        *TV
 	ChannelMap map[string]string // EPG ID → M3U name
+	Strategies map[string]string // M3U name → strategy that matched it
 }
 
 // MergeResult holds the merged result from multiple EPG sources.
@@ -18,10 +22,56 @@ type MergeResult struct {
 	ChannelMap map[string]string // EPG ID → M3U name
 }
 
+// CountStrategies counts the M3U channels matched by each strategy across
+// results, crediting each channel to the first result (in priority order)
+// that matched it, as MergeEPGs does. Channels no result matched are counted
+// under MatchPlaceholder. Every strategy is present in the result.
+func CountStrategies(results []*FilterResult, m3uChannels []m3u.Channel) map[string]int {
+	counts := map[string]int{
+		MatchExplicit:       0,
+		MatchTVGID:          0,
+		MatchDisplayName:    0,
+		MatchWhitespace:     0,
+		MatchNormalizedName: 0,
+		MatchPlaceholder:    0,
+	}
+
+	seen := make(map[string]bool, len(m3uChannels))
+
+	for _, ch := range m3uChannels {
+		if ch.Name == "" || seen[ch.Name] {
+			continue
+		}
+
+		seen[ch.Name] = true
+		strategy := MatchPlaceholder
+
+		for _, result := range results {
+			if matched, ok := result.Strategies[ch.Name]; ok {
+				strategy = matched
+
+				break
+			}
+		}
+
+		counts[strategy]++
+	}
+
+	return counts
+}
+
 // MergeEPGs merges multiple filtered EPG results with program-level deduplication.
 // Priority: earlier EPGs in the slice have higher priority for channel metadata.
 // Programs from all EPGs are merged, with duplicates (same start time) skipped.
+// A channel whose owning EPG has no icon takes the first icon a later EPG has
+// for it.
 func MergeEPGs(results []*FilterResult) *MergeResult {
+	return MergeEPGsWithChannels(results, nil)
+}
+
+// MergeEPGsWithChannels merges like MergeEPGs, then fills the icons still
+// missing from matched channels with the M3U channel's tvg-logo.
+func MergeEPGsWithChannels(results []*FilterResult, m3uChannels []m3u.Channel) *MergeResult {
 	merged := &MergeResult{
 		Channels:   make([]Channel, 0, 100),
 		Programs:   make([]Programme, 0, 1000),
@@ -35,6 +85,9 @@ func MergeEPGs(results []*FilterResult) *MergeResult {
 	// Track M3U name → primary EPG ID (first EPG to match owns the channel).
 	m3uToEPGID := make(map[string]string, 100)
 
+	// Track M3U name → index of its entry in merged.Channels.
+	m3uToChannel := make(map[string]int, 100)
+
 	// Track programs per channel for deduplication.
 	channelPrograms := make(map[string][]Programme, 100)
 
@@ -54,8 +107,18 @@ func MergeEPGs(results []*FilterResult) *MergeResult {
 				for _, ch := range r.EPG.Channels {
 					if ch.ID == epgID {
 						ch.DisplayName = m3uName
+						m3uToChannel[m3uName] = len(merged.Channels)
 						merged.Channels = append(merged.Channels, ch)
 
+						break
+					}
+				}
+			} else if i, owned := m3uToChannel[m3uName]; owned && merged.Channels[i].Icon.Src == "" {
+				// Backfill a missing icon from a lower-priority EPG.
+				for _, ch := range r.EPG.Channels {
+					if ch.ID == epgID && ch.Icon.Src != "" {
+						merged.Channels[i].Icon = ch.Icon
+
 						break
 					}
 				}
@@ -81,14 +144,83 @@ func MergeEPGs(results []*FilterResult) *MergeResult {
 		}
 	}
 
+	backfillM3ULogos(merged.Channels, m3uToChannel, m3uChannels)
+
 	// Flatten programs.
 	for _, progs := range channelPrograms {
 		merged.Programs = append(merged.Programs, progs...)
 	}
 
+	// Map iteration order is random; sort for stable output.
+	sortProgrammes(merged.Programs)
+
 	return merged
 }
 
+// backfillM3ULogos sets the icon of channels that have none to the tvg-logo of
+// their M3U channel (the first one with a logo, when names repeat).
+func backfillM3ULogos(channels []Channel, m3uToChannel map[string]int, m3uChannels []m3u.Channel) {
+	for _, m3uChannel := range m3uChannels {
+		if m3uChannel.TVGLogo == "" {
+			continue
+		}
+
+		if i, ok := m3uToChannel[m3uChannel.Name]; ok && channels[i].Icon.Src == "" {
+			channels[i].Icon = Icon{Src: m3uChannel.TVGLogo}
+		}
+	}
+}
+
+// sortProgrammes sorts programmes by channel, then start time. Programmes
+// with unparseable start times sort after the rest of their channel, by raw
+// start. Each start is parsed once, up front.
+func sortProgrammes(programs []Programme) {
+	type sortKey struct {
+		idx     int
+		channel string
+		start   time.Time
+		parsed  bool
+		raw     string
+	}
+
+	keys := make([]sortKey, len(programs))
+
+	for i := range programs {
+		start, err := ParseTime(programs[i].Start)
+		keys[i] = sortKey{
+			idx:     i,
+			channel: programs[i].Channel,
+			start:   start,
+			parsed:  err == nil,
+			raw:     programs[i].Start,
+		}
+	}
+
+	sort.SliceStable(keys, func(i, j int) bool {
+		a, b := keys[i], keys[j]
+		if a.channel != b.channel {
+			return a.channel < b.channel
+		}
+
+		if a.parsed != b.parsed {
+			return a.parsed
+		}
+
+		if !a.parsed {
+			return a.raw < b.raw
+		}
+
+		return a.start.Before(b.start)
+	})
+
+	sorted := make([]Programme, len(programs))
+	for i, key := range keys {
+		sorted[i] = programs[key.idx]
+	}
+
+	copy(programs, sorted)
+}
+
 // hasOverlap checks if a program overlaps with existing programs.
 // Programs overlap if they have the same start time (duplicate).
 func hasOverlap(existing []Programme, newProg Programme) bool {
@@ -102,12 +234,45 @@ func hasOverlap(existing []Programme, newProg Programme) bool {
 	return false
 }
 
+// MatchRate returns the fraction of named M3U channels that have a real EPG
+// match in channelMap (EPG ID → M3U name). Returns 1 if there are no channels.
+func MatchRate(m3uChannels []m3u.Channel, channelMap map[string]string) float64 {
+	matched := make(map[string]bool, len(channelMap))
+
+	for _, m3uName := range channelMap {
+		matched[m3uName] = true
+	}
+
+	names := make(map[string]bool, len(m3uChannels))
+	hits := 0
+
+	for _, ch := range m3uChannels {
+		if ch.Name == "" || names[ch.Name] {
+			continue
+		}
+
+		names[ch.Name] = true
+
+		if matched[ch.Name] {
+			hits++
+		}
+	}
+
+	if len(names) == 0 {
+		return 1
+	}
+
+	return float64(hits) / float64(len(names))
+}
+
 // AddFakeChannels adds fake EPG channel entries for M3U channels not matched by any EPG.
+// Placeholder programme categories are mapped from M3U groups through categories.
 func AddFakeChannels(
 	log logrus.FieldLogger,
 	epgData *TV,
 	m3uChannels []m3u.Channel,
 	channelMap map[string]string,
+	categories map[string]string,
 ) *TV {
 	// Build set of matched M3U names from channelMap values.
 	matchedM3UNames := make(map[string]bool, len(channelMap))
@@ -124,7 +289,7 @@ func AddFakeChannels(
 	}
 
 	// Build category map.
-	categoryMap := buildCategoryMap(m3uChannels)
+	categoryMap := buildCategoryMap(m3uChannels, categories)
 
 	// Generate fake channels for unmatched M3U channels.
 	fakeChannels := make([]Channel, 0, len(m3uChannels))
@@ -145,6 +310,11 @@ func AddFakeChannels(
 
 		channelID := generateChannelID(m3uChannel.Name)
 
+		// Same-named channels share one placeholder.
+		if _, exists := newChannelMap[channelID]; exists {
+			continue
+		}
+
 		fakeChannel := Channel{
 			ID:          channelID,
 			DisplayName: m3uChannel.Name,
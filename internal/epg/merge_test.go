@@ -0,0 +1,199 @@
+package epg
+
+import (
+	"testing"
+
+	"github.com/savid/iptv/internal/m3u"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMergeEPGs_DeterministicOrder(t *testing.T) {
+	results := []*FilterResult{
+		{
+			EPG: &TV{
+				Channels: []Channel{
+					{ID: "hbo.us", DisplayName: "HBO"},
+					{ID: "espn.us", DisplayName: "ESPN"},
+					{ID: "cnn.us", DisplayName: "CNN"},
+				},
+				Programs: []Programme{
+					{Channel: "hbo.us", Start: "20260104140000 +0000", Title: "HBO 3"},
+					{Channel: "espn.us", Start: "20260104130000 +0000", Title: "ESPN 2"},
+					{Channel: "hbo.us", Start: "20260104120000 +0000", Title: "HBO 1"},
+					{Channel: "cnn.us", Start: "20260104120000 +0000", Title: "CNN 1"},
+					{Channel: "espn.us", Start: "20260104120000 +0000", Title: "ESPN 1"},
+					// 08:30 -0400 is 12:30 UTC, so this sorts between HBO 1 and HBO 3.
+					{Channel: "hbo.us", Start: "20260104083000 -0400", Title: "HBO 2"},
+				},
+			},
+			ChannelMap: map[string]string{"hbo.us": "HBO", "espn.us": "ESPN", "cnn.us": "CNN"},
+		},
+	}
+
+	expected := []string{"CNN 1", "ESPN 1", "ESPN 2", "HBO 1", "HBO 2", "HBO 3"}
+
+	for i := 0; i < 20; i++ {
+		merged := MergeEPGs(results)
+
+		titles := make([]string, 0, len(merged.Programs))
+		for _, prog := range merged.Programs {
+			titles = append(titles, prog.Title)
+		}
+
+		require.Equal(t, expected, titles)
+	}
+}
+
+func TestMergeEPGsWithChannels_BackfillsIcons(t *testing.T) {
+	results := []*FilterResult{
+		{
+			EPG: &TV{
+				Channels: []Channel{
+					{ID: "espn.us", DisplayName: "ESPN"},
+					{ID: "cnn.us", DisplayName: "CNN"},
+					{ID: "hbo.us", DisplayName: "HBO", Icon: Icon{Src: "http://epg-a.example.com/hbo.png"}},
+				},
+			},
+			ChannelMap: map[string]string{"espn.us": "ESPN", "cnn.us": "CNN", "hbo.us": "HBO"},
+		},
+		{
+			EPG: &TV{
+				Channels: []Channel{
+					{ID: "cnn.b", DisplayName: "CNN", Icon: Icon{Src: "http://epg-b.example.com/cnn.png", Width: 200}},
+					{ID: "hbo.b", DisplayName: "HBO", Icon: Icon{Src: "http://epg-b.example.com/hbo.png"}},
+				},
+			},
+			ChannelMap: map[string]string{"cnn.b": "CNN", "hbo.b": "HBO"},
+		},
+	}
+
+	m3uChannels := []m3u.Channel{
+		{Name: "ESPN", TVGLogo: "http://m3u.example.com/espn.png"},
+		{Name: "CNN", TVGLogo: "http://m3u.example.com/cnn.png"},
+		{Name: "HBO", TVGLogo: "http://m3u.example.com/hbo.png"},
+	}
+
+	icons := func(merged *MergeResult) map[string]Icon {
+		byID := make(map[string]Icon, len(merged.Channels))
+		for _, ch := range merged.Channels {
+			byID[ch.ID] = ch.Icon
+		}
+
+		return byID
+	}
+
+	withM3U := icons(MergeEPGsWithChannels(results, m3uChannels))
+
+	// An empty owning icon is filled from the M3U logo.
+	require.Equal(t, "http://m3u.example.com/espn.png", withM3U["espn.us"].Src)
+
+	// A later EPG's icon is preferred over the M3U logo.
+	require.Equal(t, Icon{Src: "http://epg-b.example.com/cnn.png", Width: 200}, withM3U["cnn.us"])
+
+	// The owning EPG's icon still wins when it has one.
+	require.Equal(t, "http://epg-a.example.com/hbo.png", withM3U["hbo.us"].Src)
+
+	// Without M3U channels only later EPGs backfill.
+	withoutM3U := icons(MergeEPGs(results))
+	require.Empty(t, withoutM3U["espn.us"].Src)
+	require.Equal(t, "http://epg-b.example.com/cnn.png", withoutM3U["cnn.us"].Src)
+}
+
+func TestParseTime(t *testing.T) {
+	withOffset, err := ParseTime("20260104120000 -0500")
+	require.NoError(t, err)
+	require.Equal(t, "2026-01-04T17:00:00Z", withOffset.UTC().Format("2006-01-02T15:04:05Z"))
+
+	noOffset, err := ParseTime("20260104120000")
+	require.NoError(t, err)
+	require.Equal(t, "2026-01-04T12:00:00Z", noOffset.UTC().Format("2006-01-02T15:04:05Z"))
+
+	_, err = ParseTime("not a time")
+	require.Error(t, err)
+}
+
+func TestMatchRate(t *testing.T) {
+	channels := []m3u.Channel{
+		{Name: "ESPN"},
+		{Name: "ESPN"}, // Duplicates count once.
+		{Name: "CNN"},
+		{Name: "HBO"},
+		{Name: "BBC"},
+		{Name: ""},
+	}
+
+	require.InDelta(t, 0.5, MatchRate(channels, map[string]string{"espn.us": "ESPN", "cnn.us": "CNN"}), 0.0001)
+	require.InDelta(t, 0.0, MatchRate(channels, nil), 0.0001)
+	require.InDelta(t, 1.0, MatchRate(nil, nil), 0.0001)
+}
+
+func TestCountStrategies(t *testing.T) {
+	m3uChannels := []m3u.Channel{
+		{Name: "HBO", TVGID: "hbo.us"},
+		{Name: "ESPN"},
+		{Name: "CNN HD"},
+		{Name: "Local"},
+		{Name: "Unknown"},
+		{Name: "ESPN 2"},
+		{Name: "HBO", TVGID: "hbo.us"},
+	}
+
+	primary := FilterForMergeWithOptions(newTestLogger(), &TV{
+		Channels: []Channel{
+			{ID: "hbo.us", DisplayName: "Home Box Office"},
+			{ID: "cnn.us", DisplayName: "CNN"},
+			{ID: "local.us", DisplayName: "Local 4"},
+		},
+	}, m3uChannels, MatchOptions{ChannelMap: map[string]string{"Local": "local.us"}})
+
+	secondary := FilterForMerge(newTestLogger(), &TV{
+		Channels: []Channel{
+			{ID: "espn.us", DisplayName: "ESPN"},
+			{ID: "espn2.us", DisplayName: "ESPN  2"},
+			{ID: "hbo2.us", DisplayName: "HBO"},
+		},
+	}, m3uChannels)
+
+	counts := CountStrategies([]*FilterResult{primary, secondary}, m3uChannels)
+
+	require.Equal(t, map[string]int{
+		MatchExplicit:       1,
+		MatchTVGID:          1,
+		MatchDisplayName:    1,
+		MatchWhitespace:     1,
+		MatchNormalizedName: 1,
+		MatchPlaceholder:    1,
+	}, counts)
+}
+
+func TestCountStrategies_NoResults(t *testing.T) {
+	counts := CountStrategies(nil, []m3u.Channel{{Name: "A"}, {Name: "B"}})
+
+	require.Equal(t, 2, counts[MatchPlaceholder])
+	require.Equal(t, 0, counts[MatchTVGID])
+}
+
+func TestSortProgrammes_UnparseableStartsLast(t *testing.T) {
+	programs := []Programme{
+		{Channel: "espn.us", Start: "zzz", Title: "Bad Z"},
+		{Channel: "espn.us", Start: "20260104130000 +0000", Title: "ESPN 2"},
+		{Channel: "cnn.us", Start: "bad", Title: "CNN Bad"},
+		{Channel: "espn.us", Start: "aaa", Title: "Bad A"},
+		{Channel: "espn.us", Start: "20260104120000 +0000", Title: "ESPN 1"},
+	}
+
+	expected := []string{"CNN Bad", "ESPN 1", "ESPN 2", "Bad A", "Bad Z"}
+
+	// The order doesn't depend on the input order.
+	for _, input := range [][]Programme{programs, {programs[4], programs[3], programs[2], programs[1], programs[0]}} {
+		sorted := append([]Programme(nil), input...)
+		sortProgrammes(sorted)
+
+		titles := make([]string, 0, len(sorted))
+		for _, prog := range sorted {
+			titles = append(titles, prog.Title)
+		}
+
+		require.Equal(t, expected, titles)
+	}
+}
@@ -0,0 +1,24 @@
+package epg
+
+import "time"
+
+// NextProgrammes returns, for each channel, the soonest programme starting at
+// or after now, i.e. the one following whatever is currently airing. Channels
+// with nothing scheduled after now are omitted. Programmes with an
+// unparseable start are ignored.
+func NextProgrammes(tv *TV, now time.Time) map[string]Programme {
+	byChannel := programmesByChannel(tv)
+	next := make(map[string]Programme, len(byChannel))
+
+	for channel, progs := range byChannel {
+		for _, p := range progs {
+			if !p.start.Before(now) {
+				next[channel] = tv.Programs[p.idx]
+
+				break
+			}
+		}
+	}
+
+	return next
+}
@@ -0,0 +1,32 @@
+package epg
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNextProgrammes(t *testing.T) {
+	now := time.Date(2026, 1, 4, 12, 30, 0, 0, time.UTC)
+
+	tv := &TV{
+		Programs: []Programme{
+			// Out of order, to exercise the per-channel sort.
+			{Channel: "espn.us", Start: "20260104140000 +0000", Stop: "20260104150000 +0000", Title: "Later"},
+			{Channel: "espn.us", Start: "20260104120000 +0000", Stop: "20260104130000 +0000", Title: "Now"},
+			{Channel: "espn.us", Start: "20260104130000 +0000", Stop: "20260104140000 +0000", Title: "Next", SubTitle: "Part 2"},
+			{Channel: "cnn.us", Start: "20260104110000 +0000", Stop: "20260104123000 +0000", Title: "Ending"},
+			{Channel: "cnn.us", Start: "20260104123000 +0000", Stop: "20260104133000 +0000", Title: "Starting"},
+			{Channel: "hbo.us", Start: "20260104100000 +0000", Stop: "20260104130000 +0000", Title: "Last"},
+			{Channel: "bad.us", Start: "garbage", Title: "Unparseable"},
+		},
+	}
+
+	next := NextProgrammes(tv, now)
+
+	require.Len(t, next, 2)
+	require.Equal(t, "Next", next["espn.us"].Title)
+	require.Equal(t, "Part 2", next["espn.us"].SubTitle)
+	require.Equal(t, "Starting", next["cnn.us"].Title, "a programme starting exactly now is next")
+}
@@ -0,0 +1,237 @@
+package epg
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+
+	"github.com/savid/iptv/internal/m3u"
+)
+
+// ChannelNames returns channelMap (EPG ID → M3U name) extended with the
+// generated ID of each M3U channel's placeholder, so channels added by
+// AddFakeChannels align with the lineup too. Mapped IDs take precedence.
+func ChannelNames(m3uChannels []m3u.Channel, channelMap map[string]string) map[string]string {
+	names := make(map[string]string, len(channelMap)+len(m3uChannels))
+
+	for _, ch := range m3uChannels {
+		names[generateChannelID(ch.Name)] = ch.Name
+	}
+
+	for epgID, name := range channelMap {
+		names[epgID] = name
+	}
+
+	return names
+}
+
+// SortByLineup returns a copy of the EPG with channels ordered to match the
+// M3U lineup order, using channelMap (EPG ID → M3U name) to align them;
+// placeholder channels are aligned by their generated IDs. Programmes are grouped by channel in the same order, preserving their
+// relative order within each channel. Channels not present in the lineup are
+// placed last in their original order.
+func SortByLineup(tv *TV, m3uChannels []m3u.Channel, channelMap map[string]string) *TV {
+	// Lineup position of each M3U name (first occurrence wins).
+	lineupIndex := make(map[string]int, len(m3uChannels))
+
+	for i, ch := range m3uChannels {
+		if _, exists := lineupIndex[ch.Name]; !exists {
+			lineupIndex[ch.Name] = i
+		}
+	}
+
+	names := ChannelNames(m3uChannels, channelMap)
+
+	position := func(epgID string) int {
+		if idx, ok := lineupIndex[names[epgID]]; ok {
+			return idx
+		}
+
+		return len(m3uChannels)
+	}
+
+	channels := make([]Channel, len(tv.Channels))
+	copy(channels, tv.Channels)
+
+	sort.SliceStable(channels, func(i, j int) bool {
+		return position(channels[i].ID) < position(channels[j].ID)
+	})
+
+	// Final channel order, used to group programmes.
+	channelOrder := make(map[string]int, len(channels))
+
+	for i, ch := range channels {
+		channelOrder[ch.ID] = i
+	}
+
+	programOrder := func(channelID string) int {
+		if idx, ok := channelOrder[channelID]; ok {
+			return idx
+		}
+
+		return len(channels)
+	}
+
+	programs := make([]Programme, len(tv.Programs))
+	copy(programs, tv.Programs)
+
+	sort.SliceStable(programs, func(i, j int) bool {
+		return programOrder(programs[i].Channel) < programOrder(programs[j].Channel)
+	})
+
+	return &TV{
+		XMLName:  tv.XMLName,
+		Channels: channels,
+		Programs: programs,
+	}
+}
+
+// AddGuideNumbers returns a copy of the EPG with each channel's GuideNumber
+// set to its 1-based position in the lineup, using channelMap (EPG ID → M3U
+// name) to align them; placeholder channels are aligned by their generated
+// IDs. Channels not in the lineup are left without a number.
+func AddGuideNumbers(tv *TV, m3uChannels []m3u.Channel, channelMap map[string]string) *TV {
+	numbers := make([]string, len(m3uChannels))
+	for i := range m3uChannels {
+		numbers[i] = strconv.Itoa(i + 1)
+	}
+
+	return AddFormattedGuideNumbers(tv, m3uChannels, numbers, channelMap)
+}
+
+// AddFormattedGuideNumbers is AddGuideNumbers with the guide number of each
+// lineup channel given in numbers, in lineup order.
+func AddFormattedGuideNumbers(tv *TV, m3uChannels []m3u.Channel, numbers []string, channelMap map[string]string) *TV {
+	// Guide number of each M3U name (first occurrence wins).
+	guideNumbers := make(map[string]string, len(m3uChannels))
+
+	for i, ch := range m3uChannels {
+		if _, exists := guideNumbers[ch.Name]; !exists {
+			guideNumbers[ch.Name] = numbers[i]
+		}
+	}
+
+	names := ChannelNames(m3uChannels, channelMap)
+
+	channels := make([]Channel, len(tv.Channels))
+	copy(channels, tv.Channels)
+
+	for i := range channels {
+		if name, ok := names[channels[i].ID]; ok {
+			channels[i].GuideNumber = guideNumbers[name]
+		}
+	}
+
+	return &TV{
+		XMLName:  tv.XMLName,
+		Channels: channels,
+		Programs: tv.Programs,
+	}
+}
+
+// DuplicateName returns the lineup name of the nth (1-based) channel called
+// name: the name itself for the first, "Name (n)" for later ones.
+func DuplicateName(name string, n int) string {
+	if n <= 1 {
+		return name
+	}
+
+	return fmt.Sprintf("%s (%d)", name, n)
+}
+
+// AddDuplicateNames returns a copy of the EPG in which every channel whose M3U
+// name appears more than once in m3uChannels also carries the names
+// DuplicateName gives the later occurrences, so each suffixed lineup entry
+// has a matching display-name. channelMap (EPG ID → M3U name) aligns them;
+// placeholder channels for unmatched M3U channels are aligned by their
+// generated IDs.
+func AddDuplicateNames(tv *TV, m3uChannels []m3u.Channel, channelMap map[string]string) *TV {
+	counts := make(map[string]int, len(m3uChannels))
+
+	for _, ch := range m3uChannels {
+		counts[ch.Name]++
+	}
+
+	placeholders := make(map[string]string)
+
+	for name, count := range counts {
+		if count > 1 {
+			placeholders[generateChannelID(name)] = name
+		}
+	}
+
+	channels := make([]Channel, len(tv.Channels))
+	copy(channels, tv.Channels)
+
+	for i := range channels {
+		name, ok := channelMap[channels[i].ID]
+		if !ok {
+			name, ok = placeholders[channels[i].ID]
+		}
+
+		if !ok || counts[name] < 2 {
+			continue
+		}
+
+		altNames := make([]string, 0, len(channels[i].AltNames)+counts[name]-1)
+		altNames = append(altNames, channels[i].AltNames...)
+
+		for n := 2; n <= counts[name]; n++ {
+			altNames = append(altNames, DuplicateName(name, n))
+		}
+
+		channels[i].AltNames = altNames
+	}
+
+	return &TV{
+		XMLName:  tv.XMLName,
+		Channels: channels,
+		Programs: tv.Programs,
+	}
+}
+
+// SelectChannels returns a copy of the EPG with only the channels (and their
+// programmes) that belong to m3uChannels, using channelMap (EPG ID → M3U
+// name) to align them. Placeholder channels generated for unmatched M3U
+// channels are included too.
+func SelectChannels(tv *TV, m3uChannels []m3u.Channel, channelMap map[string]string) *TV {
+	names := make(map[string]bool, len(m3uChannels))
+
+	for _, ch := range m3uChannels {
+		names[ch.Name] = true
+	}
+
+	keep := make(map[string]bool, len(m3uChannels))
+
+	for epgID, name := range channelMap {
+		if names[name] {
+			keep[epgID] = true
+		}
+	}
+
+	for name := range names {
+		keep[generateChannelID(name)] = true
+	}
+
+	channels := make([]Channel, 0, len(m3uChannels))
+
+	for _, ch := range tv.Channels {
+		if keep[ch.ID] {
+			channels = append(channels, ch)
+		}
+	}
+
+	programs := make([]Programme, 0)
+
+	for _, prog := range tv.Programs {
+		if keep[prog.Channel] {
+			programs = append(programs, prog)
+		}
+	}
+
+	return &TV{
+		XMLName:  tv.XMLName,
+		Channels: channels,
+		Programs: programs,
+	}
+}
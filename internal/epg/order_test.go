@@ -0,0 +1,215 @@
+package epg
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/savid/iptv/internal/m3u"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChannelNames(t *testing.T) {
+	m3uChannels := []m3u.Channel{{Name: "ESPN"}, {Name: "Local Access"}}
+
+	tv := AddFakeChannels(newTestLogger(), &TV{}, m3uChannels, map[string]string{"espn.us": "ESPN"}, nil)
+	require.Len(t, tv.Channels, 1)
+
+	names := ChannelNames(m3uChannels, map[string]string{"espn.us": "ESPN"})
+	require.Equal(t, "ESPN", names["espn.us"])
+	require.Equal(t, "Local Access", names[tv.Channels[0].ID])
+}
+
+func TestSortByLineup(t *testing.T) {
+	tv := &TV{
+		Channels: []Channel{
+			{ID: "cnn.us", DisplayName: "CNN"},
+			{ID: "unknown.us", DisplayName: "Unknown"},
+			{ID: "espn.us", DisplayName: "ESPN"},
+			{ID: "hbo.us", DisplayName: "HBO"},
+		},
+		Programs: []Programme{
+			{Channel: "cnn.us", Start: "20260104120000 +0000", Title: "CNN 1"},
+			{Channel: "espn.us", Start: "20260104120000 +0000", Title: "ESPN 1"},
+			{Channel: "hbo.us", Start: "20260104120000 +0000", Title: "HBO 1"},
+			{Channel: "cnn.us", Start: "20260104130000 +0000", Title: "CNN 2"},
+			{Channel: "espn.us", Start: "20260104130000 +0000", Title: "ESPN 2"},
+		},
+	}
+	m3uChannels := []m3u.Channel{
+		{Name: "ESPN"},
+		{Name: "HBO"},
+		{Name: "CNN"},
+	}
+	channelMap := map[string]string{
+		"espn.us": "ESPN",
+		"hbo.us":  "HBO",
+		"cnn.us":  "CNN",
+	}
+
+	sorted := SortByLineup(tv, m3uChannels, channelMap)
+
+	ids := make([]string, 0, len(sorted.Channels))
+	for _, ch := range sorted.Channels {
+		ids = append(ids, ch.ID)
+	}
+
+	require.Equal(t, []string{"espn.us", "hbo.us", "cnn.us", "unknown.us"}, ids)
+
+	titles := make([]string, 0, len(sorted.Programs))
+	for _, prog := range sorted.Programs {
+		titles = append(titles, prog.Title)
+	}
+
+	require.Equal(t, []string{"ESPN 1", "ESPN 2", "HBO 1", "CNN 1", "CNN 2"}, titles)
+
+	// Original data must be left untouched.
+	require.Equal(t, "cnn.us", tv.Channels[0].ID)
+
+	xmlData, err := Marshal(sorted)
+	require.NoError(t, err)
+
+	output := string(xmlData)
+	require.Less(t, strings.Index(output, `id="espn.us"`), strings.Index(output, `id="hbo.us"`))
+	require.Less(t, strings.Index(output, `id="hbo.us"`), strings.Index(output, `id="cnn.us"`))
+}
+
+func TestSortByLineup_Placeholders(t *testing.T) {
+	m3uChannels := []m3u.Channel{{Name: "Unmatched A"}, {Name: "CNN"}, {Name: "Unmatched B"}}
+	channelMap := map[string]string{"cnn.us": "CNN"}
+
+	tv := AddFakeChannels(newTestLogger(), &TV{
+		Channels: []Channel{{ID: "cnn.us", DisplayName: "CNN"}},
+	}, m3uChannels, channelMap, nil)
+
+	sorted := SortByLineup(tv, m3uChannels, channelMap)
+
+	names := make([]string, 0, len(sorted.Channels))
+	for _, ch := range sorted.Channels {
+		names = append(names, ch.DisplayName)
+	}
+
+	require.Equal(t, []string{"Unmatched A", "CNN", "Unmatched B"}, names)
+}
+
+func TestAddGuideNumbers(t *testing.T) {
+	tv := &TV{
+		Channels: []Channel{
+			{ID: "cnn.us", DisplayName: "CNN"},
+			{ID: "unknown.us", DisplayName: "Unknown"},
+			{ID: "espn.us", DisplayName: "ESPN"},
+		},
+	}
+	m3uChannels := []m3u.Channel{{Name: "ESPN"}, {Name: "HBO"}, {Name: "CNN"}}
+	channelMap := map[string]string{"espn.us": "ESPN", "cnn.us": "CNN"}
+
+	numbered := AddGuideNumbers(tv, m3uChannels, channelMap)
+
+	require.Equal(t, "3", numbered.Channels[0].GuideNumber)
+	require.Empty(t, numbered.Channels[1].GuideNumber)
+	require.Equal(t, "1", numbered.Channels[2].GuideNumber)
+
+	// The input is not modified.
+	require.Empty(t, tv.Channels[0].GuideNumber)
+
+	data, err := Marshal(numbered)
+	require.NoError(t, err)
+
+	out := string(data)
+	require.Contains(t, out, "<display-name>CNN</display-name>\n    <display-name>3</display-name>")
+	require.Equal(t, 1, strings.Count(out, "<display-name>Unknown</display-name>"))
+	require.Equal(t, 5, strings.Count(out, "<display-name>"))
+}
+
+func TestAddFormattedGuideNumbers_Placeholders(t *testing.T) {
+	m3uChannels := []m3u.Channel{{Name: "ESPN"}, {Name: "Local Access"}}
+	channelMap := map[string]string{"espn.us": "ESPN"}
+
+	tv := AddFakeChannels(newTestLogger(), &TV{
+		Channels: []Channel{{ID: "espn.us", DisplayName: "ESPN"}},
+	}, m3uChannels, channelMap, nil)
+
+	numbered := AddFormattedGuideNumbers(tv, m3uChannels, []string{"1.1", "2.1"}, channelMap)
+
+	numbers := make(map[string]string, len(numbered.Channels))
+	for _, ch := range numbered.Channels {
+		numbers[ch.DisplayName] = ch.GuideNumber
+	}
+
+	require.Equal(t, map[string]string{"ESPN": "1.1", "Local Access": "2.1"}, numbers)
+
+	data, err := Marshal(numbered)
+	require.NoError(t, err)
+	require.Contains(t, string(data), "<display-name>Local Access</display-name>\n    <display-name>2.1</display-name>")
+}
+
+func TestAddDuplicateNames(t *testing.T) {
+	tv := &TV{
+		Channels: []Channel{
+			{ID: "espn.us", DisplayName: "ESPN"},
+			{ID: "cnn.us", DisplayName: "CNN"},
+		},
+	}
+	m3uChannels := []m3u.Channel{{Name: "ESPN"}, {Name: "CNN"}, {Name: "ESPN"}, {Name: "ESPN"}}
+	channelMap := map[string]string{"espn.us": "ESPN", "cnn.us": "CNN"}
+
+	named := AddDuplicateNames(tv, m3uChannels, channelMap)
+
+	require.Equal(t, []string{"ESPN (2)", "ESPN (3)"}, named.Channels[0].AltNames)
+	require.Empty(t, named.Channels[1].AltNames)
+
+	// The input is not modified.
+	require.Empty(t, tv.Channels[0].AltNames)
+
+	named.Channels[0].GuideNumber = "1"
+
+	data, err := Marshal(named)
+	require.NoError(t, err)
+	require.Contains(t, string(data), "<display-name>ESPN</display-name>\n    "+
+		"<display-name>ESPN (2)</display-name>\n    "+
+		"<display-name>ESPN (3)</display-name>\n    "+
+		"<display-name>1</display-name>")
+}
+
+func TestAddDuplicateNames_Placeholders(t *testing.T) {
+	m3uChannels := []m3u.Channel{{Name: "Local Sports"}, {Name: "Local Sports"}}
+	channelMap := map[string]string{}
+
+	// Neither channel matched a guide, so both share one placeholder that
+	// isn't in the channel map.
+	tv := AddFakeChannels(newTestLogger(), &TV{}, m3uChannels, channelMap, nil)
+	require.Len(t, tv.Channels, 1)
+
+	named := AddDuplicateNames(tv, m3uChannels, channelMap)
+	require.Equal(t, "Local Sports", named.Channels[0].DisplayName)
+	require.Equal(t, []string{"Local Sports (2)"}, named.Channels[0].AltNames)
+}
+
+func TestDuplicateName(t *testing.T) {
+	require.Equal(t, "ESPN", DuplicateName("ESPN", 1))
+	require.Equal(t, "ESPN (2)", DuplicateName("ESPN", 2))
+}
+
+func TestSelectChannels(t *testing.T) {
+	tv := &TV{
+		Channels: []Channel{
+			{ID: "espn.us", DisplayName: "ESPN"},
+			{ID: "cnn.us", DisplayName: "CNN"},
+			{ID: generateChannelID("Local Sports"), DisplayName: "Local Sports"},
+		},
+		Programs: []Programme{
+			{Channel: "espn.us", Title: "SportsCenter"},
+			{Channel: "cnn.us", Title: "News"},
+			{Channel: generateChannelID("Local Sports"), Title: "Local Sports"},
+		},
+	}
+	channelMap := map[string]string{"espn.us": "ESPN", "cnn.us": "CNN"}
+	sports := []m3u.Channel{{Name: "ESPN"}, {Name: "Local Sports"}}
+
+	selected := SelectChannels(tv, sports, channelMap)
+
+	require.Len(t, selected.Channels, 2)
+	require.Equal(t, "espn.us", selected.Channels[0].ID)
+	require.Equal(t, "Local Sports", selected.Channels[1].DisplayName)
+	require.Len(t, selected.Programs, 2)
+	require.Len(t, tv.Channels, 3)
+}
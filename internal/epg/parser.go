@@ -2,55 +2,396 @@
 package epg
 
 import (
+	"bytes"
 	"encoding/xml"
+	"errors"
 	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/savid/iptv/internal/m3u"
+	"github.com/sirupsen/logrus"
+)
+
+// Generator info emitted on <tv> by Marshal when the TV carries none.
+const (
+	DefaultGeneratorName = "iptv-proxy"
+	DefaultGeneratorURL  = "https://github.com/savid/iptv"
+)
+
+// XMLTV timestamp layouts, with and without a timezone offset.
+const (
+	timeLayout         = "20060102150405 -0700"
+	timeLayoutNoOffset = "20060102150405"
 )
 
 // TV represents the root element of an XMLTV EPG file.
 type TV struct {
-	XMLName  xml.Name    `xml:"tv"`
-	Channels []Channel   `xml:"channel"`
-	Programs []Programme `xml:"programme"`
+	XMLName           xml.Name    `xml:"tv"`
+	GeneratorInfoName string      `xml:"generator-info-name,attr,omitempty"`
+	GeneratorInfoURL  string      `xml:"generator-info-url,attr,omitempty"`
+	Channels          []Channel   `xml:"channel"`
+	Programs          []Programme `xml:"programme"`
 }
 
 // Channel represents a channel in the EPG.
 type Channel struct {
-	ID          string `xml:"id,attr"`
-	DisplayName string `xml:"display-name"`
-	Icon        Icon   `xml:"icon"`
+	ID          string   `xml:"id,attr"`
+	DisplayName string   `xml:"display-name"`
+	Icon        Icon     `xml:"icon"`
+	URLs        []string `xml:"url"`
+
+	// AltNames are emitted as additional <display-name>s after DisplayName.
+	// They are never parsed.
+	AltNames []string `xml:"-"`
+
+	// GuideNumber, when set, is emitted as an additional <display-name>
+	// (the XMLTV convention for channel numbers). It is never parsed.
+	GuideNumber string `xml:"-"`
+}
+
+// channelXML is the marshalled form of Channel.
+type channelXML struct {
+	ID           string   `xml:"id,attr"`
+	DisplayNames []string `xml:"display-name"`
+	Icon         Icon     `xml:"icon"`
+	URLs         []string `xml:"url"`
+}
+
+// MarshalXML emits the channel with its alternative names and guide number as
+// further display-names.
+func (c Channel) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	out := channelXML{
+		ID:           c.ID,
+		DisplayNames: append([]string{c.DisplayName}, c.AltNames...),
+		Icon:         c.Icon,
+		URLs:         c.URLs,
+	}
+
+	if c.GuideNumber != "" {
+		out.DisplayNames = append(out.DisplayNames, c.GuideNumber)
+	}
+
+	if err := e.EncodeElement(out, start); err != nil {
+		return fmt.Errorf("failed to encode channel %q: %w", c.ID, err)
+	}
+
+	return nil
 }
 
 // Icon represents a channel or programme icon.
 type Icon struct {
-	Src string `xml:"src,attr"`
+	Src    string `xml:"src,attr"`
+	Width  int    `xml:"width,attr,omitempty"`
+	Height int    `xml:"height,attr,omitempty"`
 }
 
 // Programme represents a programme/show in the EPG.
 type Programme struct {
-	Channel     string `xml:"channel,attr"`
-	Start       string `xml:"start,attr"`
-	Stop        string `xml:"stop,attr"`
-	Title       string `xml:"title"`
-	Description string `xml:"desc"`
-	Category    string `xml:"category,omitempty"`
+	Channel         string           `xml:"channel,attr"`
+	Start           string           `xml:"start,attr"`
+	Stop            string           `xml:"stop,attr"`
+	Title           string           `xml:"title"`
+	SubTitle        string           `xml:"sub-title,omitempty"`
+	Description     string           `xml:"desc"`
+	Category        string           `xml:"category,omitempty"`
+	Video           *Video           `xml:"video,omitempty"`
+	Audio           *Audio           `xml:"audio,omitempty"`
+	PreviouslyShown *PreviouslyShown `xml:"previously-shown,omitempty"`
+	New             *Flag            `xml:"new,omitempty"` // First showing; Plex can record only these
+	StarRating      *StarRating      `xml:"star-rating,omitempty"`
+
+	// sourceCategory is the source guide's category, kept when FilterForMerge
+	// replaces Category with the channel's M3U group. Nil means Category is
+	// still the source's.
+	sourceCategory *string
+}
+
+// PreviouslyShown marks a repeat, recording when and where it was first
+// shown. Both attributes are optional.
+type PreviouslyShown struct {
+	Start   string `xml:"start,attr,omitempty"`
+	Channel string `xml:"channel,attr,omitempty"`
+}
+
+// Flag is an empty XMLTV marker element such as <new/>.
+type Flag struct{}
+
+// Video describes a programme's picture (e.g. aspect "16:9", quality "HDTV").
+type Video struct {
+	Present string `xml:"present,omitempty"`
+	Colour  string `xml:"colour,omitempty"`
+	Aspect  string `xml:"aspect,omitempty"`
+	Quality string `xml:"quality,omitempty"`
+}
+
+// Audio describes a programme's sound (e.g. stereo "stereo", "dolby digital").
+type Audio struct {
+	Present string `xml:"present,omitempty"`
+	Stereo  string `xml:"stereo,omitempty"`
+}
+
+// StarRating represents a programme's critic or user rating (e.g. "8/10").
+type StarRating struct {
+	System string `xml:"system,attr,omitempty"`
+	Value  string `xml:"value"`
 }
 
 // Parse parses EPG XML data into a TV structure.
 func Parse(data []byte) (*TV, error) {
-	var tv TV
-	if err := xml.Unmarshal(data, &tv); err != nil {
-		return nil, fmt.Errorf("failed to parse EPG XML: %w", err)
+	return ParseWithLogger(discardLogger(), data)
+}
+
+// ParseWithLogger parses EPG XML data into a TV structure, stopping after the
+// first complete <tv> document. Trailing bytes after </tv> (stray garbage or
+// a second concatenated document) are ignored with a warning.
+//
+// Repeated programme strings (channel IDs, times, titles, descriptions) are
+// interned so programmes syndicated across many channels share storage.
+func ParseWithLogger(log logrus.FieldLogger, data []byte) (*TV, error) {
+	tv, err := decodeTV(log, data)
+	if err != nil {
+		return nil, err
 	}
 
-	return &tv, nil
+	internProgrammes(tv)
+
+	return tv, nil
+}
+
+// decodeTV decodes the first <tv> document in data.
+func decodeTV(log logrus.FieldLogger, data []byte) (*TV, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+
+	for {
+		token, err := decoder.Token()
+		if errors.Is(err, io.EOF) {
+			return nil, errors.New("failed to parse EPG XML: no <tv> element found")
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse EPG XML: %w", err)
+		}
+
+		start, ok := token.(xml.StartElement)
+		if !ok {
+			continue
+		}
+
+		if start.Name.Local != "tv" {
+			return nil, fmt.Errorf("failed to parse EPG XML: expected <tv> root element, got <%s>", start.Name.Local)
+		}
+
+		var tv TV
+		if err := decoder.DecodeElement(&tv, &start); err != nil {
+			return nil, fmt.Errorf("failed to parse EPG XML: %w", err)
+		}
+
+		if trailing := bytes.TrimSpace(data[decoder.InputOffset():]); len(trailing) > 0 {
+			log.WithField("bytes", len(trailing)).Warn("Ignoring trailing data after </tv> in EPG")
+		}
+
+		return &tv, nil
+	}
 }
 
-// Marshal serializes the TV structure to XML.
+// ApplyTimezone rewrites programme start/stop times that carry no offset so
+// they are interpreted in loc instead of UTC. Times with an offset are kept.
+func ApplyTimezone(tv *TV, loc *time.Location) {
+	for i := range tv.Programs {
+		tv.Programs[i].Start = withLocation(tv.Programs[i].Start, loc)
+		tv.Programs[i].Stop = withLocation(tv.Programs[i].Stop, loc)
+	}
+}
+
+// defaultProgrammeDuration is the length assumed for a channel's last
+// programme when it has no stop time.
+const defaultProgrammeDuration = time.Hour
+
+// CleanDisplayNames passes each channel's display-name through m3u.CleanName
+// so it can equal the cleaned lineup name byte for byte. Returns the number
+// of names changed.
+func CleanDisplayNames(tv *TV) int {
+	changed := 0
+
+	for i := range tv.Channels {
+		if cleaned := m3u.CleanName(tv.Channels[i].DisplayName); cleaned != tv.Channels[i].DisplayName {
+			tv.Channels[i].DisplayName = cleaned
+			changed++
+		}
+	}
+
+	return changed
+}
+
+// timedProgramme is a programme index with its parsed start time.
+type timedProgramme struct {
+	idx   int
+	start time.Time
+}
+
+// programmesByChannel groups the programmes with a parseable start time by
+// channel, each channel's list sorted by start. Programmes with equal starts
+// keep their document order.
+func programmesByChannel(tv *TV) map[string][]timedProgramme {
+	byChannel := make(map[string][]timedProgramme)
+
+	for i, prog := range tv.Programs {
+		start, err := ParseTime(prog.Start)
+		if err != nil {
+			continue
+		}
+
+		byChannel[prog.Channel] = append(byChannel[prog.Channel], timedProgramme{idx: i, start: start})
+	}
+
+	for _, progs := range byChannel {
+		sort.SliceStable(progs, func(i, j int) bool {
+			return progs[i].start.Before(progs[j].start)
+		})
+	}
+
+	return byChannel
+}
+
+// FillMissingStops sets the stop time of programmes that have none to the
+// start of the channel's next programme, or to start plus
+// defaultProgrammeDuration for the channel's last programme. Programmes with
+// an unparseable start are left as is. Returns the number of stops filled.
+func FillMissingStops(tv *TV) int {
+	missing := false
+
+	for _, prog := range tv.Programs {
+		if strings.TrimSpace(prog.Stop) == "" {
+			missing = true
+
+			break
+		}
+	}
+
+	if !missing {
+		return 0
+	}
+
+	byChannel := programmesByChannel(tv)
+
+	filled := 0
+
+	for _, progs := range byChannel {
+		for i, p := range progs {
+			if strings.TrimSpace(tv.Programs[p.idx].Stop) != "" {
+				continue
+			}
+
+			stop := p.start.Add(defaultProgrammeDuration)
+
+			// The next programme that starts later; equal starts are skipped.
+			for _, next := range progs[i+1:] {
+				if next.start.After(p.start) {
+					stop = next.start
+
+					break
+				}
+			}
+
+			tv.Programs[p.idx].Stop = FormatTime(stop)
+			filled++
+		}
+	}
+
+	return filled
+}
+
+func withLocation(s string, loc *time.Location) string {
+	t, err := time.ParseInLocation(timeLayoutNoOffset, strings.TrimSpace(s), loc)
+	if err != nil {
+		return s
+	}
+
+	return FormatTime(t)
+}
+
+func discardLogger() logrus.FieldLogger {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+
+	return logger
+}
+
+// MarshalOptions customizes the XML produced by MarshalWithOptions.
+type MarshalOptions struct {
+	// GeneratorName and GeneratorURL fill the <tv> generator-info
+	// attributes when the TV doesn't set its own. Empty values leave the
+	// attribute out.
+	GeneratorName string
+	GeneratorURL  string
+
+	// Compact omits indentation and newlines between elements, shrinking
+	// large guides.
+	Compact bool
+}
+
+// Marshal serializes the TV structure to XML, identifying this proxy as the
+// generator unless the TV names another.
 func Marshal(tv *TV) ([]byte, error) {
-	data, err := xml.MarshalIndent(tv, "", "  ")
+	return MarshalWithOptions(tv, MarshalOptions{
+		GeneratorName: DefaultGeneratorName,
+		GeneratorURL:  DefaultGeneratorURL,
+	})
+}
+
+// MarshalWithOptions serializes the TV structure to XML like Marshal,
+// applying opts. tv is not modified.
+func MarshalWithOptions(tv *TV, opts MarshalOptions) ([]byte, error) {
+	out := *tv
+
+	if out.GeneratorInfoName == "" {
+		out.GeneratorInfoName = opts.GeneratorName
+	}
+
+	if out.GeneratorInfoURL == "" {
+		out.GeneratorInfoURL = opts.GeneratorURL
+	}
+
+	var (
+		data []byte
+		err  error
+	)
+
+	if opts.Compact {
+		data, err = xml.Marshal(&out)
+	} else {
+		data, err = xml.MarshalIndent(&out, "", "  ")
+	}
+
 	if err != nil {
 		return nil, fmt.Errorf("failed to marshal EPG XML: %w", err)
 	}
 
 	return append([]byte(xml.Header), data...), nil
 }
+
+// FormatTime formats t as an XMLTV timestamp such as "20260104120000 +0000",
+// keeping t's offset.
+func FormatTime(t time.Time) string {
+	return t.Format(timeLayout)
+}
+
+// ParseTime parses an XMLTV timestamp such as "20260104120000 +0000".
+// Timestamps without an offset are interpreted as UTC.
+func ParseTime(s string) (time.Time, error) {
+	s = strings.TrimSpace(s)
+
+	if t, err := time.Parse(timeLayout, s); err == nil {
+		return t, nil
+	}
+
+	t, err := time.Parse(timeLayoutNoOffset, s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid XMLTV time %q: %w", s, err)
+	}
+
+	return t, nil
+}
@@ -3,7 +3,10 @@ package epg
 import (
 	"strings"
 	"testing"
+	"time"
 
+	"github.com/savid/iptv/internal/m3u"
+	"github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/require"
 )
 
@@ -214,9 +217,45 @@ func TestMarshal_EmptyTV(t *testing.T) {
 	data, err := Marshal(tv)
 	require.NoError(t, err)
 	require.NotEmpty(t, data)
+	require.Contains(t, string(data), "<tv ")
+	require.Contains(t, string(data), "</tv>")
+}
+
+func TestMarshal_GeneratorInfo(t *testing.T) {
+	tv := &TV{}
+
+	data, err := Marshal(tv)
+	require.NoError(t, err)
+	require.Contains(t, string(data), `<tv generator-info-name="iptv-proxy" generator-info-url="https://github.com/savid/iptv">`)
+	require.Empty(t, tv.GeneratorInfoName)
+
+	data, err = MarshalWithOptions(tv, MarshalOptions{GeneratorName: "custom"})
+	require.NoError(t, err)
+	require.Contains(t, string(data), `<tv generator-info-name="custom">`)
+
+	data, err = MarshalWithOptions(tv, MarshalOptions{})
+	require.NoError(t, err)
 	require.Contains(t, string(data), "<tv>")
 }
 
+func TestParse_GeneratorInfoRoundTrip(t *testing.T) {
+	input := `<?xml version="1.0" encoding="UTF-8"?>
+<tv generator-info-name="upstream-grabber" generator-info-url="http://grabber.example.com/">
+  <channel id="espn.us"><display-name>ESPN</display-name></channel>
+</tv>`
+
+	tv, err := Parse([]byte(input))
+	require.NoError(t, err)
+	require.Equal(t, "upstream-grabber", tv.GeneratorInfoName)
+	require.Equal(t, "http://grabber.example.com/", tv.GeneratorInfoURL)
+	require.Len(t, tv.Channels, 1)
+
+	data, err := Marshal(tv)
+	require.NoError(t, err)
+	require.Contains(t, string(data), `generator-info-name="upstream-grabber"`)
+	require.Contains(t, string(data), `generator-info-url="http://grabber.example.com/"`)
+}
+
 func TestRoundTrip(t *testing.T) {
 	original := &TV{
 		Channels: []Channel{
@@ -292,3 +331,313 @@ func TestParse_SpecialCharacters(t *testing.T) {
 	require.Equal(t, "Show <Special>", tv.Programs[0].Title)
 	require.Equal(t, `Description with "quotes"`, tv.Programs[0].Description)
 }
+
+func TestStarRating_RoundTrip(t *testing.T) {
+	input := `<?xml version="1.0" encoding="UTF-8"?>
+<tv>
+  <channel id="hbo.us">
+    <display-name>HBO</display-name>
+  </channel>
+  <programme channel="hbo.us" start="20260104200000 +0000" stop="20260104220000 +0000">
+    <title>Movie</title>
+    <star-rating system="imdb">
+      <value>8/10</value>
+    </star-rating>
+  </programme>
+  <programme channel="hbo.us" start="20260104220000 +0000" stop="20260104230000 +0000">
+    <title>Unrated</title>
+  </programme>
+</tv>`
+
+	tv, err := Parse([]byte(input))
+	require.NoError(t, err)
+	require.Len(t, tv.Programs, 2)
+	require.NotNil(t, tv.Programs[0].StarRating)
+	require.Equal(t, "imdb", tv.Programs[0].StarRating.System)
+	require.Equal(t, "8/10", tv.Programs[0].StarRating.Value)
+	require.Nil(t, tv.Programs[1].StarRating)
+
+	merged := MergeEPGs([]*FilterResult{{EPG: tv, ChannelMap: map[string]string{"hbo.us": "HBO"}}})
+
+	data, err := Marshal(&TV{Channels: merged.Channels, Programs: merged.Programs})
+	require.NoError(t, err)
+	require.Equal(t, 1, strings.Count(string(data), "<star-rating"))
+	require.Contains(t, string(data), `<star-rating system="imdb">`)
+
+	reparsed, err := Parse(data)
+	require.NoError(t, err)
+
+	var rated *Programme
+
+	for i := range reparsed.Programs {
+		if reparsed.Programs[i].Title == "Movie" {
+			rated = &reparsed.Programs[i]
+		}
+	}
+
+	require.NotNil(t, rated)
+	require.Equal(t, tv.Programs[0].StarRating, rated.StarRating)
+}
+
+func TestChannelURLs_RoundTrip(t *testing.T) {
+	input := `<?xml version="1.0" encoding="UTF-8"?>
+<tv>
+  <channel id="hbo.us">
+    <display-name>HBO</display-name>
+    <url>https://www.hbo.com</url>
+    <url>https://www.max.com</url>
+  </channel>
+  <channel id="cnn.us">
+    <display-name>CNN</display-name>
+  </channel>
+</tv>`
+
+	tv, err := Parse([]byte(input))
+	require.NoError(t, err)
+	require.Equal(t, []string{"https://www.hbo.com", "https://www.max.com"}, tv.Channels[0].URLs)
+	require.Empty(t, tv.Channels[1].URLs)
+
+	m3uChannels := []m3u.Channel{{Name: "HBO", TVGID: "hbo.us"}, {Name: "CNN", TVGID: "cnn.us"}}
+	result := FilterForMerge(newTestLogger(), tv, m3uChannels)
+	merged := MergeEPGs([]*FilterResult{result})
+
+	data, err := Marshal(&TV{Channels: merged.Channels, Programs: merged.Programs})
+	require.NoError(t, err)
+	require.Equal(t, 2, strings.Count(string(data), "<url>"))
+
+	reparsed, err := Parse(data)
+	require.NoError(t, err)
+
+	for _, ch := range reparsed.Channels {
+		switch ch.ID {
+		case "hbo.us":
+			require.Equal(t, []string{"https://www.hbo.com", "https://www.max.com"}, ch.URLs)
+		case "cnn.us":
+			require.Empty(t, ch.URLs)
+		}
+	}
+}
+
+func TestVideoAudio_RoundTrip(t *testing.T) {
+	input := `<?xml version="1.0" encoding="UTF-8"?>
+<tv>
+  <channel id="hbo.us">
+    <display-name>HBO</display-name>
+  </channel>
+  <programme channel="hbo.us" start="20260104200000 +0000" stop="20260104220000 +0000">
+    <title>Movie</title>
+    <video>
+      <aspect>16:9</aspect>
+      <quality>HDTV</quality>
+    </video>
+    <audio>
+      <stereo>dolby digital</stereo>
+    </audio>
+  </programme>
+  <programme channel="hbo.us" start="20260104220000 +0000" stop="20260104230000 +0000">
+    <title>Plain</title>
+  </programme>
+</tv>`
+
+	tv, err := Parse([]byte(input))
+	require.NoError(t, err)
+	require.Len(t, tv.Programs, 2)
+	require.Equal(t, &Video{Aspect: "16:9", Quality: "HDTV"}, tv.Programs[0].Video)
+	require.Equal(t, &Audio{Stereo: "dolby digital"}, tv.Programs[0].Audio)
+	require.Nil(t, tv.Programs[1].Video)
+	require.Nil(t, tv.Programs[1].Audio)
+
+	merged := MergeEPGs([]*FilterResult{{EPG: tv, ChannelMap: map[string]string{"hbo.us": "HBO"}}})
+
+	data, err := Marshal(&TV{Channels: merged.Channels, Programs: merged.Programs})
+	require.NoError(t, err)
+	require.Equal(t, 1, strings.Count(string(data), "<video>"))
+	require.Equal(t, 1, strings.Count(string(data), "<audio>"))
+	require.NotContains(t, string(data), "<colour>")
+
+	reparsed, err := Parse(data)
+	require.NoError(t, err)
+
+	var movie *Programme
+
+	for i := range reparsed.Programs {
+		if reparsed.Programs[i].Title == "Movie" {
+			movie = &reparsed.Programs[i]
+		}
+	}
+
+	require.NotNil(t, movie)
+	require.Equal(t, tv.Programs[0].Video, movie.Video)
+	require.Equal(t, tv.Programs[0].Audio, movie.Audio)
+}
+
+func TestIcon_DimensionsRoundTrip(t *testing.T) {
+	input := `<?xml version="1.0" encoding="UTF-8"?>
+<tv>
+  <channel id="hbo.us">
+    <display-name>HBO</display-name>
+    <icon src="http://example.com/hbo.png" width="120" height="90"/>
+  </channel>
+  <channel id="cnn.us">
+    <display-name>CNN</display-name>
+    <icon src="http://example.com/cnn.png"/>
+  </channel>
+</tv>`
+
+	tv, err := Parse([]byte(input))
+	require.NoError(t, err)
+	require.Equal(t, Icon{Src: "http://example.com/hbo.png", Width: 120, Height: 90}, tv.Channels[0].Icon)
+	require.Equal(t, Icon{Src: "http://example.com/cnn.png"}, tv.Channels[1].Icon)
+
+	m3uChannels := []m3u.Channel{{Name: "HBO", TVGID: "hbo.us"}, {Name: "CNN", TVGID: "cnn.us"}}
+	filtered, channelMap := Filter(logrus.New(), tv, m3uChannels)
+	merged := MergeEPGs([]*FilterResult{{EPG: filtered, ChannelMap: channelMap}})
+
+	data, err := Marshal(&TV{Channels: merged.Channels, Programs: merged.Programs})
+	require.NoError(t, err)
+	require.Contains(t, string(data), `<icon src="http://example.com/hbo.png" width="120" height="90"></icon>`)
+	require.Contains(t, string(data), `<icon src="http://example.com/cnn.png"></icon>`)
+
+	reparsed, err := Parse(data)
+	require.NoError(t, err)
+
+	icons := make(map[string]Icon, len(reparsed.Channels))
+
+	for _, ch := range reparsed.Channels {
+		icons[ch.ID] = ch.Icon
+	}
+
+	require.Equal(t, tv.Channels[0].Icon, icons["hbo.us"])
+	require.Equal(t, tv.Channels[1].Icon, icons["cnn.us"])
+}
+
+func TestFormatTime(t *testing.T) {
+	require.Equal(t, "20260104120000 +0000", FormatTime(time.Date(2026, 1, 4, 12, 0, 0, 0, time.UTC)))
+	require.Equal(t, "20260104120000 +0100", FormatTime(time.Date(2026, 1, 4, 12, 0, 0, 0, time.FixedZone("", 3600))))
+
+	ts := time.Date(2026, 7, 1, 8, 30, 15, 0, time.FixedZone("", -5*3600))
+
+	parsed, err := ParseTime(FormatTime(ts))
+	require.NoError(t, err)
+	require.True(t, ts.Equal(parsed))
+}
+
+func TestFillMissingStops(t *testing.T) {
+	input := `<?xml version="1.0" encoding="UTF-8"?>
+<tv>
+  <channel id="espn.us"><display-name>ESPN</display-name></channel>
+  <programme channel="espn.us" start="20260104140000 +0100">
+    <title>Last</title>
+  </programme>
+  <programme channel="espn.us" start="20260104120000 +0000">
+    <title>First</title>
+  </programme>
+  <programme channel="espn.us" start="20260104123000 +0000" stop="20260104130000 +0000">
+    <title>Has Stop</title>
+  </programme>
+</tv>`
+
+	tv, err := Parse([]byte(input))
+	require.NoError(t, err)
+	require.Empty(t, tv.Programs[0].Stop)
+
+	require.Equal(t, 2, FillMissingStops(tv))
+
+	// The next programme on the channel starts at 12:30.
+	require.Equal(t, "First", tv.Programs[1].Title)
+	require.Equal(t, "20260104123000 +0000", tv.Programs[1].Stop)
+
+	// The last programme gets the default duration, keeping its offset.
+	require.Equal(t, "Last", tv.Programs[0].Title)
+	require.Equal(t, "20260104150000 +0100", tv.Programs[0].Stop)
+
+	require.Equal(t, "20260104130000 +0000", tv.Programs[2].Stop)
+	require.Zero(t, FillMissingStops(tv))
+}
+
+func TestParse_TrailingGarbage(t *testing.T) {
+	tests := []struct {
+		name    string
+		trailer string
+	}{
+		{name: "stray bytes", trailer: "\x00\x00garbage"},
+		{name: "second document", trailer: `<?xml version="1.0"?><tv><channel id="other"/></tv>`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			input := `<?xml version="1.0" encoding="UTF-8"?>
+<tv>
+  <channel id="espn.us"><display-name>ESPN</display-name></channel>
+  <programme channel="espn.us" start="20260104120000 +0000" stop="20260104130000 +0000">
+    <title>SportsCenter</title>
+  </programme>
+</tv>
+` + tt.trailer
+
+			tv, err := Parse([]byte(input))
+			require.NoError(t, err)
+			require.Len(t, tv.Channels, 1)
+			require.Equal(t, "espn.us", tv.Channels[0].ID)
+			require.Len(t, tv.Programs, 1)
+		})
+	}
+}
+
+func TestParse_WrongRootElement(t *testing.T) {
+	_, err := Parse([]byte(`<html><body>Login required</body></html>`))
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "expected <tv> root element")
+}
+
+func TestApplyTimezone(t *testing.T) {
+	loc, err := time.LoadLocation("America/New_York")
+	require.NoError(t, err)
+
+	tv := &TV{
+		Programs: []Programme{
+			{Start: "20260104120000", Stop: "20260104130000"},
+			{Start: "20260104120000 +0100", Stop: "20260104130000 +0100"},
+		},
+	}
+
+	ApplyTimezone(tv, loc)
+
+	require.Equal(t, "20260104120000 -0500", tv.Programs[0].Start)
+	require.Equal(t, "20260104130000 -0500", tv.Programs[0].Stop)
+	require.Equal(t, "20260104120000 +0100", tv.Programs[1].Start)
+}
+
+func TestMarshal_Compact(t *testing.T) {
+	tv := &TV{
+		Channels: []Channel{
+			{ID: "espn.us", DisplayName: "ESPN", Icon: Icon{Src: "http://logo.example.com/espn.png"}},
+			{ID: "cnn.us", DisplayName: "CNN"},
+		},
+		Programs: []Programme{
+			{Channel: "espn.us", Start: "20260104120000 +0000", Stop: "20260104130000 +0000", Title: "SportsCenter", Description: "Sports news"},
+			{Channel: "cnn.us", Start: "20260104120000 +0000", Title: "Headlines", Category: "News"},
+		},
+	}
+
+	indented, err := Marshal(tv)
+	require.NoError(t, err)
+
+	compact, err := MarshalWithOptions(tv, MarshalOptions{
+		GeneratorName: DefaultGeneratorName,
+		GeneratorURL:  DefaultGeneratorURL,
+		Compact:       true,
+	})
+	require.NoError(t, err)
+
+	require.Less(t, len(compact), len(indented))
+	require.Equal(t, 1, strings.Count(string(compact), "\n"), "only the XML header ends in a newline")
+
+	fromIndented, err := Parse(indented)
+	require.NoError(t, err)
+
+	fromCompact, err := Parse(compact)
+	require.NoError(t, err)
+
+	require.Equal(t, fromIndented, fromCompact)
+}
@@ -0,0 +1,167 @@
+package epg
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/savid/iptv/internal/m3u"
+	"github.com/savid/iptv/internal/names"
+	"github.com/sirupsen/logrus"
+)
+
+// DefaultQualityRanking orders quality markers from best to worst. The empty
+// string stands for channels without any quality marker.
+var DefaultQualityRanking = []string{"UHD", "4K", "FHD", "HD", "", "SD"}
+
+// detectQuality returns the quality marker found in a channel name, matching
+// "(X)" anywhere or " X" as a trailing word (case-insensitive). Returns ""
+// if none of the ranked markers are present.
+func detectQuality(name string, ranking []string) string {
+	upperName := strings.ToUpper(strings.TrimSpace(name))
+
+	for _, marker := range ranking {
+		if marker == "" {
+			continue
+		}
+
+		upperMarker := strings.ToUpper(marker)
+		if strings.Contains(upperName, "("+upperMarker+")") || strings.HasSuffix(upperName, " "+upperMarker) {
+			return marker
+		}
+	}
+
+	return ""
+}
+
+// IsHD returns true if the channel looks high definition: its resolution
+// attribute (e.g. "1080p" or "1920x1080") is at least 720 lines, or its name
+// carries a quality marker ranked above unmarked channels in ranking.
+func IsHD(ch m3u.Channel, ranking []string) bool {
+	if lines, ok := resolutionLines(ch.Attributes[m3u.AttrResolution]); ok {
+		return lines >= hdLines
+	}
+
+	if len(ranking) == 0 {
+		ranking = DefaultQualityRanking
+	}
+
+	unmarked := len(ranking)
+
+	for i, marker := range ranking {
+		if marker == "" {
+			unmarked = i
+
+			break
+		}
+	}
+
+	return detectQuality(ch.Name, ranking) != "" && qualityRank(ch.Name, ranking) < unmarked
+}
+
+// hdLines is the minimum vertical resolution considered high definition.
+const hdLines = 720
+
+// resolutionLines parses the vertical resolution from values like "1080p",
+// "720i", "1080" or "1920x1080".
+func resolutionLines(resolution string) (int, bool) {
+	resolution = strings.ToLower(strings.TrimSpace(resolution))
+	if resolution == "" {
+		return 0, false
+	}
+
+	if _, height, found := strings.Cut(resolution, "x"); found {
+		resolution = height
+	}
+
+	resolution = strings.TrimRight(resolution, "pi")
+
+	lines, err := strconv.Atoi(resolution)
+	if err != nil {
+		return 0, false
+	}
+
+	return lines, true
+}
+
+// qualityRank returns the position of a channel name's quality marker in the
+// ranking (lower is better). Unranked markers sort after all ranked ones.
+func qualityRank(name string, ranking []string) int {
+	marker := detectQuality(name, ranking)
+
+	for i, m := range ranking {
+		if strings.EqualFold(m, marker) {
+			return i
+		}
+	}
+
+	return len(ranking)
+}
+
+// CollapseQualityVariants groups channels whose normalized names and regions
+// match and keeps only the highest-quality variant of each, according to
+// ranking. The surviving channel keeps its original lineup position; ties
+// keep the earliest channel.
+func CollapseQualityVariants(log logrus.FieldLogger, channels []m3u.Channel, ranking []string) []m3u.Channel {
+	if len(ranking) == 0 {
+		ranking = DefaultQualityRanking
+	}
+
+	// Best channel index per variant key.
+	best := make(map[string]int, len(channels))
+
+	for i, ch := range channels {
+		key := variantKey(ch.Name, ranking)
+
+		current, exists := best[key]
+		if !exists || qualityRank(ch.Name, ranking) < qualityRank(channels[current].Name, ranking) {
+			best[key] = i
+		}
+	}
+
+	collapsed := make([]m3u.Channel, 0, len(best))
+
+	for i, ch := range channels {
+		if best[variantKey(ch.Name, ranking)] == i {
+			collapsed = append(collapsed, ch)
+
+			continue
+		}
+
+		log.WithFields(logrus.Fields{
+			"channel": ch.Name,
+			"kept":    channels[best[variantKey(ch.Name, ranking)]].Name,
+		}).Debug("Dropped lower-quality channel variant")
+	}
+
+	if dropped := len(channels) - len(collapsed); dropped > 0 {
+		log.WithField("dropped", dropped).Info("Collapsed quality variants of duplicate channels")
+	}
+
+	return collapsed
+}
+
+// variantKey identifies quality variants of the same channel. Ranked markers
+// are stripped as well as the built-in quality suffixes, so custom markers
+// (e.g. "(HEVC)") group with their unmarked counterpart.
+func variantKey(name string, ranking []string) string {
+	return names.Region(name) + "|" + names.Normalize(stripQualityMarker(name, ranking))
+}
+
+// stripQualityMarker removes the quality marker detected by detectQuality
+// from a channel name.
+func stripQualityMarker(name string, ranking []string) string {
+	marker := detectQuality(name, ranking)
+	if marker == "" {
+		return name
+	}
+
+	trimmed := strings.TrimSpace(name)
+	upperName := strings.ToUpper(trimmed)
+	upperMarker := strings.ToUpper(marker)
+
+	if idx := strings.Index(upperName, "("+upperMarker+")"); idx >= 0 {
+		return trimmed[:idx] + trimmed[idx+len(marker)+2:]
+	}
+
+	return trimmed[:len(trimmed)-len(marker)-1]
+}
@@ -0,0 +1,102 @@
+package epg
+
+import (
+	"testing"
+
+	"github.com/savid/iptv/internal/m3u"
+	"github.com/stretchr/testify/require"
+)
+
+func channelNames(channels []m3u.Channel) []string {
+	names := make([]string, 0, len(channels))
+	for _, ch := range channels {
+		names = append(names, ch.Name)
+	}
+
+	return names
+}
+
+func TestCollapseQualityVariants_PrefersHD(t *testing.T) {
+	channels := []m3u.Channel{
+		{Name: "ESPN (SD)"},
+		{Name: "CNN"},
+		{Name: "ESPN HD"},
+		{Name: "ESPN"},
+		{Name: "HBO FHD"},
+		{Name: "HBO HD"},
+	}
+
+	collapsed := CollapseQualityVariants(newTestLogger(), channels, nil)
+
+	require.Equal(t, []string{"CNN", "ESPN HD", "HBO FHD"}, channelNames(collapsed))
+}
+
+func TestCollapseQualityVariants_KeepsRegionsApart(t *testing.T) {
+	channels := []m3u.Channel{
+		{Name: "US: ESPN"},
+		{Name: "UK: ESPN HD"},
+		{Name: "US: ESPN HD"},
+	}
+
+	collapsed := CollapseQualityVariants(newTestLogger(), channels, nil)
+
+	require.Equal(t, []string{"UK: ESPN HD", "US: ESPN HD"}, channelNames(collapsed))
+}
+
+func TestCollapseQualityVariants_CustomRanking(t *testing.T) {
+	channels := []m3u.Channel{
+		{Name: "ESPN HD"},
+		{Name: "ESPN (SD)"},
+	}
+
+	// A ranking that prefers SD (e.g. for bandwidth-constrained setups).
+	collapsed := CollapseQualityVariants(newTestLogger(), channels, []string{"SD", "", "HD"})
+
+	require.Equal(t, []string{"ESPN (SD)"}, channelNames(collapsed))
+}
+
+func TestDetectQuality(t *testing.T) {
+	ranking := DefaultQualityRanking
+
+	require.Equal(t, "HD", detectQuality("ESPN HD", ranking))
+	require.Equal(t, "FHD", detectQuality("ESPN FHD", ranking))
+	require.Equal(t, "4K", detectQuality("ESPN (4K)", ranking))
+	require.Equal(t, "SD", detectQuality("ESPN (sd)", ranking))
+	require.Equal(t, "", detectQuality("ESPN", ranking))
+}
+
+func TestCollapseQualityVariants_CustomMarker(t *testing.T) {
+	channels := []m3u.Channel{
+		{Name: "ESPN HD"},
+		{Name: "ESPN (HEVC)"},
+		{Name: "CNN HEVC"},
+		{Name: "CNN"},
+	}
+
+	collapsed := CollapseQualityVariants(newTestLogger(), channels, []string{"HEVC", "HD", ""})
+
+	require.Equal(t, []string{"ESPN (HEVC)", "CNN HEVC"}, channelNames(collapsed))
+}
+
+func TestIsHD(t *testing.T) {
+	tests := []struct {
+		name    string
+		channel m3u.Channel
+		want    bool
+	}{
+		{"HD marker", m3u.Channel{Name: "ESPN HD"}, true},
+		{"FHD marker", m3u.Channel{Name: "ESPN (FHD)"}, true},
+		{"no marker", m3u.Channel{Name: "ESPN"}, false},
+		{"SD marker", m3u.Channel{Name: "ESPN SD"}, false},
+		{"1080p resolution", m3u.Channel{Name: "ESPN", Attributes: map[string]string{m3u.AttrResolution: "1080p"}}, true},
+		{"WxH resolution", m3u.Channel{Name: "ESPN", Attributes: map[string]string{m3u.AttrResolution: "1280x720"}}, true},
+		{"SD resolution overrides marker", m3u.Channel{Name: "ESPN HD", Attributes: map[string]string{m3u.AttrResolution: "576i"}}, false},
+		{"unparseable resolution", m3u.Channel{Name: "ESPN HD", Attributes: map[string]string{m3u.AttrResolution: "high"}}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.want, IsHD(tt.channel, DefaultQualityRanking))
+		})
+	}
+}
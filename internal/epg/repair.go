@@ -0,0 +1,76 @@
+package epg
+
+import (
+	"sort"
+	"time"
+)
+
+// RepairOverlaps makes each channel's timeline non-overlapping. Programmes are
+// considered in start order: one that starts before the previous programme
+// stops has its start moved to that stop, or is dropped when it ends by then.
+// Programmes with unparseable times are left as is. Programme order is
+// otherwise preserved. Returns the number of programmes trimmed or dropped.
+func RepairOverlaps(tv *TV) int {
+	type timed struct {
+		idx         int
+		start, stop time.Time
+	}
+
+	byChannel := make(map[string][]timed)
+
+	for i, prog := range tv.Programs {
+		start, startErr := ParseTime(prog.Start)
+		stop, stopErr := ParseTime(prog.Stop)
+
+		if startErr != nil || stopErr != nil {
+			continue
+		}
+
+		byChannel[prog.Channel] = append(byChannel[prog.Channel], timed{idx: i, start: start, stop: stop})
+	}
+
+	dropped := make(map[int]bool)
+	repaired := 0
+
+	for _, progs := range byChannel {
+		sort.SliceStable(progs, func(i, j int) bool {
+			return progs[i].start.Before(progs[j].start)
+		})
+
+		prev := -1
+
+		for i, p := range progs {
+			if prev == -1 || !p.start.Before(progs[prev].stop) {
+				prev = i
+
+				continue
+			}
+
+			repaired++
+
+			if !p.stop.After(progs[prev].stop) {
+				dropped[p.idx] = true
+
+				continue
+			}
+
+			tv.Programs[p.idx].Start = tv.Programs[progs[prev].idx].Stop
+			progs[i].start = progs[prev].stop
+			prev = i
+		}
+	}
+
+	if len(dropped) > 0 {
+		kept := make([]Programme, 0, len(tv.Programs)-len(dropped))
+
+		for i, prog := range tv.Programs {
+			if !dropped[i] {
+				kept = append(kept, prog)
+			}
+		}
+
+		tv.Programs = kept
+	}
+
+	return repaired
+}
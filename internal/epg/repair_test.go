@@ -0,0 +1,37 @@
+package epg
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRepairOverlaps(t *testing.T) {
+	tv := &TV{
+		Programs: []Programme{
+			{Channel: "espn.us", Start: "20260104120000 +0000", Stop: "20260104130000 +0000", Title: "A"},
+			{Channel: "espn.us", Start: "20260104124500 +0000", Stop: "20260104140000 +0000", Title: "B"},
+			{Channel: "espn.us", Start: "20260104131500 +0000", Stop: "20260104133000 +0000", Title: "Contained"},
+			{Channel: "espn.us", Start: "20260104140000 +0000", Stop: "20260104150000 +0000", Title: "C"},
+			{Channel: "cnn.us", Start: "20260104124500 +0000", Stop: "20260104140000 +0000", Title: "Other Channel"},
+			{Channel: "espn.us", Start: "bad", Stop: "20260104150000 +0000", Title: "Unparseable"},
+		},
+	}
+
+	require.Equal(t, 2, RepairOverlaps(tv))
+
+	titles := make([]string, 0, len(tv.Programs))
+
+	for _, prog := range tv.Programs {
+		titles = append(titles, prog.Title)
+	}
+
+	require.Equal(t, []string{"A", "B", "C", "Other Channel", "Unparseable"}, titles)
+
+	// B is trimmed to start where A stops, adjacent to C.
+	require.Equal(t, "20260104130000 +0000", tv.Programs[1].Start)
+	require.Equal(t, "20260104140000 +0000", tv.Programs[1].Stop)
+	require.Equal(t, "20260104124500 +0000", tv.Programs[3].Start)
+
+	require.Zero(t, RepairOverlaps(tv))
+}
@@ -0,0 +1,271 @@
+package epg
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/savid/iptv/internal/m3u"
+	"github.com/savid/iptv/internal/names"
+)
+
+// maxCloseMatches is the number of close EPG matches reported per unmatched channel.
+const maxCloseMatches = 5
+
+// MatchReport describes how M3U channels matched EPG channels.
+type MatchReport struct {
+	Matched   []MatchedChannel   `json:"matched"`
+	Unmatched []UnmatchedChannel `json:"unmatched"`
+	Summary   MatchSummary       `json:"summary"`
+}
+
+// MatchedChannel is an M3U channel with a real EPG match.
+type MatchedChannel struct {
+	Name           string `json:"name"`
+	TVGID          string `json:"tvgId"`
+	EPGID          string `json:"epgId"`
+	EPGDisplayName string `json:"epgDisplayName"`
+	Strategy       string `json:"strategy"`
+	Programmes     int    `json:"programmes"`
+}
+
+// UnmatchedChannel is an M3U channel without a real EPG match, with the
+// source EPG channels whose names share the most words with it.
+type UnmatchedChannel struct {
+	Name         string   `json:"name"`
+	TVGID        string   `json:"tvgId"`
+	CloseMatches []string `json:"closeMatches"`
+}
+
+// MatchSummary holds match statistics.
+type MatchSummary struct {
+	Total             int            `json:"total"`
+	Matched           int            `json:"matched"`
+	Unmatched         int            `json:"unmatched"`
+	MatchRate         float64        `json:"matchRate"`
+	ByStrategy        map[string]int `json:"byStrategy"`
+	WithProgrammes    int            `json:"withProgrammes"`
+	WithoutProgrammes int            `json:"withoutProgrammes"`
+}
+
+// AnalyzeMatches builds a match report for m3uChannels against a filtered
+// EPG and its channel map (EPG ID → M3U name). sourceChannels are the EPG
+// channels before filtering, used to suggest close matches. Channels that
+// only received generated placeholder data are reported as unmatched. The
+// strategy is inferred: a tvg-id equal to the EPG ID, then an exact display
+// name, otherwise normalized.
+func AnalyzeMatches(
+	m3uChannels []m3u.Channel,
+	sourceChannels []Channel,
+	filtered *TV,
+	channelMap map[string]string,
+) *MatchReport {
+	// Real (non-placeholder) programme count per EPG channel.
+	programCount := make(map[string]int, len(filtered.Channels))
+
+	for _, prog := range filtered.Programs {
+		if prog.Description != PlaceholderDescription {
+			programCount[prog.Channel]++
+		}
+	}
+
+	// M3U name → EPG channel (first match).
+	m3uToEPG := make(map[string]Channel, len(filtered.Channels))
+
+	for _, ch := range filtered.Channels {
+		name, ok := channelMap[ch.ID]
+		if !ok {
+			continue
+		}
+
+		// Channels generated for unmatched M3U entries don't count as matches.
+		if ch.ID == generateChannelID(name) && programCount[ch.ID] == 0 {
+			continue
+		}
+
+		if _, exists := m3uToEPG[name]; !exists {
+			m3uToEPG[name] = ch
+		}
+	}
+
+	report := &MatchReport{
+		Matched:   make([]MatchedChannel, 0, len(m3uChannels)),
+		Unmatched: make([]UnmatchedChannel, 0),
+		Summary: MatchSummary{
+			Total: len(m3uChannels),
+			ByStrategy: map[string]int{
+				MatchTVGID:          0,
+				MatchDisplayName:    0,
+				MatchWhitespace:     0,
+				MatchNormalizedName: 0,
+			},
+		},
+	}
+
+	for _, m3uCh := range m3uChannels {
+		epgCh, ok := m3uToEPG[m3uCh.Name]
+		if !ok {
+			report.Unmatched = append(report.Unmatched, UnmatchedChannel{
+				Name:         m3uCh.Name,
+				TVGID:        m3uCh.TVGID,
+				CloseMatches: findClosestMatches(m3uCh.Name, sourceChannels),
+			})
+
+			continue
+		}
+
+		strategy := MatchNormalizedName
+
+		switch {
+		case m3uCh.TVGID != "" && epgCh.ID == m3uCh.TVGID:
+			strategy = MatchTVGID
+		case m3uCh.Name == epgCh.DisplayName:
+			strategy = MatchDisplayName
+		case names.CollapseWhitespace(m3uCh.Name) == names.CollapseWhitespace(epgCh.DisplayName):
+			strategy = MatchWhitespace
+		}
+
+		report.Matched = append(report.Matched, MatchedChannel{
+			Name:           m3uCh.Name,
+			TVGID:          m3uCh.TVGID,
+			EPGID:          epgCh.ID,
+			EPGDisplayName: epgCh.DisplayName,
+			Strategy:       strategy,
+			Programmes:     programCount[epgCh.ID],
+		})
+
+		report.Summary.ByStrategy[strategy]++
+
+		if programCount[epgCh.ID] > 0 {
+			report.Summary.WithProgrammes++
+		} else {
+			report.Summary.WithoutProgrammes++
+		}
+	}
+
+	report.Summary.Matched = len(report.Matched)
+	report.Summary.Unmatched = len(report.Unmatched)
+
+	if report.Summary.Total > 0 {
+		report.Summary.MatchRate = float64(report.Summary.Matched) / float64(report.Summary.Total)
+	}
+
+	return report
+}
+
+// MatchDiff is the change in matching between two reports, by M3U channel
+// name. Channels present in only one report are ignored.
+type MatchDiff struct {
+	NewlyMatched    []string         `json:"newlyMatched"`
+	NewlyUnmatched  []string         `json:"newlyUnmatched"`
+	StrategyChanged []StrategyChange `json:"strategyChanged"`
+}
+
+// StrategyChange is a channel matched in both reports by different strategies.
+type StrategyChange struct {
+	Name string `json:"name"`
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// Regressed returns true if any channel went from matched to unmatched.
+func (d *MatchDiff) Regressed() bool {
+	return len(d.NewlyUnmatched) > 0
+}
+
+// DiffReports compares current against a baseline report. Results follow the
+// order of current.
+func DiffReports(baseline, current *MatchReport) *MatchDiff {
+	baseMatched := make(map[string]string, len(baseline.Matched))
+
+	for _, ch := range baseline.Matched {
+		baseMatched[ch.Name] = ch.Strategy
+	}
+
+	baseUnmatched := make(map[string]bool, len(baseline.Unmatched))
+
+	for _, ch := range baseline.Unmatched {
+		baseUnmatched[ch.Name] = true
+	}
+
+	diff := &MatchDiff{
+		NewlyMatched:    make([]string, 0),
+		NewlyUnmatched:  make([]string, 0),
+		StrategyChanged: make([]StrategyChange, 0),
+	}
+
+	for _, ch := range current.Matched {
+		if baseUnmatched[ch.Name] {
+			diff.NewlyMatched = append(diff.NewlyMatched, ch.Name)
+
+			continue
+		}
+
+		if from, ok := baseMatched[ch.Name]; ok && from != ch.Strategy {
+			diff.StrategyChanged = append(diff.StrategyChanged, StrategyChange{
+				Name: ch.Name,
+				From: from,
+				To:   ch.Strategy,
+			})
+		}
+	}
+
+	for _, ch := range current.Unmatched {
+		if _, ok := baseMatched[ch.Name]; ok {
+			diff.NewlyUnmatched = append(diff.NewlyUnmatched, ch.Name)
+		}
+	}
+
+	return diff
+}
+
+// findClosestMatches finds EPG channels with similar names using simple token matching.
+func findClosestMatches(m3uName string, epgChannels []Channel) []string {
+	tokens := strings.Fields(strings.ToLower(m3uName))
+	if len(tokens) == 0 {
+		return nil
+	}
+
+	type scored struct {
+		name  string
+		score int
+	}
+
+	candidates := make([]scored, 0, 10)
+
+	for _, ch := range epgChannels {
+		epgTokens := strings.Fields(strings.ToLower(ch.DisplayName))
+
+		// Count matching tokens
+		matches := 0
+
+		for _, t1 := range tokens {
+			for _, t2 := range epgTokens {
+				if t1 == t2 {
+					matches++
+
+					break
+				}
+			}
+		}
+
+		if matches > 0 {
+			candidates = append(candidates, scored{
+				name:  ch.DisplayName,
+				score: matches,
+			})
+		}
+	}
+
+	// Sort by score (descending), keeping source order for ties.
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].score > candidates[j].score
+	})
+
+	result := make([]string, 0, maxCloseMatches)
+
+	for i := 0; i < len(candidates) && i < maxCloseMatches; i++ {
+		result = append(result, candidates[i].name)
+	}
+
+	return result
+}
@@ -0,0 +1,89 @@
+package epg
+
+import (
+	"testing"
+
+	"github.com/savid/iptv/internal/m3u"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAnalyzeMatches(t *testing.T) {
+	source := &TV{
+		Channels: []Channel{
+			{ID: "espn.us", DisplayName: "ESPN"},
+			{ID: "cnn.us", DisplayName: "CNN"},
+			{ID: "hbo.us", DisplayName: "HBO"},
+			{ID: "bbc.one", DisplayName: "BBC One London"},
+		},
+		Programs: []Programme{
+			{Channel: "espn.us", Title: "SportsCenter"},
+			{Channel: "hbo.us", Title: "Movie"},
+		},
+	}
+	m3uChannels := []m3u.Channel{
+		{Name: "ESPN HD", TVGID: "espn.us"},
+		{Name: "CNN"},
+		{Name: "US: HBO"},
+		{Name: "BBC One"},
+	}
+
+	filtered, channelMap := Filter(newTestLogger(), source, m3uChannels)
+
+	report := AnalyzeMatches(m3uChannels, source.Channels, filtered, channelMap)
+
+	require.Equal(t, 4, report.Summary.Total)
+	require.Equal(t, 3, report.Summary.Matched)
+	require.Equal(t, 1, report.Summary.Unmatched)
+	require.InDelta(t, 0.75, report.Summary.MatchRate, 0.001)
+	require.Equal(t, map[string]int{
+		MatchTVGID:          1,
+		MatchDisplayName:    1,
+		MatchWhitespace:     0,
+		MatchNormalizedName: 1,
+	}, report.Summary.ByStrategy)
+	require.Equal(t, 2, report.Summary.WithProgrammes)
+	require.Equal(t, 1, report.Summary.WithoutProgrammes)
+
+	strategies := make(map[string]string)
+	for _, ch := range report.Matched {
+		strategies[ch.Name] = ch.Strategy
+	}
+
+	require.Equal(t, MatchTVGID, strategies["ESPN HD"])
+	require.Equal(t, MatchDisplayName, strategies["CNN"])
+	require.Equal(t, MatchNormalizedName, strategies["US: HBO"])
+
+	// The placeholder channel is reported as unmatched, with close matches.
+	require.Len(t, report.Unmatched, 1)
+	require.Equal(t, "BBC One", report.Unmatched[0].Name)
+	require.Equal(t, []string{"BBC One London"}, report.Unmatched[0].CloseMatches)
+}
+
+func TestDiffReports(t *testing.T) {
+	baseline := &MatchReport{
+		Matched: []MatchedChannel{
+			{Name: "ESPN", Strategy: MatchTVGID},
+			{Name: "CNN", Strategy: MatchDisplayName},
+			{Name: "HBO", Strategy: MatchNormalizedName},
+		},
+		Unmatched: []UnmatchedChannel{{Name: "BBC One"}, {Name: "ITV"}},
+	}
+	current := &MatchReport{
+		Matched: []MatchedChannel{
+			{Name: "ESPN", Strategy: MatchTVGID},
+			{Name: "CNN", Strategy: MatchNormalizedName},
+			{Name: "BBC One", Strategy: MatchNormalizedName},
+			{Name: "New Channel", Strategy: MatchDisplayName},
+		},
+		Unmatched: []UnmatchedChannel{{Name: "HBO"}, {Name: "ITV"}},
+	}
+
+	diff := DiffReports(baseline, current)
+
+	require.Equal(t, []string{"BBC One"}, diff.NewlyMatched)
+	require.Equal(t, []string{"HBO"}, diff.NewlyUnmatched)
+	require.Equal(t, []StrategyChange{{Name: "CNN", From: MatchDisplayName, To: MatchNormalizedName}}, diff.StrategyChanged)
+	require.True(t, diff.Regressed())
+
+	require.False(t, DiffReports(current, current).Regressed())
+}
@@ -0,0 +1,67 @@
+package epg
+
+import (
+	"time"
+
+	"github.com/savid/iptv/internal/m3u"
+	"github.com/sirupsen/logrus"
+)
+
+// stalePlaceholderWindow is how long the placeholder added to a stale channel
+// lasts.
+const stalePlaceholderWindow = 24 * time.Hour
+
+// FillStaleChannels returns a copy of the EPG in which every channel without a
+// programme ending after now gets a placeholder programme starting at the
+// current hour. Such channels (e.g. whose guide data is entirely in the past)
+// otherwise look unmatched to clients. channelMap (EPG ID → M3U name) is used
+// to title the placeholders, and categories to map M3U groups to their
+// programme categories. Programmes with unparseable stop times count as current. The
+// input is not modified.
+func FillStaleChannels(
+	log logrus.FieldLogger,
+	tv *TV,
+	m3uChannels []m3u.Channel,
+	channelMap map[string]string,
+	categories map[string]string,
+	now time.Time,
+) *TV {
+	current := make(map[string]bool, len(tv.Channels))
+
+	for _, prog := range tv.Programs {
+		stop, err := ParseTime(prog.Stop)
+		if err != nil || stop.After(now) {
+			current[prog.Channel] = true
+		}
+	}
+
+	categoryMap := buildCategoryMap(m3uChannels, categories)
+	start := now.UTC().Truncate(time.Hour)
+	stop := start.Add(stalePlaceholderWindow)
+
+	placeholders := make([]Programme, 0)
+
+	for _, ch := range tv.Channels {
+		if current[ch.ID] {
+			continue
+		}
+
+		placeholders = append(placeholders, placeholderProgramme(ch, categoryMap, channelMap, start, stop))
+	}
+
+	if len(placeholders) == 0 {
+		return tv
+	}
+
+	log.WithField("count", len(placeholders)).Info("Added placeholder programmes for channels with no current guide data")
+
+	programs := make([]Programme, 0, len(tv.Programs)+len(placeholders))
+	programs = append(programs, tv.Programs...)
+	programs = append(programs, placeholders...)
+
+	return &TV{
+		XMLName:  tv.XMLName,
+		Channels: tv.Channels,
+		Programs: programs,
+	}
+}
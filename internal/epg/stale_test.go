@@ -0,0 +1,55 @@
+package epg
+
+import (
+	"testing"
+	"time"
+
+	"github.com/savid/iptv/internal/m3u"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFillStaleChannels(t *testing.T) {
+	now := time.Date(2026, 3, 10, 14, 25, 0, 0, time.UTC)
+
+	tv := &TV{
+		Channels: []Channel{
+			{ID: "old.us", DisplayName: "Old"},
+			{ID: "live.us", DisplayName: "Live"},
+		},
+		Programs: []Programme{
+			{Channel: "old.us", Start: "20260309100000 +0000", Stop: "20260309110000 +0000", Title: "Yesterday"},
+			{Channel: "live.us", Start: "20260310140000 +0000", Stop: "20260310150000 +0000", Title: "Now"},
+		},
+	}
+	m3uChannels := []m3u.Channel{{Name: "Old Channel", Group: "News"}, {Name: "Live"}}
+	channelMap := map[string]string{"old.us": "Old Channel", "live.us": "Live"}
+
+	filled := FillStaleChannels(logrus.New(), tv, m3uChannels, channelMap, nil, now)
+
+	require.Len(t, filled.Programs, 3)
+	require.Equal(t, Programme{
+		Channel:     "old.us",
+		Start:       FormatTime(time.Date(2026, 3, 10, 14, 0, 0, 0, time.UTC)),
+		Stop:        FormatTime(time.Date(2026, 3, 11, 14, 0, 0, 0, time.UTC)),
+		Title:       "Old Channel",
+		Description: PlaceholderDescription,
+		Category:    "News",
+	}, filled.Programs[2])
+
+	// The input is not modified.
+	require.Len(t, tv.Programs, 2)
+}
+
+func TestFillStaleChannels_NothingStale(t *testing.T) {
+	now := time.Date(2026, 3, 10, 14, 25, 0, 0, time.UTC)
+
+	tv := &TV{
+		Channels: []Channel{{ID: "live.us", DisplayName: "Live"}},
+		Programs: []Programme{
+			{Channel: "live.us", Start: "20260310140000 +0000", Stop: "20260310150000 +0000", Title: "Now"},
+		},
+	}
+
+	require.Same(t, tv, FillStaleChannels(logrus.New(), tv, nil, nil, nil, now))
+}
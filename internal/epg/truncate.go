@@ -0,0 +1,63 @@
+package epg
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// ellipsis is appended to truncated descriptions.
+const ellipsis = "…"
+
+// TruncateDescriptions returns a copy of the EPG with programme descriptions
+// longer than maxLength characters cut at a word boundary and suffixed with
+// "…". The input is not modified. A maxLength of 0 or less returns tv as is.
+func TruncateDescriptions(tv *TV, maxLength int) *TV {
+	if maxLength <= 0 {
+		return tv
+	}
+
+	programs := make([]Programme, len(tv.Programs))
+	copy(programs, tv.Programs)
+
+	for i := range programs {
+		programs[i].Description = truncateAtWord(programs[i].Description, maxLength)
+	}
+
+	return &TV{
+		XMLName:  tv.XMLName,
+		Channels: tv.Channels,
+		Programs: programs,
+	}
+}
+
+// truncateAtWord shortens s to at most maxLength characters (including the
+// ellipsis), breaking at the last whitespace that fits. A single word longer
+// than the limit is cut mid-word.
+func truncateAtWord(s string, maxLength int) string {
+	if utf8.RuneCountInString(s) <= maxLength {
+		return s
+	}
+
+	runes := []rune(s)
+	limit := maxLength - utf8.RuneCountInString(ellipsis)
+
+	if limit <= 0 {
+		return string(runes[:maxLength])
+	}
+
+	cut := limit
+
+	// Back up to the last whitespace so the final word isn't split.
+	for i := limit; i > 0; i-- {
+		if unicode.IsSpace(runes[i]) {
+			cut = i
+
+			break
+		}
+	}
+
+	return strings.TrimRightFunc(string(runes[:cut]), func(r rune) bool {
+		return unicode.IsSpace(r) || unicode.IsPunct(r)
+	}) + ellipsis
+}
@@ -0,0 +1,39 @@
+package epg
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTruncateDescriptions(t *testing.T) {
+	tv := &TV{
+		Programs: []Programme{
+			{Channel: "a", Description: "The quick brown fox jumps over the lazy dog."},
+			{Channel: "b", Description: "Short."},
+			{Channel: "c", Description: "Supercalifragilisticexpialidocious"},
+		},
+	}
+
+	truncated := TruncateDescriptions(tv, 20)
+
+	// Cut at the last word boundary that fits, ellipsis included.
+	require.Equal(t, "The quick brown fox…", truncated.Programs[0].Description)
+	require.Equal(t, "Short.", truncated.Programs[1].Description)
+
+	// A single over-long word is cut mid-word.
+	require.Equal(t, "Supercalifragilisti…", truncated.Programs[2].Description)
+
+	// The stored data is left intact.
+	require.Equal(t, "The quick brown fox jumps over the lazy dog.", tv.Programs[0].Description)
+}
+
+func TestTruncateDescriptions_Unlimited(t *testing.T) {
+	tv := &TV{Programs: []Programme{{Description: "Anything at all"}}}
+
+	require.Same(t, tv, TruncateDescriptions(tv, 0))
+}
+
+func TestTruncateAtWord_TrailingPunctuation(t *testing.T) {
+	require.Equal(t, "Breaking news…", truncateAtWord("Breaking news, more at eleven", 16))
+}
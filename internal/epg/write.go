@@ -0,0 +1,118 @@
+package epg
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+)
+
+// writeFlushEvery is how many channels and programmes Write encodes between
+// flushes to the underlying writer.
+const writeFlushEvery = 500
+
+// flusher is implemented by writers that can push buffered data to the
+// client, such as http.ResponseWriter.
+type flusher interface {
+	Flush()
+}
+
+// Write streams the TV structure as XML to w, producing the same output as
+// Marshal without building the whole document in memory.
+func Write(w io.Writer, tv *TV) error {
+	return WriteWithOptions(w, tv, MarshalOptions{
+		GeneratorName: DefaultGeneratorName,
+		GeneratorURL:  DefaultGeneratorURL,
+	})
+}
+
+// WriteWithOptions streams the TV structure like Write, applying opts. The
+// output matches MarshalWithOptions. tv is not modified.
+func WriteWithOptions(w io.Writer, tv *TV, opts MarshalOptions) error {
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return fmt.Errorf("failed to write EPG XML: %w", err)
+	}
+
+	start := xml.StartElement{Name: xml.Name{Local: "tv"}}
+
+	for _, attr := range []xml.Attr{
+		{Name: xml.Name{Local: "generator-info-name"}, Value: generatorValue(tv.GeneratorInfoName, opts.GeneratorName)},
+		{Name: xml.Name{Local: "generator-info-url"}, Value: generatorValue(tv.GeneratorInfoURL, opts.GeneratorURL)},
+	} {
+		if attr.Value != "" {
+			start.Attr = append(start.Attr, attr)
+		}
+	}
+
+	enc := xml.NewEncoder(w)
+
+	if !opts.Compact {
+		enc.Indent("", "  ")
+	}
+
+	written := 0
+
+	flush := func() error {
+		written++
+
+		if written%writeFlushEvery != 0 {
+			return nil
+		}
+
+		if err := enc.Flush(); err != nil {
+			return err
+		}
+
+		if f, ok := w.(flusher); ok {
+			f.Flush()
+		}
+
+		return nil
+	}
+
+	if err := enc.EncodeToken(start); err != nil {
+		return fmt.Errorf("failed to write EPG XML: %w", err)
+	}
+
+	channelStart := xml.StartElement{Name: xml.Name{Local: "channel"}}
+
+	for _, ch := range tv.Channels {
+		if err := enc.EncodeElement(ch, channelStart); err != nil {
+			return fmt.Errorf("failed to write EPG XML: %w", err)
+		}
+
+		if err := flush(); err != nil {
+			return fmt.Errorf("failed to write EPG XML: %w", err)
+		}
+	}
+
+	programmeStart := xml.StartElement{Name: xml.Name{Local: "programme"}}
+
+	for i := range tv.Programs {
+		if err := enc.EncodeElement(&tv.Programs[i], programmeStart); err != nil {
+			return fmt.Errorf("failed to write EPG XML: %w", err)
+		}
+
+		if err := flush(); err != nil {
+			return fmt.Errorf("failed to write EPG XML: %w", err)
+		}
+	}
+
+	if err := enc.EncodeToken(start.End()); err != nil {
+		return fmt.Errorf("failed to write EPG XML: %w", err)
+	}
+
+	if err := enc.Close(); err != nil {
+		return fmt.Errorf("failed to write EPG XML: %w", err)
+	}
+
+	return nil
+}
+
+// generatorValue returns the TV's own generator attribute, or fallback.
+func generatorValue(own, fallback string) string {
+	if own != "" {
+		return own
+	}
+
+	return fallback
+}
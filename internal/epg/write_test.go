@@ -0,0 +1,96 @@
+package epg
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// flushRecorder is a buffer that counts Flush calls.
+type flushRecorder struct {
+	bytes.Buffer
+	flushes int
+}
+
+func (f *flushRecorder) Flush() {
+	f.flushes++
+}
+
+func TestWrite_MatchesMarshal(t *testing.T) {
+	tv := &TV{
+		Channels: []Channel{
+			{
+				ID:          "espn.us",
+				DisplayName: "ESPN & Friends",
+				Icon:        Icon{Src: "http://logo.example.com/espn.png", Width: 100},
+				URLs:        []string{"http://espn.example.com"},
+				AltNames:    []string{"ESPN (2)"},
+				GuideNumber: "1.1",
+			},
+			{ID: "cnn.us", DisplayName: "CNN"},
+		},
+		Programs: []Programme{
+			{
+				Channel:         "espn.us",
+				Start:           "20260104120000 +0000",
+				Stop:            "20260104130000 +0000",
+				Title:           "SportsCenter",
+				SubTitle:        "Episode <1>",
+				Description:     "Sports news",
+				Category:        "Sports",
+				Video:           &Video{Aspect: "16:9", Quality: "HDTV"},
+				PreviouslyShown: &PreviouslyShown{Start: "20250101000000 +0000"},
+				StarRating:      &StarRating{Value: "4/5"},
+			},
+			{Channel: "cnn.us", Start: "20260104120000 +0000", Title: "News", New: &Flag{}},
+		},
+	}
+
+	for name, opts := range map[string]MarshalOptions{
+		"defaults": {GeneratorName: DefaultGeneratorName, GeneratorURL: DefaultGeneratorURL},
+		"none":     {},
+		"compact":  {GeneratorName: DefaultGeneratorName, Compact: true},
+	} {
+		t.Run(name, func(t *testing.T) {
+			buffered, err := MarshalWithOptions(tv, opts)
+			require.NoError(t, err)
+
+			var streamed bytes.Buffer
+			require.NoError(t, WriteWithOptions(&streamed, tv, opts))
+			require.Equal(t, string(buffered), streamed.String())
+		})
+	}
+}
+
+func TestWrite_EmptyTV(t *testing.T) {
+	tv := &TV{GeneratorInfoName: "upstream"}
+
+	buffered, err := Marshal(tv)
+	require.NoError(t, err)
+
+	var streamed bytes.Buffer
+	require.NoError(t, Write(&streamed, tv))
+	require.Equal(t, string(buffered), streamed.String())
+}
+
+func TestWrite_FlushesPeriodically(t *testing.T) {
+	tv := &TV{}
+
+	for i := range 3 * writeFlushEvery {
+		tv.Programs = append(tv.Programs, Programme{
+			Channel: "ch",
+			Start:   fmt.Sprintf("202601041%05d +0000", i),
+			Title:   "Show",
+		})
+	}
+
+	buffered, err := Marshal(tv)
+	require.NoError(t, err)
+
+	var streamed flushRecorder
+	require.NoError(t, Write(&streamed, tv))
+	require.Equal(t, string(buffered), streamed.String())
+	require.Equal(t, 3, streamed.flushes)
+}
@@ -4,13 +4,21 @@ package hdhr
 import (
 	"encoding/json"
 	"encoding/xml"
+	"errors"
 	"fmt"
+	"hash/fnv"
+	"io"
+	"net"
 	"net/http"
-	"regexp"
+	"net/url"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/savid/iptv/internal/config"
 	"github.com/savid/iptv/internal/data"
+	"github.com/savid/iptv/internal/epg"
+	"github.com/savid/iptv/internal/m3u"
 	"github.com/sirupsen/logrus"
 )
 
@@ -60,6 +68,8 @@ type LineupItem struct {
 	GuideNumber string `json:"GuideNumber"`
 	GuideName   string `json:"GuideName"`
 	URL         string `json:"URL"`
+	HD          int    `json:"HD,omitempty"`
+	ImageURL    string `json:"ImageURL,omitempty"`
 }
 
 // LineupStatus represents the lineup scanning status.
@@ -67,10 +77,22 @@ type LineupItem struct {
 //
 //nolint:tagliatelle // HDHomeRun protocol requires PascalCase JSON field names
 type LineupStatus struct {
-	ScanInProgress int      `json:"ScanInProgress"`
-	ScanPossible   int      `json:"ScanPossible"`
-	Source         string   `json:"Source"`
-	SourceList     []string `json:"SourceList"`
+	ScanInProgress int           `json:"ScanInProgress"`
+	ScanPossible   int           `json:"ScanPossible"`
+	Source         string        `json:"Source"`
+	SourceList     []string      `json:"SourceList"`
+	Tuners         []TunerStatus `json:"Tuners,omitempty"` // With --tuner-locks
+}
+
+// TunerStatus is one of a device's tuners, named and described as in the
+// HDHomeRun status.json.
+type TunerStatus struct {
+	Resource  string    `json:"Resource"` // e.g. "tuner0"
+	InUse     bool      `json:"InUse"`
+	VctNumber string    `json:"VctNumber,omitempty"`
+	VctName   string    `json:"VctName,omitempty"`
+	TargetIP  string    `json:"TargetIP,omitempty"`
+	Since     time.Time `json:"Since,omitzero"`
 }
 
 // Handlers provides HTTP handlers for HDHomeRun emulation.
@@ -79,8 +101,25 @@ type Handlers struct {
 	cfg      *config.Config
 	store    *data.Store
 	group    string // Group name filter (empty = all channels)
+	shard    int    // 1-based sub-tuner of the group (0 = the whole group)
+	name     string // Device name suffix (empty for the root device)
 	deviceID string // Unique device ID for this handler
 	baseURL  string // Base URL including group path prefix
+	client   *http.Client
+	urls     URLTransformer
+	clientIP func(*http.Request) net.IP // Resolves the requesting client
+
+	offlineClip *OfflineClip // Looped when a proxied stream fails, may be nil
+}
+
+// newStreamClient returns the HTTP client used to relay upstream streams.
+// There is deliberately no overall timeout since streams run indefinitely;
+// only the wait for response headers is bounded.
+func newStreamClient(cfg *config.Config) *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone() //nolint:forcetypeassert // DefaultTransport is always *http.Transport
+	transport.ResponseHeaderTimeout = cfg.StreamTimeout
+
+	return &http.Client{Transport: transport}
 }
 
 // NewHandlers creates a new HDHomeRun handlers instance for all channels (root device).
@@ -92,21 +131,84 @@ func NewHandlers(log logrus.FieldLogger, cfg *config.Config, store *data.Store)
 		group:    "",
 		deviceID: cfg.DeviceID,
 		baseURL:  cfg.BaseURL,
+		client:   newStreamClient(cfg),
+		urls:     newURLTransformer(cfg),
+		clientIP: remoteIP,
 	}
 }
 
 // NewGroupHandlers creates a new HDHomeRun handlers instance for a specific group.
 func NewGroupHandlers(log logrus.FieldLogger, cfg *config.Config, store *data.Store, group string) *Handlers {
-	slug := Slugify(group)
+	slug := store.GroupSlug(group)
 
 	return &Handlers{
 		log:      log.WithFields(logrus.Fields{"component": "hdhr", "group": group}),
 		cfg:      cfg,
 		store:    store,
 		group:    group,
+		name:     group,
 		deviceID: fmt.Sprintf("iptv-%s", slug),
 		baseURL:  fmt.Sprintf("%s/%s", cfg.BaseURL, slug),
+		client:   newStreamClient(cfg),
+		urls:     newURLTransformer(cfg),
+		clientIP: remoteIP,
+	}
+}
+
+// NewShardHandlers creates a new HDHomeRun handlers instance for a sub-tuner
+// holding a slice of a large group's (or the root's) channels.
+func NewShardHandlers(log logrus.FieldLogger, cfg *config.Config, store *data.Store, shard data.Shard) *Handlers {
+	return &Handlers{
+		log:      log.WithFields(logrus.Fields{"component": "hdhr", "group": shard.Group, "shard": shard.Index}),
+		cfg:      cfg,
+		store:    store,
+		group:    shard.Group,
+		shard:    shard.Index,
+		name:     shard.Name,
+		deviceID: fmt.Sprintf("iptv-%s", shard.Slug),
+		baseURL:  fmt.Sprintf("%s/%s", cfg.BaseURL, shard.Slug),
+		client:   newStreamClient(cfg),
+		urls:     newURLTransformer(cfg),
+		clientIP: remoteIP,
+	}
+}
+
+// Group returns the group name this handler serves (empty for the root device).
+func (h *Handlers) Group() string {
+	return h.group
+}
+
+// Shard returns the 1-based sub-tuner this handler serves, or 0 when it
+// serves the whole group.
+func (h *Handlers) Shard() int {
+	return h.shard
+}
+
+// IsRoot returns true for the root device, which serves every channel.
+func (h *Handlers) IsRoot() bool {
+	return h.group == "" && h.shard == 0
+}
+
+// SetURLTransformer replaces the transformer applied to stream URLs when
+// tuning.
+func (h *Handlers) SetURLTransformer(t URLTransformer) {
+	h.urls = t
+}
+
+// SetClientIP replaces the function resolving a request's client IP, which
+// defaults to the direct peer's address.
+func (h *Handlers) SetClientIP(fn func(*http.Request) net.IP) {
+	h.clientIP = fn
+}
+
+// remoteIP returns the IP of the request's direct peer.
+func remoteIP(r *http.Request) net.IP {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
 	}
+
+	return net.ParseIP(host)
 }
 
 // DeviceID returns the device ID for this handler.
@@ -117,28 +219,62 @@ func (h *Handlers) DeviceID() string {
 // Slugify converts a group name to a URL-safe slug.
 // Example: "US Sports" -> "us-sports".
 func Slugify(s string) string {
-	s = strings.TrimSpace(s)
-	s = strings.ToLower(s)
-	s = strings.ReplaceAll(s, " ", "-")
-	s = strings.ReplaceAll(s, "_", "-")
+	return data.Slugify(s)
+}
 
-	reg := regexp.MustCompile(`[^a-z0-9-]+`)
-	s = reg.ReplaceAllString(s, "")
+// Default identities advertised when no --model-rule matches the client.
+const (
+	defaultDeviceModel    = "HDTC-2US"
+	defaultDiscoveryModel = "1.0"
+	defaultFirmwareName   = "bin_1.0"
+)
+
+// modelRule returns the --model-rule matching the request's User-Agent.
+func (h *Handlers) modelRule(r *http.Request) (config.ModelRule, bool) {
+	rules, err := h.cfg.ModelRuleList()
+	if err != nil {
+		h.log.WithError(err).Warn("Ignoring invalid model rules")
 
-	// Collapse multiple hyphens and trim leading/trailing hyphens
-	reg = regexp.MustCompile(`-+`)
-	s = reg.ReplaceAllString(s, "-")
+		return config.ModelRule{}, false
+	}
 
-	return strings.Trim(s, "-")
+	return config.MatchModelRule(rules, r.UserAgent())
 }
 
-// RootXML serves the UPnP device description at /.
-func (h *Handlers) RootXML(w http.ResponseWriter, _ *http.Request) {
-	friendlyName := h.cfg.DeviceName
-	if h.group != "" {
-		friendlyName = fmt.Sprintf("%s (%s)", h.cfg.DeviceName, h.group)
+// identity returns the device ID and friendly name advertised to r. A
+// request with lineup filter parameters describes a distinct device, so Plex
+// doesn't mistake a filtered lineup for the full one.
+func (h *Handlers) identity(r *http.Request) (string, string) {
+	deviceID := h.deviceID
+	name := h.name
+
+	if query := lineupQuery(r); query != "" {
+		hash := fnv.New32a()
+		_, _ = hash.Write([]byte(query))
+		deviceID = fmt.Sprintf("%s-%08x", deviceID, hash.Sum32())
+
+		filters := make([]string, 0, 2)
+
+		for _, param := range []string{lineupGroupParam, lineupNameParam} {
+			if value := r.URL.Query().Get(param); value != "" {
+				filters = append(filters, fmt.Sprintf("%s=%s", param, value))
+			}
+		}
+
+		name = strings.TrimSpace(name + " " + strings.Join(filters, " "))
+	}
+
+	if name == "" {
+		return deviceID, h.cfg.DeviceName
 	}
 
+	return deviceID, fmt.Sprintf("%s (%s)", h.cfg.DeviceName, name)
+}
+
+// RootXML serves the UPnP device description at /.
+func (h *Handlers) RootXML(w http.ResponseWriter, r *http.Request) {
+	deviceID, friendlyName := h.identity(r)
+
 	device := DeviceXML{
 		Xmlns:   "urn:schemas-upnp-org:device-1-0",
 		URLBase: h.baseURL,
@@ -148,10 +284,16 @@ func (h *Handlers) RootXML(w http.ResponseWriter, _ *http.Request) {
 	device.Device.DeviceType = "urn:schemas-upnp-org:device:MediaServer:1"
 	device.Device.FriendlyName = friendlyName
 	device.Device.Manufacturer = "Silicondust"
-	device.Device.ModelName = "HDTC-2US"
-	device.Device.ModelNumber = "HDTC-2US"
-	device.Device.SerialNumber = h.deviceID
-	device.Device.UDN = fmt.Sprintf("uuid:%s", h.deviceID)
+	device.Device.ModelName = defaultDeviceModel
+	device.Device.ModelNumber = defaultDeviceModel
+
+	if rule, ok := h.modelRule(r); ok {
+		device.Device.ModelName = rule.ModelNumber
+		device.Device.ModelNumber = rule.ModelNumber
+	}
+
+	device.Device.SerialNumber = deviceID
+	device.Device.UDN = fmt.Sprintf("uuid:%s", deviceID)
 
 	w.Header().Set("Content-Type", "application/xml")
 	w.WriteHeader(http.StatusOK)
@@ -173,24 +315,29 @@ func (h *Handlers) RootXML(w http.ResponseWriter, _ *http.Request) {
 }
 
 // Discovery serves device discovery JSON at /discover.json and /discovery.json.
-func (h *Handlers) Discovery(w http.ResponseWriter, _ *http.Request) {
-	friendlyName := h.cfg.DeviceName
-	if h.group != "" {
-		friendlyName = fmt.Sprintf("%s (%s)", h.cfg.DeviceName, h.group)
-	}
+func (h *Handlers) Discovery(w http.ResponseWriter, r *http.Request) {
+	deviceID, friendlyName := h.identity(r)
 
 	discovery := DiscoveryJSON{
 		FriendlyName:    friendlyName,
 		Manufacturer:    "Golang",
 		ManufacturerURL: "https://github.com/savid/iptv",
-		ModelNumber:     "1.0",
-		FirmwareName:    "bin_1.0",
+		ModelNumber:     defaultDiscoveryModel,
+		FirmwareName:    defaultFirmwareName,
 		TunerCount:      h.cfg.TunerCount,
 		FirmwareVersion: "1.0",
-		DeviceID:        h.deviceID,
-		DeviceAuth:      "iptv-proxy",
+		DeviceID:        deviceID,
+		DeviceAuth:      h.cfg.DeviceAuth,
 		BaseURL:         h.baseURL,
-		LineupURL:       fmt.Sprintf("%s/lineup.json", h.baseURL),
+		LineupURL:       fmt.Sprintf("%s/lineup.json%s", h.baseURL, lineupQuery(r)),
+	}
+
+	if rule, ok := h.modelRule(r); ok {
+		discovery.ModelNumber = rule.ModelNumber
+
+		if rule.FirmwareName != "" {
+			discovery.FirmwareName = rule.FirmwareName
+		}
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -203,35 +350,138 @@ func (h *Handlers) Discovery(w http.ResponseWriter, _ *http.Request) {
 	}
 }
 
+// Channels returns the channels this handler serves, in lineup order. The
+// position of each channel determines its /auto/v{n} tuning number.
+func (h *Handlers) Channels() ([]m3u.Channel, bool) {
+	var (
+		channels []m3u.Channel
+		ok       bool
+	)
+
+	if h.shard > 0 {
+		channels, ok = h.store.GetChannelsByShard(h.group, h.shard)
+	} else {
+		channels, ok = h.store.GetChannelsByGroup(h.group)
+		if ok && h.group == "" && h.cfg.DedupeRootLineup {
+			channels = dedupeByURL(channels)
+		}
+	}
+
+	if ok && h.cfg.DuplicateNames == config.DuplicateNamesMerge {
+		channels = mergeByName(channels)
+	}
+
+	return channels, ok
+}
+
+// mergeByName drops channels whose name was already seen, keeping the first
+// occurrence.
+func mergeByName(channels []m3u.Channel) []m3u.Channel {
+	seen := make(map[string]bool, len(channels))
+	merged := make([]m3u.Channel, 0, len(channels))
+
+	for _, ch := range channels {
+		if seen[ch.Name] {
+			continue
+		}
+
+		seen[ch.Name] = true
+		merged = append(merged, ch)
+	}
+
+	return merged
+}
+
+// dedupeByURL drops channels whose stream URL was already seen, keeping the
+// first occurrence. Providers list a channel once per group it belongs to.
+func dedupeByURL(channels []m3u.Channel) []m3u.Channel {
+	seen := make(map[string]bool, len(channels))
+	deduped := make([]m3u.Channel, 0, len(channels))
+
+	for _, ch := range channels {
+		if seen[ch.URL] {
+			continue
+		}
+
+		seen[ch.URL] = true
+		deduped = append(deduped, ch)
+	}
+
+	return deduped
+}
+
 // Lineup serves channel lineup at /lineup.json.
-func (h *Handlers) Lineup(w http.ResponseWriter, _ *http.Request) {
-	channels, ok := h.store.GetChannelsByGroup(h.group)
+//
+// The lineup can be narrowed per request with ?group= (group-title, case
+// insensitive) and ?q= (channel name substring, case insensitive); guide
+// numbers then run from 1 within the filtered set.
+func (h *Handlers) Lineup(w http.ResponseWriter, r *http.Request) {
+	channels, ok := h.Channels()
 	if !ok || len(channels) == 0 {
 		http.Error(w, "No channels available", http.StatusServiceUnavailable)
 
 		return
 	}
 
+	// Tuning numbers address the unfiltered lineup; guide numbers are
+	// renumbered within the filtered set.
+	tuneNumbers := h.GuideNumbers(channels)
+	matches := filterLineup(channels, r.URL.Query())
+
+	filtered := make([]m3u.Channel, 0, len(matches))
+	for _, i := range matches {
+		filtered = append(filtered, channels[i])
+	}
+
+	channels = filtered
+	numbers := h.GuideNumbers(channels)
+
 	lineup := make([]LineupItem, 0, len(channels))
 
 	// Track name occurrences to suffix duplicates
 	nameCount := make(map[string]int, len(channels))
+	health := h.store.ChannelHealth()
 
 	for i, channel := range channels {
 		guideName := channel.Name
 
-		// If we've seen this name before, suffix it
-		if count := nameCount[channel.Name]; count > 0 {
-			guideName = fmt.Sprintf("%s (%d)", channel.Name, count+1)
+		// If we've seen this name before, suffix it; the EPG carries the
+		// suffixed names too (see epg.AddDuplicateNames).
+		nameCount[channel.Name]++
+
+		if h.cfg.DuplicateNames == config.DuplicateNamesSuffix {
+			guideName = epg.DuplicateName(channel.Name, nameCount[channel.Name])
 		}
 
-		nameCount[channel.Name]++
+		// Dead channels are left out of the lineup only, so the remaining
+		// channels keep their guide and tuning numbers.
+		if h.cfg.HideDeadChannels && health.Dead(channel.URL, h.cfg.DeadAfter) {
+			continue
+		}
 
-		lineup = append(lineup, LineupItem{
-			GuideNumber: fmt.Sprintf("%d", i+1),
+		item := LineupItem{
+			GuideNumber: numbers[i],
 			GuideName:   guideName,
 			URL:         channel.URL,
-		})
+		}
+
+		// Proxied streams are tuned through this device so the proxy can
+		// apply its upstream and tuner limits.
+		if h.cfg.ProxyStreams {
+			item.URL = h.baseURL + "/auto/v" + tuneNumbers[matches[i]]
+		}
+
+		if h.cfg.LineupHDFlag && epg.IsHD(channel, h.cfg.QualityRanking) {
+			item.HD = 1
+		}
+
+		// Data URIs left after fetching (--data-uri-logos pass) would bloat
+		// lineup.json, and clients expect a fetchable URL.
+		if h.cfg.LineupLogos && !data.IsDataURI(channel.TVGLogo) {
+			item.ImageURL = channel.TVGLogo
+		}
+
+		lineup = append(lineup, item)
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -244,6 +494,53 @@ func (h *Handlers) Lineup(w http.ResponseWriter, _ *http.Request) {
 	}
 }
 
+// Query parameters that filter /lineup.json.
+const (
+	lineupGroupParam = "group"
+	lineupNameParam  = "q"
+)
+
+// filterLineup returns the indices of the channels matching the lineup filter
+// parameters in query, or of every channel when no filter is set.
+func filterLineup(channels []m3u.Channel, query url.Values) []int {
+	group := strings.TrimSpace(query.Get(lineupGroupParam))
+	name := strings.ToLower(strings.TrimSpace(query.Get(lineupNameParam)))
+
+	filtered := make([]int, 0, len(channels))
+
+	for i, ch := range channels {
+		if group != "" && !strings.EqualFold(ch.Group, group) {
+			continue
+		}
+
+		if name != "" && !strings.Contains(strings.ToLower(ch.Name), name) {
+			continue
+		}
+
+		filtered = append(filtered, i)
+	}
+
+	return filtered
+}
+
+// lineupQuery returns the lineup filter parameters of r as a query string
+// suffix ("?group=..."), or "" when none are set.
+func lineupQuery(r *http.Request) string {
+	query := url.Values{}
+
+	for _, param := range []string{lineupGroupParam, lineupNameParam} {
+		if value := r.URL.Query().Get(param); value != "" {
+			query.Set(param, value)
+		}
+	}
+
+	if len(query) == 0 {
+		return ""
+	}
+
+	return "?" + query.Encode()
+}
+
 // LineupStatus serves the lineup scanning status at /lineup_status.json.
 func (h *Handlers) LineupStatus(w http.ResponseWriter, _ *http.Request) {
 	status := LineupStatus{
@@ -253,6 +550,10 @@ func (h *Handlers) LineupStatus(w http.ResponseWriter, _ *http.Request) {
 		SourceList:     []string{"Cable"},
 	}
 
+	if h.cfg.TunerLocks {
+		status.Tuners = h.tunerStatus()
+	}
+
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 
@@ -263,8 +564,105 @@ func (h *Handlers) LineupStatus(w http.ResponseWriter, _ *http.Request) {
 	}
 }
 
+// tunerStatus lists this device's tuners and the streams reserving them.
+func (h *Handlers) tunerStatus() []TunerStatus {
+	leases := h.store.Tuners().Snapshot(h.deviceID, h.cfg.TunerCount)
+	tuners := make([]TunerStatus, 0, len(leases))
+
+	for i, lease := range leases {
+		tuner := TunerStatus{Resource: fmt.Sprintf("tuner%d", i)}
+
+		if lease != nil {
+			tuner.InUse = true
+			tuner.VctNumber = lease.Channel
+			tuner.VctName = lease.Name
+			tuner.Since = lease.Since
+
+			if host, _, err := net.SplitHostPort(lease.Client); err == nil {
+				tuner.TargetIP = host
+			} else {
+				tuner.TargetIP = lease.Client
+			}
+		}
+
+		tuners = append(tuners, tuner)
+	}
+
+	return tuners
+}
+
+// ownsPathPrefix returns true if prefix (the path before "/auto/v") addresses
+// this handler's device.
+func (h *Handlers) ownsPathPrefix(prefix string) bool {
+	if h.shard > 0 {
+		slug, ok := h.store.ShardSlug(h.group, h.shard)
+
+		return ok && prefix == "/"+slug
+	}
+
+	if h.group == "" {
+		return prefix == ""
+	}
+
+	return prefix == "/"+h.store.GroupSlug(h.group)
+}
+
+// Errors resolving a tuning number to a channel.
+var (
+	errInvalidChannelNumber = errors.New("invalid channel number")
+	errChannelNotFound      = errors.New("channel not found")
+)
+
+// GuideNumbers returns the guide number of each of channels, in order,
+// formatted with the configured --number-format.
+func (h *Handlers) GuideNumbers(channels []m3u.Channel) []string {
+	return m3u.GuideNumbers(h.cfg.NumberFormat, channels, h.store.GetGroups())
+}
+
+// channelIndex resolves a tuning number to a 0-based index into channels. A
+// formatted guide number is matched first; a plain 1-based lineup position
+// is accepted otherwise.
+func (h *Handlers) channelIndex(channels []m3u.Channel, number string) (int, error) {
+	formatted := h.cfg.NumberFormat != "" && h.cfg.NumberFormat != m3u.DefaultNumberFormat
+	if formatted {
+		for i, guideNumber := range h.GuideNumbers(channels) {
+			if guideNumber == number {
+				return i, nil
+			}
+		}
+	}
+
+	position, err := strconv.Atoi(number)
+	if err != nil {
+		if formatted {
+			return 0, errChannelNotFound
+		}
+
+		return 0, errInvalidChannelNumber
+	}
+
+	if position < 1 || position > len(channels) {
+		return 0, errChannelNotFound
+	}
+
+	return position - 1, nil
+}
+
+// writeChannelError responds to a tuning number channelIndex rejected.
+func (h *Handlers) writeChannelError(w http.ResponseWriter, number string, err error) {
+	if errors.Is(err, errInvalidChannelNumber) {
+		http.Error(w, "Invalid channel number", http.StatusBadRequest)
+
+		return
+	}
+
+	h.log.WithField("channel", number).Error("Channel not found")
+	http.Error(w, "Channel not found", http.StatusNotFound)
+}
+
 // AutoTune handles HDHomeRun-style tuning URLs at /auto/v{channel}.
-// This redirects to the upstream URL for the requested channel.
+// This redirects to the upstream URL for the requested channel, or relays
+// the stream itself when stream proxying is enabled.
 func (h *Handlers) AutoTune(w http.ResponseWriter, r *http.Request) {
 	// Extract channel number from path: /auto/v{channel} or /{group}/auto/v{channel}
 	path := r.URL.Path
@@ -277,38 +675,233 @@ func (h *Handlers) AutoTune(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// The path prefix must address this device: empty for the root device,
+	// "/{slug}" for a group device.
+	if !h.ownsPathPrefix(path[:autoIdx]) {
+		http.NotFound(w, r)
+
+		return
+	}
+
 	channelNum := path[autoIdx+7:] // Everything after "/auto/v"
 
-	channels, ok := h.store.GetChannelsByGroup(h.group)
+	channels, ok := h.Channels()
 	if !ok || len(channels) == 0 {
 		http.Error(w, "No channels available", http.StatusServiceUnavailable)
 
 		return
 	}
 
-	// Find channel by number (1-indexed)
-	var channelIdx int
-	if _, err := fmt.Sscanf(channelNum, "%d", &channelIdx); err != nil {
-		http.Error(w, "Invalid channel number", http.StatusBadRequest)
+	channelIdx, err := h.channelIndex(channels, channelNum)
+	if err != nil {
+		h.writeChannelError(w, channelNum, err)
 
 		return
 	}
 
-	if channelIdx < 1 || channelIdx > len(channels) {
-		h.log.WithField("channel", channelIdx).Error("Channel not found")
-		http.Error(w, "Channel not found", http.StatusNotFound)
+	channel := channels[channelIdx]
+
+	h.store.Tunes().Record()
+
+	log := h.log.WithFields(logrus.Fields{
+		"channel": channelNum,
+		"name":    channel.Name,
+		"group":   h.name,
+	})
+
+	streamURL, err := h.urls.TransformURL(r.Context(), channel)
+	if err != nil {
+		log.WithError(err).Error("Failed to build stream URL")
+		http.Error(w, "Stream unavailable", http.StatusBadGateway)
 
 		return
 	}
 
-	channel := channels[channelIdx-1]
+	if h.cfg.ProxyStreams {
+		log, release, reserved := h.reserveTuner(log, w, r, channelNum, channel)
+		if !reserved {
+			return
+		}
+		defer release()
 
-	h.log.WithFields(logrus.Fields{
-		"channel": channelIdx,
-		"name":    channel.Name,
-		"group":   h.group,
-	}).Debug("AutoTune redirect")
+		log.Debug("AutoTune proxy")
+		h.proxyStream(log, w, r, streamURL)
+
+		return
+	}
+
+	log.Debug("AutoTune redirect")
 
 	// Redirect directly to upstream URL
-	http.Redirect(w, r, channel.URL, http.StatusTemporaryRedirect)
+	http.Redirect(w, r, streamURL, http.StatusTemporaryRedirect)
+}
+
+// Catchup serves a past programme on a channel: /catchup/v{channel}?start=
+// &end= with Unix timestamps, as emitted in the proxied playlist's
+// catchup-source. The time-shifted upstream URL is built from the channel's
+// own catchup attributes and proxied or redirected like a live tune.
+func (h *Handlers) Catchup(w http.ResponseWriter, r *http.Request) {
+	channelNum, ok := strings.CutPrefix(r.URL.Path, "/catchup/v")
+	if !ok {
+		http.Error(w, "Invalid channel", http.StatusBadRequest)
+
+		return
+	}
+
+	channels, ok := h.Channels()
+	if !ok || len(channels) == 0 {
+		http.Error(w, "No channels available", http.StatusServiceUnavailable)
+
+		return
+	}
+
+	channelIdx, err := h.channelIndex(channels, channelNum)
+	if err != nil {
+		h.writeChannelError(w, channelNum, err)
+
+		return
+	}
+
+	start, errStart := strconv.ParseInt(r.URL.Query().Get("start"), 10, 64)
+	end, errEnd := strconv.ParseInt(r.URL.Query().Get("end"), 10, 64)
+
+	if errStart != nil || errEnd != nil || end <= start {
+		http.Error(w, "Invalid start or end time", http.StatusBadRequest)
+
+		return
+	}
+
+	channel := channels[channelIdx]
+	if !channel.HasCatchup() {
+		http.Error(w, "Catchup not available for channel", http.StatusNotFound)
+
+		return
+	}
+
+	log := h.log.WithFields(logrus.Fields{
+		"channel": channelNum,
+		"name":    channel.Name,
+		"start":   start,
+		"end":     end,
+	})
+
+	streamURL, err := h.urls.TransformURL(r.Context(), channel)
+	if err != nil {
+		log.WithError(err).Error("Failed to build stream URL")
+		http.Error(w, "Stream unavailable", http.StatusBadGateway)
+
+		return
+	}
+
+	catchupURL, err := m3u.CatchupURL(channel, streamURL, time.Unix(start, 0), time.Unix(end, 0), time.Now())
+	if err != nil {
+		log.WithError(err).Error("Failed to build catchup URL")
+		http.Error(w, "Catchup unavailable", http.StatusBadGateway)
+
+		return
+	}
+
+	if h.cfg.ProxyStreams {
+		log, release, reserved := h.reserveTuner(log, w, r, channelNum, channel)
+		if !reserved {
+			return
+		}
+		defer release()
+
+		log.Debug("Catchup proxy")
+		h.proxyStream(log, w, r, catchupURL)
+
+		return
+	}
+
+	log.Debug("Catchup redirect")
+
+	http.Redirect(w, r, catchupURL, http.StatusTemporaryRedirect)
+}
+
+// reserveTuner reserves one of the device's tuners for a proxied stream when
+// --tuner-locks is set, responding with 503 when all are in use. The returned
+// logger carries the tuner number; release frees it.
+func (h *Handlers) reserveTuner(
+	log logrus.FieldLogger,
+	w http.ResponseWriter,
+	r *http.Request,
+	channelNum string,
+	channel m3u.Channel,
+) (logrus.FieldLogger, func(), bool) {
+	if !h.cfg.TunerLocks {
+		return log, func() {}, true
+	}
+
+	client := r.RemoteAddr
+	if ip := h.clientIP(r); ip != nil {
+		client = ip.String()
+	}
+
+	tuner, release, reserved := h.store.Tuners().Reserve(h.deviceID, h.cfg.TunerCount, data.TunerLease{
+		Channel: channelNum,
+		Name:    channel.Name,
+		Client:  client,
+	})
+	if !reserved {
+		log.Warn("All tuners in use")
+		w.Header().Set("X-HDHomeRun-Error", "805 All Tuners In Use")
+		http.Error(w, "All tuners in use", http.StatusServiceUnavailable)
+
+		return log, nil, false
+	}
+
+	return log.WithField("tuner", tuner), release, true
+}
+
+// proxyStream relays the upstream stream to the client. The upstream request
+// is tied to the client's request context, so a client disconnect tears down
+// the upstream connection and ends the copy.
+func (h *Handlers) proxyStream(log logrus.FieldLogger, w http.ResponseWriter, r *http.Request, url string) {
+	req, err := http.NewRequestWithContext(r.Context(), http.MethodGet, url, nil)
+	if err != nil {
+		log.WithError(err).Error("Failed to create upstream stream request")
+		http.Error(w, "Invalid upstream URL", http.StatusBadGateway)
+
+		return
+	}
+
+	release, err := h.store.Upstreams().Acquire(r.Context(), req.URL.Host, h.cfg.MaxConnsPerHost)
+	if err != nil {
+		log.WithError(err).Debug("Client went away while queued for an upstream connection")
+
+		return
+	}
+	defer release()
+
+	resp, err := h.client.Do(req)
+	if err != nil {
+		release()
+
+		if r.Context().Err() == nil {
+			log.WithError(err).Error("Failed to connect to upstream stream")
+			h.upstreamUnavailable(log, w, r)
+		}
+
+		return
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		release()
+		log.WithField("status", resp.StatusCode).Error("Upstream stream returned error")
+		h.upstreamUnavailable(log, w, r)
+
+		return
+	}
+
+	if contentType := resp.Header.Get("Content-Type"); contentType != "" {
+		w.Header().Set("Content-Type", contentType)
+	}
+
+	w.WriteHeader(http.StatusOK)
+
+	if _, err := io.Copy(w, resp.Body); err != nil && r.Context().Err() == nil {
+		log.WithError(err).Debug("Stream copy ended")
+	}
 }
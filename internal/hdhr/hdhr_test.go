@@ -1,12 +1,21 @@
 package hdhr
 
 import (
+	"context"
 	"encoding/json"
 	"encoding/xml"
+	"fmt"
 	"io"
+	"net"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/savid/iptv/internal/config"
 	"github.com/savid/iptv/internal/data"
@@ -145,6 +154,75 @@ func TestDiscovery_ValidJSON(t *testing.T) {
 	require.Equal(t, cfg.BaseURL+"/lineup.json", discovery.LineupURL)
 }
 
+func TestDiscovery_DeviceAuth(t *testing.T) {
+	cfg := newTestConfig()
+	cfg.DeviceAuth = "custom-auth-token"
+
+	w := httptest.NewRecorder()
+	NewHandlers(newTestLogger(), cfg, data.NewStore()).Discovery(w, httptest.NewRequest(http.MethodGet, "/discover.json", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var discovery DiscoveryJSON
+
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&discovery))
+	require.Equal(t, "custom-auth-token", discovery.DeviceAuth)
+
+	// The default is unchanged.
+	w = httptest.NewRecorder()
+	NewHandlers(newTestLogger(), newTestConfig(), data.NewStore()).Discovery(w, httptest.NewRequest(http.MethodGet, "/discover.json", nil))
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&discovery))
+	require.Equal(t, "iptv-proxy", discovery.DeviceAuth)
+}
+
+func TestDiscovery_ModelRules(t *testing.T) {
+	cfg := newTestConfig()
+	cfg.ModelRules = []string{
+		"PlexMediaServer=HDHR5-4K:hdhomerun5_atsc",
+		"plex=HDHR3-US",
+	}
+
+	handlers := NewHandlers(newTestLogger(), cfg, data.NewStore())
+
+	tests := []struct {
+		name         string
+		userAgent    string
+		modelNumber  string
+		firmwareName string
+		deviceModel  string
+	}{
+		{"first rule", "PlexMediaServer/1.40.0", "HDHR5-4K", "hdhomerun5_atsc", "HDHR5-4K"},
+		{"case-insensitive fallback rule", "Plex/2.0 (Android)", "HDHR3-US", defaultFirmwareName, "HDHR3-US"},
+		{"no rule", "curl/8.5.0", defaultDiscoveryModel, defaultFirmwareName, defaultDeviceModel},
+		{"no user agent", "", defaultDiscoveryModel, defaultFirmwareName, defaultDeviceModel},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/discover.json", nil)
+			req.Header.Set("User-Agent", tt.userAgent)
+
+			w := httptest.NewRecorder()
+			handlers.Discovery(w, req)
+
+			var discovery DiscoveryJSON
+			require.NoError(t, json.NewDecoder(w.Body).Decode(&discovery))
+			require.Equal(t, tt.modelNumber, discovery.ModelNumber)
+			require.Equal(t, tt.firmwareName, discovery.FirmwareName)
+
+			req = httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Header.Set("User-Agent", tt.userAgent)
+
+			w = httptest.NewRecorder()
+			handlers.RootXML(w, req)
+
+			var device DeviceXML
+			require.NoError(t, xml.NewDecoder(w.Body).Decode(&device))
+			require.Equal(t, tt.deviceModel, device.Device.ModelName)
+			require.Equal(t, tt.deviceModel, device.Device.ModelNumber)
+		})
+	}
+}
+
 func TestDiscovery_TunerCount(t *testing.T) {
 	tests := []struct {
 		name       string
@@ -220,6 +298,86 @@ func TestLineup_ValidJSON(t *testing.T) {
 	require.Equal(t, "CNN", lineup[2].GuideName)
 }
 
+func TestLineup_QueryFilter(t *testing.T) {
+	store := data.NewStore()
+	store.SetM3U([]m3u.Channel{
+		{Name: "ESPN", URL: "http://stream.example.com/1", Group: "Sports"},
+		{Name: "CNN", URL: "http://stream.example.com/2", Group: "News"},
+		{Name: "ESPN2", URL: "http://stream.example.com/3", Group: "Sports"},
+		{Name: "Fox Sports", URL: "http://stream.example.com/4", Group: "Sports"},
+		{Name: "ESPN News", URL: "http://stream.example.com/5", Group: "News"},
+	})
+
+	handlers := NewHandlers(newTestLogger(), newTestConfig(), store)
+
+	tests := []struct {
+		name     string
+		query    string
+		expected []string
+	}{
+		{"no filter", "", []string{"ESPN", "CNN", "ESPN2", "Fox Sports", "ESPN News"}},
+		{"group", "?group=sports", []string{"ESPN", "ESPN2", "Fox Sports"}},
+		{"name substring", "?q=espn", []string{"ESPN", "ESPN2", "ESPN News"}},
+		{"group and name", "?group=News&q=ESPN", []string{"ESPN News"}},
+		{"no match", "?group=Movies", []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/lineup.json"+tt.query, nil)
+			w := httptest.NewRecorder()
+
+			handlers.Lineup(w, req)
+			require.Equal(t, http.StatusOK, w.Code)
+
+			var lineup []LineupItem
+			require.NoError(t, json.NewDecoder(w.Body).Decode(&lineup))
+
+			names := make([]string, 0, len(lineup))
+
+			for i, item := range lineup {
+				require.Equal(t, strconv.Itoa(i+1), item.GuideNumber)
+
+				names = append(names, item.GuideName)
+			}
+
+			require.Equal(t, tt.expected, names)
+		})
+	}
+}
+
+func TestDiscovery_LineupQuery(t *testing.T) {
+	cfg := newTestConfig()
+	handlers := NewHandlers(newTestLogger(), cfg, data.NewStore())
+
+	req := httptest.NewRequest(http.MethodGet, "/discover.json?q=ESPN&group=US+Sports&other=1", nil)
+	w := httptest.NewRecorder()
+
+	handlers.Discovery(w, req)
+
+	var discovery DiscoveryJSON
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&discovery))
+	require.Equal(t, cfg.BaseURL+"/lineup.json?group=US+Sports&q=ESPN", discovery.LineupURL)
+
+	// A filtered lineup is advertised as its own device.
+	require.NotEqual(t, cfg.DeviceID, discovery.DeviceID)
+	require.Contains(t, discovery.DeviceID, cfg.DeviceID+"-")
+	require.Equal(t, "IPTV-Proxy (group=US Sports q=ESPN)", discovery.FriendlyName)
+
+	other := DiscoveryJSON{}
+	w = httptest.NewRecorder()
+	handlers.Discovery(w, httptest.NewRequest(http.MethodGet, "/discover.json?group=News", nil))
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&other))
+	require.NotEqual(t, discovery.DeviceID, other.DeviceID)
+
+	// Unfiltered discovery keeps the configured identity.
+	w = httptest.NewRecorder()
+	handlers.Discovery(w, httptest.NewRequest(http.MethodGet, "/discover.json", nil))
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&other))
+	require.Equal(t, cfg.DeviceID, other.DeviceID)
+	require.Equal(t, "IPTV-Proxy", other.FriendlyName)
+}
+
 func TestLineup_NoData(t *testing.T) {
 	log := newTestLogger()
 	cfg := newTestConfig()
@@ -337,6 +495,107 @@ func TestAutoTune_ValidChannel(t *testing.T) {
 	}
 }
 
+func TestCatchup(t *testing.T) {
+	store := data.NewStore()
+	store.SetM3U([]m3u.Channel{
+		{
+			Name: "ESPN",
+			URL:  "http://stream.example.com/espn",
+			Attributes: map[string]string{
+				m3u.AttrCatchup:       "default",
+				m3u.AttrCatchupSource: "http://archive.example.com/espn?from={utc}&to={utcend}",
+			},
+		},
+	})
+
+	handlers := NewHandlers(newTestLogger(), newTestConfig(), store)
+
+	tests := []struct {
+		name         string
+		path         string
+		expectedCode int
+		expectedURL  string
+	}{
+		{"valid", "/catchup/v1?start=1767528000&end=1767529800", http.StatusTemporaryRedirect, "http://archive.example.com/espn?from=1767528000&to=1767529800"},
+		{"missing times", "/catchup/v1", http.StatusBadRequest, ""},
+		{"end before start", "/catchup/v1?start=1767529800&end=1767528000", http.StatusBadRequest, ""},
+		{"invalid channel", "/catchup/vabc?start=1&end=2", http.StatusBadRequest, ""},
+		{"unknown channel", "/catchup/v2?start=1&end=2", http.StatusNotFound, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			handlers.Catchup(w, httptest.NewRequest(http.MethodGet, tt.path, nil))
+
+			require.Equal(t, tt.expectedCode, w.Code)
+
+			if tt.expectedURL != "" {
+				require.Equal(t, tt.expectedURL, w.Header().Get("Location"))
+			}
+		})
+	}
+}
+
+func TestNumberFormat_LineupAndAutoTune(t *testing.T) {
+	cfg := newTestConfig()
+	cfg.NumberFormat = "{group}.{channel}"
+
+	store := data.NewStore()
+	store.SetM3U([]m3u.Channel{
+		{Name: "ESPN", URL: "http://stream.example.com/espn", Group: "Sports"},
+		{Name: "CNN", URL: "http://stream.example.com/cnn", Group: "News"},
+		{Name: "FS1", URL: "http://stream.example.com/fs1", Group: "Sports"},
+	})
+
+	handlers := NewHandlers(newTestLogger(), cfg, store)
+
+	req := httptest.NewRequest(http.MethodGet, "/lineup.json", nil)
+	w := httptest.NewRecorder()
+
+	handlers.Lineup(w, req)
+
+	var lineup []LineupItem
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&lineup))
+
+	numbers := make([]string, 0, len(lineup))
+	for _, item := range lineup {
+		numbers = append(numbers, item.GuideNumber)
+	}
+
+	require.Equal(t, []string{"2.1", "1.1", "2.2"}, numbers)
+
+	// Group devices keep the same numbers.
+	sports := NewGroupHandlers(newTestLogger(), cfg, store, "Sports")
+	require.Equal(t, []string{"2.1", "2.2"}, sports.GuideNumbers([]m3u.Channel{
+		{Name: "ESPN", Group: "Sports"},
+		{Name: "FS1", Group: "Sports"},
+	}))
+
+	tests := []struct {
+		path     string
+		code     int
+		location string
+	}{
+		{"/auto/v2.2", http.StatusTemporaryRedirect, "http://stream.example.com/fs1"},
+		{"/auto/v1.1", http.StatusTemporaryRedirect, "http://stream.example.com/cnn"},
+		{"/auto/v1", http.StatusTemporaryRedirect, "http://stream.example.com/espn"}, // plain position
+		{"/auto/v3.1", http.StatusNotFound, ""},
+		{"/auto/v", http.StatusBadRequest, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			w := httptest.NewRecorder()
+
+			handlers.AutoTune(w, req)
+			require.Equal(t, tt.code, w.Code)
+			require.Equal(t, tt.location, w.Header().Get("Location"))
+		})
+	}
+}
+
 func TestAutoTune_InvalidChannel(t *testing.T) {
 	log := newTestLogger()
 	cfg := newTestConfig()
@@ -601,3 +860,599 @@ func TestGroupHandlers_AutoTune_OutOfRange(t *testing.T) {
 
 	require.Equal(t, http.StatusNotFound, resp.StatusCode)
 }
+
+func TestAutoTune_ProxyStream(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "video/mp2t")
+		_, _ = w.Write([]byte("stream-data"))
+	}))
+	defer upstream.Close()
+
+	cfg := newTestConfig()
+	cfg.ProxyStreams = true
+
+	store := data.NewStore()
+	store.SetM3U([]m3u.Channel{{Name: "ESPN", URL: upstream.URL}})
+
+	handlers := NewHandlers(newTestLogger(), cfg, store)
+
+	req := httptest.NewRequest(http.MethodGet, "/auto/v1", nil)
+	w := httptest.NewRecorder()
+
+	handlers.AutoTune(w, req)
+
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Equal(t, "video/mp2t", w.Header().Get("Content-Type"))
+	require.Equal(t, "stream-data", w.Body.String())
+}
+
+func TestAutoTune_ProxyStreamCancelledByClient(t *testing.T) {
+	started := make(chan struct{})
+	upstreamDone := make(chan struct{})
+
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer close(upstreamDone)
+
+		_, _ = w.Write([]byte("first-chunk"))
+		w.(http.Flusher).Flush() //nolint:forcetypeassert // httptest server supports flushing
+
+		close(started)
+
+		// Keep streaming until the proxy tears down the connection.
+		for {
+			select {
+			case <-r.Context().Done():
+				return
+			case <-time.After(10 * time.Millisecond):
+				if _, err := w.Write([]byte("more")); err != nil {
+					return
+				}
+
+				w.(http.Flusher).Flush() //nolint:forcetypeassert // httptest server supports flushing
+			}
+		}
+	}))
+	defer upstream.Close()
+
+	cfg := newTestConfig()
+	cfg.ProxyStreams = true
+
+	store := data.NewStore()
+	store.SetM3U([]m3u.Channel{{Name: "ESPN", URL: upstream.URL}})
+
+	handlers := NewHandlers(newTestLogger(), cfg, store)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	req := httptest.NewRequest(http.MethodGet, "/auto/v1", nil).WithContext(ctx)
+
+	handlerDone := make(chan struct{})
+
+	go func() {
+		defer close(handlerDone)
+
+		handlers.AutoTune(httptest.NewRecorder(), req)
+	}()
+
+	select {
+	case <-started:
+	case <-time.After(5 * time.Second):
+		t.Fatal("upstream never received the stream request")
+	}
+
+	cancel()
+
+	select {
+	case <-upstreamDone:
+	case <-time.After(5 * time.Second):
+		t.Fatal("upstream request was not cancelled")
+	}
+
+	select {
+	case <-handlerDone:
+	case <-time.After(5 * time.Second):
+		t.Fatal("AutoTune did not return after client cancellation")
+	}
+}
+
+func TestLineup_DedupeRootLineup(t *testing.T) {
+	cfg := newTestConfig()
+	cfg.DedupeRootLineup = true
+
+	store := data.NewStore()
+	store.SetM3U([]m3u.Channel{
+		{Name: "ESPN", URL: "http://stream.example.com/espn", Group: "Sports"},
+		{Name: "CNN", URL: "http://stream.example.com/cnn", Group: "News"},
+		{Name: "ESPN", URL: "http://stream.example.com/espn", Group: "Favourites"},
+	})
+
+	decodeLineup := func(handlers *Handlers) []LineupItem {
+		w := httptest.NewRecorder()
+
+		handlers.Lineup(w, httptest.NewRequest(http.MethodGet, "/lineup.json", nil))
+		require.Equal(t, http.StatusOK, w.Code)
+
+		var lineup []LineupItem
+
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&lineup))
+
+		return lineup
+	}
+
+	root := decodeLineup(NewHandlers(newTestLogger(), cfg, store))
+	require.Len(t, root, 2)
+	require.Equal(t, "ESPN", root[0].GuideName)
+	require.Equal(t, "CNN", root[1].GuideName)
+
+	// Group tuners still list the channel in each of its groups.
+	sports := decodeLineup(NewGroupHandlers(newTestLogger(), cfg, store, "Sports"))
+	require.Len(t, sports, 1)
+	require.Equal(t, "ESPN", sports[0].GuideName)
+
+	favourites := decodeLineup(NewGroupHandlers(newTestLogger(), cfg, store, "Favourites"))
+	require.Len(t, favourites, 1)
+	require.Equal(t, "ESPN", favourites[0].GuideName)
+
+	// Without the option the root lineup suffixes the duplicate.
+	cfg.DedupeRootLineup = false
+
+	root = decodeLineup(NewHandlers(newTestLogger(), cfg, store))
+	require.Len(t, root, 3)
+	require.Equal(t, "ESPN (2)", root[2].GuideName)
+}
+
+func TestLineup_DuplicateNames(t *testing.T) {
+	store := data.NewStore()
+	store.SetM3U([]m3u.Channel{
+		{Name: "ESPN", URL: "http://stream.example.com/espn-east", Group: "Sports"},
+		{Name: "CNN", URL: "http://stream.example.com/cnn", Group: "News"},
+		{Name: "ESPN", URL: "http://stream.example.com/espn-west", Group: "Sports"},
+	})
+
+	lineupFor := func(mode string) []LineupItem {
+		cfg := newTestConfig()
+		cfg.DuplicateNames = mode
+
+		w := httptest.NewRecorder()
+
+		NewHandlers(newTestLogger(), cfg, store).Lineup(w, httptest.NewRequest(http.MethodGet, "/lineup.json", nil))
+		require.Equal(t, http.StatusOK, w.Code)
+
+		var lineup []LineupItem
+
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&lineup))
+
+		return lineup
+	}
+
+	suffixed := lineupFor(config.DuplicateNamesSuffix)
+	require.Len(t, suffixed, 3)
+	require.Equal(t, "ESPN (2)", suffixed[2].GuideName)
+
+	kept := lineupFor(config.DuplicateNamesKeep)
+	require.Len(t, kept, 3)
+	require.Equal(t, "ESPN", kept[2].GuideName)
+	require.Equal(t, "3", kept[2].GuideNumber)
+
+	merged := lineupFor(config.DuplicateNamesMerge)
+	require.Len(t, merged, 2)
+	require.Equal(t, "http://stream.example.com/espn-east", merged[0].URL)
+	require.Equal(t, "CNN", merged[1].GuideName)
+}
+
+func TestLineup_HideDeadChannels(t *testing.T) {
+	live := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	t.Cleanup(live.Close)
+
+	dead := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	t.Cleanup(dead.Close)
+
+	store := data.NewStore()
+	store.SetM3U([]m3u.Channel{
+		{Name: "ESPN", URL: live.URL + "/espn", Group: "Sports"},
+		{Name: "Fox", URL: dead.URL + "/fox", Group: "Sports"},
+		{Name: "CNN", URL: live.URL + "/cnn", Group: "News"},
+	})
+
+	cfg := newTestConfig()
+	cfg.ProbeInterval = time.Minute
+	cfg.ProbeRate = 1000
+	cfg.DeadAfter = 2
+
+	prober := data.NewProber(newTestLogger(), cfg, store)
+	for range cfg.DeadAfter {
+		prober.ProbeAll(context.Background())
+	}
+
+	lineupFor := func(hide bool) []string {
+		cfg.HideDeadChannels = hide
+
+		w := httptest.NewRecorder()
+
+		NewHandlers(newTestLogger(), cfg, store).Lineup(w, httptest.NewRequest(http.MethodGet, "/lineup.json", nil))
+		require.Equal(t, http.StatusOK, w.Code)
+
+		var lineup []LineupItem
+
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&lineup))
+
+		items := make([]string, 0, len(lineup))
+		for _, item := range lineup {
+			items = append(items, item.GuideNumber+" "+item.GuideName)
+		}
+
+		return items
+	}
+
+	require.Equal(t, []string{"1 ESPN", "2 Fox", "3 CNN"}, lineupFor(false))
+	// Hiding Fox doesn't renumber CNN, so its tuning URL stays put.
+	require.Equal(t, []string{"1 ESPN", "3 CNN"}, lineupFor(true))
+
+	channels, ok := NewHandlers(newTestLogger(), cfg, store).Channels()
+	require.True(t, ok)
+	require.Len(t, channels, 3)
+}
+
+func TestAutoTune_DedupeRootLineupNumbering(t *testing.T) {
+	cfg := newTestConfig()
+	cfg.DedupeRootLineup = true
+
+	store := data.NewStore()
+	store.SetM3U([]m3u.Channel{
+		{Name: "ESPN", URL: "http://stream.example.com/espn", Group: "Sports"},
+		{Name: "ESPN", URL: "http://stream.example.com/espn", Group: "Favourites"},
+		{Name: "CNN", URL: "http://stream.example.com/cnn", Group: "News"},
+	})
+
+	handlers := NewHandlers(newTestLogger(), cfg, store)
+
+	w := httptest.NewRecorder()
+
+	handlers.AutoTune(w, httptest.NewRequest(http.MethodGet, "/auto/v2", nil))
+	require.Equal(t, http.StatusTemporaryRedirect, w.Code)
+	require.Equal(t, "http://stream.example.com/cnn", w.Header().Get("Location"))
+}
+
+func TestGroupHandlers_AutoTune_MismatchedGroup(t *testing.T) {
+	store := data.NewStore()
+	store.SetM3U([]m3u.Channel{
+		{Name: "ESPN", URL: "http://stream.example.com/espn", Group: "Sports"},
+		{Name: "HBO", URL: "http://stream.example.com/hbo", Group: "Movies"},
+	})
+
+	handlers := NewGroupHandlers(newTestLogger(), newTestConfig(), store, "Sports")
+
+	tests := []struct {
+		name string
+		path string
+	}{
+		{"other group", "/movies/auto/v1"},
+		{"missing group", "/auto/v1"},
+		{"nested prefix", "/x/sports/auto/v1"},
+		{"slug prefix", "/sportsx/auto/v1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			handlers.AutoTune(w, httptest.NewRequest(http.MethodGet, tt.path, nil))
+
+			require.Equal(t, http.StatusNotFound, w.Code)
+		})
+	}
+}
+
+func TestAutoTune_RootRejectsGroupPrefix(t *testing.T) {
+	store := data.NewStore()
+	store.SetM3U([]m3u.Channel{{Name: "ESPN", URL: "http://stream.example.com/espn", Group: "Sports"}})
+
+	handlers := NewHandlers(newTestLogger(), newTestConfig(), store)
+
+	w := httptest.NewRecorder()
+	handlers.AutoTune(w, httptest.NewRequest(http.MethodGet, "/sports/auto/v1", nil))
+
+	require.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestAutoTune_ProxyStreamServesOfflineClip(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		http.Error(w, "gone", http.StatusServiceUnavailable)
+	}))
+	defer upstream.Close()
+
+	clip := []byte("offline-clip")
+	clipPath := filepath.Join(t.TempDir(), "offline.ts")
+	require.NoError(t, os.WriteFile(clipPath, clip, 0o600))
+
+	cfg := newTestConfig()
+	cfg.ProxyStreams = true
+	cfg.OfflineClip = clipPath
+
+	store := data.NewStore()
+	store.SetM3U([]m3u.Channel{{Name: "ESPN", URL: upstream.URL}})
+
+	offlineClip, err := LoadOfflineClip(clipPath)
+	require.NoError(t, err)
+
+	minOfflineClipLoop = 10 * time.Millisecond
+
+	t.Cleanup(func() { minOfflineClipLoop = time.Second })
+
+	handlers := NewHandlers(newTestLogger(), cfg, store)
+	handlers.SetOfflineClip(offlineClip)
+
+	proxy := httptest.NewServer(http.HandlerFunc(handlers.AutoTune))
+	defer proxy.Close()
+
+	req, err := http.NewRequestWithContext(context.Background(), http.MethodGet, proxy.URL+"/auto/v1", nil)
+	require.NoError(t, err)
+
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, "video/mp2t", resp.Header.Get("Content-Type"))
+
+	// The clip loops, paced, until the client disconnects.
+	body := make([]byte, 2*len(clip))
+	_, err = io.ReadFull(resp.Body, body)
+	require.NoError(t, err)
+	require.Equal(t, "offline-clipoffline-clip", string(body))
+}
+
+func TestAutoTune_ProxyStreamFailsWithoutOfflineClip(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		http.Error(w, "gone", http.StatusServiceUnavailable)
+	}))
+	defer upstream.Close()
+
+	cfg := newTestConfig()
+	cfg.ProxyStreams = true
+
+	store := data.NewStore()
+	store.SetM3U([]m3u.Channel{{Name: "ESPN", URL: upstream.URL}})
+
+	w := httptest.NewRecorder()
+	NewHandlers(newTestLogger(), cfg, store).AutoTune(w, httptest.NewRequest(http.MethodGet, "/auto/v1", nil))
+
+	require.Equal(t, http.StatusBadGateway, w.Code)
+}
+
+// BenchmarkGroupLineup measures /lineup.json for one group of a large
+// playlist (8000 channels across 300 groups).
+func BenchmarkGroupLineup(b *testing.B) {
+	const (
+		channelCount = 8000
+		groupCount   = 300
+	)
+
+	channels := make([]m3u.Channel, 0, channelCount)
+
+	for i := range channelCount {
+		channels = append(channels, m3u.Channel{
+			Name:  fmt.Sprintf("Channel %d", i),
+			URL:   fmt.Sprintf("http://stream.example.com/%d", i),
+			Group: fmt.Sprintf("Group %d", i%groupCount),
+		})
+	}
+
+	store := data.NewStore()
+	store.SetM3U(channels)
+
+	handlers := NewGroupHandlers(newTestLogger(), newTestConfig(), store, "Group 42")
+	req := httptest.NewRequest(http.MethodGet, "/group-42/lineup.json", nil)
+
+	b.ResetTimer()
+
+	for range b.N {
+		handlers.Lineup(httptest.NewRecorder(), req)
+	}
+}
+
+func TestLineup_HDFlag(t *testing.T) {
+	store := data.NewStore()
+	store.SetM3U([]m3u.Channel{
+		{Name: "ESPN HD", URL: "http://stream.example.com/1"},
+		{Name: "CNN", URL: "http://stream.example.com/2"},
+	})
+
+	cfg := newTestConfig()
+
+	w := httptest.NewRecorder()
+	NewHandlers(newTestLogger(), cfg, store).Lineup(w, httptest.NewRequest(http.MethodGet, "/lineup.json", nil))
+
+	// Off by default, so the field is omitted entirely.
+	require.NotContains(t, w.Body.String(), `"HD"`)
+
+	cfg.LineupHDFlag = true
+
+	w = httptest.NewRecorder()
+	NewHandlers(newTestLogger(), cfg, store).Lineup(w, httptest.NewRequest(http.MethodGet, "/lineup.json", nil))
+
+	var lineup []LineupItem
+
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &lineup))
+	require.Equal(t, 1, lineup[0].HD)
+	require.Equal(t, 0, lineup[1].HD)
+}
+
+func TestAutoTune_ProxyStreamLimitsConnectionsPerHost(t *testing.T) {
+	const (
+		limit    = 2
+		requests = 10
+	)
+
+	var active, peak atomic.Int32
+
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		current := active.Add(1)
+		defer active.Add(-1)
+
+		for {
+			old := peak.Load()
+			if current <= old || peak.CompareAndSwap(old, current) {
+				break
+			}
+		}
+
+		time.Sleep(20 * time.Millisecond)
+		_, _ = w.Write([]byte("stream-data"))
+	}))
+	defer upstream.Close()
+
+	cfg := newTestConfig()
+	cfg.ProxyStreams = true
+	cfg.MaxConnsPerHost = limit
+
+	store := data.NewStore()
+	store.SetM3U([]m3u.Channel{{Name: "ESPN", URL: upstream.URL}})
+
+	handlers := NewHandlers(newTestLogger(), cfg, store)
+
+	var wg sync.WaitGroup
+
+	codes := make([]int, requests)
+
+	for i := range requests {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			w := httptest.NewRecorder()
+			handlers.AutoTune(w, httptest.NewRequest(http.MethodGet, "/auto/v1", nil))
+			codes[i] = w.Code
+		}()
+	}
+
+	wg.Wait()
+
+	require.LessOrEqual(t, peak.Load(), int32(limit))
+
+	for _, code := range codes {
+		require.Equal(t, http.StatusOK, code)
+	}
+}
+
+func TestAutoTune_TunerLocks(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte("stream-data"))
+		w.(http.Flusher).Flush()
+
+		<-r.Context().Done()
+	}))
+	defer upstream.Close()
+
+	cfg := newTestConfig()
+	cfg.ProxyStreams = true
+	cfg.ProxyCatchup = true
+	cfg.TunerLocks = true
+	cfg.TunerCount = 1
+
+	store := data.NewStore()
+	store.SetM3U([]m3u.Channel{{
+		Name: "ESPN",
+		URL:  upstream.URL,
+		Attributes: map[string]string{
+			m3u.AttrCatchup:       "default",
+			m3u.AttrCatchupSource: upstream.URL + "?from={utc}&to={utcend}",
+		},
+	}})
+
+	handlers := NewHandlers(newTestLogger(), cfg, store)
+
+	// Leases record the client the server resolved, e.g. behind a proxy.
+	handlers.SetClientIP(func(*http.Request) net.IP {
+		return net.ParseIP("198.51.100.7")
+	})
+
+	tuners := func() []TunerStatus {
+		w := httptest.NewRecorder()
+		handlers.LineupStatus(w, httptest.NewRequest(http.MethodGet, "/lineup_status.json", nil))
+
+		var status LineupStatus
+
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&status))
+
+		return status.Tuners
+	}
+
+	require.Equal(t, []TunerStatus{{Resource: "tuner0"}}, tuners())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+
+		req := httptest.NewRequestWithContext(ctx, http.MethodGet, "/auto/v1", nil)
+		req.RemoteAddr = "192.0.2.10:5000"
+		handlers.AutoTune(httptest.NewRecorder(), req)
+	}()
+
+	require.Eventually(t, func() bool {
+		return tuners()[0].InUse
+	}, time.Second, 5*time.Millisecond)
+
+	tuner := tuners()[0]
+	require.Equal(t, "1", tuner.VctNumber)
+	require.Equal(t, "ESPN", tuner.VctName)
+	require.Equal(t, "198.51.100.7", tuner.TargetIP)
+
+	// The only tuner is reserved until the stream ends, for live and
+	// catchup streams alike.
+	w := httptest.NewRecorder()
+	handlers.AutoTune(w, httptest.NewRequest(http.MethodGet, "/auto/v1", nil))
+	require.Equal(t, http.StatusServiceUnavailable, w.Code)
+	require.Equal(t, "805 All Tuners In Use", w.Header().Get("X-HDHomeRun-Error"))
+
+	w = httptest.NewRecorder()
+	handlers.Catchup(w, httptest.NewRequest(http.MethodGet, "/catchup/v1?start=1700000000&end=1700003600", nil))
+	require.Equal(t, http.StatusServiceUnavailable, w.Code)
+
+	cancel()
+	<-done
+
+	require.False(t, tuners()[0].InUse)
+}
+
+func TestLineup_ProxyStreamsURLs(t *testing.T) {
+	store := data.NewStore()
+	store.SetM3U([]m3u.Channel{
+		{Name: "ESPN", URL: "http://stream.example.com/1", Group: "Sports"},
+		{Name: "CNN", URL: "http://stream.example.com/2", Group: "News"},
+		{Name: "Fox Sports", URL: "http://stream.example.com/3", Group: "Sports"},
+	})
+
+	cfg := newTestConfig()
+	cfg.ProxyStreams = true
+
+	lineupFor := func(query string) []LineupItem {
+		w := httptest.NewRecorder()
+
+		NewHandlers(newTestLogger(), cfg, store).Lineup(w, httptest.NewRequest(http.MethodGet, "/lineup.json"+query, nil))
+		require.Equal(t, http.StatusOK, w.Code)
+
+		var lineup []LineupItem
+
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&lineup))
+
+		return lineup
+	}
+
+	lineup := lineupFor("")
+	require.Len(t, lineup, 3)
+	require.Equal(t, cfg.BaseURL+"/auto/v2", lineup[1].URL)
+
+	// A filtered lineup renumbers its guide numbers but still tunes the
+	// channel's position in the full lineup.
+	lineup = lineupFor("?group=sports")
+	require.Len(t, lineup, 2)
+	require.Equal(t, "2", lineup[1].GuideNumber)
+	require.Equal(t, cfg.BaseURL+"/auto/v3", lineup[1].URL)
+}
@@ -0,0 +1,125 @@
+package hdhr
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// MPEG-TS framing used to estimate a clip's running time.
+const (
+	tsPacketSize = 188
+	tsSyncByte   = 0x47
+	pcrClockRate = 90000 // PCR base ticks per second
+)
+
+// minOfflineClipLoop bounds how often the offline clip is repeated, for clips
+// whose running time can't be read or is shorter than this.
+var minOfflineClipLoop = time.Second
+
+// OfflineClip is an MPEG-TS clip looped to clients while their upstream
+// stream is unavailable.
+type OfflineClip struct {
+	data     []byte
+	duration time.Duration // Estimated running time, 0 if unknown
+}
+
+// LoadOfflineClip reads the clip at path.
+func LoadOfflineClip(path string) (*OfflineClip, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read offline clip: %w", err)
+	}
+
+	if len(data) == 0 {
+		return nil, errors.New("offline clip is empty")
+	}
+
+	return &OfflineClip{data: data, duration: tsDuration(data)}, nil
+}
+
+// tsDuration estimates the running time of an MPEG-TS clip from the first and
+// last program clock references of the PCR stream, or returns 0 when the
+// data isn't MPEG-TS or carries no usable PCRs.
+func tsDuration(data []byte) time.Duration {
+	var (
+		first, last uint64
+		pcrPID      = -1
+	)
+
+	for off := 0; off+tsPacketSize <= len(data); off += tsPacketSize {
+		packet := data[off : off+tsPacketSize]
+		if packet[0] != tsSyncByte {
+			return 0
+		}
+
+		// The PCR lives in the adaptation field, which must be present, long
+		// enough and flagged as carrying one.
+		if packet[3]&0x20 == 0 || packet[4] < 7 || packet[5]&0x10 == 0 {
+			continue
+		}
+
+		pid := int(packet[1]&0x1f)<<8 | int(packet[2])
+		base := uint64(packet[6])<<25 | uint64(packet[7])<<17 | uint64(packet[8])<<9 |
+			uint64(packet[9])<<1 | uint64(packet[10])>>7
+
+		switch {
+		case pcrPID < 0:
+			pcrPID, first, last = pid, base, base
+		case pid == pcrPID:
+			last = base
+		}
+	}
+
+	if last <= first {
+		return 0
+	}
+
+	return time.Duration(last-first) * time.Second / pcrClockRate
+}
+
+// SetOfflineClip sets the clip looped to clients when a proxied upstream
+// stream fails. Without one they get a 502.
+func (h *Handlers) SetOfflineClip(clip *OfflineClip) {
+	h.offlineClip = clip
+}
+
+// upstreamUnavailable responds to a failed upstream stream, looping the
+// offline clip at roughly its running time until the client disconnects, or
+// with a 502 when no clip is set.
+func (h *Handlers) upstreamUnavailable(log logrus.FieldLogger, w http.ResponseWriter, r *http.Request) {
+	clip := h.offlineClip
+	if clip == nil {
+		http.Error(w, "Upstream unavailable", http.StatusBadGateway)
+
+		return
+	}
+
+	log.Info("Serving offline clip")
+
+	w.Header().Set("Content-Type", "video/mp2t")
+	w.WriteHeader(http.StatusOK)
+
+	ticker := time.NewTicker(max(clip.duration, minOfflineClipLoop))
+	defer ticker.Stop()
+
+	for {
+		if _, err := w.Write(clip.data); err != nil {
+			return
+		}
+
+		if flusher, ok := w.(http.Flusher); ok {
+			flusher.Flush()
+		}
+
+		select {
+		case <-r.Context().Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
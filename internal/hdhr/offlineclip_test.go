@@ -0,0 +1,40 @@
+package hdhr
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// tsPacket returns an MPEG-TS packet on pid carrying a PCR of base ticks.
+func tsPacket(pid int, base uint64) []byte {
+	packet := make([]byte, tsPacketSize)
+	packet[0] = tsSyncByte
+	packet[1] = byte(pid >> 8 & 0x1f)
+	packet[2] = byte(pid)
+	packet[3] = 0x20 // Adaptation field only
+	packet[4] = 7
+	packet[5] = 0x10 // PCR flag
+	packet[6] = byte(base >> 25)
+	packet[7] = byte(base >> 17)
+	packet[8] = byte(base >> 9)
+	packet[9] = byte(base >> 1)
+	packet[10] = byte(base << 7)
+
+	return packet
+}
+
+func TestTSDuration(t *testing.T) {
+	clip := make([]byte, 0, 4*tsPacketSize)
+	clip = append(clip, tsPacket(256, 900000)...)
+	clip = append(clip, tsPacket(257, 0)...) // Not the PCR stream
+	clip = append(clip, tsPacket(256, 900000+pcrClockRate)...)
+	clip = append(clip, tsPacket(256, 900000+5*pcrClockRate/2)...)
+
+	require.Equal(t, 2500*time.Millisecond, tsDuration(clip))
+
+	// Data that isn't MPEG-TS, or has no PCRs, has no known duration.
+	require.Zero(t, tsDuration([]byte("offline-clip")))
+	require.Zero(t, tsDuration(tsPacket(256, 900000)))
+}
@@ -0,0 +1,87 @@
+package hdhr
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/savid/iptv/internal/config"
+	"github.com/savid/iptv/internal/m3u"
+)
+
+// URLTransformer rewrites a channel's stream URL just before it is tuned,
+// e.g. to substitute a short-lived auth token.
+type URLTransformer interface {
+	TransformURL(ctx context.Context, channel m3u.Channel) (string, error)
+}
+
+// NoopURLTransformer returns stream URLs unchanged.
+type NoopURLTransformer struct{}
+
+// TransformURL returns the channel's URL as is.
+func (NoopURLTransformer) TransformURL(_ context.Context, channel m3u.Channel) (string, error) {
+	return channel.URL, nil
+}
+
+// QueryParamTransformer sets a query parameter on every stream URL to the
+// current value returned by Token.
+type QueryParamTransformer struct {
+	Param string
+	Token func() (string, error)
+}
+
+// TransformURL returns the channel's URL with Param set to the current token.
+func (t QueryParamTransformer) TransformURL(_ context.Context, channel m3u.Channel) (string, error) {
+	token, err := t.Token()
+	if err != nil {
+		return "", fmt.Errorf("failed to get stream token: %w", err)
+	}
+
+	parsed, err := url.Parse(channel.URL)
+	if err != nil {
+		return "", fmt.Errorf("invalid stream URL: %w", err)
+	}
+
+	query := parsed.Query()
+	query.Set(t.Param, token)
+	parsed.RawQuery = query.Encode()
+
+	return parsed.String(), nil
+}
+
+// newURLTransformer builds the transformer configured by the stream token
+// options. The token file is re-read on every tune so an external process
+// can refresh it; the environment variable is read on every tune as well.
+func newURLTransformer(cfg *config.Config) URLTransformer {
+	switch {
+	case cfg.StreamTokenParam == "":
+		return NoopURLTransformer{}
+	case cfg.StreamTokenFile != "":
+		return QueryParamTransformer{
+			Param: cfg.StreamTokenParam,
+			Token: func() (string, error) {
+				content, err := os.ReadFile(cfg.StreamTokenFile)
+				if err != nil {
+					return "", err
+				}
+
+				return strings.TrimSpace(string(content)), nil
+			},
+		}
+	default:
+		return QueryParamTransformer{
+			Param: cfg.StreamTokenParam,
+			Token: func() (string, error) {
+				token := os.Getenv(cfg.StreamTokenEnv)
+				if token == "" {
+					return "", errors.New(cfg.StreamTokenEnv + " is not set")
+				}
+
+				return token, nil
+			},
+		}
+	}
+}
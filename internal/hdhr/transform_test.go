@@ -0,0 +1,65 @@
+package hdhr
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/savid/iptv/internal/data"
+	"github.com/savid/iptv/internal/m3u"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAutoTune_URLTransformer(t *testing.T) {
+	store := data.NewStore()
+	store.SetM3U([]m3u.Channel{
+		{Name: "ESPN", URL: "http://stream.example.com/espn?token=expired&quality=hd"},
+	})
+
+	handlers := NewHandlers(newTestLogger(), newTestConfig(), store)
+	handlers.SetURLTransformer(QueryParamTransformer{
+		Param: "token",
+		Token: func() (string, error) { return "fresh", nil },
+	})
+
+	w := httptest.NewRecorder()
+
+	handlers.AutoTune(w, httptest.NewRequest(http.MethodGet, "/auto/v1", nil))
+
+	require.Equal(t, http.StatusTemporaryRedirect, w.Code)
+	require.Equal(t, "http://stream.example.com/espn?quality=hd&token=fresh", w.Header().Get("Location"))
+}
+
+func TestNewURLTransformer_TokenFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "token")
+	require.NoError(t, os.WriteFile(path, []byte("first\n"), 0o600))
+
+	cfg := newTestConfig()
+	cfg.StreamTokenParam = "auth"
+	cfg.StreamTokenFile = path
+
+	transformer := newURLTransformer(cfg)
+	channel := m3u.Channel{URL: "http://stream.example.com/espn"}
+
+	streamURL, err := transformer.TransformURL(context.Background(), channel)
+	require.NoError(t, err)
+	require.Equal(t, "http://stream.example.com/espn?auth=first", streamURL)
+
+	// The file is re-read on each tune, picking up a refreshed token.
+	require.NoError(t, os.WriteFile(path, []byte("second"), 0o600))
+
+	streamURL, err = transformer.TransformURL(context.Background(), channel)
+	require.NoError(t, err)
+	require.Equal(t, "http://stream.example.com/espn?auth=second", streamURL)
+}
+
+func TestNewURLTransformer_DefaultIsNoop(t *testing.T) {
+	channel := m3u.Channel{URL: "http://stream.example.com/espn?token=abc"}
+
+	streamURL, err := newURLTransformer(newTestConfig()).TransformURL(context.Background(), channel)
+	require.NoError(t, err)
+	require.Equal(t, channel.URL, streamURL)
+}
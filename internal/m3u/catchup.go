@@ -0,0 +1,105 @@
+package m3u
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Catchup #EXTINF attributes.
+const (
+	AttrCatchup       = "catchup"
+	AttrCatchupSource = "catchup-source"
+	AttrCatchupDays   = "catchup-days"
+)
+
+// Catchup modes.
+const (
+	CatchupDefault = "default" // catchup-source is a complete URL template.
+	CatchupAppend  = "append"  // catchup-source is appended to the stream URL.
+	CatchupShift   = "shift"   // utc/lutc query parameters on the stream URL.
+)
+
+// ErrNoCatchup is returned when a channel does not support catchup.
+var ErrNoCatchup = errors.New("channel does not support catchup")
+
+// HasCatchup reports whether the channel advertises catchup that CatchupURL
+// can build a URL for.
+func (c Channel) HasCatchup() bool {
+	_, err := c.catchupTemplate(c.URL)
+
+	return err == nil
+}
+
+// catchupTemplate returns the channel's catchup URL template, resolved
+// against streamURL for the modes that extend the live stream URL.
+func (c Channel) catchupTemplate(streamURL string) (string, error) {
+	mode := strings.ToLower(c.Attributes[AttrCatchup])
+	source := c.Attributes[AttrCatchupSource]
+
+	switch {
+	case mode == CatchupAppend && source != "":
+		return streamURL + source, nil
+	case (mode == CatchupShift || mode == "timeshift") && source == "":
+		separator := "?"
+		if strings.Contains(streamURL, "?") {
+			separator = "&"
+		}
+
+		return streamURL + separator + "utc={utc}&lutc={lutc}", nil
+	case source != "" && mode != "disabled":
+		return source, nil
+	default:
+		return "", ErrNoCatchup
+	}
+}
+
+// CatchupURL returns the upstream URL for the programme between start and
+// end on the channel, expanding its catchup template. streamURL is the live
+// stream URL the append and shift modes build on.
+func CatchupURL(channel Channel, streamURL string, start, end, now time.Time) (string, error) {
+	template, err := channel.catchupTemplate(streamURL)
+	if err != nil {
+		return "", err
+	}
+
+	return ExpandCatchup(template, start, end, now), nil
+}
+
+// ExpandCatchup substitutes the common catchup-source placeholders in
+// template: Unix timestamps ({utc}, {utcend}, {lutc} and their ${start},
+// ${end}, ${now} spellings), the duration and offset in seconds, and the
+// start time's UTC date parts ({Y}, {m}, {d}, {H}, {M}, {S}).
+func ExpandCatchup(template string, start, end, now time.Time) string {
+	unix := func(t time.Time) string { return strconv.FormatInt(t.Unix(), 10) }
+	duration := strconv.FormatInt(int64(end.Sub(start)/time.Second), 10)
+	offset := strconv.FormatInt(int64(now.Sub(start)/time.Second), 10)
+	utcStart := start.UTC()
+
+	replacer := strings.NewReplacer(
+		"${start}", unix(start),
+		"${end}", unix(end),
+		"${now}", unix(now),
+		"${timestamp}", unix(now),
+		"${duration}", duration,
+		"${offset}", offset,
+		"{utc}", unix(start),
+		"{start}", unix(start),
+		"{utcend}", unix(end),
+		"{end}", unix(end),
+		"{lutc}", unix(now),
+		"{now}", unix(now),
+		"{timestamp}", unix(now),
+		"{duration}", duration,
+		"{offset}", offset,
+		"{Y}", utcStart.Format("2006"),
+		"{m}", utcStart.Format("01"),
+		"{d}", utcStart.Format("02"),
+		"{H}", utcStart.Format("15"),
+		"{M}", utcStart.Format("04"),
+		"{S}", utcStart.Format("05"),
+	)
+
+	return replacer.Replace(template)
+}
@@ -0,0 +1,112 @@
+package m3u
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCatchupURL(t *testing.T) {
+	start := time.Date(2026, 1, 4, 12, 0, 0, 0, time.UTC)
+	end := start.Add(30 * time.Minute)
+	now := start.Add(2 * time.Hour)
+
+	tests := []struct {
+		name       string
+		attributes map[string]string
+		streamURL  string
+		expected   string
+	}{
+		{
+			name: "default with full template",
+			attributes: map[string]string{
+				AttrCatchup:       "default",
+				AttrCatchupSource: "http://up.example.com/espn/{Y}-{m}-{d}/{H}{M}{S}.ts?d={duration}",
+			},
+			streamURL: "http://up.example.com/espn.ts",
+			expected:  "http://up.example.com/espn/2026-01-04/120000.ts?d=1800",
+		},
+		{
+			name: "append",
+			attributes: map[string]string{
+				AttrCatchup:       "append",
+				AttrCatchupSource: "?start=${start}&end=${end}",
+			},
+			streamURL: "http://up.example.com/espn.ts",
+			expected:  "http://up.example.com/espn.ts?start=1767528000&end=1767529800",
+		},
+		{
+			name:       "shift",
+			attributes: map[string]string{AttrCatchup: "shift"},
+			streamURL:  "http://up.example.com/espn.ts?token=abc",
+			expected:   "http://up.example.com/espn.ts?token=abc&utc=1767528000&lutc=1767535200",
+		},
+		{
+			name:       "source without mode",
+			attributes: map[string]string{AttrCatchupSource: "http://up.example.com/archive?from={utc}&offset={offset}"},
+			streamURL:  "http://up.example.com/espn.ts",
+			expected:   "http://up.example.com/archive?from=1767528000&offset=7200",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			channel := Channel{Name: "ESPN", URL: tt.streamURL, Attributes: tt.attributes}
+			require.True(t, channel.HasCatchup())
+
+			got, err := CatchupURL(channel, tt.streamURL, start, end, now)
+			require.NoError(t, err)
+			require.Equal(t, tt.expected, got)
+		})
+	}
+}
+
+func TestCatchupURL_NotSupported(t *testing.T) {
+	for _, attributes := range []map[string]string{
+		nil,
+		{AttrCatchup: "append"},
+		{AttrCatchup: "disabled", AttrCatchupSource: "http://up.example.com/archive"},
+	} {
+		channel := Channel{Name: "ESPN", URL: "http://up.example.com/espn.ts", Attributes: attributes}
+		require.False(t, channel.HasCatchup())
+
+		_, err := CatchupURL(channel, channel.URL, time.Now(), time.Now(), time.Now())
+		require.ErrorIs(t, err, ErrNoCatchup)
+	}
+}
+
+func TestRewriteWithOptions_CatchupSource(t *testing.T) {
+	channels := []Channel{
+		{
+			Name: "ESPN",
+			URL:  "http://up.example.com/espn.ts",
+			Attributes: map[string]string{
+				AttrCatchup:       "append",
+				AttrCatchupSource: "?utc={utc}",
+				AttrCatchupDays:   "7",
+			},
+		},
+		{Name: "CNN", URL: "http://up.example.com/cnn.ts"},
+	}
+
+	output := RewriteWithOptions(channels, nil, RewriteOptions{
+		CatchupSource: func(i int, _ Channel) string {
+			return fmt.Sprintf("http://proxy:8080/catchup/v%d?start={utc}&end={utcend}", i+1)
+		},
+	})
+
+	lines := strings.Split(output, "\n")
+	require.Contains(t, lines[1], `catchup="default"`)
+	require.Contains(t, lines[1], `catchup-days="7"`)
+	require.Contains(t, lines[1], `catchup-source="http://proxy:8080/catchup/v1?start={utc}&end={utcend}"`)
+	require.NotContains(t, output, `catchup-source="?utc={utc}"`)
+
+	// Channels without catchup are left alone.
+	require.NotContains(t, lines[4], "catchup")
+
+	// The channel's own attributes are not modified.
+	require.Equal(t, "append", channels[0].Attributes[AttrCatchup])
+}
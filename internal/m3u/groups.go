@@ -0,0 +1,54 @@
+package m3u
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/savid/iptv/internal/names"
+)
+
+// NormalizeGroups returns a copy of channels with group-titles that differ
+// only in whitespace or case merged into one. Each merged group is named after
+// the first spelling seen, with runs of whitespace collapsed to single spaces
+// and, when titleCase is set, each word capitalized. The original group-title
+// stays available in each channel's Attributes.
+func NormalizeGroups(channels []Channel, titleCase bool) []Channel {
+	canonical := make(map[string]string)
+	normalized := make([]Channel, len(channels))
+
+	for i, ch := range channels {
+		collapsed := names.CollapseWhitespace(ch.Group)
+		key := strings.ToLower(collapsed)
+
+		name, seen := canonical[key]
+		if !seen {
+			name = collapsed
+			if titleCase {
+				name = titleCaseWords(name)
+			}
+
+			canonical[key] = name
+		}
+
+		ch.Group = name
+		normalized[i] = ch
+	}
+
+	return normalized
+}
+
+// titleCaseWords upper-cases the first letter of each space-separated word,
+// leaving the rest of the word untouched so acronyms like "US" survive.
+func titleCaseWords(s string) string {
+	words := strings.Split(s, " ")
+
+	for i, word := range words {
+		r, size := utf8.DecodeRuneInString(word)
+		if size > 0 {
+			words[i] = string(unicode.ToUpper(r)) + word[size:]
+		}
+	}
+
+	return strings.Join(words, " ")
+}
@@ -0,0 +1,45 @@
+package m3u
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNormalizeGroups(t *testing.T) {
+	channels := []Channel{
+		{Name: "ESPN", Group: "US Sports", Attributes: map[string]string{AttrGroupTitle: "US Sports"}},
+		{Name: "Fox Sports", Group: "US  Sports", Attributes: map[string]string{AttrGroupTitle: "US  Sports"}},
+		{Name: "NBC Sports", Group: " us sports ", Attributes: map[string]string{AttrGroupTitle: " us sports "}},
+		{Name: "CNN", Group: "News"},
+		{Name: "Loose"},
+	}
+
+	normalized := NormalizeGroups(channels, false)
+
+	groups := make([]string, 0, len(normalized))
+
+	for _, ch := range normalized {
+		groups = append(groups, ch.Group)
+	}
+
+	require.Equal(t, []string{"US Sports", "US Sports", "US Sports", "News", ""}, groups)
+
+	// Original spellings are kept in the attributes and the input is unchanged.
+	require.Equal(t, "US  Sports", normalized[1].Attributes[AttrGroupTitle])
+	require.Equal(t, " us sports ", channels[2].Group)
+}
+
+func TestNormalizeGroups_TitleCase(t *testing.T) {
+	channels := []Channel{
+		{Name: "NBC Sports", Group: "us  sports"},
+		{Name: "ESPN", Group: "US Sports"},
+		{Name: "Télé", Group: "émissions françaises"},
+	}
+
+	normalized := NormalizeGroups(channels, true)
+
+	require.Equal(t, "Us Sports", normalized[0].Group)
+	require.Equal(t, "Us Sports", normalized[1].Group)
+	require.Equal(t, "Émissions Françaises", normalized[2].Group)
+}
@@ -0,0 +1,45 @@
+package m3u
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Zero-width non-joiner and joiner are format characters that shape Persian
+// and Indic scripts and emoji sequences, so CleanName keeps them.
+const (
+	zeroWidthNonJoiner = '\u200c'
+	zeroWidthJoiner    = '\u200d'
+)
+
+// CleanName trims surrounding whitespace from name and strips control and
+// invisible format characters (zero-width spaces, byte order marks,
+// direction marks), which break exact name matching in Plex.
+func CleanName(name string) string {
+	cleaned := strings.Map(func(r rune) rune {
+		if r == zeroWidthNonJoiner || r == zeroWidthJoiner {
+			return r
+		}
+
+		if unicode.IsControl(r) || unicode.Is(unicode.Cf, r) {
+			return -1
+		}
+
+		return r
+	}, name)
+
+	return strings.TrimSpace(cleaned)
+}
+
+// CleanNames returns a copy of channels with each name passed through
+// CleanName.
+func CleanNames(channels []Channel) []Channel {
+	cleaned := make([]Channel, len(channels))
+
+	for i, ch := range channels {
+		ch.Name = CleanName(ch.Name)
+		cleaned[i] = ch
+	}
+
+	return cleaned
+}
@@ -0,0 +1,41 @@
+package m3u
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCleanName(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{"unchanged", "ESPN HD", "ESPN HD"},
+		{"trailing space", "ESPN ", "ESPN"},
+		{"zero-width space", "ES\u200bPN", "ESPN"},
+		{"zero-width space before trailing space", "ESPN\u200b ", "ESPN"},
+		{"byte order mark", "\ufeffESPN", "ESPN"},
+		{"control characters", "ESPN\t\x00", "ESPN"},
+		{"non-breaking space", "\u00a0ESPN\u00a0", "ESPN"},
+		{"inner spaces kept", "Fox  Sports 1", "Fox  Sports 1"},
+		{"zero-width non-joiner kept", "\u0645\u06cc\u200c\u062e\u0648\u0627\u0647\u0645", "\u0645\u06cc\u200c\u062e\u0648\u0627\u0647\u0645"},
+		{"zero-width joiner kept", "\U0001f468\u200d\U0001f373 Cooking", "\U0001f468\u200d\U0001f373 Cooking"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.expected, CleanName(tt.input))
+		})
+	}
+}
+
+func TestCleanNames(t *testing.T) {
+	channels := []Channel{{Name: "ESPN \u200b"}, {Name: "CNN"}}
+
+	cleaned := CleanNames(channels)
+	require.Equal(t, "ESPN", cleaned[0].Name)
+	require.Equal(t, "CNN", cleaned[1].Name)
+	require.Equal(t, "ESPN \u200b", channels[0].Name)
+}
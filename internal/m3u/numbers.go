@@ -0,0 +1,82 @@
+package m3u
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+)
+
+// Placeholders in a guide number format.
+const (
+	NumberIndex   = "{n}"       // 1-based position in the lineup
+	NumberGroup   = "{group}"   // 1-based position of the channel's group (0 if ungrouped)
+	NumberChannel = "{channel}" // 1-based position of the channel within its group
+)
+
+// DefaultNumberFormat numbers channels by lineup position.
+const DefaultNumberFormat = NumberIndex
+
+// ValidateNumberFormat checks that format yields a unique number per channel:
+// it must contain {n}, or both {group} and {channel} separated by something
+// other than digits, so that e.g. group 1 channel 11 and group 11 channel 1
+// don't both become "111". An empty format is the default.
+func ValidateNumberFormat(format string) error {
+	if format == "" || strings.Contains(format, NumberIndex) {
+		return nil
+	}
+
+	groupIdx := strings.Index(format, NumberGroup)
+	channelIdx := strings.Index(format, NumberChannel)
+
+	if groupIdx < 0 || channelIdx < 0 {
+		return errors.New("must contain {n}, or both {group} and {channel}")
+	}
+
+	var between string
+	if groupIdx < channelIdx {
+		between = format[groupIdx+len(NumberGroup) : channelIdx]
+	} else {
+		between = format[channelIdx+len(NumberChannel) : groupIdx]
+	}
+
+	if !strings.ContainsFunc(between, func(r rune) bool { return r < '0' || r > '9' }) {
+		return errors.New("{group} and {channel} must be separated by a non-digit, e.g. {group}.{channel}")
+	}
+
+	return nil
+}
+
+// GuideNumbers returns the guide number of each channel, in order, by
+// expanding format. groups lists every group-title in the order they are
+// numbered, so a channel keeps its major number on every device. An empty
+// format uses DefaultNumberFormat.
+func GuideNumbers(format string, channels []Channel, groups []string) []string {
+	numbers := make([]string, len(channels))
+
+	if format == "" || format == DefaultNumberFormat {
+		for i := range channels {
+			numbers[i] = strconv.Itoa(i + 1)
+		}
+
+		return numbers
+	}
+
+	groupIndex := make(map[string]int, len(groups))
+	for i, group := range groups {
+		groupIndex[group] = i + 1
+	}
+
+	inGroup := make(map[string]int, len(groups))
+
+	for i, ch := range channels {
+		inGroup[ch.Group]++
+
+		numbers[i] = strings.NewReplacer(
+			NumberIndex, strconv.Itoa(i+1),
+			NumberGroup, strconv.Itoa(groupIndex[ch.Group]),
+			NumberChannel, strconv.Itoa(inGroup[ch.Group]),
+		).Replace(format)
+	}
+
+	return numbers
+}
@@ -0,0 +1,36 @@
+package m3u
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGuideNumbers(t *testing.T) {
+	channels := []Channel{
+		{Name: "ESPN", Group: "Sports"},
+		{Name: "CNN", Group: "News"},
+		{Name: "FS1", Group: "Sports"},
+		{Name: "Local"},
+	}
+	groups := []string{"News", "Sports"}
+
+	require.Equal(t, []string{"1", "2", "3", "4"}, GuideNumbers("", channels, groups))
+	require.Equal(t, []string{"1", "2", "3", "4"}, GuideNumbers(DefaultNumberFormat, channels, groups))
+	require.Equal(t, []string{"2.1", "1.1", "2.2", "0.1"}, GuideNumbers("{group}.{channel}", channels, groups))
+	require.Equal(t, []string{"101", "102", "103", "104"}, GuideNumbers("10{n}", channels, groups))
+}
+
+func TestValidateNumberFormat(t *testing.T) {
+	require.NoError(t, ValidateNumberFormat(""))
+	require.NoError(t, ValidateNumberFormat("{n}"))
+	require.NoError(t, ValidateNumberFormat("{group}.{channel}"))
+	require.NoError(t, ValidateNumberFormat("{group}.{n}"))
+	require.Error(t, ValidateNumberFormat("{group}"))
+	require.Error(t, ValidateNumberFormat("{channel}"))
+	require.Error(t, ValidateNumberFormat("5"))
+	require.Error(t, ValidateNumberFormat("{group}{channel}"))
+	require.Error(t, ValidateNumberFormat("{group}0{channel}"))
+	require.Error(t, ValidateNumberFormat("{channel}{group}"))
+	require.NoError(t, ValidateNumberFormat("{channel}-{group}"))
+}
@@ -7,6 +7,8 @@ import (
 	"errors"
 	"fmt"
 	"regexp"
+	"sort"
+	"strconv"
 	"strings"
 )
 
@@ -15,8 +17,62 @@ var (
 	ErrIncompleteChannel = errors.New("found #EXTINF without URL at end of file")
 	// ErrOrphanedChannel is returned when a new #EXTINF is found before the previous one has a URL.
 	ErrOrphanedChannel = errors.New("found #EXTINF without URL for previous channel")
+	// ErrHLSPlaylist is returned when the data is an HLS master or media
+	// playlist (a single stream's variants or segments) rather than an IPTV
+	// channel playlist.
+	ErrHLSPlaylist = errors.New("data is an HLS stream playlist, not an IPTV channel playlist; " +
+		"point --m3u at the provider's channel list (e.g. get.php?...&type=m3u_plus) instead of a stream URL")
 )
 
+// hlsTags are #EXT-X- tags that define an HLS master or media playlist and
+// never appear in an IPTV channel playlist.
+var hlsTags = []string{
+	"#EXT-X-STREAM-INF",
+	"#EXT-X-I-FRAME-STREAM-INF",
+	"#EXT-X-TARGETDURATION",
+	"#EXT-X-MEDIA-SEQUENCE",
+	"#EXT-X-PLAYLIST-TYPE",
+	"#EXT-X-ENDLIST",
+}
+
+// Well-known #EXTINF attributes promoted to Channel fields.
+const (
+	AttrTVGID      = "tvg-id"
+	AttrTVGName    = "tvg-name"
+	AttrTVGLogo    = "tvg-logo"
+	AttrGroupTitle = "group-title"
+	AttrTVGChno    = "tvg-chno"
+
+	// AttrResolution is a non-standard attribute some providers use for the
+	// stream resolution (e.g. "1080p").
+	AttrResolution = "resolution"
+)
+
+// groupAttributes lists the attributes providers use for a channel's group,
+// in order of precedence: group-title is authoritative, then the non-standard
+// tvg-group and group-name.
+var groupAttributes = []string{AttrGroupTitle, "tvg-group", "group-name"}
+
+// DurationLive is the #EXTINF duration used for live streams.
+const DurationLive = -1
+
+// groupAttribute returns the first non-empty group attribute.
+func groupAttribute(attrs map[string]string) string {
+	for _, attr := range groupAttributes {
+		if group := attrs[attr]; group != "" {
+			return group
+		}
+	}
+
+	return ""
+}
+
+// promotedAttributes lists the well-known attributes in the order Rewrite emits them.
+var promotedAttributes = []string{AttrTVGID, AttrTVGName, AttrTVGLogo, AttrGroupTitle}
+
+// attributePattern matches key="value" pairs on an #EXTINF line.
+var attributePattern = regexp.MustCompile(`([A-Za-z0-9_.:-]+)="([^"]*)"`)
+
 // Channel represents a single channel entry in an M3U playlist.
 type Channel struct {
 	Name     string
@@ -26,10 +82,59 @@ type Channel struct {
 	TVGLogo  string
 	Group    string
 	Original string
+
+	// Duration is the #EXTINF duration in seconds; DurationLive (-1) for live
+	// streams.
+	Duration int
+
+	// Attributes holds every key="value" attribute found on the #EXTINF line,
+	// including the well-known ones promoted to the fields above.
+	Attributes map[string]string
+}
+
+// MaxSkippedSamples caps how many skipped lines ParseLenient records.
+const MaxSkippedSamples = 20
+
+// ParseReport describes the entries ParseLenient skipped.
+type ParseReport struct {
+	// Skipped is the number of lines dropped.
+	Skipped int `json:"skipped"`
+	// Samples holds the first MaxSkippedSamples dropped lines, verbatim.
+	Samples []string `json:"samples"`
+}
+
+// skip records a dropped line.
+func (r *ParseReport) skip(line string) {
+	r.Skipped++
+
+	if len(r.Samples) < MaxSkippedSamples {
+		r.Samples = append(r.Samples, line)
+	}
 }
 
 // Parse extracts channel information from M3U playlist data.
 func Parse(data []byte) ([]Channel, error) {
+	return parse(data, nil)
+}
+
+// ParseLenient is Parse, but skips malformed entries instead of failing: an
+// #EXTINF with no URL and a URL with no #EXTINF. The skipped lines are
+// counted and sampled in the returned report. HLS playlists are still
+// rejected.
+func ParseLenient(data []byte) ([]Channel, ParseReport, error) {
+	report := ParseReport{Samples: make([]string, 0)}
+
+	channels, err := parse(data, &report)
+	if err != nil {
+		return nil, ParseReport{}, err
+	}
+
+	return channels, report, nil
+}
+
+// parse extracts channels from data. When report is nil malformed entries
+// are errors; otherwise they are skipped and recorded in report.
+func parse(data []byte, report *ParseReport) ([]Channel, error) {
 	channels := make([]Channel, 0, 100)
 	reader := bytes.NewReader(data)
 	scanner := bufio.NewScanner(reader)
@@ -48,25 +153,44 @@ func Parse(data []byte) ([]Channel, error) {
 			continue
 		}
 
+		if isHLSTag(line) {
+			return nil, fmt.Errorf("%w (found %s)", ErrHLSPlaylist, strings.SplitN(line, ":", 2)[0])
+		}
+
 		if strings.HasPrefix(line, "#EXTINF:") {
 			if currentChannel != nil {
-				return nil, ErrOrphanedChannel
+				if report == nil {
+					return nil, ErrOrphanedChannel
+				}
+
+				report.skip(currentChannel.Original)
 			}
 
+			attrs := parseAttributes(line)
+
 			currentChannel = &Channel{
-				Original: line,
+				Original:   line,
+				Duration:   parseDuration(line),
+				TVGID:      attrs[AttrTVGID],
+				TVGName:    attrs[AttrTVGName],
+				TVGLogo:    attrs[AttrTVGLogo],
+				Group:      groupAttribute(attrs),
+				Attributes: attrs,
 			}
 
-			currentChannel.TVGID = extractAttribute(line, "tvg-id")
-			currentChannel.TVGName = extractAttribute(line, "tvg-name")
-			currentChannel.TVGLogo = extractAttribute(line, "tvg-logo")
-			currentChannel.Group = extractAttribute(line, "group-title")
-
 			parts := strings.SplitN(line, ",", 2)
 			if len(parts) == 2 {
 				currentChannel.Name = strings.TrimSpace(parts[1])
 			}
-		} else if !strings.HasPrefix(line, "#") && currentChannel != nil {
+		} else if !strings.HasPrefix(line, "#") {
+			if currentChannel == nil {
+				if report != nil {
+					report.skip(line)
+				}
+
+				continue
+			}
+
 			currentChannel.URL = line
 			channels = append(channels, *currentChannel)
 			currentChannel = nil
@@ -78,27 +202,178 @@ func Parse(data []byte) ([]Channel, error) {
 	}
 
 	if currentChannel != nil {
-		return nil, ErrIncompleteChannel
+		if report == nil {
+			return nil, ErrIncompleteChannel
+		}
+
+		report.skip(currentChannel.Original)
 	}
 
 	return channels, nil
 }
 
-func extractAttribute(line, attr string) string {
-	pattern := fmt.Sprintf(`%s="([^"]*)"`, regexp.QuoteMeta(attr))
-	re := regexp.MustCompile(pattern)
-	matches := re.FindStringSubmatch(line)
+// isHLSTag returns true if line is one of hlsTags.
+func isHLSTag(line string) bool {
+	if !strings.HasPrefix(line, "#EXT-X-") {
+		return false
+	}
 
-	if len(matches) > 1 {
-		return matches[1]
+	for _, tag := range hlsTags {
+		if line == tag || strings.HasPrefix(line, tag+":") {
+			return true
+		}
 	}
 
-	return ""
+	return false
+}
+
+// parseDuration extracts the duration that follows "#EXTINF:". Missing or
+// malformed durations are treated as live.
+func parseDuration(line string) int {
+	rest := strings.TrimPrefix(line, "#EXTINF:")
+
+	end := strings.IndexAny(rest, " \t,")
+	if end == -1 {
+		end = len(rest)
+	}
+
+	// Some playlists write fractional durations (e.g. "10.5").
+	duration, err := strconv.ParseFloat(rest[:end], 64)
+	if err != nil {
+		return DurationLive
+	}
+
+	return int(duration)
+}
+
+// IsLive returns true if the channel is a live stream rather than a VOD
+// entry. Durations of zero or less are treated as live.
+func (c Channel) IsLive() bool {
+	return c.Duration <= 0
+}
+
+// LiveOnly returns the live channels, dropping VOD entries.
+func LiveOnly(channels []Channel) []Channel {
+	live := make([]Channel, 0, len(channels))
+
+	for _, ch := range channels {
+		if ch.IsLive() {
+			live = append(live, ch)
+		}
+	}
+
+	return live
+}
+
+// parseAttributes extracts all key="value" attributes from an #EXTINF line in
+// a single pass. When a key appears more than once, the first value wins.
+func parseAttributes(line string) map[string]string {
+	matches := attributePattern.FindAllStringSubmatch(line, -1)
+	attrs := make(map[string]string, len(matches))
+
+	for _, match := range matches {
+		if _, exists := attrs[match[1]]; !exists {
+			attrs[match[1]] = match[2]
+		}
+	}
+
+	return attrs
+}
+
+// formatAttributes renders the channel's attributes for an #EXTINF line.
+// Promoted attributes are always emitted first (from the struct fields),
+// followed by any other attributes sorted by key.
+func formatAttributes(channel Channel, tvgID string) string {
+	promoted := map[string]string{
+		AttrTVGID:      tvgID,
+		AttrTVGName:    channel.TVGName,
+		AttrTVGLogo:    channel.TVGLogo,
+		AttrGroupTitle: channel.Group,
+	}
+
+	parts := make([]string, 0, len(promotedAttributes)+len(channel.Attributes))
+
+	for _, key := range promotedAttributes {
+		parts = append(parts, fmt.Sprintf("%s=\"%s\"", key, promoted[key]))
+	}
+
+	extra := make([]string, 0, len(channel.Attributes))
+
+	for key := range channel.Attributes {
+		if _, isPromoted := promoted[key]; !isPromoted {
+			extra = append(extra, key)
+		}
+	}
+
+	sort.Strings(extra)
+
+	for _, key := range extra {
+		parts = append(parts, fmt.Sprintf("%s=\"%s\"", key, channel.Attributes[key]))
+	}
+
+	return strings.Join(parts, " ")
+}
+
+// RewriteOptions customizes the playlist produced by RewriteWithOptions.
+type RewriteOptions struct {
+	// StreamURL returns the URL emitted for the channel at index i. When nil,
+	// the channel's upstream URL is used.
+	StreamURL func(i int, channel Channel) string
+
+	// PreserveTVGID keeps each channel's original tvg-id instead of replacing
+	// it with the matched EPG channel ID.
+	PreserveTVGID bool
+
+	// CatchupSource returns the catchup-source template emitted for the
+	// channel at index i, for channels that support catchup. The catchup
+	// mode is set to default since the template is a complete URL. When nil,
+	// catchup attributes are passed through unchanged.
+	CatchupSource func(i int, channel Channel) string
+
+	// ChannelNumber returns the tvg-chno emitted for the channel at index i,
+	// replacing any upstream value. An empty number leaves the channel's
+	// tvg-chno unchanged. When nil, tvg-chno is passed through unchanged.
+	ChannelNumber func(i int, channel Channel) string
+}
+
+// withCatchupSource returns a copy of channel whose catchup attributes point
+// at source.
+func withCatchupSource(channel Channel, source string) Channel {
+	attributes := make(map[string]string, len(channel.Attributes)+2)
+
+	for key, value := range channel.Attributes {
+		attributes[key] = value
+	}
+
+	attributes[AttrCatchup] = CatchupDefault
+	attributes[AttrCatchupSource] = source
+	channel.Attributes = attributes
+
+	return channel
+}
+
+// withChannelNumber returns a copy of channel whose tvg-chno is number.
+func withChannelNumber(channel Channel, number string) Channel {
+	attributes := make(map[string]string, len(channel.Attributes)+1)
+
+	for key, value := range channel.Attributes {
+		attributes[key] = value
+	}
+
+	attributes[AttrTVGChno] = number
+	channel.Attributes = attributes
+
+	return channel
 }
 
 // Rewrite generates an M3U playlist with upstream URLs.
 // If channelMap is provided (EPG channel ID → M3U name), it sets tvg-id from matched EPG IDs.
 func Rewrite(channels []Channel, channelMap map[string]string) string {
+	return RewriteWithOptions(channels, channelMap, RewriteOptions{})
+}
+
+// RewriteWithOptions generates an M3U playlist like Rewrite, applying opts.
+func RewriteWithOptions(channels []Channel, channelMap map[string]string, opts RewriteOptions) string {
 	// Build reverse map: M3U name → EPG channel ID (use first match).
 	// Keep the full EPG ID (including any suffix) so Plex can match correctly.
 	nameToEPGID := make(map[string]string, len(channelMap))
@@ -116,18 +391,32 @@ func Rewrite(channels []Channel, channelMap map[string]string) string {
 	for i, channel := range channels {
 		// Use matched EPG channel ID if available, otherwise keep original tvg-id.
 		tvgID := channel.TVGID
-		if epgID, ok := nameToEPGID[channel.Name]; ok {
+		if epgID, ok := nameToEPGID[channel.Name]; ok && !opts.PreserveTVGID {
 			tvgID = epgID
 		}
 
-		sb.WriteString(fmt.Sprintf("#EXTINF:-1 tvg-id=\"%s\" tvg-name=\"%s\" tvg-logo=\"%s\" group-title=\"%s\",%s\n",
-			tvgID,
-			channel.TVGName,
-			channel.TVGLogo,
-			channel.Group,
-			channel.Name,
-		))
-		sb.WriteString(channel.URL + "\n")
+		duration := DurationLive
+		if !channel.IsLive() {
+			duration = channel.Duration
+		}
+
+		if opts.CatchupSource != nil && channel.HasCatchup() {
+			channel = withCatchupSource(channel, opts.CatchupSource(i, channel))
+		}
+
+		if opts.ChannelNumber != nil {
+			if number := opts.ChannelNumber(i, channel); number != "" {
+				channel = withChannelNumber(channel, number)
+			}
+		}
+
+		sb.WriteString(fmt.Sprintf("#EXTINF:%d %s,%s\n", duration, formatAttributes(channel, tvgID), channel.Name))
+		streamURL := channel.URL
+		if opts.StreamURL != nil {
+			streamURL = opts.StreamURL(i, channel)
+		}
+
+		sb.WriteString(streamURL + "\n")
 
 		if i < len(channels)-1 {
 			sb.WriteString("\n")
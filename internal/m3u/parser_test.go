@@ -1,6 +1,8 @@
 package m3u
 
 import (
+	"fmt"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/require"
@@ -113,6 +115,27 @@ http://stream.example.com/local`,
 	}
 }
 
+func TestParse_GroupAttributeAliases(t *testing.T) {
+	input := `#EXTM3U
+#EXTINF:-1 tvg-id="espn.us" tvg-group="Sports",ESPN
+http://stream.example.com/espn
+#EXTINF:-1 tvg-id="cnn.us" group-name="News",CNN
+http://stream.example.com/cnn
+#EXTINF:-1 tvg-id="hbo.us" tvg-group="Premium" group-title="Movies",HBO
+http://stream.example.com/hbo
+#EXTINF:-1 tvg-id="pbs.us",PBS
+http://stream.example.com/pbs`
+
+	channels, err := Parse([]byte(input))
+	require.NoError(t, err)
+	require.Len(t, channels, 4)
+
+	require.Equal(t, "Sports", channels[0].Group)
+	require.Equal(t, "News", channels[1].Group)
+	require.Equal(t, "Movies", channels[2].Group, "group-title takes precedence")
+	require.Empty(t, channels[3].Group)
+}
+
 func TestParse_ChannelNameFromComma(t *testing.T) {
 	input := `#EXTM3U
 #EXTINF:-1 tvg-name="Short Name",This Is The Full Channel Name
@@ -175,6 +198,94 @@ http://stream.example.com/2`
 	require.ErrorIs(t, err, ErrOrphanedChannel)
 }
 
+func TestParseLenient_SkipsMalformedEntries(t *testing.T) {
+	input := `#EXTM3U
+http://stream.example.com/stray
+#EXTINF:-1 tvg-name="Channel1",Channel 1
+#EXTINF:-1 tvg-name="Channel2",Channel 2
+http://stream.example.com/2
+#EXTINF:-1 tvg-name="Channel3",Channel 3`
+
+	channels, report, err := ParseLenient([]byte(input))
+	require.NoError(t, err)
+	require.Len(t, channels, 1)
+	require.Equal(t, "Channel 2", channels[0].Name)
+
+	require.Equal(t, 3, report.Skipped)
+	require.Equal(t, []string{
+		"http://stream.example.com/stray",
+		`#EXTINF:-1 tvg-name="Channel1",Channel 1`,
+		`#EXTINF:-1 tvg-name="Channel3",Channel 3`,
+	}, report.Samples)
+}
+
+func TestParseLenient_CapsSamples(t *testing.T) {
+	var sb strings.Builder
+
+	sb.WriteString("#EXTM3U\n")
+
+	for i := range MaxSkippedSamples + 5 {
+		fmt.Fprintf(&sb, "#EXTINF:-1,Channel %d\n", i)
+	}
+
+	channels, report, err := ParseLenient([]byte(sb.String()))
+	require.NoError(t, err)
+	require.Empty(t, channels)
+	require.Equal(t, MaxSkippedSamples+5, report.Skipped)
+	require.Len(t, report.Samples, MaxSkippedSamples)
+	require.Equal(t, "#EXTINF:-1,Channel 0", report.Samples[0])
+}
+
+func TestParse_ErrHLSPlaylist(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		tag   string
+	}{
+		{
+			name: "master playlist",
+			input: `#EXTM3U
+#EXT-X-VERSION:3
+#EXT-X-STREAM-INF:BANDWIDTH=1280000,RESOLUTION=1280x720
+720p/index.m3u8
+#EXT-X-STREAM-INF:BANDWIDTH=2560000,RESOLUTION=1920x1080
+1080p/index.m3u8`,
+			tag: "#EXT-X-STREAM-INF",
+		},
+		{
+			name: "media playlist",
+			input: `#EXTM3U
+#EXT-X-TARGETDURATION:10
+#EXTINF:9.009,
+segment0.ts
+#EXTINF:9.009,
+segment1.ts
+#EXT-X-ENDLIST`,
+			tag: "#EXT-X-TARGETDURATION",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse([]byte(tt.input))
+			require.ErrorIs(t, err, ErrHLSPlaylist)
+			require.Contains(t, err.Error(), "not an IPTV channel playlist")
+			require.Contains(t, err.Error(), tt.tag)
+		})
+	}
+}
+
+func TestParse_IgnoresOtherExtXTags(t *testing.T) {
+	input := `#EXTM3U
+#EXT-X-SESSION-DATA:DATA-ID="com.example.provider"
+#EXTINF:-1,Channel 1
+http://stream.example.com/1`
+
+	channels, err := Parse([]byte(input))
+	require.NoError(t, err)
+	require.Len(t, channels, 1)
+}
+
 func TestParse_SpecialCharacters(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -291,7 +402,7 @@ func TestRewrite_RoundTrip(t *testing.T) {
 	require.Equal(t, original[0].Group, parsed[0].Group)
 }
 
-func TestExtractAttribute(t *testing.T) {
+func TestParseAttributes(t *testing.T) {
 	tests := []struct {
 		name     string
 		line     string
@@ -338,8 +449,173 @@ func TestExtractAttribute(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			result := extractAttribute(tt.line, tt.attr)
+			result := parseAttributes(tt.line)[tt.attr]
 			require.Equal(t, tt.expected, result)
 		})
 	}
 }
+
+func TestParse_Attributes(t *testing.T) {
+	input := `#EXTM3U
+#EXTINF:-1 tvg-id="espn.us" tvg-name="ESPN" tvg-rec="7" timeshift="2" catchup-correction="-1.5" group-title="Sports",ESPN
+http://stream.example.com/espn`
+
+	channels, err := Parse([]byte(input))
+	require.NoError(t, err)
+	require.Len(t, channels, 1)
+
+	ch := channels[0]
+	require.Equal(t, "espn.us", ch.TVGID)
+	require.Equal(t, "Sports", ch.Group)
+	require.Equal(t, "7", ch.Attributes["tvg-rec"])
+	require.Equal(t, "2", ch.Attributes["timeshift"])
+	require.Equal(t, "-1.5", ch.Attributes["catchup-correction"])
+	require.Equal(t, "espn.us", ch.Attributes[AttrTVGID])
+}
+
+func TestRewrite_UnknownAttributesRoundTrip(t *testing.T) {
+	input := `#EXTM3U
+#EXTINF:-1 tvg-id="espn.us" tvg-name="ESPN" tvg-rec="7" timeshift="2" group-title="Sports",ESPN
+http://stream.example.com/espn`
+
+	channels, err := Parse([]byte(input))
+	require.NoError(t, err)
+
+	rewritten := Rewrite(channels, nil)
+	require.Contains(t, rewritten, `tvg-rec="7"`)
+	require.Contains(t, rewritten, `timeshift="2"`)
+
+	parsed, err := Parse([]byte(rewritten))
+	require.NoError(t, err)
+	require.Len(t, parsed, 1)
+	require.Equal(t, "7", parsed[0].Attributes["tvg-rec"])
+	require.Equal(t, "2", parsed[0].Attributes["timeshift"])
+	require.Equal(t, channels[0].TVGID, parsed[0].TVGID)
+	require.Equal(t, channels[0].Name, parsed[0].Name)
+}
+
+func TestRewrite_PromotedFieldsOverrideAttributes(t *testing.T) {
+	channels := []Channel{
+		{
+			Name:       "ESPN",
+			URL:        "http://stream.example.com/espn",
+			Group:      "New Group",
+			Attributes: map[string]string{AttrGroupTitle: "Old Group", "tvg-rec": "3"},
+		},
+	}
+
+	rewritten := Rewrite(channels, nil)
+	require.Contains(t, rewritten, `group-title="New Group"`)
+	require.NotContains(t, rewritten, "Old Group")
+	require.Contains(t, rewritten, `tvg-rec="3"`)
+}
+
+func TestRewriteWithOptions_StreamURL(t *testing.T) {
+	channels := []Channel{
+		{Name: "ESPN", URL: "http://upstream.example.com/espn"},
+		{Name: "CNN", URL: "http://upstream.example.com/cnn"},
+	}
+
+	result := RewriteWithOptions(channels, nil, RewriteOptions{
+		StreamURL: func(i int, _ Channel) string {
+			return fmt.Sprintf("http://proxy.local/auto/v%d", i+1)
+		},
+	})
+
+	require.Contains(t, result, "http://proxy.local/auto/v1\n")
+	require.Contains(t, result, "http://proxy.local/auto/v2\n")
+	require.NotContains(t, result, "upstream.example.com")
+
+	// Default rewrite still emits upstream URLs.
+	require.Contains(t, Rewrite(channels, nil), "http://upstream.example.com/espn\n")
+}
+
+func TestRewriteWithOptions_ChannelNumber(t *testing.T) {
+	channels := []Channel{
+		{Name: "ESPN", URL: "http://upstream.example.com/espn", Attributes: map[string]string{AttrTVGChno: "501"}},
+		{Name: "CNN", URL: "http://upstream.example.com/cnn"},
+	}
+
+	result := RewriteWithOptions(channels, nil, RewriteOptions{
+		ChannelNumber: func(i int, _ Channel) string {
+			return fmt.Sprintf("%d", i+1)
+		},
+	})
+
+	require.Contains(t, result, `tvg-chno="1"`)
+	require.Contains(t, result, `tvg-chno="2"`)
+	require.NotContains(t, result, `tvg-chno="501"`)
+
+	// The input channels are not modified.
+	require.Equal(t, "501", channels[0].Attributes[AttrTVGChno])
+	require.Contains(t, Rewrite(channels, nil), `tvg-chno="501"`)
+}
+
+func TestRewriteWithOptions_PreserveTVGID(t *testing.T) {
+	channels := []Channel{
+		{Name: "ESPN", TVGID: "provider-123", URL: "http://upstream.example.com/espn"},
+	}
+	channelMap := map[string]string{"espn.us": "ESPN"}
+
+	result := RewriteWithOptions(channels, channelMap, RewriteOptions{PreserveTVGID: true})
+	require.Contains(t, result, `tvg-id="provider-123"`)
+	require.NotContains(t, result, "espn.us")
+
+	// By default the matched EPG ID replaces the upstream tvg-id.
+	require.Contains(t, Rewrite(channels, channelMap), `tvg-id="espn.us"`)
+}
+
+func TestParse_Duration(t *testing.T) {
+	input := `#EXTM3U
+#EXTINF:-1 tvg-id="espn.us",ESPN
+http://stream.example.com/espn
+#EXTINF:3600 tvg-id="movie.1",Some Movie
+http://vod.example.com/movie.mp4
+#EXTINF:10.5,Clip
+http://vod.example.com/clip.mp4
+#EXTINF:,No Duration
+http://stream.example.com/none
+`
+
+	channels, err := Parse([]byte(input))
+	require.NoError(t, err)
+	require.Len(t, channels, 4)
+
+	require.Equal(t, DurationLive, channels[0].Duration)
+	require.True(t, channels[0].IsLive())
+
+	require.Equal(t, 3600, channels[1].Duration)
+	require.False(t, channels[1].IsLive())
+
+	require.Equal(t, 10, channels[2].Duration)
+	require.Equal(t, DurationLive, channels[3].Duration)
+
+	live := LiveOnly(channels)
+	require.Len(t, live, 2)
+	require.Equal(t, "ESPN", live[0].Name)
+	require.Equal(t, "No Duration", live[1].Name)
+}
+
+func TestRewrite_DurationRoundTrip(t *testing.T) {
+	input := `#EXTM3U
+#EXTINF:-1 tvg-id="espn.us",ESPN
+http://stream.example.com/espn
+#EXTINF:3600 tvg-id="movie.1",Some Movie
+http://vod.example.com/movie.mp4
+`
+
+	channels, err := Parse([]byte(input))
+	require.NoError(t, err)
+
+	output := Rewrite(channels, nil)
+	require.Contains(t, output, "#EXTINF:-1 tvg-id=\"espn.us\"")
+	require.Contains(t, output, "#EXTINF:3600 tvg-id=\"movie.1\"")
+
+	reparsed, err := Parse([]byte(output))
+	require.NoError(t, err)
+	require.Equal(t, DurationLive, reparsed[0].Duration)
+	require.Equal(t, 3600, reparsed[1].Duration)
+
+	// Channels built without a duration are written as live.
+	require.Contains(t, Rewrite([]Channel{{Name: "Manual", URL: "http://x"}}, nil), "#EXTINF:-1 ")
+}
@@ -0,0 +1,38 @@
+package m3u
+
+import "net/url"
+
+// ResolveURLs returns a copy of channels with relative stream and logo URLs
+// resolved against base, the URL the playlist was fetched from. Absolute
+// URLs are kept as-is; if base cannot be parsed, channels are returned
+// unchanged.
+func ResolveURLs(channels []Channel, base string) []Channel {
+	baseURL, err := url.Parse(base)
+	if err != nil || !baseURL.IsAbs() {
+		return channels
+	}
+
+	resolved := make([]Channel, len(channels))
+
+	for i, ch := range channels {
+		ch.URL = resolveURL(baseURL, ch.URL)
+		ch.TVGLogo = resolveURL(baseURL, ch.TVGLogo)
+		resolved[i] = ch
+	}
+
+	return resolved
+}
+
+// resolveURL resolves ref against base if it is a relative URL.
+func resolveURL(base *url.URL, ref string) string {
+	if ref == "" {
+		return ref
+	}
+
+	parsed, err := url.Parse(ref)
+	if err != nil || parsed.IsAbs() {
+		return ref
+	}
+
+	return base.ResolveReference(parsed).String()
+}
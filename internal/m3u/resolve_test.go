@@ -0,0 +1,33 @@
+package m3u
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolveURLs(t *testing.T) {
+	channels := []Channel{
+		{Name: "Absolute", URL: "http://cdn.example.com/live/1.ts", TVGLogo: "data:image/png;base64,AAAA"},
+		{Name: "Relative", URL: "live/2.ts", TVGLogo: "/logos/2.png"},
+		{Name: "Empty"},
+	}
+
+	resolved := ResolveURLs(channels, "http://backup.example.com/lists/playlist.m3u?user=a")
+
+	require.Equal(t, "http://cdn.example.com/live/1.ts", resolved[0].URL)
+	require.Equal(t, "data:image/png;base64,AAAA", resolved[0].TVGLogo)
+	require.Equal(t, "http://backup.example.com/lists/live/2.ts", resolved[1].URL)
+	require.Equal(t, "http://backup.example.com/logos/2.png", resolved[1].TVGLogo)
+	require.Empty(t, resolved[2].URL)
+	require.Empty(t, resolved[2].TVGLogo)
+
+	// The input is not modified.
+	require.Equal(t, "live/2.ts", channels[1].URL)
+}
+
+func TestResolveURLs_RelativeBase(t *testing.T) {
+	channels := []Channel{{Name: "Relative", URL: "live/2.ts"}}
+
+	require.Equal(t, channels, ResolveURLs(channels, "playlist.m3u"))
+}
@@ -0,0 +1,141 @@
+// Package names normalizes channel names, which come from both M3U playlists
+// and EPG sources, so the same channel can be recognized across the two.
+package names
+
+import (
+	"maps"
+	"slices"
+	"strings"
+)
+
+// Common country/region prefixes to strip for normalized matching.
+// Order matters: longer/more specific prefixes should come first.
+var defaultCountryPrefixes = []string{
+	// Double-space variants first (more specific)
+	"USA  ", "World  ", "AUS  ",
+	// Colon variants
+	"US:", "AU:", "AUS:", "UK:", "PH:", "BR:", "CA:", "NZ:", "MX:", "ID:",
+	// Space variants
+	"USA ", "UK ", "PH ", "BR ", "ID ", "MY ", "MX ", "AUS ",
+	// Multi-word prefixes
+	"Carib ", "World ", "Latin ", "US ",
+}
+
+// defaultRegionPrefixes maps name prefixes to normalized region codes for
+// region-aware matching.
+var defaultRegionPrefixes = map[string]string{
+	"US:": "us", "USA ": "us", "USA  ": "us", "US ": "us",
+	"AU:": "au", "AUS:": "au", "AUS ": "au", "AUS  ": "au",
+	"UK:": "uk", "UK ": "uk",
+	"PH:": "ph", "PH ": "ph",
+	"BR:": "br", "BR ": "br",
+	"CA:": "ca",
+	"NZ:": "nz",
+	"MX:": "mx", "MX ": "mx",
+	"ID:": "id", "ID ": "id",
+	"MY ":    "my",
+	"Carib ": "carib",
+	"World ": "world", "World  ": "world",
+	"Latin ": "latin",
+}
+
+// Common quality/variant suffixes to strip for normalized matching.
+var defaultQualitySuffixes = []string{
+	"(HD)", "(FHD)", "(SD)", "(4K)", "(UHD)",
+	"(S)", "(A)", "(H)", "(D)", "(C)", "(P)", "(FL)", "(F)", "(E)", "(R)",
+	"(North America)", "(EMEA)", "(PRIME)", "(TUBI)",
+	" FHD", " HD",
+}
+
+// Normalizer reduces channel names to a comparable form using prefix and
+// suffix tables. All matching against the tables is case-insensitive.
+type Normalizer struct {
+	// CountryPrefixes are stripped from the start of names, in order, so
+	// longer or more specific prefixes must come first.
+	CountryPrefixes []string
+
+	// RegionPrefixes maps name prefixes to region codes.
+	RegionPrefixes map[string]string
+
+	// QualitySuffixes are removed wherever they appear in names.
+	QualitySuffixes []string
+}
+
+// NewNormalizer returns a Normalizer with the default tables. The tables are
+// copies, so callers may extend them.
+func NewNormalizer() *Normalizer {
+	return &Normalizer{
+		CountryPrefixes: slices.Clone(defaultCountryPrefixes),
+		RegionPrefixes:  maps.Clone(defaultRegionPrefixes),
+		QualitySuffixes: slices.Clone(defaultQualitySuffixes),
+	}
+}
+
+// defaultNormalizer backs the package-level functions.
+var defaultNormalizer = NewNormalizer()
+
+// Normalize normalizes name with the default tables.
+func Normalize(name string) string {
+	return defaultNormalizer.Normalize(name)
+}
+
+// CollapseWhitespace trims name and collapses each run of whitespace inside
+// it to a single space, leaving everything else (including case) as is.
+func CollapseWhitespace(name string) string {
+	return strings.Join(strings.Fields(name), " ")
+}
+
+// Region returns the region code of name with the default tables.
+func Region(name string) string {
+	return defaultNormalizer.Region(name)
+}
+
+// Region returns the normalized region code from a channel name, or empty
+// string if none.
+func (n *Normalizer) Region(name string) string {
+	upperName := strings.ToUpper(name)
+
+	for prefix, region := range n.RegionPrefixes {
+		if strings.HasPrefix(upperName, strings.ToUpper(prefix)) {
+			return region
+		}
+	}
+
+	return ""
+}
+
+// Normalize strips country prefixes, quality suffixes, and normalizes
+// whitespace, returning the lowercased result.
+func (n *Normalizer) Normalize(name string) string {
+	normalized := name
+
+	// Strip country prefixes (case-insensitive).
+	upperName := strings.ToUpper(normalized)
+	for _, prefix := range n.CountryPrefixes {
+		if strings.HasPrefix(upperName, strings.ToUpper(prefix)) {
+			normalized = normalized[len(prefix):]
+			upperName = strings.ToUpper(normalized)
+		}
+	}
+
+	// Strip quality suffixes.
+	for _, suffix := range n.QualitySuffixes {
+		upperName = strings.ToUpper(normalized)
+		upperSuffix := strings.ToUpper(suffix)
+
+		for strings.Contains(upperName, upperSuffix) {
+			idx := strings.Index(upperName, upperSuffix)
+			if idx >= 0 {
+				normalized = normalized[:idx] + normalized[idx+len(suffix):]
+				upperName = strings.ToUpper(normalized)
+			}
+		}
+	}
+
+	// Normalize whitespace: collapse multiple spaces, trim.
+	normalized = strings.Join(strings.Fields(normalized), " ")
+	normalized = strings.TrimSpace(normalized)
+
+	// Convert to lowercase for comparison.
+	return strings.ToLower(normalized)
+}
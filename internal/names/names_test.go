@@ -0,0 +1,107 @@
+package names
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name:     "simple name",
+			input:    "ESPN",
+			expected: "espn",
+		},
+		{
+			name:     "US prefix with colon",
+			input:    "US: ESPN",
+			expected: "espn",
+		},
+		{
+			name:     "USA prefix with spaces",
+			input:    "USA  ESPN",
+			expected: "espn",
+		},
+		{
+			name:     "AU prefix",
+			input:    "AU: Fox Sports 501",
+			expected: "fox sports 501",
+		},
+		{
+			name:     "quality suffix HD",
+			input:    "ESPN (HD)",
+			expected: "espn",
+		},
+		{
+			name:     "multiple suffixes",
+			input:    "US ESPN 1 (HD) (S)",
+			expected: "espn 1",
+		},
+		{
+			name:     "Carib prefix",
+			input:    "Carib ESPN (D)",
+			expected: "espn",
+		},
+		{
+			name:     "normalize whitespace",
+			input:    "FOX   NEWS  ",
+			expected: "fox news",
+		},
+		{
+			name:     "EMEA suffix",
+			input:    "PH TFC (EMEA)",
+			expected: "tfc",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := Normalize(tt.input)
+			require.Equal(t, tt.expected, result)
+		})
+	}
+}
+
+func TestRegion(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"US: ESPN", "us"},
+		{"usa  ESPN", "us"},
+		{"AUS: Seven", "au"},
+		{"Carib ESPN (D)", "carib"},
+		{"ESPN", ""},
+		{"USATODAY", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			require.Equal(t, tt.expected, Region(tt.input))
+		})
+	}
+}
+
+func TestNormalizer_CustomTables(t *testing.T) {
+	n := NewNormalizer()
+	n.CountryPrefixes = append(n.CountryPrefixes, "DE:")
+	n.RegionPrefixes["DE:"] = "de"
+	n.QualitySuffixes = append(n.QualitySuffixes, " 50FPS")
+
+	require.Equal(t, "sky sport", n.Normalize("DE: Sky Sport 50fps"))
+	require.Equal(t, "de", n.Region("DE: Sky Sport"))
+
+	// The defaults are unaffected.
+	require.Equal(t, "de: sky sport 50fps", Normalize("DE: Sky Sport 50fps"))
+	require.Empty(t, Region("DE: Sky Sport"))
+}
+
+func TestCollapseWhitespace(t *testing.T) {
+	require.Equal(t, "ESPN 2", CollapseWhitespace("  ESPN  \t2 "))
+	require.Equal(t, "Fox News", CollapseWhitespace("Fox News"))
+}
@@ -0,0 +1,143 @@
+package server
+
+import (
+	"crypto/subtle"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// authMiddleware enforces HTTP basic auth on all paths except /health.
+func (r *Routes) authMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.URL.Path == "/health" {
+			next.ServeHTTP(w, req)
+
+			return
+		}
+
+		user, pass, ok := req.BasicAuth()
+		if !ok ||
+			subtle.ConstantTimeCompare([]byte(user), []byte(r.cfg.AuthUser)) != 1 ||
+			subtle.ConstantTimeCompare([]byte(pass), []byte(r.cfg.AuthPass)) != 1 {
+			w.Header().Set("WWW-Authenticate", `Basic realm="iptv"`)
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+
+			return
+		}
+
+		next.ServeHTTP(w, req)
+	})
+}
+
+// allowlistMiddleware rejects requests whose client IP is outside the allowed
+// CIDRs with 403. /health is always accessible.
+func (r *Routes) allowlistMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.URL.Path == "/health" {
+			next.ServeHTTP(w, req)
+
+			return
+		}
+
+		ip := r.clientIP(req)
+		if ip == nil || !containsIP(r.allowNets, ip) {
+			r.log.WithField("remote", req.RemoteAddr).Debug("Rejected request outside allowlist")
+			http.Error(w, "Forbidden", http.StatusForbidden)
+
+			return
+		}
+
+		next.ServeHTTP(w, req)
+	})
+}
+
+// pathPrefixMiddleware strips the --base path prefix (e.g. "/iptv") so the
+// advertised URLs resolve to the routes. Requests without the prefix are
+// passed through unchanged, for reverse proxies that strip it themselves, so
+// a prefix that equals a group slug shadows that group's unprefixed routes.
+func pathPrefixMiddleware(prefix string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		path, ok := stripPathPrefix(prefix, req.URL.Path)
+		if !ok {
+			next.ServeHTTP(w, req)
+
+			return
+		}
+
+		stripped := req.Clone(req.Context())
+		stripped.URL.Path = path
+		stripped.URL.RawPath = strings.TrimPrefix(req.URL.RawPath, prefix)
+
+		next.ServeHTTP(w, stripped)
+	})
+}
+
+// stripPathPrefix returns path without prefix, or false when path is not
+// under prefix. The prefix itself maps to "/".
+func stripPathPrefix(prefix, path string) (string, bool) {
+	if prefix == "" || path != prefix && !strings.HasPrefix(path, prefix+"/") {
+		return path, false
+	}
+
+	if path == prefix {
+		return "/", true
+	}
+
+	return path[len(prefix):], true
+}
+
+// clientIP returns the originating client IP. X-Forwarded-For is only honored
+// when the direct peer is a trusted proxy; the header is walked right to left
+// and the first address that is not itself a trusted proxy is returned.
+func (r *Routes) clientIP(req *http.Request) net.IP {
+	host, _, err := net.SplitHostPort(req.RemoteAddr)
+	if err != nil {
+		host = req.RemoteAddr
+	}
+
+	ip := net.ParseIP(host)
+	if ip == nil || !containsIP(r.trustedNets, ip) {
+		return ip
+	}
+
+	forwarded := strings.Split(req.Header.Get("X-Forwarded-For"), ",")
+
+	for i := len(forwarded) - 1; i >= 0; i-- {
+		hop := net.ParseIP(strings.TrimSpace(forwarded[i]))
+		if hop == nil {
+			break
+		}
+
+		ip = hop
+
+		if !containsIP(r.trustedNets, hop) {
+			break
+		}
+	}
+
+	return ip
+}
+
+// parseCIDRs parses CIDR strings, skipping invalid entries (validated in config).
+func parseCIDRs(cidrs []string) []*net.IPNet {
+	nets := make([]*net.IPNet, 0, len(cidrs))
+
+	for _, cidr := range cidrs {
+		if _, ipNet, err := net.ParseCIDR(cidr); err == nil {
+			nets = append(nets, ipNet)
+		}
+	}
+
+	return nets
+}
+
+func containsIP(nets []*net.IPNet, ip net.IP) bool {
+	for _, ipNet := range nets {
+		if ipNet.Contains(ip) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,166 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/savid/iptv/internal/hdhr"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAuthMiddleware(t *testing.T) {
+	log, _ := newTestLogger()
+	cfg := newTestConfig()
+	cfg.AuthUser = "plex"
+	cfg.AuthPass = "secret"
+
+	handler := NewRoutes(log, cfg, newTestStore()).Handler()
+
+	tests := []struct {
+		name       string
+		path       string
+		user       string
+		pass       string
+		setAuth    bool
+		wantStatus int
+	}{
+		{name: "no credentials", path: "/lineup.json", wantStatus: http.StatusUnauthorized},
+		{name: "wrong password", path: "/lineup.json", user: "plex", pass: "wrong", setAuth: true, wantStatus: http.StatusUnauthorized},
+		{name: "correct credentials", path: "/lineup.json", user: "plex", pass: "secret", setAuth: true, wantStatus: http.StatusOK},
+		{name: "health exempt", path: "/health", wantStatus: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			if tt.setAuth {
+				req.SetBasicAuth(tt.user, tt.pass)
+			}
+
+			w := httptest.NewRecorder()
+
+			handler.ServeHTTP(w, req)
+
+			require.Equal(t, tt.wantStatus, w.Code)
+
+			if tt.wantStatus == http.StatusUnauthorized {
+				require.NotEmpty(t, w.Header().Get("WWW-Authenticate"))
+			}
+		})
+	}
+}
+
+func TestAuthMiddleware_DisabledByDefault(t *testing.T) {
+	log, _ := newTestLogger()
+	cfg := newTestConfig()
+
+	handler := NewRoutes(log, cfg, newTestStore()).Handler()
+
+	req := httptest.NewRequest(http.MethodGet, "/lineup.json", nil)
+	w := httptest.NewRecorder()
+
+	handler.ServeHTTP(w, req)
+
+	require.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestAllowlistMiddleware(t *testing.T) {
+	log, _ := newTestLogger()
+	cfg := newTestConfig()
+	cfg.AllowCIDRs = []string{"192.168.1.0/24", "10.0.0.5/32"}
+	cfg.TrustedProxies = []string{"172.16.0.0/12"}
+
+	handler := NewRoutes(log, cfg, newTestStore()).Handler()
+
+	tests := []struct {
+		name       string
+		path       string
+		remote     string
+		forwarded  string
+		wantStatus int
+	}{
+		{name: "allowed LAN address", path: "/lineup.json", remote: "192.168.1.20:5000", wantStatus: http.StatusOK},
+		{name: "allowed single host", path: "/lineup.json", remote: "10.0.0.5:5000", wantStatus: http.StatusOK},
+		{name: "disallowed address", path: "/lineup.json", remote: "203.0.113.9:5000", wantStatus: http.StatusForbidden},
+		{name: "health always allowed", path: "/health", remote: "203.0.113.9:5000", wantStatus: http.StatusOK},
+		{name: "forwarded from trusted proxy", path: "/lineup.json", remote: "172.16.0.2:5000", forwarded: "192.168.1.30", wantStatus: http.StatusOK},
+		{name: "forwarded disallowed via trusted proxy", path: "/lineup.json", remote: "172.16.0.2:5000", forwarded: "203.0.113.9", wantStatus: http.StatusForbidden},
+		{name: "forwarded header from untrusted peer ignored", path: "/lineup.json", remote: "203.0.113.9:5000", forwarded: "192.168.1.30", wantStatus: http.StatusForbidden},
+		{name: "spoofed leftmost entry ignored", path: "/lineup.json", remote: "172.16.0.2:5000", forwarded: "192.168.1.30, 203.0.113.9", wantStatus: http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			req.RemoteAddr = tt.remote
+
+			if tt.forwarded != "" {
+				req.Header.Set("X-Forwarded-For", tt.forwarded)
+			}
+
+			w := httptest.NewRecorder()
+
+			handler.ServeHTTP(w, req)
+
+			require.Equal(t, tt.wantStatus, w.Code)
+		})
+	}
+}
+
+func TestPathPrefix(t *testing.T) {
+	log, _ := newTestLogger()
+	cfg := newTestConfig()
+	cfg.BaseURL = "https://host.example.com/iptv"
+
+	handler := NewRoutes(log, cfg, newTestStore()).Handler()
+
+	get := func(path string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		w := httptest.NewRecorder()
+
+		handler.ServeHTTP(w, req)
+
+		return w
+	}
+
+	w := get("/iptv/discover.json")
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var discovery hdhr.DiscoveryJSON
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &discovery))
+	require.Equal(t, "https://host.example.com/iptv/lineup.json", discovery.LineupURL)
+
+	// The advertised lineup URL is served, and tuning works under the prefix.
+	require.Equal(t, http.StatusOK, get(strings.TrimPrefix(discovery.LineupURL, "https://host.example.com")).Code)
+	require.Equal(t, http.StatusTemporaryRedirect, get("/iptv/auto/v1").Code)
+	require.Equal(t, http.StatusTemporaryRedirect, get("/iptv/sports/auto/v1").Code)
+
+	require.Equal(t, http.StatusOK, get("/iptv/sports/lineup.json").Code)
+	require.Equal(t, http.StatusOK, get("/iptv/health").Code)
+	require.Equal(t, http.StatusOK, get("/iptv").Code)
+
+	// Requests whose prefix was already stripped by the reverse proxy still work.
+	require.Equal(t, http.StatusOK, get("/lineup.json").Code)
+}
+
+func TestStripPathPrefix(t *testing.T) {
+	tests := []struct {
+		prefix, path, want string
+		ok                 bool
+	}{
+		{"/iptv", "/iptv/lineup.json", "/lineup.json", true},
+		{"/iptv", "/iptv", "/", true},
+		{"/iptv", "/iptvx/lineup.json", "/iptvx/lineup.json", false},
+		{"/iptv", "/lineup.json", "/lineup.json", false},
+		{"", "/lineup.json", "/lineup.json", false},
+	}
+
+	for _, tt := range tests {
+		got, ok := stripPathPrefix(tt.prefix, tt.path)
+		require.Equal(t, tt.want, got, tt.path)
+		require.Equal(t, tt.ok, ok, tt.path)
+	}
+}
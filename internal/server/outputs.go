@@ -0,0 +1,44 @@
+package server
+
+import (
+	"bytes"
+
+	"github.com/savid/iptv/internal/data"
+	"github.com/savid/iptv/internal/epg"
+)
+
+// WriteOutputs writes the M3U and EPG to the --write-m3u and --write-epg
+// paths, built exactly as /iptv.m3u and /epg.xml serve them. Failures are
+// logged but do not fail the refresh, since the in-memory data is already
+// updated.
+func (r *Routes) WriteOutputs() {
+	if r.cfg.WriteM3U != "" {
+		if playlist, ok := r.playlist(false); ok {
+			r.writeOutput(r.cfg.WriteM3U, "M3U", playlist, nil)
+		}
+	}
+
+	if r.cfg.WriteEPG != "" {
+		if guide, ok := r.guide(r.hdhrHandlers); ok {
+			var buf bytes.Buffer
+
+			err := epg.WriteWithOptions(&buf, guide, epgMarshalOptions(r.cfg))
+			r.writeOutput(r.cfg.WriteEPG, "EPG", buf.Bytes(), err)
+		}
+	}
+}
+
+// writeOutput writes content to path unless rendering it failed with err.
+func (r *Routes) writeOutput(path, kind string, content []byte, err error) {
+	if err == nil {
+		err = data.WriteFileAtomic(path, content, 0o644)
+	}
+
+	if err != nil {
+		r.log.WithError(err).WithField("path", path).Errorf("Failed to write %s file", kind)
+
+		return
+	}
+
+	r.log.WithField("path", path).Debugf("Wrote %s file", kind)
+}
@@ -0,0 +1,115 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/savid/iptv/internal/config"
+	"github.com/savid/iptv/internal/data"
+	"github.com/savid/iptv/internal/epg"
+	"github.com/savid/iptv/internal/hdhr"
+	"github.com/savid/iptv/internal/m3u"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteOutputs_MatchServedFiles(t *testing.T) {
+	log, _ := newTestLogger()
+	dir := t.TempDir()
+
+	cfg := newTestConfig()
+	cfg.WriteM3U = filepath.Join(dir, "iptv.m3u")
+	cfg.WriteEPG = filepath.Join(dir, "epg.xml")
+	cfg.DedupeRootLineup = true
+	cfg.DuplicateNames = config.DuplicateNamesSuffix
+	cfg.M3UChannelNumbers = true
+	cfg.EPGGuideNumbers = true
+
+	store := data.NewStore()
+	store.SetM3U([]m3u.Channel{
+		{Name: "ESPN", TVGID: "espn.us", URL: "http://stream.example.com/espn", Group: "Sports"},
+		{Name: "ESPN", TVGID: "espn.us", URL: "http://stream.example.com/espn", Group: "Favourites"},
+		{Name: "CNN", TVGID: "cnn.us", URL: "http://stream.example.com/cnn", Group: "News"},
+		{Name: "CNN", TVGID: "cnn.us", URL: "http://stream.example.com/cnn-backup", Group: "News"},
+	})
+
+	start := time.Now().Truncate(time.Hour)
+	store.SetEPG(&epg.TV{
+		Channels: []epg.Channel{
+			{ID: "espn.us", DisplayName: "ESPN"},
+			{ID: "cnn.us", DisplayName: "CNN"},
+		},
+		Programs: []epg.Programme{
+			{Channel: "espn.us", Start: start.Format("20060102150405 -0700"), Stop: start.Add(time.Hour).Format("20060102150405 -0700"), Title: "SportsCenter"},
+		},
+	}, map[string]string{"espn.us": "ESPN", "cnn.us": "CNN"})
+
+	routes := NewRoutes(log, cfg, store)
+	routes.WriteOutputs()
+
+	playlist, err := os.ReadFile(cfg.WriteM3U)
+	require.NoError(t, err)
+	// The root lineup dedupes by URL.
+	require.Equal(t, 1, strings.Count(string(playlist), "http://stream.example.com/espn"))
+
+	handler := routes.Handler()
+
+	for path, file := range map[string]string{"/iptv.m3u": cfg.WriteM3U, "/epg.xml": cfg.WriteEPG} {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		require.Equal(t, http.StatusOK, w.Code)
+
+		written, err := os.ReadFile(file)
+		require.NoError(t, err)
+		require.Equal(t, w.Body.String(), string(written), path)
+
+		info, err := os.Stat(file)
+		require.NoError(t, err)
+		require.Equal(t, os.FileMode(0o644), info.Mode().Perm())
+	}
+}
+
+func TestWriteOutputs_ChannelNumbersMatchLineup(t *testing.T) {
+	log, _ := newTestLogger()
+
+	cfg := newTestConfig()
+	cfg.WriteM3U = filepath.Join(t.TempDir(), "iptv.m3u")
+	cfg.M3UChannelNumbers = true
+	cfg.DedupeRootLineup = true
+
+	store := data.NewStore()
+	store.SetM3U([]m3u.Channel{
+		{Name: "ESPN", URL: "http://stream.example.com/espn", Group: "Sports"},
+		{Name: "ESPN", URL: "http://stream.example.com/espn", Group: "Favourites"},
+		{Name: "CNN", URL: "http://stream.example.com/cnn", Group: "News"},
+	})
+
+	routes := NewRoutes(log, cfg, store)
+	routes.WriteOutputs()
+
+	written, err := os.ReadFile(cfg.WriteM3U)
+	require.NoError(t, err)
+
+	channels, err := m3u.Parse(written)
+	require.NoError(t, err)
+
+	w := httptest.NewRecorder()
+	routes.Handler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/lineup.json", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var lineup []hdhr.LineupItem
+
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&lineup))
+
+	// The deduplicated ESPN leaves CNN as channel 2 in both.
+	require.Len(t, channels, len(lineup))
+
+	for i, ch := range channels {
+		require.Equal(t, lineup[i].GuideNumber, ch.Attributes[m3u.AttrTVGChno], ch.Name)
+	}
+}
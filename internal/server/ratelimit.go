@@ -0,0 +1,113 @@
+package server
+
+import (
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxTuneBuckets is how many client buckets are kept before idle, full
+// buckets are swept.
+const maxTuneBuckets = 1024
+
+// tuneLimiter is a per-client token bucket: each client may tune burst times
+// in quick succession, refilled at rate tunes per minute.
+type tuneLimiter struct {
+	mu      sync.Mutex
+	rate    float64 // Tokens per second
+	burst   float64
+	buckets map[string]*tuneBucket
+	now     func() time.Time
+}
+
+type tuneBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// newTuneLimiter creates a limiter allowing perMinute tunes per minute per
+// client, with a burst of the same size (at least 1).
+func newTuneLimiter(perMinute float64) *tuneLimiter {
+	return &tuneLimiter{
+		rate:    perMinute / 60,
+		burst:   max(perMinute, 1),
+		buckets: make(map[string]*tuneBucket),
+		now:     time.Now,
+	}
+}
+
+// allow takes a token from client's bucket, reporting false if it is empty.
+func (l *tuneLimiter) allow(client string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+
+	bucket, ok := l.buckets[client]
+	if !ok {
+		if len(l.buckets) >= maxTuneBuckets {
+			l.sweep(now)
+		}
+
+		bucket = &tuneBucket{tokens: l.burst, last: now}
+		l.buckets[client] = bucket
+	}
+
+	bucket.tokens = min(l.burst, bucket.tokens+now.Sub(bucket.last).Seconds()*l.rate)
+	bucket.last = now
+
+	if bucket.tokens < 1 {
+		return false
+	}
+
+	bucket.tokens--
+
+	return true
+}
+
+// sweep drops buckets that have refilled completely, which behave exactly
+// like a new bucket.
+func (l *tuneLimiter) sweep(now time.Time) {
+	for client, bucket := range l.buckets {
+		if bucket.tokens+now.Sub(bucket.last).Seconds()*l.rate >= l.burst {
+			delete(l.buckets, client)
+		}
+	}
+}
+
+// isTunePath reports whether path is a tuning URL: /auto/... or
+// /catchup/... on the root device, or /{slug}/auto/... on a group device.
+func isTunePath(path string) bool {
+	parts := strings.SplitN(strings.TrimPrefix(path, "/"), "/", 3)
+
+	return (len(parts) > 1 && (parts[0] == "auto" || parts[0] == "catchup")) ||
+		(len(parts) > 2 && parts[1] == "auto")
+}
+
+// tuneLimitMiddleware answers tuning requests from clients over the
+// configured tune rate with 429.
+func (r *Routes) tuneLimitMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if !isTunePath(req.URL.Path) {
+			next.ServeHTTP(w, req)
+
+			return
+		}
+
+		client := req.RemoteAddr
+		if ip := r.clientIP(req); ip != nil {
+			client = ip.String()
+		}
+
+		if !r.tuneLimiter.allow(client) {
+			r.log.WithField("client", client).Warn("Tune rate limit exceeded")
+			w.Header().Set("Retry-After", "60")
+			http.Error(w, "Too many tune requests", http.StatusTooManyRequests)
+
+			return
+		}
+
+		next.ServeHTTP(w, req)
+	})
+}
@@ -0,0 +1,76 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTuneLimiter_Refill(t *testing.T) {
+	now := time.Date(2026, 1, 4, 12, 0, 0, 0, time.UTC)
+
+	limiter := newTuneLimiter(2)
+	limiter.now = func() time.Time { return now }
+
+	require.True(t, limiter.allow("10.0.0.1"))
+	require.True(t, limiter.allow("10.0.0.1"))
+	require.False(t, limiter.allow("10.0.0.1"))
+
+	// Other clients have their own bucket.
+	require.True(t, limiter.allow("10.0.0.2"))
+
+	// Two tunes a minute refill one token every 30 seconds.
+	now = now.Add(30 * time.Second)
+
+	require.True(t, limiter.allow("10.0.0.1"))
+	require.False(t, limiter.allow("10.0.0.1"))
+}
+
+func TestIsTunePath(t *testing.T) {
+	require.True(t, isTunePath("/auto/v1"))
+	require.True(t, isTunePath("/sports/auto/v1"))
+	require.True(t, isTunePath("/catchup/v1"))
+	require.False(t, isTunePath("/lineup.json"))
+	require.False(t, isTunePath("/sports/lineup.json"))
+	require.False(t, isTunePath("/auto"))
+}
+
+func TestTuneRate_RapidTunesGet429(t *testing.T) {
+	log, _ := newTestLogger()
+	cfg := newTestConfig()
+	cfg.TuneRate = 3
+
+	handler := NewRoutes(log, cfg, newTestStore()).Handler()
+
+	tune := func(path, remote string) int {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.RemoteAddr = remote
+		w := httptest.NewRecorder()
+
+		handler.ServeHTTP(w, req)
+
+		return w.Code
+	}
+
+	var limited bool
+
+	for range 10 {
+		if tune("/auto/v1", "192.0.2.1:1234") == http.StatusTooManyRequests {
+			limited = true
+
+			break
+		}
+	}
+
+	require.True(t, limited, "rapid tunes from one IP should be limited")
+
+	// Group tuning URLs share the client's bucket.
+	require.Equal(t, http.StatusTooManyRequests, tune("/sports/auto/v1", "192.0.2.1:5678"))
+
+	// Other clients and non-tuning requests are unaffected.
+	require.Equal(t, http.StatusTemporaryRedirect, tune("/auto/v1", "192.0.2.2:1234"))
+	require.Equal(t, http.StatusOK, tune("/lineup.json", "192.0.2.1:1234"))
+}
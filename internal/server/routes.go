@@ -2,10 +2,16 @@
 package server
 
 import (
+	"crypto/sha256"
 	"encoding/json"
+	"fmt"
+	"net"
 	"net/http"
+	"net/url"
+	"strconv"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/savid/iptv/internal/config"
 	"github.com/savid/iptv/internal/data"
@@ -22,9 +28,36 @@ type Routes struct {
 	store        *data.Store
 	hdhrHandlers *hdhr.Handlers
 
+	// Access logging: requests are logged at accessLogLevel, except paths in
+	// accessLogSkip which are only logged at debug level.
+	accessLogLevel logrus.Level
+	accessLogSkip  map[string]bool
+
+	// IP allowlist and trusted reverse proxies, parsed from config.
+	allowNets   []*net.IPNet
+	trustedNets []*net.IPNet
+
+	// Per-client tune rate limit, nil when disabled.
+	tuneLimiter *tuneLimiter
+
+	// Clip looped when a proxied stream fails, nil when not configured.
+	offlineClip *hdhr.OfflineClip
+
 	// Group handlers are created dynamically based on M3U data.
 	groupHandlersMu sync.RWMutex
 	groupHandlers   map[string]*hdhr.Handlers // slug -> handlers
+
+	// SHA-256 of each device's guide, valid while the store data and the
+	// disabled set are at epgSumsVersion.
+	epgSumsMu      sync.Mutex
+	epgSums        map[*hdhr.Handlers][]byte
+	epgSumsVersion guideVersion
+}
+
+// guideVersion identifies the data a served guide was built from.
+type guideVersion struct {
+	data     uint64
+	disabled uint64
 }
 
 // NewRoutes creates a new routes instance.
@@ -33,12 +66,52 @@ func NewRoutes(
 	cfg *config.Config,
 	store *data.Store,
 ) *Routes {
-	return &Routes{
-		log:           log.WithField("component", "routes"),
-		cfg:           cfg,
-		store:         store,
-		hdhrHandlers:  hdhr.NewHandlers(log, cfg, store),
-		groupHandlers: make(map[string]*hdhr.Handlers),
+	accessLogLevel, err := logrus.ParseLevel(cfg.AccessLogLevel)
+	if err != nil {
+		accessLogLevel = logrus.InfoLevel
+	}
+
+	accessLogSkip := make(map[string]bool, len(cfg.AccessLogSkip))
+
+	for _, path := range cfg.AccessLogSkip {
+		accessLogSkip[path] = true
+	}
+
+	var limiter *tuneLimiter
+	if cfg.TuneRate > 0 {
+		limiter = newTuneLimiter(cfg.TuneRate)
+	}
+
+	routes := &Routes{
+		log:            log.WithField("component", "routes"),
+		cfg:            cfg,
+		store:          store,
+		hdhrHandlers:   hdhr.NewHandlers(log, cfg, store),
+		accessLogLevel: accessLogLevel,
+		accessLogSkip:  accessLogSkip,
+		allowNets:      parseCIDRs(cfg.AllowCIDRs),
+		trustedNets:    parseCIDRs(cfg.TrustedProxies),
+		tuneLimiter:    limiter,
+		groupHandlers:  make(map[string]*hdhr.Handlers),
+	}
+
+	// Tuner leases record the client behind any trusted reverse proxy.
+	routes.hdhrHandlers.SetClientIP(routes.clientIP)
+
+	return routes
+}
+
+// SetOfflineClip sets the clip every device loops when a proxied upstream
+// stream fails.
+func (r *Routes) SetOfflineClip(clip *hdhr.OfflineClip) {
+	r.groupHandlersMu.Lock()
+	defer r.groupHandlersMu.Unlock()
+
+	r.offlineClip = clip
+	r.hdhrHandlers.SetOfflineClip(clip)
+
+	for _, handler := range r.groupHandlers {
+		handler.SetOfflineClip(clip)
 	}
 }
 
@@ -53,18 +126,53 @@ func (r *Routes) Handler() http.Handler {
 	mux.HandleFunc("/lineup_status.json", r.hdhrHandlers.LineupStatus)
 	mux.HandleFunc("/auto/", r.hdhrHandlers.AutoTune)
 
+	if r.cfg.ProxyCatchup {
+		mux.HandleFunc("GET /catchup/", r.hdhrHandlers.Catchup)
+	}
+
 	// Data endpoints
 	mux.HandleFunc("/iptv.m3u", r.handleM3U)
 	mux.HandleFunc("/epg.xml", r.handleEPG)
 
+	// Decoded data URI logos
+	mux.HandleFunc("GET "+data.LogoPathPrefix+"{key}", r.handleLogo)
+
 	// Health check
 	mux.HandleFunc("/health", r.handleHealth)
 
+	// API endpoints
+	mux.HandleFunc("/api/unmatched.json", r.handleUnmatched)
+	mux.HandleFunc("/api/match-report.json", r.handleMatchReport)
+	mux.HandleFunc("GET /api/next.json", r.handleNext)
+	mux.HandleFunc("GET /api/debug/m3u.json", r.handleM3UDebug)
+	mux.HandleFunc("GET /api/channels.json", r.handleChannels)
+	mux.HandleFunc("GET /api/channels/disabled.json", r.handleDisabledList)
+	mux.HandleFunc("POST /api/channels/{id}/disable", r.handleSetChannelDisabled(true))
+	mux.HandleFunc("POST /api/channels/{id}/enable", r.handleSetChannelDisabled(false))
+
 	// Catch-all for root XML and group routes
 	mux.HandleFunc("/", r.handleRootOrGroup)
 
+	var handler http.Handler = mux
+
+	if r.tuneLimiter != nil {
+		handler = r.tuneLimitMiddleware(handler)
+	}
+
+	if r.cfg.AuthEnabled() {
+		handler = r.authMiddleware(handler)
+	}
+
+	if len(r.allowNets) > 0 {
+		handler = r.allowlistMiddleware(handler)
+	}
+
+	if prefix := r.cfg.PathPrefix(); prefix != "" {
+		handler = pathPrefixMiddleware(prefix, handler)
+	}
+
 	// Wrap with logging middleware
-	return r.loggingMiddleware(mux)
+	return r.loggingMiddleware(handler)
 }
 
 // handleRootOrGroup handles the root path and dynamically routes to group handlers.
@@ -116,6 +224,10 @@ func (r *Routes) handleRootOrGroup(w http.ResponseWriter, req *http.Request) {
 		handler.Lineup(w, req)
 	case remainder == "lineup_status.json":
 		handler.LineupStatus(w, req)
+	case remainder == "epg.xml":
+		r.serveEPG(w, req, handler)
+	case remainder == "api/next.json":
+		r.serveNext(w, req, handler)
 	case strings.HasPrefix(remainder, "auto/"):
 		handler.AutoTune(w, req)
 	default:
@@ -123,12 +235,36 @@ func (r *Routes) handleRootOrGroup(w http.ResponseWriter, req *http.Request) {
 	}
 }
 
-// getGroupHandler returns the handler for a group slug, creating it if necessary.
+// getGroupHandler returns the handler for a group or sub-tuner slug,
+// creating it if necessary. Cached handlers are replaced if a refresh
+// remapped the slug to another group or sub-tuner.
 func (r *Routes) getGroupHandler(slug string) *hdhr.Handlers {
+	var (
+		groupName string
+		shard     data.Shard
+	)
+
+	if group, ok := r.store.GroupBySlug(slug); ok {
+		// Groups beyond --max-group-tuners are only on the root device.
+		if !r.store.IsTunerGroup(group) {
+			return nil
+		}
+
+		groupName = group
+	} else if shard, ok = r.store.ShardBySlug(slug); ok {
+		groupName = shard.Group
+	} else {
+		return nil
+	}
+
+	current := func(handler *hdhr.Handlers) bool {
+		return handler.Group() == groupName && handler.Shard() == shard.Index
+	}
+
 	// Check cache first
 	r.groupHandlersMu.RLock()
 
-	if handler, ok := r.groupHandlers[slug]; ok {
+	if handler, cached := r.groupHandlers[slug]; cached && current(handler) {
 		r.groupHandlersMu.RUnlock()
 
 		return handler
@@ -136,37 +272,30 @@ func (r *Routes) getGroupHandler(slug string) *hdhr.Handlers {
 
 	r.groupHandlersMu.RUnlock()
 
-	// Find the group name that matches this slug
-	groups := r.store.GetGroups()
-
-	var groupName string
-
-	for _, g := range groups {
-		if hdhr.Slugify(g) == slug {
-			groupName = g
-
-			break
-		}
-	}
-
-	if groupName == "" {
-		return nil
-	}
-
 	// Create and cache the handler
 	r.groupHandlersMu.Lock()
 	defer r.groupHandlersMu.Unlock()
 
 	// Double-check after acquiring write lock
-	if handler, ok := r.groupHandlers[slug]; ok {
+	if handler, cached := r.groupHandlers[slug]; cached && current(handler) {
 		return handler
 	}
 
-	handler := hdhr.NewGroupHandlers(r.log, r.cfg, r.store, groupName)
+	var handler *hdhr.Handlers
+	if shard.Index > 0 {
+		handler = hdhr.NewShardHandlers(r.log, r.cfg, r.store, shard)
+	} else {
+		handler = hdhr.NewGroupHandlers(r.log, r.cfg, r.store, groupName)
+	}
+
+	handler.SetClientIP(r.clientIP)
+	handler.SetOfflineClip(r.offlineClip)
+
 	r.groupHandlers[slug] = handler
 
 	r.log.WithFields(logrus.Fields{
 		"group":    groupName,
+		"shard":    shard.Index,
 		"slug":     slug,
 		"deviceID": handler.DeviceID(),
 	}).Info("Created group tuner handler")
@@ -175,34 +304,85 @@ func (r *Routes) getGroupHandler(slug string) *hdhr.Handlers {
 }
 
 func (r *Routes) handleM3U(w http.ResponseWriter, req *http.Request) {
-	channels, ok := r.store.GetM3U()
+	// ?proxy=1 points every entry at the proxy's tuning URL instead of upstream.
+	proxy, _ := strconv.ParseBool(req.URL.Query().Get("proxy"))
+
+	rewritten, ok := r.playlist(proxy)
 	if !ok {
 		http.Error(w, "No M3U data available", http.StatusServiceUnavailable)
 
 		return
 	}
 
-	_, channelMap, _ := r.store.GetEPG()
+	w.Header().Set("Content-Type", "application/x-mpegurl")
 
-	rewritten := m3u.Rewrite(channels, channelMap)
+	if r.writeCacheHeaders(w, req, rewritten) {
+		return
+	}
 
-	w.Header().Set("Content-Type", "application/x-mpegurl")
 	w.WriteHeader(http.StatusOK)
 
-	if _, err := w.Write([]byte(rewritten)); err != nil {
+	if _, err := w.Write(rewritten); err != nil {
 		r.log.WithError(err).Error("Failed to write M3U response")
 	}
 }
 
+// playlist renders the root lineup's channels as an M3U playlist. It returns
+// false if no playlist has been loaded.
+func (r *Routes) playlist(proxy bool) ([]byte, bool) {
+	// Same channels (and numbering) as the root lineup, so ?proxy=1 URLs line up.
+	channels, ok := r.hdhrHandlers.Channels()
+	if !ok {
+		return nil, false
+	}
+
+	_, channelMap, _ := r.store.GetEPG()
+
+	opts := m3u.RewriteOptions{PreserveTVGID: r.cfg.PreserveTVGID}
+	numbers := r.hdhrHandlers.GuideNumbers(channels)
+
+	if r.cfg.M3UChannelNumbers {
+		opts.ChannelNumber = func(i int, _ m3u.Channel) string {
+			return numbers[i]
+		}
+	}
+
+	if proxy {
+		opts.StreamURL = func(i int, _ m3u.Channel) string {
+			return fmt.Sprintf("%s/auto/v%s", r.cfg.BaseURL, url.PathEscape(numbers[i]))
+		}
+
+		if r.cfg.ProxyCatchup {
+			opts.CatchupSource = func(i int, _ m3u.Channel) string {
+				return fmt.Sprintf("%s/catchup/v%s?start={utc}&end={utcend}", r.cfg.BaseURL, url.PathEscape(numbers[i]))
+			}
+		}
+	}
+
+	return []byte(m3u.RewriteWithOptions(channels, channelMap, opts)), true
+}
+
 func (r *Routes) handleEPG(w http.ResponseWriter, req *http.Request) {
-	epgData, _, ok := r.store.GetEPG()
+	r.serveEPG(w, req, r.hdhrHandlers)
+}
+
+// serveEPG serves the EPG for a tuner device. Group devices get only the
+// channels in their lineup.
+func (r *Routes) serveEPG(w http.ResponseWriter, req *http.Request, handler *hdhr.Handlers) {
+	// Read the version before the data, so a refresh in between can only
+	// leave a sum cached under the older version.
+	version := guideVersion{data: r.store.Version(), disabled: r.store.Disabled().Version()}
+
+	epgData, ok := r.guide(handler)
 	if !ok {
 		http.Error(w, "No EPG data available", http.StatusServiceUnavailable)
 
 		return
 	}
 
-	xmlData, err := epg.Marshal(epgData)
+	opts := epgMarshalOptions(r.cfg)
+
+	sum, err := r.guideSum(handler, version, epgData, opts)
 	if err != nil {
 		r.log.WithError(err).Error("Failed to marshal EPG")
 		http.Error(w, "Failed to generate EPG", http.StatusInternalServerError)
@@ -211,22 +391,469 @@ func (r *Routes) handleEPG(w http.ResponseWriter, req *http.Request) {
 	}
 
 	w.Header().Set("Content-Type", "application/xml")
+
+	if r.writeCacheHeadersSum(w, req, sum) {
+		return
+	}
+
 	w.WriteHeader(http.StatusOK)
 
-	if _, err := w.Write(xmlData); err != nil {
+	if err := epg.WriteWithOptions(w, epgData, opts); err != nil {
 		r.log.WithError(err).Error("Failed to write EPG response")
 	}
 }
 
+// guideSum returns the SHA-256 of handler's encoded guide for the ETag. The
+// guide is only encoded to hash it once per version, so a request streams it
+// to the client without encoding it twice, and a 304 doesn't encode it at all.
+func (r *Routes) guideSum(handler *hdhr.Handlers, version guideVersion, epgData *epg.TV, opts epg.MarshalOptions) ([]byte, error) {
+	r.epgSumsMu.Lock()
+	defer r.epgSumsMu.Unlock()
+
+	if r.epgSumsVersion != version || r.epgSums == nil {
+		r.epgSums = make(map[*hdhr.Handlers][]byte)
+		r.epgSumsVersion = version
+	}
+
+	if sum, ok := r.epgSums[handler]; ok {
+		return sum, nil
+	}
+
+	hash := sha256.New()
+
+	if err := epg.WriteWithOptions(hash, epgData, opts); err != nil {
+		return nil, err
+	}
+
+	sum := hash.Sum(nil)
+	r.epgSums[handler] = sum
+
+	return sum, nil
+}
+
+// guide returns the EPG for handler's lineup, ordered, named and numbered as
+// the lineup is. It returns false if no EPG or lineup is available.
+func (r *Routes) guide(handler *hdhr.Handlers) (*epg.TV, bool) {
+	epgData, channelMap, ok := r.store.GetEPG()
+	if !ok {
+		return nil, false
+	}
+
+	// The full playlist orders the root EPG; a group's lineup orders its own.
+	orderChannels, hasOrder := r.store.GetM3U()
+
+	if !handler.IsRoot() {
+		lineup, hasLineup := handler.Channels()
+		if !hasLineup {
+			return nil, false
+		}
+
+		epgData = epg.SelectChannels(epgData, lineup, channelMap)
+		orderChannels, hasOrder = lineup, true
+	}
+
+	if r.cfg.EPGSortChannels && hasOrder {
+		epgData = epg.SortByLineup(epgData, orderChannels, channelMap)
+	}
+
+	if r.cfg.DuplicateNames == config.DuplicateNamesSuffix {
+		// Give each suffixed lineup name ("ESPN (2)") a matching display-name.
+		if channels, hasChannels := handler.Channels(); hasChannels {
+			epgData = epg.AddDuplicateNames(epgData, channels, channelMap)
+		}
+	}
+
+	if r.cfg.EPGGuideNumbers {
+		// Number channels exactly as the lineup does so Plex can correlate them.
+		if channels, hasChannels := handler.Channels(); hasChannels {
+			epgData = epg.AddFormattedGuideNumbers(epgData, channels, handler.GuideNumbers(channels), channelMap)
+		}
+	}
+
+	return epg.TruncateDescriptions(epgData, r.cfg.MaxDescLength), true
+}
+
+// writeCacheHeaders sets Cache-Control and ETag headers for a response body.
+// Returns true if the client's cached copy is current and a 304 was written.
+func (r *Routes) writeCacheHeaders(w http.ResponseWriter, req *http.Request, body []byte) bool {
+	sum := sha256.Sum256(body)
+
+	return r.writeCacheHeadersSum(w, req, sum[:])
+}
+
+// writeCacheHeadersSum is writeCacheHeaders for a body already hashed with
+// SHA-256.
+func (r *Routes) writeCacheHeadersSum(w http.ResponseWriter, req *http.Request, sum []byte) bool {
+	etag := fmt.Sprintf(`"%x"`, sum[:16])
+
+	w.Header().Set("Cache-Control", fmt.Sprintf("max-age=%d", int(r.cfg.EffectiveCacheMaxAge().Seconds())))
+	w.Header().Set("ETag", etag)
+
+	if match := req.Header.Get("If-None-Match"); match != "" && match == etag {
+		w.WriteHeader(http.StatusNotModified)
+
+		return true
+	}
+
+	return false
+}
+
+// channelStatus is a playlist channel with its stream probe results.
+type channelStatus struct {
+	Name        string    `json:"name"`
+	TVGID       string    `json:"tvgId,omitempty"`
+	Group       string    `json:"group,omitempty"`
+	Probed      bool      `json:"probed"`
+	Alive       bool      `json:"alive"`
+	Dead        bool      `json:"dead"`
+	Failures    int       `json:"failures"`
+	LastProbe   time.Time `json:"lastProbe,omitzero"`
+	LastSuccess time.Time `json:"lastSuccess,omitzero"`
+	LastError   string    `json:"lastError,omitempty"`
+}
+
+// handleChannels lists every playlist channel, including hidden ones, with
+// the results of the background stream probes.
+func (r *Routes) handleChannels(w http.ResponseWriter, _ *http.Request) {
+	channels, ok := r.store.GetM3U()
+	if !ok {
+		http.Error(w, "No M3U data available", http.StatusServiceUnavailable)
+
+		return
+	}
+
+	health := r.store.ChannelHealth()
+	statuses := make([]channelStatus, 0, len(channels))
+
+	for _, ch := range channels {
+		status := channelStatus{
+			Name:  ch.Name,
+			TVGID: ch.TVGID,
+			Group: ch.Group,
+		}
+
+		if probe, probed := health.Get(ch.URL); probed {
+			status.Probed = true
+			status.Alive = probe.Alive
+			status.Dead = health.Dead(ch.URL, r.cfg.DeadAfter)
+			status.Failures = probe.Failures
+			status.LastProbe = probe.LastProbe
+			status.LastSuccess = probe.LastSuccess
+			status.LastError = probe.LastError
+		}
+
+		statuses = append(statuses, status)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	if err := json.NewEncoder(w).Encode(statuses); err != nil {
+		r.log.WithError(err).Error("Failed to write channels response")
+	}
+}
+
+// nextProgramme is the programme following the one currently airing on a
+// lineup channel.
+type nextProgramme struct {
+	Name        string    `json:"name"`
+	GuideNumber string    `json:"guideNumber"`
+	EPGID       string    `json:"epgId"`
+	Title       string    `json:"title"`
+	SubTitle    string    `json:"subTitle,omitempty"`
+	Start       time.Time `json:"start"`
+	Stop        time.Time `json:"stop,omitzero"`
+}
+
+func (r *Routes) handleNext(w http.ResponseWriter, req *http.Request) {
+	r.serveNext(w, req, r.hdhrHandlers)
+}
+
+// serveNext lists the next programme of each channel in a tuner device's
+// lineup, in lineup order. Channels with nothing scheduled are omitted.
+func (r *Routes) serveNext(w http.ResponseWriter, _ *http.Request, handler *hdhr.Handlers) {
+	epgData, channelMap, ok := r.store.GetEPG()
+	if !ok {
+		http.Error(w, "No EPG data available", http.StatusServiceUnavailable)
+
+		return
+	}
+
+	channels, ok := handler.Channels()
+	if !ok {
+		http.Error(w, "No channels available", http.StatusServiceUnavailable)
+
+		return
+	}
+
+	epgIDs := make(map[string]string, len(channelMap))
+
+	for epgID, name := range channelMap {
+		if existing, exists := epgIDs[name]; !exists || epgID < existing {
+			epgIDs[name] = epgID
+		}
+	}
+
+	next := epg.NextProgrammes(epgData, time.Now())
+	numbers := handler.GuideNumbers(channels)
+	result := make([]nextProgramme, 0, len(channels))
+
+	for i, ch := range channels {
+		epgID, mapped := epgIDs[ch.Name]
+		if !mapped {
+			continue
+		}
+
+		prog, scheduled := next[epgID]
+		if !scheduled {
+			continue
+		}
+
+		// Start parsed in NextProgrammes; an unparseable stop is left zero.
+		start, _ := epg.ParseTime(prog.Start)
+		stop, _ := epg.ParseTime(prog.Stop)
+
+		result = append(result, nextProgramme{
+			Name:        ch.Name,
+			GuideNumber: numbers[i],
+			EPGID:       epgID,
+			Title:       prog.Title,
+			SubTitle:    prog.SubTitle,
+			Start:       start,
+			Stop:        stop,
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	if err := json.NewEncoder(w).Encode(result); err != nil {
+		r.log.WithError(err).Error("Failed to write next programmes response")
+	}
+}
+
+// m3uDebug describes the result of the last M3U parse.
+type m3uDebug struct {
+	Lenient  bool     `json:"lenient"`
+	Channels int      `json:"channels"`
+	Skipped  int      `json:"skipped"`
+	Samples  []string `json:"samples"`
+}
+
+// handleM3UDebug reports the lines the lenient parser skipped in the last
+// M3U fetch.
+func (r *Routes) handleM3UDebug(w http.ResponseWriter, _ *http.Request) {
+	channels, _ := r.store.GetM3U()
+	report := r.store.M3UParseReport()
+
+	debug := m3uDebug{
+		Lenient:  r.cfg.M3ULenient,
+		Channels: len(channels),
+		Skipped:  report.Skipped,
+		Samples:  report.Samples,
+	}
+
+	if debug.Samples == nil {
+		debug.Samples = []string{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	if err := json.NewEncoder(w).Encode(debug); err != nil {
+		r.log.WithError(err).Error("Failed to write M3U debug response")
+	}
+}
+
+// unmatchedChannel describes an M3U channel that only has placeholder guide data.
+type unmatchedChannel struct {
+	Name  string `json:"name"`
+	Group string `json:"group"`
+	TVGID string `json:"tvgId"`
+	EPGID string `json:"epgId"`
+}
+
+// handleUnmatched lists channels that ended up with only placeholder EPG data.
+func (r *Routes) handleUnmatched(w http.ResponseWriter, req *http.Request) {
+	epgData, channelMap, ok := r.store.GetEPG()
+	if !ok {
+		http.Error(w, "No EPG data available", http.StatusServiceUnavailable)
+
+		return
+	}
+
+	channels, _ := r.store.GetM3U()
+
+	byName := make(map[string]m3u.Channel, len(channels))
+
+	for _, ch := range channels {
+		if _, exists := byName[ch.Name]; !exists {
+			byName[ch.Name] = ch
+		}
+	}
+
+	// Placeholder channels are not in channelMap; resolve them by generated ID.
+	names := epg.ChannelNames(channels, channelMap)
+	placeholderIDs := epg.PlaceholderOnlyChannels(epgData)
+	unmatched := make([]unmatchedChannel, 0, len(placeholderIDs))
+
+	for _, epgID := range placeholderIDs {
+		name, mapped := names[epgID]
+		if !mapped {
+			continue
+		}
+
+		ch := byName[name]
+
+		unmatched = append(unmatched, unmatchedChannel{
+			Name:  name,
+			Group: ch.Group,
+			TVGID: ch.TVGID,
+			EPGID: epgID,
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	if err := json.NewEncoder(w).Encode(unmatched); err != nil {
+		r.log.WithError(err).Error("Failed to write unmatched response")
+	}
+}
+
+// handleLogo serves a decoded data URI logo.
+func (r *Routes) handleLogo(w http.ResponseWriter, req *http.Request) {
+	logo, ok := r.store.Logo(req.PathValue("key"))
+	if !ok || !data.IsImageType(logo.ContentType) {
+		http.NotFound(w, req)
+
+		return
+	}
+
+	// Keys are content hashes, so the response never changes.
+	w.Header().Set("Content-Type", logo.ContentType)
+	w.Header().Set("Cache-Control", "max-age=86400, immutable")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	// SVG logos may carry script; never let them run on this origin.
+	w.Header().Set("Content-Security-Policy", "sandbox")
+	w.WriteHeader(http.StatusOK)
+
+	if _, err := w.Write(logo.Data); err != nil {
+		r.log.WithError(err).Error("Failed to write logo response")
+	}
+}
+
+// handleDisabledList lists the tvg-ids and names of disabled channels.
+func (r *Routes) handleDisabledList(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	if err := json.NewEncoder(w).Encode(r.store.Disabled().List()); err != nil {
+		r.log.WithError(err).Error("Failed to write disabled channels response")
+	}
+}
+
+// handleSetChannelDisabled disables or enables a channel by tvg-id or name.
+// The key need not be in the current playlist, so a channel can be hidden
+// ahead of (or across) upstream changes.
+func (r *Routes) handleSetChannelDisabled(disabled bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		id := req.PathValue("id")
+		if id == "" {
+			http.Error(w, "Missing channel id", http.StatusBadRequest)
+
+			return
+		}
+
+		set := r.store.Disabled()
+
+		update := set.Enable
+		if disabled {
+			update = set.Disable
+		}
+
+		if err := update(id); err != nil {
+			r.log.WithError(err).WithField("channel", id).Error("Failed to update disabled channels")
+			http.Error(w, "Failed to update channel", http.StatusInternalServerError)
+
+			return
+		}
+
+		r.log.WithFields(logrus.Fields{
+			"channel":  id,
+			"disabled": disabled,
+		}).Info("Updated channel state")
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// handleMatchReport analyzes how the stored M3U channels matched the stored
+// EPG, the same report the matcher CLI prints.
+func (r *Routes) handleMatchReport(w http.ResponseWriter, _ *http.Request) {
+	epgData, channelMap, ok := r.store.GetEPG()
+	if !ok {
+		http.Error(w, "No EPG data available", http.StatusServiceUnavailable)
+
+		return
+	}
+
+	channels, ok := r.store.GetM3U()
+	if !ok {
+		http.Error(w, "No M3U data available", http.StatusServiceUnavailable)
+
+		return
+	}
+
+	report := epg.AnalyzeMatches(channels, r.store.GetEPGSourceChannels(), epgData, channelMap)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	if err := json.NewEncoder(w).Encode(report); err != nil {
+		r.log.WithError(err).Error("Failed to write match report response")
+	}
+}
+
+// sourceStatus is the /health view of one EPG source.
+type sourceStatus struct {
+	Source      string `json:"source"`
+	OK          bool   `json:"ok"`
+	LastSuccess string `json:"lastSuccess,omitempty"`
+	LastError   string `json:"lastError,omitempty"`
+	LastErrorAt string `json:"lastErrorAt,omitempty"`
+}
+
 func (r *Routes) handleHealth(w http.ResponseWriter, req *http.Request) {
+	recentTunes, peakTunes := r.store.Tunes().Stats()
+
+	sources := r.store.EPGSources().Snapshot()
+	epgSources := make([]sourceStatus, 0, len(sources))
+
+	for _, source := range sources {
+		epgSources = append(epgSources, sourceStatus{
+			Source:      source.Source,
+			OK:          source.OK,
+			LastSuccess: formatHealthTime(source.LastSuccess),
+			LastError:   source.LastError,
+			LastErrorAt: formatHealthTime(source.LastErrorAt),
+		})
+	}
+
 	status := struct {
-		Status   string `json:"status"`
-		HasData  bool   `json:"hasData"`
-		LastSync string `json:"lastSync"`
+		Status      string         `json:"status"`
+		HasData     bool           `json:"hasData"`
+		LastSync    string         `json:"lastSync"`
+		RecentTunes int            `json:"recentTunes"`
+		PeakTunes   int            `json:"peakTunes"`
+		EPGSources  []sourceStatus `json:"epgSources"`
 	}{
-		Status:   "ok",
-		HasData:  r.store.HasData(),
-		LastSync: r.store.LastSync().Format("2006-01-02T15:04:05Z"),
+		Status:      "ok",
+		HasData:     r.store.HasData(),
+		LastSync:    r.store.LastSync().Format("2006-01-02T15:04:05Z"),
+		RecentTunes: recentTunes,
+		PeakTunes:   peakTunes,
+		EPGSources:  epgSources,
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -237,14 +864,78 @@ func (r *Routes) handleHealth(w http.ResponseWriter, req *http.Request) {
 	}
 }
 
+// formatHealthTime formats t for /health, or returns "" if it is unset.
+func formatHealthTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+
+	return t.UTC().Format(time.RFC3339)
+}
+
 func (r *Routes) loggingMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
-		r.log.WithFields(logrus.Fields{
-			"method": req.Method,
-			"path":   req.URL.Path,
-			"remote": req.RemoteAddr,
-		}).Info("HTTP request")
+		// Skip paths are matched as routed, without the --base prefix.
+		path, _ := stripPathPrefix(r.cfg.PathPrefix(), req.URL.Path)
+
+		level := r.accessLogLevel
+		if r.accessLogSkip[path] {
+			level = logrus.DebugLevel
+		}
 
-		next.ServeHTTP(w, req)
+		start := time.Now()
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+
+		next.ServeHTTP(rec, req)
+
+		r.log.WithFields(logrus.Fields{
+			"method":   req.Method,
+			"path":     req.URL.Path,
+			"remote":   req.RemoteAddr,
+			"status":   rec.status,
+			"bytes":    rec.bytes,
+			"duration": time.Since(start),
+		}).Log(level, "HTTP request")
 	})
 }
+
+// statusRecorder wraps an http.ResponseWriter to capture the response status
+// code and the number of body bytes written.
+type statusRecorder struct {
+	http.ResponseWriter
+	status      int
+	bytes       int64
+	wroteHeader bool
+}
+
+// WriteHeader records the status code before delegating.
+func (s *statusRecorder) WriteHeader(status int) {
+	if !s.wroteHeader {
+		s.status = status
+		s.wroteHeader = true
+	}
+
+	s.ResponseWriter.WriteHeader(status)
+}
+
+// Write records the number of bytes written before delegating.
+func (s *statusRecorder) Write(b []byte) (int, error) {
+	s.wroteHeader = true
+
+	n, err := s.ResponseWriter.Write(b)
+	s.bytes += int64(n)
+
+	return n, err
+}
+
+// Flush implements http.Flusher so streaming responses are not buffered.
+func (s *statusRecorder) Flush() {
+	if flusher, ok := s.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Unwrap returns the underlying ResponseWriter for http.ResponseController.
+func (s *statusRecorder) Unwrap() http.ResponseWriter {
+	return s.ResponseWriter
+}
@@ -0,0 +1,1049 @@
+package server
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/savid/iptv/internal/config"
+	"github.com/savid/iptv/internal/data"
+	"github.com/savid/iptv/internal/epg"
+	"github.com/savid/iptv/internal/hdhr"
+	"github.com/savid/iptv/internal/m3u"
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestConfig() *config.Config {
+	cfg := config.DefaultConfig()
+	cfg.BaseURL = "http://localhost:8080"
+	cfg.DeviceID = "test-device-001"
+
+	return cfg
+}
+
+func newTestLogger() (*logrus.Logger, *test.Hook) {
+	logger, hook := test.NewNullLogger()
+	logger.SetOutput(io.Discard)
+	logger.SetLevel(logrus.DebugLevel)
+
+	return logger, hook
+}
+
+func accessLogEntries(hook *test.Hook, path string) []logrus.Entry {
+	entries := make([]logrus.Entry, 0)
+
+	for _, entry := range hook.AllEntries() {
+		if entry.Message == "HTTP request" && entry.Data["path"] == path {
+			entries = append(entries, *entry)
+		}
+	}
+
+	return entries
+}
+
+func TestLoggingMiddleware_SkippedPathLoggedAtDebug(t *testing.T) {
+	log, hook := newTestLogger()
+	cfg := newTestConfig()
+	cfg.AccessLogSkip = []string{"/health"}
+
+	handler := NewRoutes(log, cfg, data.NewStore()).Handler()
+
+	for _, path := range []string{"/health", "/discover.json"} {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		w := httptest.NewRecorder()
+
+		handler.ServeHTTP(w, req)
+	}
+
+	healthEntries := accessLogEntries(hook, "/health")
+	require.Len(t, healthEntries, 1)
+	require.Equal(t, logrus.DebugLevel, healthEntries[0].Level)
+
+	discoverEntries := accessLogEntries(hook, "/discover.json")
+	require.Len(t, discoverEntries, 1)
+	require.Equal(t, logrus.InfoLevel, discoverEntries[0].Level)
+}
+
+func TestLoggingMiddleware_SkippedPathUnderPrefix(t *testing.T) {
+	log, hook := newTestLogger()
+	cfg := newTestConfig()
+	cfg.BaseURL = "https://host.example.com/iptv"
+	cfg.AccessLogSkip = []string{"/health"}
+
+	handler := NewRoutes(log, cfg, data.NewStore()).Handler()
+
+	req := httptest.NewRequest(http.MethodGet, "/iptv/health", nil)
+	w := httptest.NewRecorder()
+
+	handler.ServeHTTP(w, req)
+
+	entries := accessLogEntries(hook, "/iptv/health")
+	require.Len(t, entries, 1)
+	require.Equal(t, logrus.DebugLevel, entries[0].Level)
+}
+
+func TestLoggingMiddleware_AccessLogLevel(t *testing.T) {
+	log, hook := newTestLogger()
+	cfg := newTestConfig()
+	cfg.AccessLogLevel = "warn"
+
+	handler := NewRoutes(log, cfg, data.NewStore()).Handler()
+
+	req := httptest.NewRequest(http.MethodGet, "/discover.json", nil)
+	w := httptest.NewRecorder()
+
+	handler.ServeHTTP(w, req)
+
+	entries := accessLogEntries(hook, "/discover.json")
+	require.Len(t, entries, 1)
+	require.Equal(t, logrus.WarnLevel, entries[0].Level)
+}
+
+func TestLoggingMiddleware_RecordsStatusAndBytes(t *testing.T) {
+	log, hook := newTestLogger()
+	cfg := newTestConfig()
+
+	handler := NewRoutes(log, cfg, data.NewStore()).Handler()
+
+	req := httptest.NewRequest(http.MethodGet, "/iptv.m3u", nil)
+	w := httptest.NewRecorder()
+
+	handler.ServeHTTP(w, req)
+
+	entries := accessLogEntries(hook, "/iptv.m3u")
+	require.Len(t, entries, 1)
+	require.Equal(t, http.StatusServiceUnavailable, entries[0].Data["status"])
+	require.Equal(t, int64(w.Body.Len()), entries[0].Data["bytes"])
+	require.Contains(t, entries[0].Data, "duration")
+}
+
+func TestStatusRecorder_DefaultsAndFlush(t *testing.T) {
+	w := httptest.NewRecorder()
+	rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+
+	n, err := rec.Write([]byte("hello"))
+	require.NoError(t, err)
+	require.Equal(t, 5, n)
+
+	// WriteHeader after an implicit 200 must not change the recorded status.
+	rec.WriteHeader(http.StatusNotFound)
+	require.Equal(t, http.StatusOK, rec.status)
+	require.Equal(t, int64(5), rec.bytes)
+
+	var flusher http.Flusher = rec
+
+	flusher.Flush()
+	require.True(t, w.Flushed)
+}
+
+func newTestStore() *data.Store {
+	store := data.NewStore()
+	store.SetM3U([]m3u.Channel{
+		{Name: "ESPN", URL: "http://stream.example.com/espn", Group: "Sports"},
+		{Name: "CNN", URL: "http://stream.example.com/cnn", Group: "News"},
+	})
+	store.SetEPG(&epg.TV{
+		Channels: []epg.Channel{
+			{ID: "espn.us", DisplayName: "ESPN"},
+			{ID: "cnn.us", DisplayName: "CNN"},
+		},
+		Programs: []epg.Programme{
+			{Channel: "espn.us", Start: "20260104120000 +0000", Stop: "20260104130000 +0000", Title: "SportsCenter"},
+		},
+	}, map[string]string{"espn.us": "ESPN", "cnn.us": "CNN"})
+
+	return store
+}
+
+func TestCacheHeaders_ConfiguredMaxAge(t *testing.T) {
+	log, _ := newTestLogger()
+	cfg := newTestConfig()
+	cfg.CacheMaxAge = 2 * time.Hour
+
+	handler := NewRoutes(log, cfg, newTestStore()).Handler()
+
+	for _, path := range []string{"/epg.xml", "/iptv.m3u"} {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		w := httptest.NewRecorder()
+
+		handler.ServeHTTP(w, req)
+
+		require.Equal(t, http.StatusOK, w.Code, path)
+		require.Equal(t, "max-age=7200", w.Header().Get("Cache-Control"), path)
+		require.NotEmpty(t, w.Header().Get("ETag"), path)
+	}
+}
+
+func TestCacheHeaders_DefaultsToRefreshInterval(t *testing.T) {
+	log, _ := newTestLogger()
+	cfg := newTestConfig()
+	cfg.RefreshInterval = 15 * time.Minute
+
+	handler := NewRoutes(log, cfg, newTestStore()).Handler()
+
+	req := httptest.NewRequest(http.MethodGet, "/epg.xml", nil)
+	w := httptest.NewRecorder()
+
+	handler.ServeHTTP(w, req)
+
+	require.Equal(t, "max-age=900", w.Header().Get("Cache-Control"))
+}
+
+func TestCacheHeaders_IfNoneMatch(t *testing.T) {
+	log, _ := newTestLogger()
+	cfg := newTestConfig()
+
+	handler := NewRoutes(log, cfg, newTestStore()).Handler()
+
+	req := httptest.NewRequest(http.MethodGet, "/epg.xml", nil)
+	w := httptest.NewRecorder()
+
+	handler.ServeHTTP(w, req)
+
+	etag := w.Header().Get("ETag")
+	require.NotEmpty(t, etag)
+
+	req = httptest.NewRequest(http.MethodGet, "/epg.xml", nil)
+	req.Header.Set("If-None-Match", etag)
+
+	w = httptest.NewRecorder()
+
+	handler.ServeHTTP(w, req)
+
+	require.Equal(t, http.StatusNotModified, w.Code)
+	require.Empty(t, w.Body.String())
+}
+
+func TestServeEPG_ETagFollowsData(t *testing.T) {
+	log, _ := newTestLogger()
+	cfg := newTestConfig()
+	cfg.EPGGuideNumbers = true
+
+	store := newTestStore()
+	routes := NewRoutes(log, cfg, store)
+	handler := routes.Handler()
+
+	get := func() *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/epg.xml", nil))
+		require.Equal(t, http.StatusOK, w.Code)
+
+		sum := sha256.Sum256(w.Body.Bytes())
+		require.Equal(t, fmt.Sprintf(`"%x"`, sum[:16]), w.Header().Get("ETag"))
+
+		return w
+	}
+
+	first := get().Header().Get("ETag")
+	require.Equal(t, first, get().Header().Get("ETag"))
+	require.Len(t, routes.epgSums, 1, "the guide is hashed once per version")
+
+	// Disabling a channel renumbers the guide.
+	require.NoError(t, store.Disabled().Disable("CNN"))
+
+	disabled := get().Header().Get("ETag")
+	require.NotEqual(t, first, disabled)
+
+	// A refresh replaces the guide.
+	epgData, channelMap, _ := store.GetEPG()
+	store.SetEPG(&epg.TV{Channels: epgData.Channels[:1]}, channelMap)
+	require.NotEqual(t, disabled, get().Header().Get("ETag"))
+}
+
+func TestHandleUnmatched(t *testing.T) {
+	log, _ := newTestLogger()
+	cfg := newTestConfig()
+
+	handler := NewRoutes(log, cfg, newTestStore()).Handler()
+
+	req := httptest.NewRequest(http.MethodGet, "/api/unmatched.json", nil)
+	w := httptest.NewRecorder()
+
+	handler.ServeHTTP(w, req)
+
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var unmatched []unmatchedChannel
+
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &unmatched))
+	require.Len(t, unmatched, 1)
+	require.Equal(t, "CNN", unmatched[0].Name)
+	require.Equal(t, "News", unmatched[0].Group)
+	require.Equal(t, "cnn.us", unmatched[0].EPGID)
+}
+
+func TestHandleUnmatched_FetchedPlaceholders(t *testing.T) {
+	const playlist = `#EXTM3U
+#EXTINF:-1 tvg-id="espn.us" group-title="Sports",ESPN
+http://stream.example.com/espn
+#EXTINF:-1 group-title="Local",Local Access
+http://stream.example.com/local
+`
+
+	start := time.Now().UTC().Truncate(time.Hour)
+	guide := `<?xml version="1.0" encoding="UTF-8"?>
+<tv>
+  <channel id="espn.us"><display-name>ESPN</display-name></channel>
+  <programme channel="espn.us" start="` + start.Format("20060102150405 -0700") + `" stop="` +
+		start.Add(time.Hour).Format("20060102150405 -0700") + `"><title>SportsCenter</title></programme>
+</tv>`
+
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/epg.xml" {
+			_, _ = io.WriteString(w, guide)
+
+			return
+		}
+
+		_, _ = io.WriteString(w, playlist)
+	}))
+	defer upstream.Close()
+
+	log, _ := newTestLogger()
+	cfg := newTestConfig()
+	cfg.M3UURL = upstream.URL + "/playlist.m3u"
+	cfg.EPGURL = upstream.URL + "/epg.xml"
+
+	store := data.NewStore()
+	require.NoError(t, data.NewFetcher(log, cfg, store).FetchAll(t.Context()))
+
+	req := httptest.NewRequest(http.MethodGet, "/api/unmatched.json", nil)
+	w := httptest.NewRecorder()
+
+	NewRoutes(log, cfg, store).Handler().ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var unmatched []unmatchedChannel
+
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &unmatched))
+	require.Len(t, unmatched, 1)
+	require.Equal(t, "Local Access", unmatched[0].Name)
+	require.Equal(t, "Local", unmatched[0].Group)
+	require.NotEmpty(t, unmatched[0].EPGID)
+}
+
+func TestHandleNext(t *testing.T) {
+	log, _ := newTestLogger()
+	cfg := newTestConfig()
+
+	now := time.Now().UTC().Truncate(time.Minute)
+	at := func(d time.Duration) string { return epg.FormatTime(now.Add(d)) }
+
+	store := newTestStore()
+	store.SetEPG(&epg.TV{
+		Programs: []epg.Programme{
+			{Channel: "espn.us", Start: at(-30 * time.Minute), Stop: at(30 * time.Minute), Title: "SportsCenter"},
+			{Channel: "espn.us", Start: at(90 * time.Minute), Stop: at(150 * time.Minute), Title: "Baseball Tonight"},
+			{Channel: "espn.us", Start: at(30 * time.Minute), Stop: at(90 * time.Minute), Title: "NFL Live", SubTitle: "Week 1"},
+			{Channel: "cnn.us", Start: at(-time.Hour), Stop: at(time.Hour), Title: "Newsroom"},
+		},
+	}, map[string]string{"espn.us": "ESPN", "cnn.us": "CNN"})
+
+	handler := NewRoutes(log, cfg, store).Handler()
+
+	for _, path := range []string{"/api/next.json", "/sports/api/next.json"} {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		w := httptest.NewRecorder()
+
+		handler.ServeHTTP(w, req)
+
+		require.Equal(t, http.StatusOK, w.Code, path)
+
+		var next []nextProgramme
+
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &next))
+		require.Len(t, next, 1, path)
+		require.Equal(t, "ESPN", next[0].Name)
+		require.Equal(t, "espn.us", next[0].EPGID)
+		require.Equal(t, "NFL Live", next[0].Title)
+		require.Equal(t, "Week 1", next[0].SubTitle)
+		require.True(t, now.Add(30*time.Minute).Equal(next[0].Start))
+		require.True(t, now.Add(90*time.Minute).Equal(next[0].Stop))
+	}
+}
+
+func TestHandleEPG_DuplicateNamesKeepGuideData(t *testing.T) {
+	log, _ := newTestLogger()
+	cfg := newTestConfig()
+
+	store := newTestStore()
+	store.SetM3U([]m3u.Channel{
+		{Name: "ESPN", URL: "http://stream.example.com/espn", Group: "Sports"},
+		{Name: "CNN", URL: "http://stream.example.com/cnn", Group: "News"},
+		{Name: "ESPN", URL: "http://stream.example.com/espn-backup", Group: "Sports"},
+	})
+
+	handler := NewRoutes(log, cfg, store).Handler()
+
+	req := httptest.NewRequest(http.MethodGet, "/lineup.json", nil)
+	w := httptest.NewRecorder()
+
+	handler.ServeHTTP(w, req)
+
+	var lineup []hdhr.LineupItem
+
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &lineup))
+	require.Equal(t, "ESPN", lineup[0].GuideName)
+	require.Equal(t, "ESPN (2)", lineup[2].GuideName)
+
+	// Both lineup names are display-names of the ESPN guide channel.
+	for _, path := range []string{"/epg.xml", "/sports/epg.xml"} {
+		req = httptest.NewRequest(http.MethodGet, path, nil)
+		w = httptest.NewRecorder()
+
+		handler.ServeHTTP(w, req)
+
+		require.Equal(t, http.StatusOK, w.Code, path)
+		require.Contains(t, w.Body.String(),
+			`<channel id="espn.us">`+"\n    <display-name>ESPN</display-name>\n    <display-name>ESPN (2)</display-name>", path)
+	}
+}
+
+func TestHandleM3UDebug(t *testing.T) {
+	log, _ := newTestLogger()
+	cfg := newTestConfig()
+	cfg.M3ULenient = true
+
+	store := newTestStore()
+	store.SetM3UParseReport(m3u.ParseReport{Skipped: 2, Samples: []string{"#EXTINF:-1,Broken", "http://stray"}})
+
+	handler := NewRoutes(log, cfg, store).Handler()
+
+	req := httptest.NewRequest(http.MethodGet, "/api/debug/m3u.json", nil)
+	w := httptest.NewRecorder()
+
+	handler.ServeHTTP(w, req)
+
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var debug m3uDebug
+
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &debug))
+	require.Equal(t, m3uDebug{
+		Lenient:  true,
+		Channels: 2,
+		Skipped:  2,
+		Samples:  []string{"#EXTINF:-1,Broken", "http://stray"},
+	}, debug)
+}
+
+func TestHandleChannels(t *testing.T) {
+	log, _ := newTestLogger()
+	cfg := newTestConfig()
+	cfg.DeadAfter = 2
+
+	store := newTestStore()
+	health := store.ChannelHealth()
+	health.RecordSuccess("http://stream.example.com/espn")
+	health.RecordFailure("http://stream.example.com/cnn", errors.New("unexpected status 503"))
+	health.RecordFailure("http://stream.example.com/cnn", errors.New("unexpected status 503"))
+
+	handler := NewRoutes(log, cfg, store).Handler()
+
+	req := httptest.NewRequest(http.MethodGet, "/api/channels.json", nil)
+	w := httptest.NewRecorder()
+
+	handler.ServeHTTP(w, req)
+
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var statuses []channelStatus
+
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &statuses))
+	require.Len(t, statuses, 2)
+
+	require.Equal(t, "ESPN", statuses[0].Name)
+	require.True(t, statuses[0].Probed)
+	require.True(t, statuses[0].Alive)
+	require.False(t, statuses[0].Dead)
+
+	require.Equal(t, "CNN", statuses[1].Name)
+	require.False(t, statuses[1].Alive)
+	require.True(t, statuses[1].Dead)
+	require.Equal(t, 2, statuses[1].Failures)
+	require.Equal(t, "unexpected status 503", statuses[1].LastError)
+}
+
+func TestHandleHealth_ReportsTunes(t *testing.T) {
+	log, _ := newTestLogger()
+	cfg := newTestConfig()
+
+	handler := NewRoutes(log, cfg, newTestStore()).Handler()
+
+	for i := 0; i < 2; i++ {
+		req := httptest.NewRequest(http.MethodGet, "/auto/v1", nil)
+		w := httptest.NewRecorder()
+
+		handler.ServeHTTP(w, req)
+		require.Equal(t, http.StatusTemporaryRedirect, w.Code)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/health", nil)
+	w := httptest.NewRecorder()
+
+	handler.ServeHTTP(w, req)
+
+	var status struct {
+		RecentTunes int `json:"recentTunes"`
+		PeakTunes   int `json:"peakTunes"`
+	}
+
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &status))
+	require.Equal(t, 2, status.RecentTunes)
+	require.Equal(t, 2, status.PeakTunes)
+}
+
+func TestHandleHealth_ReportsEPGSources(t *testing.T) {
+	log, _ := newTestLogger()
+	cfg := newTestConfig()
+	store := newTestStore()
+
+	store.EPGSources().Track([]string{"http://a.example.com/epg.xml", "http://b.example.com/epg.xml"})
+	store.EPGSources().RecordFailure("http://a.example.com/epg.xml", errors.New("unexpected status code: 500"))
+	store.EPGSources().RecordSuccess("http://b.example.com/epg.xml")
+
+	handler := NewRoutes(log, cfg, store).Handler()
+
+	req := httptest.NewRequest(http.MethodGet, "/health", nil)
+	w := httptest.NewRecorder()
+
+	handler.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var status struct {
+		EPGSources []struct {
+			Source      string `json:"source"`
+			OK          bool   `json:"ok"`
+			LastSuccess string `json:"lastSuccess"`
+			LastError   string `json:"lastError"`
+		} `json:"epgSources"`
+	}
+
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &status))
+	require.Len(t, status.EPGSources, 2)
+
+	require.Equal(t, "http://a.example.com/epg.xml", status.EPGSources[0].Source)
+	require.False(t, status.EPGSources[0].OK)
+	require.Equal(t, "unexpected status code: 500", status.EPGSources[0].LastError)
+	require.Empty(t, status.EPGSources[0].LastSuccess)
+
+	require.True(t, status.EPGSources[1].OK)
+	require.NotEmpty(t, status.EPGSources[1].LastSuccess)
+}
+
+func TestGroupRouting_AfterRefresh(t *testing.T) {
+	log, _ := newTestLogger()
+	cfg := newTestConfig()
+	store := newTestStore()
+
+	handler := NewRoutes(log, cfg, store).Handler()
+
+	req := httptest.NewRequest(http.MethodGet, "/sports/lineup.json", nil)
+	w := httptest.NewRecorder()
+
+	handler.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Contains(t, w.Body.String(), "ESPN")
+
+	store.SetM3U([]m3u.Channel{
+		{Name: "HBO", URL: "http://stream.example.com/hbo", Group: "Movies"},
+	})
+
+	req = httptest.NewRequest(http.MethodGet, "/sports/lineup.json", nil)
+	w = httptest.NewRecorder()
+
+	handler.ServeHTTP(w, req)
+	require.Equal(t, http.StatusNotFound, w.Code)
+
+	req = httptest.NewRequest(http.MethodGet, "/movies/lineup.json", nil)
+	w = httptest.NewRecorder()
+
+	handler.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Contains(t, w.Body.String(), "HBO")
+}
+
+func TestHandleM3U_ProxyMode(t *testing.T) {
+	log, _ := newTestLogger()
+	cfg := newTestConfig()
+
+	handler := NewRoutes(log, cfg, newTestStore()).Handler()
+
+	req := httptest.NewRequest(http.MethodGet, "/iptv.m3u", nil)
+	w := httptest.NewRecorder()
+
+	handler.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Contains(t, w.Body.String(), "http://stream.example.com/espn")
+	require.NotContains(t, w.Body.String(), "/auto/v")
+
+	req = httptest.NewRequest(http.MethodGet, "/iptv.m3u?proxy=1", nil)
+	w = httptest.NewRecorder()
+
+	handler.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Contains(t, w.Body.String(), cfg.BaseURL+"/auto/v1\n")
+	require.Contains(t, w.Body.String(), cfg.BaseURL+"/auto/v2\n")
+	require.NotContains(t, w.Body.String(), "stream.example.com")
+}
+
+func TestHandleM3U_ProxyNumberFormat(t *testing.T) {
+	log, _ := newTestLogger()
+	cfg := newTestConfig()
+	cfg.NumberFormat = "{group}.{channel}"
+
+	handler := NewRoutes(log, cfg, newTestStore()).Handler()
+
+	req := httptest.NewRequest(http.MethodGet, "/iptv.m3u?proxy=1", nil)
+	w := httptest.NewRecorder()
+
+	handler.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Contains(t, w.Body.String(), cfg.BaseURL+"/auto/v2.1\n")
+	require.Contains(t, w.Body.String(), cfg.BaseURL+"/auto/v1.1\n")
+
+	// The formatted number tunes the same channel.
+	req = httptest.NewRequest(http.MethodGet, "/auto/v2.1", nil)
+	w = httptest.NewRecorder()
+
+	handler.ServeHTTP(w, req)
+	require.Equal(t, http.StatusTemporaryRedirect, w.Code)
+	require.Equal(t, "http://stream.example.com/espn", w.Header().Get("Location"))
+}
+
+func TestHandleM3U_ChannelNumbers(t *testing.T) {
+	log, _ := newTestLogger()
+	cfg := newTestConfig()
+	cfg.NumberFormat = "{group}.{channel}"
+	cfg.M3UChannelNumbers = true
+
+	handler := NewRoutes(log, cfg, newTestStore()).Handler()
+
+	req := httptest.NewRequest(http.MethodGet, "/iptv.m3u", nil)
+	w := httptest.NewRecorder()
+
+	handler.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	channels, err := m3u.Parse(w.Body.Bytes())
+	require.NoError(t, err)
+	require.Len(t, channels, 2)
+
+	numbers := make(map[string]string, len(channels))
+	for _, ch := range channels {
+		numbers[ch.Name] = ch.Attributes[m3u.AttrTVGChno]
+	}
+
+	// Numbers match the lineup guide numbers (and tuning URLs).
+	require.Equal(t, map[string]string{"ESPN": "2.1", "CNN": "1.1"}, numbers)
+}
+
+func TestHandleM3U_ProxyCatchup(t *testing.T) {
+	log, _ := newTestLogger()
+	cfg := newTestConfig()
+	cfg.ProxyCatchup = true
+
+	store := data.NewStore()
+	store.SetM3U([]m3u.Channel{
+		{
+			Name: "ESPN",
+			URL:  "http://stream.example.com/espn",
+			Attributes: map[string]string{
+				m3u.AttrCatchup:       "append",
+				m3u.AttrCatchupSource: "?utc={utc}&lutc={lutc}",
+			},
+		},
+		{Name: "CNN", URL: "http://stream.example.com/cnn"},
+	})
+
+	handler := NewRoutes(log, cfg, store).Handler()
+
+	req := httptest.NewRequest(http.MethodGet, "/iptv.m3u?proxy=1", nil)
+	w := httptest.NewRecorder()
+
+	handler.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Contains(t, w.Body.String(),
+		`catchup="default" catchup-source="`+cfg.BaseURL+`/catchup/v1?start={utc}&end={utcend}"`)
+	require.Equal(t, 1, strings.Count(w.Body.String(), "catchup-source"))
+
+	// The template's placeholders resolve to a working proxy request.
+	req = httptest.NewRequest(http.MethodGet, "/catchup/v1?start=1767528000&end=1767529800", nil)
+	w = httptest.NewRecorder()
+
+	handler.ServeHTTP(w, req)
+	require.Equal(t, http.StatusTemporaryRedirect, w.Code)
+	require.True(t, strings.HasPrefix(w.Header().Get("Location"), "http://stream.example.com/espn?utc=1767528000&lutc="))
+
+	// Channels without catchup have nothing to serve.
+	req = httptest.NewRequest(http.MethodGet, "/catchup/v2?start=1767528000&end=1767529800", nil)
+	w = httptest.NewRecorder()
+
+	handler.ServeHTTP(w, req)
+	require.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestShardRouting(t *testing.T) {
+	log, _ := newTestLogger()
+	cfg := newTestConfig()
+	cfg.ChannelsPerTuner = 100
+
+	channels := make([]m3u.Channel, 0, 150)
+
+	for i := range 150 {
+		channels = append(channels, m3u.Channel{
+			Name:  fmt.Sprintf("Sports %03d", i+1),
+			URL:   fmt.Sprintf("http://stream.example.com/sports/%d", i+1),
+			Group: "Sports",
+		})
+	}
+
+	store := data.NewStore()
+	store.SetChannelsPerTuner(cfg.ChannelsPerTuner)
+	store.SetM3U(channels)
+
+	handler := NewRoutes(log, cfg, store).Handler()
+
+	lineup := func(path string) []hdhr.LineupItem {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		w := httptest.NewRecorder()
+
+		handler.ServeHTTP(w, req)
+		require.Equal(t, http.StatusOK, w.Code)
+
+		var items []hdhr.LineupItem
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &items))
+
+		return items
+	}
+
+	require.Len(t, lineup("/sports/lineup.json"), 150)
+	require.Len(t, lineup("/sports-1/lineup.json"), 100)
+
+	second := lineup("/sports-2/lineup.json")
+	require.Len(t, second, 50)
+	require.Equal(t, "1", second[0].GuideNumber)
+	require.Equal(t, "Sports 101", second[0].GuideName)
+	require.Equal(t, "http://stream.example.com/sports/101", second[0].URL)
+
+	require.Len(t, lineup("/all-2/lineup.json"), 50)
+
+	req := httptest.NewRequest(http.MethodGet, "/sports-2/discover.json", nil)
+	w := httptest.NewRecorder()
+
+	handler.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var discovery hdhr.DiscoveryJSON
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &discovery))
+	require.Equal(t, "iptv-sports-2", discovery.DeviceID)
+	require.Contains(t, discovery.FriendlyName, "(Sports 2)")
+
+	req = httptest.NewRequest(http.MethodGet, "/sports-2/auto/v1", nil)
+	w = httptest.NewRecorder()
+
+	handler.ServeHTTP(w, req)
+	require.Equal(t, http.StatusTemporaryRedirect, w.Code)
+	require.Equal(t, "http://stream.example.com/sports/101", w.Header().Get("Location"))
+
+	// A sub-tuner's tuning URLs don't work under another device's prefix.
+	req = httptest.NewRequest(http.MethodGet, "/sports-3/auto/v1", nil)
+	w = httptest.NewRecorder()
+
+	handler.ServeHTTP(w, req)
+	require.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestMaxGroupTunersRouting(t *testing.T) {
+	log, _ := newTestLogger()
+	cfg := newTestConfig()
+	cfg.MaxGroupTuners = 2
+
+	channels := make([]m3u.Channel, 0)
+
+	for group, count := range map[string]int{"Kids": 1, "News": 3, "Sports": 5, "Movies": 2} {
+		for i := range count {
+			channels = append(channels, m3u.Channel{
+				Name:  fmt.Sprintf("%s %d", group, i+1),
+				URL:   fmt.Sprintf("http://stream.example.com/%s/%d", group, i+1),
+				Group: group,
+			})
+		}
+	}
+
+	store := data.NewStore()
+	store.SetMaxGroupTuners(cfg.MaxGroupTuners)
+	store.SetM3U(channels)
+
+	require.Equal(t, []string{"News", "Sports"}, store.TunerGroups())
+	require.Len(t, store.GetGroups(), 4)
+
+	handler := NewRoutes(log, cfg, store).Handler()
+
+	status := func(path string) int {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		w := httptest.NewRecorder()
+
+		handler.ServeHTTP(w, req)
+
+		return w.Code
+	}
+
+	require.Equal(t, http.StatusOK, status("/sports/lineup.json"))
+	require.Equal(t, http.StatusOK, status("/news/discover.json"))
+	require.Equal(t, http.StatusNotFound, status("/movies/lineup.json"))
+	require.Equal(t, http.StatusNotFound, status("/kids/discover.json"))
+
+	// Channels of groups without a tuner stay on the root device.
+	req := httptest.NewRequest(http.MethodGet, "/lineup.json", nil)
+	w := httptest.NewRecorder()
+
+	handler.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var items []hdhr.LineupItem
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &items))
+	require.Len(t, items, 11)
+}
+
+func TestChannelDisableEnable(t *testing.T) {
+	log, _ := newTestLogger()
+	cfg := newTestConfig()
+
+	handler := NewRoutes(log, cfg, newTestStore()).Handler()
+
+	lineup := func() string {
+		req := httptest.NewRequest(http.MethodGet, "/lineup.json", nil)
+		w := httptest.NewRecorder()
+
+		handler.ServeHTTP(w, req)
+		require.Equal(t, http.StatusOK, w.Code)
+
+		return w.Body.String()
+	}
+
+	require.Contains(t, lineup(), "ESPN")
+
+	req := httptest.NewRequest(http.MethodPost, "/api/channels/ESPN/disable", nil)
+	w := httptest.NewRecorder()
+
+	handler.ServeHTTP(w, req)
+	require.Equal(t, http.StatusNoContent, w.Code)
+	require.NotContains(t, lineup(), "ESPN")
+	require.Contains(t, lineup(), "CNN")
+
+	req = httptest.NewRequest(http.MethodGet, "/api/channels/disabled.json", nil)
+	w = httptest.NewRecorder()
+
+	handler.ServeHTTP(w, req)
+	require.JSONEq(t, `["ESPN"]`, w.Body.String())
+
+	req = httptest.NewRequest(http.MethodPost, "/api/channels/ESPN/enable", nil)
+	w = httptest.NewRecorder()
+
+	handler.ServeHTTP(w, req)
+	require.Equal(t, http.StatusNoContent, w.Code)
+	require.Contains(t, lineup(), "ESPN")
+}
+
+func TestHandleLogo(t *testing.T) {
+	log, _ := newTestLogger()
+	cfg := newTestConfig()
+	store := newTestStore()
+	store.AddLogos(map[string]data.Logo{
+		"abc123": {ContentType: "image/png", Data: []byte("png-bytes")},
+		"html":   {ContentType: "text/html", Data: []byte("<script>alert(1)</script>")},
+	})
+
+	handler := NewRoutes(log, cfg, store).Handler()
+
+	req := httptest.NewRequest(http.MethodGet, "/logos/abc123", nil)
+	w := httptest.NewRecorder()
+
+	handler.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Equal(t, "image/png", w.Header().Get("Content-Type"))
+	require.Equal(t, "png-bytes", w.Body.String())
+	require.Equal(t, "nosniff", w.Header().Get("X-Content-Type-Options"))
+
+	req = httptest.NewRequest(http.MethodGet, "/logos/html", nil)
+	w = httptest.NewRecorder()
+
+	handler.ServeHTTP(w, req)
+	require.Equal(t, http.StatusNotFound, w.Code)
+
+	req = httptest.NewRequest(http.MethodGet, "/logos/missing", nil)
+	w = httptest.NewRecorder()
+
+	handler.ServeHTTP(w, req)
+	require.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestHandleLineup_Logos(t *testing.T) {
+	const playlist = `#EXTM3U
+#EXTINF:-1 tvg-logo="data:image/png;base64,cG5nLWJ5dGVz",ESPN
+http://stream.example.com/espn
+#EXTINF:-1 tvg-logo="http://logos.example.com/cnn.png",CNN
+http://stream.example.com/cnn
+#EXTINF:-1,BBC
+http://stream.example.com/bbc
+`
+
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = io.WriteString(w, playlist)
+	}))
+	defer upstream.Close()
+
+	log, _ := newTestLogger()
+	cfg := newTestConfig()
+	cfg.M3UURL = upstream.URL + "/playlist.m3u"
+	cfg.DataURILogos = config.DataURILogosServe
+	cfg.LineupLogos = true
+
+	store := data.NewStore()
+	require.NoError(t, data.NewFetcher(log, cfg, store).FetchM3U(t.Context()))
+
+	handler := NewRoutes(log, cfg, store).Handler()
+
+	req := httptest.NewRequest(http.MethodGet, "/lineup.json", nil)
+	w := httptest.NewRecorder()
+
+	handler.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var lineup []hdhr.LineupItem
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &lineup))
+	require.Len(t, lineup, 3)
+	require.True(t, strings.HasPrefix(lineup[0].ImageURL, cfg.BaseURL+data.LogoPathPrefix))
+	require.Equal(t, "http://logos.example.com/cnn.png", lineup[1].ImageURL)
+	require.Empty(t, lineup[2].ImageURL)
+	require.NotContains(t, w.Body.String(), `"ImageURL":""`)
+
+	// The proxied logo is served by the proxy itself.
+	req = httptest.NewRequest(http.MethodGet, strings.TrimPrefix(lineup[0].ImageURL, cfg.BaseURL), nil)
+	w = httptest.NewRecorder()
+
+	handler.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Equal(t, "png-bytes", w.Body.String())
+
+	// Without the toggle the lineup has no artwork.
+	cfg.LineupLogos = false
+
+	req = httptest.NewRequest(http.MethodGet, "/lineup.json", nil)
+	w = httptest.NewRecorder()
+
+	NewRoutes(log, cfg, store).Handler().ServeHTTP(w, req)
+	require.NotContains(t, w.Body.String(), "ImageURL")
+
+	// Passed-through data URIs are left out of the lineup.
+	cfg.DataURILogos = config.DataURILogosPass
+	cfg.LineupLogos = true
+
+	store = data.NewStore()
+	require.NoError(t, data.NewFetcher(log, cfg, store).FetchM3U(t.Context()))
+
+	req = httptest.NewRequest(http.MethodGet, "/lineup.json", nil)
+	w = httptest.NewRecorder()
+
+	NewRoutes(log, cfg, store).Handler().ServeHTTP(w, req)
+	require.NotContains(t, w.Body.String(), "data:")
+	require.Contains(t, w.Body.String(), "http://logos.example.com/cnn.png")
+}
+
+func TestHandleEPG_MaxDescLength(t *testing.T) {
+	log, _ := newTestLogger()
+	cfg := newTestConfig()
+	cfg.MaxDescLength = 12
+
+	store := newTestStore()
+	epgData, channelMap, _ := store.GetEPG()
+	epgData.Programs[0].Description = "Highlights and analysis from around the league"
+	store.SetEPG(epgData, channelMap)
+
+	handler := NewRoutes(log, cfg, store).Handler()
+
+	req := httptest.NewRequest(http.MethodGet, "/epg.xml", nil)
+	w := httptest.NewRecorder()
+
+	handler.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Contains(t, w.Body.String(), "<desc>Highlights…</desc>")
+
+	stored, _, _ := store.GetEPG()
+	require.Equal(t, "Highlights and analysis from around the league", stored.Programs[0].Description)
+}
+
+func TestHandleEPG_GuideNumbers(t *testing.T) {
+	log, _ := newTestLogger()
+	cfg := newTestConfig()
+	cfg.EPGGuideNumbers = true
+
+	handler := NewRoutes(log, cfg, newTestStore()).Handler()
+
+	req := httptest.NewRequest(http.MethodGet, "/epg.xml", nil)
+	w := httptest.NewRecorder()
+
+	handler.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	body := w.Body.String()
+	require.Contains(t, body, "<display-name>ESPN</display-name>\n    <display-name>1</display-name>")
+	require.Contains(t, body, "<display-name>CNN</display-name>\n    <display-name>2</display-name>")
+}
+
+func TestHandleGroupEPG(t *testing.T) {
+	log, _ := newTestLogger()
+	cfg := newTestConfig()
+
+	handler := NewRoutes(log, cfg, newTestStore()).Handler()
+
+	req := httptest.NewRequest(http.MethodGet, "/sports/epg.xml", nil)
+	w := httptest.NewRecorder()
+
+	handler.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	tv, err := epg.Parse(w.Body.Bytes())
+	require.NoError(t, err)
+	require.Len(t, tv.Channels, 1)
+	require.Equal(t, "espn.us", tv.Channels[0].ID)
+	require.Len(t, tv.Programs, 1)
+	require.Equal(t, "espn.us", tv.Programs[0].Channel)
+
+	req = httptest.NewRequest(http.MethodGet, "/unknown/epg.xml", nil)
+	w = httptest.NewRecorder()
+
+	handler.ServeHTTP(w, req)
+	require.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestHandleMatchReport(t *testing.T) {
+	log, _ := newTestLogger()
+	cfg := newTestConfig()
+
+	handler := NewRoutes(log, cfg, newTestStore()).Handler()
+
+	req := httptest.NewRequest(http.MethodGet, "/api/match-report.json", nil)
+	w := httptest.NewRecorder()
+
+	handler.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var report epg.MatchReport
+
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &report))
+	require.Equal(t, 2, report.Summary.Total)
+	require.Equal(t, 2, report.Summary.Matched)
+	require.Equal(t, 1, report.Summary.WithProgrammes)
+	require.Equal(t, 1, report.Summary.WithoutProgrammes)
+}
@@ -10,7 +10,9 @@ import (
 
 	"github.com/savid/iptv/internal/config"
 	"github.com/savid/iptv/internal/data"
+	"github.com/savid/iptv/internal/epg"
 	"github.com/savid/iptv/internal/hdhr"
+	"github.com/savid/iptv/internal/m3u"
 	"github.com/sirupsen/logrus"
 )
 
@@ -28,6 +30,7 @@ type Server struct {
 	store     *data.Store
 	fetcher   *data.Fetcher
 	refresher *data.Refresher
+	prober    *data.Prober // nil unless --probe-interval is set
 	server    *http.Server
 
 	mu     sync.Mutex
@@ -35,18 +38,61 @@ type Server struct {
 	done   chan struct{}
 }
 
-// NewServer creates a new server instance.
-func NewServer(log logrus.FieldLogger, cfg *config.Config) *Server {
+// NewServer creates a new server instance. Settings whose syntax belongs to
+// another package are validated here, since config holds plain data.
+func NewServer(log logrus.FieldLogger, cfg *config.Config) (*Server, error) {
+	if err := validateConfig(cfg); err != nil {
+		return nil, err
+	}
+
 	store := data.NewStore()
-	fetcher := data.NewFetcher(log, cfg.M3UURL, cfg.EPGURLs(), store)
+	store.Tunes().SetWindow(cfg.TuneWindow)
+	store.SetChannelsPerTuner(cfg.ChannelsPerTuner)
+	store.SetMaxGroupTuners(cfg.MaxGroupTuners)
+	fetcher := data.NewFetcher(log, cfg, store)
 	refresher := data.NewRefresher(log, fetcher, cfg.RefreshInterval)
 
+	if cfg.RefreshAt != "" {
+		// Validated in config.
+		hour, minute, _ := cfg.RefreshAtTime()
+		refresher.SetDailyAt(hour, minute)
+	}
+
+	var prober *data.Prober
+	if cfg.ProbeInterval > 0 {
+		prober = data.NewProber(log, cfg, store)
+	}
+
 	return &Server{
 		log:       log.WithField("component", "server"),
 		cfg:       cfg,
 		store:     store,
 		fetcher:   fetcher,
 		refresher: refresher,
+		prober:    prober,
+	}, nil
+}
+
+// validateConfig checks the settings config.Validate leaves to the packages
+// that interpret them.
+func validateConfig(cfg *config.Config) error {
+	if err := m3u.ValidateNumberFormat(cfg.NumberFormat); err != nil {
+		return fmt.Errorf("invalid --number-format %q: %w", cfg.NumberFormat, err)
+	}
+
+	if err := epg.ValidateMatchOrder(cfg.MatchOrder); err != nil {
+		return fmt.Errorf("invalid --match-order: %w", err)
+	}
+
+	return nil
+}
+
+// epgMarshalOptions returns the options used to serialize EPG output.
+func epgMarshalOptions(cfg *config.Config) epg.MarshalOptions {
+	return epg.MarshalOptions{
+		GeneratorName: cfg.EPGGeneratorName,
+		GeneratorURL:  cfg.EPGGeneratorURL,
+		Compact:       cfg.EPGCompact,
 	}
 }
 
@@ -59,18 +105,54 @@ func (s *Server) Start(ctx context.Context) error {
 		return errors.New("server already running")
 	}
 
+	var offlineClip *hdhr.OfflineClip
+
+	if s.cfg.OfflineClip != "" {
+		clip, err := hdhr.LoadOfflineClip(s.cfg.OfflineClip)
+		if err != nil {
+			return err
+		}
+
+		offlineClip = clip
+	}
+
+	if s.cfg.DisabledChannelsFile != "" {
+		if err := s.store.Disabled().Load(s.cfg.DisabledChannelsFile); err != nil {
+			return fmt.Errorf("failed to load disabled channels: %w", err)
+		}
+	}
+
 	// Create cancellable context
 	serverCtx, cancel := context.WithCancel(ctx)
 	s.cancel = cancel
 	s.done = make(chan struct{})
 
+	// Create routes
+	routes := NewRoutes(s.log, s.cfg, s.store)
+	routes.SetOfflineClip(offlineClip)
+
+	if s.cfg.WriteM3U != "" || s.cfg.WriteEPG != "" {
+		s.fetcher.OnRefresh(routes.WriteOutputs)
+	}
+
+	retained, err := s.fetcher.LoadRetained()
+	if err != nil {
+		s.log.WithError(err).Warn("Failed to load retained data")
+	} else if retained {
+		s.log.WithField("dir", s.cfg.RetainDir).Info("Loaded retained M3U and EPG data")
+	}
+
 	// Fetch initial data
 	s.log.Info("Fetching initial data")
 
-	if err := s.fetcher.FetchAll(serverCtx); err != nil {
-		cancel()
+	if err := s.initialFetch(serverCtx); err != nil {
+		if !retained {
+			cancel()
 
-		return fmt.Errorf("failed to fetch initial data: %w", err)
+			return fmt.Errorf("failed to fetch initial data: %w", err)
+		}
+
+		s.log.WithError(err).Warn("Failed to fetch initial data, serving retained data")
 	}
 
 	// Start data refresher
@@ -80,12 +162,17 @@ func (s *Server) Start(ctx context.Context) error {
 		return fmt.Errorf("failed to start refresher: %w", err)
 	}
 
+	if s.prober != nil {
+		if err := s.prober.Start(serverCtx); err != nil {
+			cancel()
+
+			return fmt.Errorf("failed to start channel prober: %w", err)
+		}
+	}
+
 	// Start status logger
 	go s.startStatusLogger(serverCtx)
 
-	// Create routes
-	routes := NewRoutes(s.log, s.cfg, s.store)
-
 	// Create HTTP server
 	s.server = &http.Server{
 		Addr:         s.cfg.ListenAddr(),
@@ -98,11 +185,27 @@ func (s *Server) Start(ctx context.Context) error {
 	// Start HTTP server
 	go s.run(serverCtx)
 
-	s.log.WithField("addr", s.cfg.ListenAddr()).Info("Server started")
+	s.log.WithFields(logrus.Fields{
+		"addr": s.cfg.ListenAddr(),
+		"tls":  s.cfg.TLSEnabled(),
+	}).Info("Server started")
 
 	return nil
 }
 
+// initialFetch runs the startup fetch, bounded by InitialFetchTimeout when set
+// so startup fails fast. Refreshes are only bounded by the HTTP client timeout.
+func (s *Server) initialFetch(ctx context.Context) error {
+	if s.cfg.InitialFetchTimeout > 0 {
+		var cancel context.CancelFunc
+
+		ctx, cancel = context.WithTimeout(ctx, s.cfg.InitialFetchTimeout)
+		defer cancel()
+	}
+
+	return s.fetcher.FetchAll(ctx)
+}
+
 // Stop stops the server.
 func (s *Server) Stop() error {
 	s.mu.Lock()
@@ -129,6 +232,12 @@ func (s *Server) Stop() error {
 		s.log.WithError(err).Warn("Failed to stop refresher")
 	}
 
+	if s.prober != nil {
+		if err := s.prober.Stop(); err != nil {
+			s.log.WithError(err).Warn("Failed to stop channel prober")
+		}
+	}
+
 	s.log.Info("Server stopped")
 
 	return nil
@@ -141,7 +250,15 @@ func (s *Server) run(ctx context.Context) {
 	errCh := make(chan error, 1)
 
 	go func() {
-		if err := s.server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		var err error
+
+		if s.cfg.TLSEnabled() {
+			err = s.server.ListenAndServeTLS(s.cfg.TLSCert, s.cfg.TLSKey)
+		} else {
+			err = s.server.ListenAndServe()
+		}
+
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
 			errCh <- err
 		}
 
@@ -169,24 +286,65 @@ func (s *Server) run(ctx context.Context) {
 	}
 }
 
-// startStatusLogger logs available tuners every minute.
+// startStatusLogger logs the full tuner breakdown once, then a one-line
+// summary at the configured interval.
 func (s *Server) startStatusLogger(ctx context.Context) {
-	ticker := time.NewTicker(1 * time.Minute)
-	defer ticker.Stop()
-
-	// Log immediately on start
+	// Log the full breakdown immediately on start
 	s.logTunerStatus()
+	s.logMatchSummary()
+
+	if s.cfg.StatusInterval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(s.cfg.StatusInterval)
+	defer ticker.Stop()
 
 	for {
 		select {
 		case <-ctx.Done():
 			return
 		case <-ticker.C:
-			s.logTunerStatus()
+			s.logTunerSummary()
 		}
 	}
 }
 
+// logTunerSummary logs a single line with total tuners and channels.
+func (s *Server) logTunerSummary() {
+	channels, ok := s.store.GetM3U()
+	if !ok {
+		s.log.Warn("No M3U data available for status")
+
+		return
+	}
+
+	s.log.WithFields(logrus.Fields{
+		"channels": len(channels),
+		"groups":   len(s.store.GetGroups()),
+	}).Info("Tuner status")
+}
+
+// logMatchSummary logs how many channels each strategy matched in the last
+// EPG merge.
+func (s *Server) logMatchSummary() {
+	counts, ok := s.store.MatchSummary()
+	if !ok {
+		return
+	}
+
+	s.log.WithFields(logrus.Fields{
+		epg.MatchExplicit:       counts[epg.MatchExplicit],
+		epg.MatchTVGID:          counts[epg.MatchTVGID],
+		epg.MatchDisplayName:    counts[epg.MatchDisplayName],
+		epg.MatchWhitespace:     counts[epg.MatchWhitespace],
+		epg.MatchNormalizedName: counts[epg.MatchNormalizedName],
+		epg.MatchPlaceholder:    counts[epg.MatchPlaceholder],
+	}).Info("EPG match summary")
+}
+
+// logTunerStatus logs every available tuner, skipping groups below the
+// configured channel-count threshold.
 func (s *Server) logTunerStatus() {
 	channels, ok := s.store.GetM3U()
 	if !ok {
@@ -204,15 +362,46 @@ func (s *Server) logTunerStatus() {
 	}).Info("  All Channels")
 
 	// Per-group devices
-	groups := s.store.GetGroups()
+	groups := s.store.TunerGroups()
+	hidden := 0
 
 	for _, group := range groups {
 		groupChannels, _ := s.store.GetChannelsByGroup(group)
-		slug := hdhr.Slugify(group)
+		if len(groupChannels) < s.cfg.StatusMinChannels {
+			hidden++
+
+			continue
+		}
+
+		slug := s.store.GroupSlug(group)
 
 		s.log.WithFields(logrus.Fields{
 			"channels": len(groupChannels),
 			"url":      fmt.Sprintf("%s/%s/", s.cfg.BaseURL, slug),
 		}).Info("  " + group)
 	}
+
+	if hidden > 0 {
+		s.log.WithFields(logrus.Fields{
+			"groups":      hidden,
+			"minChannels": s.cfg.StatusMinChannels,
+		}).Info("  (smaller groups omitted)")
+	}
+
+	if untuned := len(s.store.GetGroups()) - len(groups); untuned > 0 {
+		s.log.WithFields(logrus.Fields{
+			"groups":         untuned,
+			"maxGroupTuners": s.cfg.MaxGroupTuners,
+		}).Info("  (groups beyond the tuner limit are only on All Channels)")
+	}
+
+	// Sub-tuners split from large groups
+	for _, shard := range s.store.Shards() {
+		shardChannels, _ := s.store.GetChannelsByShard(shard.Group, shard.Index)
+
+		s.log.WithFields(logrus.Fields{
+			"channels": len(shardChannels),
+			"url":      fmt.Sprintf("%s/%s/", s.cfg.BaseURL, shard.Slug),
+		}).Info("  " + shard.Name)
+	}
 }
@@ -0,0 +1,123 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/savid/iptv/internal/epg"
+	"github.com/savid/iptv/internal/m3u"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLogTunerStatus_MinChannels(t *testing.T) {
+	log, hook := newTestLogger()
+	cfg := newTestConfig()
+	cfg.StatusMinChannels = 2
+
+	srv, err := NewServer(log, cfg)
+	require.NoError(t, err)
+	srv.store.SetM3U([]m3u.Channel{
+		{Name: "ESPN", Group: "Sports"},
+		{Name: "FS1", Group: "Sports"},
+		{Name: "CNN", Group: "News"},
+	})
+
+	srv.logTunerStatus()
+
+	messages := make([]string, 0)
+	for _, entry := range hook.AllEntries() {
+		messages = append(messages, entry.Message)
+	}
+
+	require.Contains(t, messages, "  Sports")
+	require.NotContains(t, messages, "  News")
+	require.Contains(t, messages, "  (smaller groups omitted)")
+}
+
+func TestLogTunerSummary(t *testing.T) {
+	log, hook := newTestLogger()
+	cfg := newTestConfig()
+
+	srv, err := NewServer(log, cfg)
+	require.NoError(t, err)
+	srv.store.SetM3U([]m3u.Channel{
+		{Name: "ESPN", Group: "Sports"},
+		{Name: "CNN", Group: "News"},
+	})
+
+	srv.logTunerSummary()
+
+	entry := hook.LastEntry()
+	require.NotNil(t, entry)
+	require.Equal(t, "Tuner status", entry.Message)
+	require.Equal(t, 2, entry.Data["channels"])
+	require.Equal(t, 2, entry.Data["groups"])
+}
+
+func TestLogMatchSummary(t *testing.T) {
+	log, hook := newTestLogger()
+	srv, err := NewServer(log, newTestConfig())
+	require.NoError(t, err)
+
+	srv.logMatchSummary()
+	require.Nil(t, hook.LastEntry())
+
+	srv.store.SetMatchSummary(map[string]int{epg.MatchTVGID: 3, epg.MatchPlaceholder: 1})
+	srv.logMatchSummary()
+
+	entry := hook.LastEntry()
+	require.NotNil(t, entry)
+	require.Equal(t, "EPG match summary", entry.Message)
+	require.Equal(t, 3, entry.Data[epg.MatchTVGID])
+	require.Equal(t, 0, entry.Data[epg.MatchDisplayName])
+	require.Equal(t, 1, entry.Data[epg.MatchPlaceholder])
+}
+
+func TestStart_InitialFetchTimeout(t *testing.T) {
+	released := make(chan struct{})
+	upstream := httptest.NewServer(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-released:
+		}
+	}))
+	defer upstream.Close()
+	defer close(released)
+
+	log, _ := newTestLogger()
+	cfg := newTestConfig()
+	cfg.M3UURL = upstream.URL + "/playlist.m3u"
+	cfg.EPGURL = upstream.URL + "/epg.xml"
+	cfg.InitialFetchTimeout = 100 * time.Millisecond
+
+	srv, err := NewServer(log, cfg)
+	require.NoError(t, err)
+
+	start := time.Now()
+	err = srv.Start(context.Background())
+
+	require.Error(t, err)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	require.Less(t, time.Since(start), 5*time.Second)
+}
+
+func TestNewServer_ValidatesFormats(t *testing.T) {
+	log, _ := newTestLogger()
+
+	cfg := newTestConfig()
+	cfg.MatchOrder = []string{"normalized", "fuzzy"}
+
+	_, err := NewServer(log, cfg)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "invalid --match-order")
+
+	cfg = newTestConfig()
+	cfg.NumberFormat = "{group}"
+
+	_, err = NewServer(log, cfg)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "invalid --number-format")
+}